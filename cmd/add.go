@@ -6,35 +6,90 @@ import (
 	"path/filepath"
 	"strings"
 
+	"gogit/ignore"
 	"gogit/index"
 	"gogit/object"
 	"gogit/repo"
 )
 
 func Add(paths []string) error {
+	return add(paths, false)
+}
+
+// AddForce stages paths like Add, including files matched by ignore rules.
+func AddForce(paths []string) error {
+	return add(paths, true)
+}
+
+func add(paths []string, force bool) error {
 	root, err := repo.Find()
 	if err != nil {
 		return err
 	}
 
-	idx, err := index.ReadIndex(root)
+	idx, lock, err := index.ReadIndexLocked(root)
 	if err != nil {
 		return err
 	}
+	defer lock.Rollback()
+
+	// With force nothing is filtered, so no matcher is needed.
+	var matcher *ignore.Matcher
+	if !force {
+		if matcher, err = ignore.New(root); err != nil {
+			return err
+		}
+		var ignored []string
+		for _, p := range paths {
+			rel, isIgnored, err := explicitlyIgnored(root, idx, matcher, p)
+			if err != nil {
+				return err
+			}
+			if isIgnored {
+				ignored = append(ignored, rel)
+			}
+		}
+		if len(ignored) > 0 {
+			return fmt.Errorf("the following paths are ignored by one of your %s files:\n%s\nuse 'gogit add -f' if you really want to add them", ignore.FileName, strings.Join(ignored, "\n"))
+		}
+	}
 
 	for _, p := range paths {
-		if err := addPath(root, idx, p); err != nil {
+		if err := addPath(root, idx, matcher, p); err != nil {
 			return err
 		}
 	}
 
-	return index.WriteIndex(root, idx)
+	return lock.Commit(idx)
 }
 
 var absFunc = filepath.Abs
 var relFunc = filepath.Rel
 
-func addPath(root string, idx *index.Index, p string) error {
+// explicitlyIgnored reports whether a path named on the command line is
+// ignored and not tracked, returning it relative to the root.
+func explicitlyIgnored(root string, idx *index.Index, matcher *ignore.Matcher, p string) (string, bool, error) {
+	absPath, err := absFunc(p)
+	if err != nil {
+		return "", false, err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return "", false, nil
+	}
+	relPath, err := relFunc(root, absPath)
+	if err != nil {
+		return "", false, err
+	}
+	relPath = filepath.ToSlash(relPath)
+	if idx.Tracked(relPath) {
+		return relPath, false, nil
+	}
+	ignored, err := matcher.Ignored(relPath, info.IsDir())
+	return relPath, ignored, err
+}
+
+func addPath(root string, idx *index.Index, matcher *ignore.Matcher, p string) error {
 	// Make path relative to repo root
 	absPath, err := absFunc(p)
 	if err != nil {
@@ -43,55 +98,93 @@ func addPath(root string, idx *index.Index, p string) error {
 
 	info, err := os.Stat(absPath)
 	if err != nil {
-		// File was deleted — remove from index
+		// File or directory was deleted — remove it from the index
 		relPath, _ := filepath.Rel(root, absPath)
 		relPath = filepath.ToSlash(relPath)
 		idx.RemoveEntry(relPath)
+		idx.RemoveDir(relPath)
 		return nil
 	}
 
 	if info.IsDir() {
-		return addDir(root, idx, absPath)
+		return addDir(root, idx, matcher, absPath)
 	}
 
 	return addFile(root, idx, absPath, info)
 }
 
-func addDir(root string, idx *index.Index, dirPath string) error {
-	return filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+// addDir stages every file below dirPath. Untracked paths matched by the
+// ignore rules are skipped unless matcher is nil. The entries are added to
+// the index in one batch.
+func addDir(root string, idx *index.Index, matcher *ignore.Matcher, dirPath string) error {
+	var staged []index.Entry
+	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if info.IsDir() {
-			if info.Name() == repo.GogitDir {
-				return filepath.SkipDir
+		if info.IsDir() && info.Name() == repo.GogitDir {
+			return filepath.SkipDir
+		}
+		if matcher != nil && path != dirPath {
+			relPath, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
 			}
+			relPath = filepath.ToSlash(relPath)
+			ignored, err := matcher.Ignored(relPath, info.IsDir())
+			if err != nil {
+				return err
+			}
+			if ignored && !idx.Tracked(relPath) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if info.IsDir() {
 			return nil
 		}
-		return addFile(root, idx, path, info)
+		entry, ok, err := fileEntry(root, path, info)
+		if ok {
+			staged = append(staged, entry)
+		}
+		return err
 	})
+	idx.AddEntries(staged)
+	return err
 }
 
 func addFile(root string, idx *index.Index, absPath string, info os.FileInfo) error {
+	entry, ok, err := fileEntry(root, absPath, info)
+	if ok {
+		idx.AddEntry(entry)
+	}
+	return err
+}
+
+// fileEntry writes the blob for a working tree file and returns its index
+// entry. Files inside the .gogit directory are not staged (ok is false).
+func fileEntry(root, absPath string, info os.FileInfo) (entry index.Entry, ok bool, err error) {
 	relPath, err := relFunc(root, absPath)
 	if err != nil {
-		return err
+		return entry, false, err
 	}
 	relPath = filepath.ToSlash(relPath)
 
 	// Skip .gogit directory
-	if strings.HasPrefix(relPath, repo.GogitDir) {
-		return nil
+	if relPath == repo.GogitDir || strings.HasPrefix(relPath, repo.GogitDir+"/") {
+		return entry, false, nil
 	}
 
 	content, err := os.ReadFile(absPath)
 	if err != nil {
-		return err
+		return entry, false, err
 	}
 
 	hash, err := object.WriteBlob(root, content)
 	if err != nil {
-		return err
+		return entry, false, err
 	}
 
 	mode := uint32(0100644)
@@ -99,16 +192,8 @@ func addFile(root string, idx *index.Index, absPath string, info os.FileInfo) er
 		mode = 0100755
 	}
 
-	entry := index.Entry{
-		Ctime: uint32(info.ModTime().Unix()),
-		Mtime: uint32(info.ModTime().Unix()),
-		Size:  uint32(info.Size()),
-		Hash:  hash,
-		Mode:  mode,
-		Path:  relPath,
-	}
-
-	idx.AddEntry(entry)
+	entry = index.Entry{Hash: hash, Mode: mode, Path: relPath}
+	entry.SetStat(info)
 	fmt.Printf("add '%s'\n", relPath)
-	return nil
+	return entry, true, nil
 }
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"gogit/index"
+	"gogit/object"
 	"gogit/repo"
 )
 
@@ -257,7 +259,7 @@ func TestAddPath_AbsError(t *testing.T) {
 	defer func() { absFunc = origFn }()
 
 	idx, _ := index.ReadIndex(dir)
-	err := addPath(dir, idx, "file.txt")
+	err := addPath(dir, idx, nil, "file.txt")
 	if err == nil {
 		t.Fatal("expected error when Abs fails")
 	}
@@ -300,3 +302,131 @@ func TestAdd_WalkErrorInDir(t *testing.T) {
 	// Walk error may propagate or not depending on macOS permissions
 	_ = err
 }
+
+func TestAdd_DirectorySkipsIgnored(t *testing.T) {
+	dir := setupTestRepo(t)
+	os.WriteFile(filepath.Join(dir, ".gogitignore"), []byte("*.log\nnode_modules/\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "debug.log"), []byte("x"), 0644)
+	os.MkdirAll(filepath.Join(dir, "node_modules", "pkg"), 0755)
+	os.WriteFile(filepath.Join(dir, "node_modules", "pkg", "index.js"), []byte("x"), 0644)
+
+	if err := Add([]string{"."}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	idx, _ := index.ReadIndex(dir)
+	var paths []string
+	for _, e := range idx.Entries {
+		paths = append(paths, e.Path)
+	}
+	if strings.Join(paths, ",") != ".gogitignore,main.go" {
+		t.Errorf("expected only non-ignored files, got %v", paths)
+	}
+}
+
+func TestAdd_ExplicitIgnoredPath(t *testing.T) {
+	dir := setupTestRepo(t)
+	os.WriteFile(filepath.Join(dir, ".gogitignore"), []byte("*.log\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "debug.log"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("x"), 0644)
+
+	err := Add([]string{"main.go", "debug.log"})
+	if err == nil || !strings.Contains(err.Error(), "ignored by one of your .gogitignore files:\ndebug.log\n") {
+		t.Fatalf("expected ignored path error, got %v", err)
+	}
+	idx, _ := index.ReadIndex(dir)
+	if len(idx.Entries) != 0 {
+		t.Errorf("nothing should be staged, got %v", idx.Entries)
+	}
+
+	if err := AddForce([]string{"debug.log"}); err != nil {
+		t.Fatalf("AddForce failed: %v", err)
+	}
+	idx, _ = index.ReadIndex(dir)
+	if idx.LookupEntry("debug.log") == nil {
+		t.Fatal("forced add should stage the ignored file")
+	}
+
+	// Once tracked, the file is no longer treated as ignored.
+	os.WriteFile(filepath.Join(dir, "debug.log"), []byte("changed"), 0644)
+	if err := Add([]string{"debug.log", "."}); err != nil {
+		t.Fatalf("Add of tracked ignored file failed: %v", err)
+	}
+}
+
+func TestAddForce_Directory(t *testing.T) {
+	dir := setupTestRepo(t)
+	os.WriteFile(filepath.Join(dir, ".gogitignore"), []byte("out/\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "out"), 0755)
+	os.WriteFile(filepath.Join(dir, "out", "a.bin"), []byte("x"), 0644)
+
+	if err := Add([]string{"out"}); err == nil {
+		t.Fatal("expected error adding an ignored directory")
+	}
+	if err := AddForce([]string{"out"}); err != nil {
+		t.Fatalf("AddForce failed: %v", err)
+	}
+	idx, _ := index.ReadIndex(dir)
+	if idx.LookupEntry("out/a.bin") == nil {
+		t.Error("forced add should stage files in an ignored directory")
+	}
+
+	// Tracked files inside an ignored directory are still updated.
+	os.WriteFile(filepath.Join(dir, "out", "a.bin"), []byte("y"), 0644)
+	os.WriteFile(filepath.Join(dir, "out", "b.bin"), []byte("y"), 0644)
+	if err := Add([]string{"."}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	idx, _ = index.ReadIndex(dir)
+	if e := idx.LookupEntry("out/a.bin"); e == nil || e.Hash != object.HashBlob([]byte("y")) {
+		t.Error("tracked file in ignored directory should be updated")
+	}
+	if idx.LookupEntry("out/b.bin") != nil {
+		t.Error("untracked file in ignored directory should be skipped")
+	}
+}
+
+func TestAdd_IgnoreRulesError(t *testing.T) {
+	dir := setupTestRepo(t)
+	os.MkdirAll(filepath.Join(dir, ".gogit", "info", "exclude"), 0755)
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("x"), 0644)
+
+	if err := Add([]string{"f.txt"}); err == nil {
+		t.Error("expected error when ignore rules cannot be read")
+	}
+}
+
+func TestAdd_IndexLocked(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, ".gogit", "index.lock"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("changed\n"), 0644)
+
+	err := Add([]string{"test.txt"})
+	if err == nil || !strings.Contains(err.Error(), "another gogit process seems to be running") {
+		t.Fatalf("expected lock error, got %v", err)
+	}
+	os.Remove(filepath.Join(dir, ".gogit", "index.lock"))
+	idx, _ := index.ReadIndex(dir)
+	if idx.LookupEntry("test.txt").Hash != object.HashBlob([]byte("hello\n")) {
+		t.Error("index should be unchanged")
+	}
+}
+
+func TestAdd_DeletedDirectory(t *testing.T) {
+	dir := setupTestRepo(t)
+	os.MkdirAll(filepath.Join(dir, "sub", "deep"), 0755)
+	os.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(dir, "sub", "deep", "b.txt"), []byte("b"), 0644)
+	os.WriteFile(filepath.Join(dir, "sub.txt"), []byte("c"), 0644)
+	Add([]string{"."})
+
+	os.RemoveAll(filepath.Join(dir, "sub"))
+	if err := Add([]string{"sub"}); err != nil {
+		t.Fatalf("Add failed for deleted directory: %v", err)
+	}
+
+	idx, _ := index.ReadIndex(dir)
+	if len(idx.Entries) != 1 || idx.Entries[0].Path != "sub.txt" {
+		t.Errorf("expected only sub.txt to remain, got %+v", idx.Entries)
+	}
+}
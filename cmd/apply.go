@@ -0,0 +1,558 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gogit/index"
+	"gogit/object"
+	"gogit/refs"
+	"gogit/repo"
+)
+
+// stdin is where Apply reads a patch when no file is named.
+var stdin io.Reader = os.Stdin
+
+// ApplyOptions selects what Apply patches and how.
+type ApplyOptions struct {
+	// Check only reports whether the patch applies, changing nothing.
+	Check bool
+	// Cached patches the index alone, leaving the working tree as it is.
+	Cached bool
+	// Index patches the index and the working tree, which must match the
+	// index for every file the patch touches.
+	Index bool
+	// ThreeWay falls back to a three-way merge for a file whose hunks do
+	// not apply, with the blob the patch was made against, as named by its
+	// "index" line, as the base. Conflicts are left in the file and as
+	// index stages. It implies Index unless Cached is set.
+	ThreeWay bool
+	// Reverse undoes the patch.
+	Reverse bool
+	// Fuzz lets a hunk that does not apply with all its context apply
+	// without its outermost context lines, dropped one at a time while
+	// more than MinContext are left on a side.
+	Fuzz       bool
+	MinContext int
+}
+
+// patchApplier applies file patches in memory, keeping the content of
+// every file they touched so that a later patch to the same file sees the
+// earlier ones, and nothing is written unless they all apply.
+type patchApplier struct {
+	root     string
+	opts     ApplyOptions
+	algo     diffAlgorithm
+	idx      *index.Index // nil when only the working tree is patched
+	worktree bool         // read and write the working tree
+	// files maps each path the patches touched to its new state, or to nil
+	// when they deleted it, and order lists the paths as first touched.
+	files     map[string]*patchedFile
+	order     []string
+	conflicts []string
+}
+
+// patchedFile is the content and mode of a file after the patches applied
+// so far.
+type patchedFile struct {
+	content []byte
+	mode    string
+	// stages holds the base, ours and theirs content of a file whose
+	// three-way merge conflicted, when content holds the conflict markers.
+	stages *[3][]byte
+}
+
+// Apply applies the patches in the named files, or in standard input when
+// none is named, to the working tree, or to the index with opts.Cached.
+// Patches in git's format may also create, delete and rename files and
+// change their mode. A hunk whose context is not found where the patch
+// says is applied at the nearest place it is found. Every file is
+// patched in memory first, so nothing changes unless the whole patch
+// applies.
+func Apply(opts ApplyOptions, patchFiles ...string) error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+	if len(patchFiles) == 0 {
+		patchFiles = []string{"-"}
+	}
+	var patches []*filePatch
+	for _, name := range patchFiles {
+		var data []byte
+		if name == "-" {
+			data, err = io.ReadAll(stdin)
+		} else {
+			data, err = os.ReadFile(name)
+		}
+		if err != nil {
+			return err
+		}
+		parsed, err := parsePatch(data)
+		if err != nil {
+			return err
+		}
+		patches = append(patches, parsed...)
+	}
+
+	algo, err := lookupDiffAlgorithm(root, "")
+	if err != nil {
+		return err
+	}
+	a := &patchApplier{
+		root:     root,
+		opts:     opts,
+		algo:     algo,
+		worktree: !opts.Cached,
+		files:    make(map[string]*patchedFile),
+	}
+	var lock *index.Lock
+	if opts.Cached || opts.Index || opts.ThreeWay {
+		if opts.Check {
+			a.idx, err = index.ReadIndex(root)
+		} else {
+			a.idx, lock, err = index.ReadIndexLocked(root)
+		}
+		if err != nil {
+			return err
+		}
+		if lock != nil {
+			defer lock.Rollback()
+		}
+	}
+
+	for _, f := range patches {
+		if opts.Reverse {
+			f = f.reversed()
+		}
+		if err := a.apply(f); err != nil {
+			return err
+		}
+	}
+	if !opts.Check {
+		if err := a.write(); err != nil {
+			return err
+		}
+		if lock != nil {
+			if err := lock.Commit(a.idx); err != nil {
+				return err
+			}
+		}
+	}
+	if len(a.conflicts) > 0 {
+		for _, path := range a.conflicts {
+			fmt.Printf("U %s\n", path)
+		}
+		return fmt.Errorf("patch applied with conflicts")
+	}
+	return nil
+}
+
+// where names what the patch is applied to, for errors.
+func (a *patchApplier) where() string {
+	if a.idx != nil {
+		return "index"
+	}
+	return "working directory"
+}
+
+// current returns the state of path before the next patch: as the patches
+// so far left it, or else as the index or the working tree holds it. It
+// returns nil for a file that does not exist.
+func (a *patchApplier) current(path string) (*patchedFile, error) {
+	if f, ok := a.files[path]; ok {
+		return f, nil
+	}
+	if a.idx != nil {
+		e := a.idx.LookupEntry(path)
+		if e == nil {
+			return nil, nil
+		}
+		if a.worktree {
+			state, _, err := checkWorktree(a.root, a.idx, e)
+			if err != nil {
+				return nil, err
+			}
+			if state != worktreeUnchanged {
+				return nil, fmt.Errorf("%s: does not match index", path)
+			}
+		}
+		content, err := object.ReadBlob(a.root, e.Hash)
+		if err != nil {
+			return nil, err
+		}
+		return &patchedFile{content: content, mode: fmt.Sprintf("%o", e.Mode)}, nil
+	}
+	absPath := filepath.Join(a.root, path)
+	info, err := os.Stat(absPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s: is a directory", path)
+	}
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	return &patchedFile{content: content, mode: worktreeMode(info)}, nil
+}
+
+// set records the state of path after a patch, nil if it was deleted.
+func (a *patchApplier) set(path string, f *patchedFile) {
+	if _, ok := a.files[path]; !ok {
+		a.order = append(a.order, path)
+	}
+	a.files[path] = f
+}
+
+// apply applies the patch of one file to the state the earlier patches
+// left.
+func (a *patchApplier) apply(f *filePatch) error {
+	for _, path := range []string{f.oldPath, f.newPath} {
+		if !validPatchPath(path) {
+			return fmt.Errorf("invalid path '%s'", path)
+		}
+	}
+
+	var old *patchedFile
+	if !f.isNew {
+		var err error
+		if old, err = a.current(f.oldPath); err != nil {
+			return err
+		}
+		if old == nil {
+			return fmt.Errorf("%s: does not exist in %s", f.oldPath, a.where())
+		}
+		if f.oldMode != "" && f.oldMode != old.mode {
+			fmt.Printf("warning: %s has type %s, expected %s\n", f.oldPath, old.mode, f.oldMode)
+		}
+	}
+	if f.isNew || f.oldPath != f.newPath {
+		existing, err := a.current(f.newPath)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fmt.Errorf("%s: already exists in %s", f.newPath, a.where())
+		}
+	}
+
+	var pre []byte
+	mode := f.newMode
+	if old != nil {
+		pre = old.content
+		if mode == "" {
+			mode = old.mode
+		}
+	}
+	if mode == "" {
+		mode = "100644"
+	}
+	patched := &patchedFile{mode: mode}
+	var err error
+	if patched.content, err = a.patchContent(f, pre); err != nil {
+		if !a.opts.ThreeWay {
+			return err
+		}
+		if err = a.threeWay(f, old, patched, err); err != nil {
+			return err
+		}
+	}
+
+	if f.isDelete {
+		if len(patched.content) > 0 {
+			return fmt.Errorf("%s: removal patch leaves file contents", f.oldPath)
+		}
+		a.set(f.oldPath, nil)
+		return nil
+	}
+	if f.isRename {
+		a.set(f.oldPath, nil)
+	}
+	a.set(f.newPath, patched)
+	return nil
+}
+
+// validPatchPath reports whether a path named by a patch stays inside the
+// working tree and out of the repository directory.
+func validPatchPath(path string) bool {
+	if path == "" || strings.HasPrefix(path, "/") {
+		return false
+	}
+	for _, part := range strings.Split(path, "/") {
+		if part == "" || part == "." || part == ".." || part == repo.GogitDir {
+			return false
+		}
+	}
+	return true
+}
+
+// patchContent returns what the hunks or the binary patch of f make of
+// pre.
+func (a *patchApplier) patchContent(f *filePatch, pre []byte) ([]byte, error) {
+	if f.binary {
+		return a.patchBinary(f, pre)
+	}
+	lines := splitLines(string(pre))
+	for _, h := range f.hunks {
+		var ok bool
+		if lines, ok = applyHunk(lines, h, a.opts.Fuzz, a.opts.MinContext); !ok {
+			return nil, fmt.Errorf("patch failed: %s:%d", f.oldPath, h.oldStart)
+		}
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// applyHunk replaces the old lines of h in image with its new ones, where
+// they are found nearest to the line the hunk names. A hunk that starts at
+// the first line must match at the start of image, and one without
+// trailing context at its end. With fuzz, a hunk that is not found is
+// looked for again without those constraints and then without its
+// outermost context lines, dropped from the side with more of them, as
+// long as more than minContext are left, and says so when it applies.
+func applyHunk(image []string, h patchHunk, fuzz bool, minContext int) ([]string, bool) {
+	var pre, post []string
+	for _, l := range h.lines {
+		if l.op != '+' {
+			pre = append(pre, l.text)
+		}
+		if l.op != '-' {
+			post = append(post, l.text)
+		}
+	}
+	leading, trailing := 0, 0
+	for leading < len(h.lines) && h.lines[leading].op == ' ' {
+		leading++
+	}
+	for trailing < len(h.lines)-leading && h.lines[len(h.lines)-1-trailing].op == ' ' {
+		trailing++
+	}
+
+	// Earlier hunks have been applied, so the hunk is expected where the
+	// new file has it.
+	pos := max(h.newStart-1, 0)
+	atStart, atEnd := h.oldStart <= 1, trailing == 0
+	for {
+		if at, ok := findLines(image, pre, pos, atStart, atEnd); ok {
+			if len(pre) < h.oldCount {
+				fmt.Printf("Context reduced to (%d/%d) to apply fragment at %d\n", leading, trailing, at+1)
+			}
+			return slices.Replace(image, at, at+len(pre), post...), true
+		}
+		if !fuzz || leading <= minContext && trailing <= minContext {
+			return nil, false
+		}
+		if atStart || atEnd {
+			atStart, atEnd = false, false
+			continue
+		}
+		if leading >= trailing {
+			pre, post = pre[1:], post[1:]
+			leading--
+			pos++
+		}
+		if trailing > leading {
+			pre, post = pre[:len(pre)-1], post[:len(post)-1]
+			trailing--
+		}
+	}
+}
+
+// findLines returns where lines occur in image, trying pos and then the
+// positions around it, nearest first. With atStart they must be at the
+// start of image, and with atEnd at its end.
+func findLines(image, lines []string, pos int, atStart, atEnd bool) (int, bool) {
+	last := len(image) - len(lines)
+	if last < 0 {
+		return 0, false
+	}
+	matches := func(at int) bool { return equalLines(image[at:at+len(lines)], lines) }
+	switch {
+	case atStart && atEnd:
+		return 0, last == 0 && matches(0)
+	case atStart:
+		return 0, matches(0)
+	case atEnd:
+		return last, matches(last)
+	}
+	pos = min(pos, last)
+	for d := 0; pos-d >= 0 || pos+d <= last; d++ {
+		if pos-d >= 0 && matches(pos-d) {
+			return pos - d, true
+		}
+		if d > 0 && pos+d <= last && matches(pos+d) {
+			return pos + d, true
+		}
+	}
+	return 0, false
+}
+
+// patchBinary applies the binary patch of f to pre, checking pre and the
+// result against full blob ids in the index line. A patch that only says
+// the files differ applies when the repository holds the new blob.
+func (a *patchApplier) patchBinary(f *filePatch, pre []byte) ([]byte, error) {
+	full := len(refs.ZeroHash)
+	if len(f.oldHash) == full && object.HashBlob(pre) != f.oldHash {
+		return nil, fmt.Errorf("the patch applies to '%s' (%s), which does not match the current contents", f.oldPath, f.oldHash)
+	}
+
+	var result []byte
+	var err error
+	switch {
+	case f.forward == nil && f.reverse != nil:
+		return nil, fmt.Errorf("cannot reverse-apply a binary patch without the reverse hunk to '%s'", f.newPath)
+	case f.forward == nil && f.isDelete:
+	case f.forward == nil:
+		if len(f.newHash) != full || !object.HasObject(a.root, f.newHash) {
+			return nil, fmt.Errorf("cannot apply binary patch to '%s' without full index line", f.newPath)
+		}
+		result, err = object.ReadBlob(a.root, f.newHash)
+	case f.forward.delta:
+		result, err = object.ApplyDelta(pre, f.forward.data)
+	default:
+		result = f.forward.data
+	}
+	if err != nil {
+		return nil, fmt.Errorf("binary patch does not apply to '%s': %v", f.oldPath, err)
+	}
+
+	if len(f.newHash) == full && object.HashBlob(result) != f.newHash {
+		return nil, fmt.Errorf("binary patch to '%s' creates incorrect result (expecting %s, got %s)", f.newPath, f.newHash, object.HashBlob(result))
+	}
+	return result, nil
+}
+
+// threeWay merges the change f makes to the blob it was made against into
+// old, for a file whose hunks did not apply with patchErr, and stores the
+// result in patched. Without that blob, or for a file that is created,
+// deleted or binary, patchErr is returned instead.
+func (a *patchApplier) threeWay(f *filePatch, old, patched *patchedFile, patchErr error) error {
+	if f.binary || f.isNew || f.isDelete || f.oldHash == "" {
+		return patchErr
+	}
+	fmt.Printf("%v\nFalling back to three-way merge...\n", patchErr)
+	baseHash, ok := a.findBlob(f.oldHash)
+	if !ok {
+		fmt.Println("repository lacks the necessary blob to fall back on 3-way merge.")
+		return patchErr
+	}
+	base, err := object.ReadBlob(a.root, baseHash)
+	if err != nil {
+		return err
+	}
+	theirs, err := a.patchContent(f, base)
+	if err != nil || isBinary(base) || isBinary(old.content) || isBinary(theirs) {
+		fmt.Printf("Failed to fall back on three-way merge...\n")
+		return patchErr
+	}
+
+	merged, conflict := mergeLines(a.algo, string(base), string(old.content), string(theirs), "ours", "theirs")
+	patched.content = []byte(merged)
+	if !conflict {
+		fmt.Printf("Applied patch to '%s' cleanly.\n", f.newPath)
+		return nil
+	}
+	fmt.Printf("Applied patch to '%s' with conflicts.\n", f.newPath)
+	patched.stages = &[3][]byte{base, old.content, theirs}
+	a.conflicts = append(a.conflicts, f.newPath)
+	return nil
+}
+
+// findBlob returns the blob an index line names, possibly abbreviated, if
+// the repository holds it.
+func (a *patchApplier) findBlob(hash string) (string, bool) {
+	if len(hash) == len(refs.ZeroHash) {
+		return hash, object.HasObject(a.root, hash)
+	}
+	matches, err := object.FindObjects(a.root, hash)
+	if err != nil || len(matches) != 1 {
+		return "", false
+	}
+	return matches[0], true
+}
+
+// write writes the patched files to the working tree and the index.
+func (a *patchApplier) write() error {
+	for _, path := range a.order {
+		f := a.files[path]
+		if a.worktree {
+			if err := a.writeFile(path, f); err != nil {
+				return err
+			}
+		}
+		if a.idx != nil {
+			if err := a.stage(path, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeFile writes a patched file to the working tree, or removes it, along
+// with any directories that leaves empty.
+func (a *patchApplier) writeFile(path string, f *patchedFile) error {
+	absPath := filepath.Join(a.root, path)
+	if f == nil {
+		if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		cleanEmptyDirs(a.root, filepath.Dir(absPath))
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return err
+	}
+	perm := os.FileMode(0644)
+	if f.mode == "100755" {
+		perm = 0755
+	}
+	if err := os.WriteFile(absPath, f.content, perm); err != nil {
+		return err
+	}
+	return os.Chmod(absPath, perm)
+}
+
+// stage records a patched file in the index, or removes it. A conflicted
+// file gets its three stages.
+func (a *patchApplier) stage(path string, f *patchedFile) error {
+	if f == nil {
+		a.idx.RemoveEntry(path)
+		return nil
+	}
+	mode, err := strconv.ParseUint(f.mode, 8, 32)
+	if err != nil {
+		return fmt.Errorf("%s: invalid mode %s", path, f.mode)
+	}
+	if f.stages != nil {
+		for i, content := range f.stages {
+			hash, err := object.WriteBlob(a.root, content)
+			if err != nil {
+				return err
+			}
+			a.idx.AddEntry(index.Entry{Hash: hash, Mode: uint32(mode), Path: path, Stage: index.StageBase + i})
+		}
+		return nil
+	}
+	hash, err := object.WriteBlob(a.root, f.content)
+	if err != nil {
+		return err
+	}
+	e := index.Entry{Hash: hash, Mode: uint32(mode), Path: path}
+	if a.worktree {
+		if info, err := os.Stat(filepath.Join(a.root, path)); err == nil {
+			e.SetStat(info)
+		}
+	}
+	a.idx.AddEntry(e)
+	return nil
+}
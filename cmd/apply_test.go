@@ -0,0 +1,404 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gogit/index"
+	"gogit/object"
+)
+
+// writePatch saves a patch in the repository's parent directory and
+// returns its path.
+func writePatch(t *testing.T, dir, patch string) string {
+	t.Helper()
+	path := filepath.Join(filepath.Dir(dir), filepath.Base(dir)+".patch")
+	if err := os.WriteFile(path, []byte(patch), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// worktreePatch writes files into the working tree, returns the patch Diff
+// prints for them and puts back what the files held before.
+func worktreePatch(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+	saved := make(map[string][]byte)
+	for name, content := range files {
+		saved[name], _ = os.ReadFile(filepath.Join(dir, name))
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	patch, err := captureStdout(t, func() error { return Diff(DiffOptions{}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range saved {
+		os.WriteFile(filepath.Join(dir, name), content, 0644)
+	}
+	return patch
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// numberedText returns the lines of numberedLines as one text.
+func numberedText(n int, edits map[int]string) string {
+	return strings.Join(numberedLines(n, edits), "")
+}
+
+func TestApply_Worktree(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	patch := worktreePatch(t, dir, map[string]string{"test.txt": "hello world\n"})
+
+	if _, err := captureStdout(t, func() error { return Apply(ApplyOptions{}, writePatch(t, dir, patch)) }); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "test.txt")); got != "hello world\n" {
+		t.Errorf("unexpected content %q", got)
+	}
+	idx, _ := index.ReadIndex(dir)
+	if e := idx.LookupEntry("test.txt"); e.Hash != object.HashBlob([]byte("hello\n")) {
+		t.Error("applying to the working tree should leave the index alone")
+	}
+}
+
+func TestApply_FileOperations(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "old.txt"), []byte("a\nb\nc\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "tool"), []byte("run\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "gone.txt"), []byte("bye\n"), 0644)
+
+	patch := `diff --git a/new/file.txt b/new/file.txt
+new file mode 100755
+--- /dev/null
++++ b/new/file.txt
+@@ -0,0 +1,2 @@
++first
++second
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+diff --git a/old.txt b/renamed.txt
+similarity index 80%
+rename from old.txt
+rename to renamed.txt
+--- a/old.txt
++++ b/renamed.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+diff --git a/tool b/tool
+old mode 100644
+new mode 100755
+`
+	if _, err := captureStdout(t, func() error { return Apply(ApplyOptions{}, writePatch(t, dir, patch)) }); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "new", "file.txt")); got != "first\nsecond\n" {
+		t.Errorf("unexpected new file %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "renamed.txt")); got != "a\nB\nc\n" {
+		t.Errorf("unexpected renamed file %q", got)
+	}
+	for _, name := range []string{"gone.txt", "old.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", name)
+		}
+	}
+	for _, name := range []string{"tool", "new/file.txt"} {
+		if info, _ := os.Stat(filepath.Join(dir, name)); info.Mode()&0111 == 0 {
+			t.Errorf("%s should be executable", name)
+		}
+	}
+}
+
+func TestApply_Offset(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "n.txt"), []byte(numberedText(20, nil)), 0644)
+	Add([]string{"n.txt"})
+	patch := worktreePatch(t, dir, map[string]string{"n.txt": numberedText(20, map[int]string{10: "ten"})})
+
+	// Lines added above the hunk move it down.
+	os.WriteFile(filepath.Join(dir, "n.txt"), []byte("x\ny\n"+numberedText(20, nil)), 0644)
+	if _, err := captureStdout(t, func() error { return Apply(ApplyOptions{}, writePatch(t, dir, patch)) }); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "n.txt")); got != "x\ny\n"+numberedText(20, map[int]string{10: "ten"}) {
+		t.Errorf("unexpected content %q", got)
+	}
+}
+
+func TestApply_Fuzz(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "n.txt"), []byte(numberedText(20, nil)), 0644)
+	Add([]string{"n.txt"})
+	patch := writePatch(t, dir, worktreePatch(t, dir, map[string]string{"n.txt": numberedText(20, map[int]string{10: "ten"})}))
+
+	// A changed context line two lines from the change.
+	changed := numberedText(20, map[int]string{8: "eight"})
+	os.WriteFile(filepath.Join(dir, "n.txt"), []byte(changed), 0644)
+	if _, err := captureStdout(t, func() error { return Apply(ApplyOptions{}, patch) }); err == nil || err.Error() != "patch failed: n.txt:7" {
+		t.Fatalf("expected the patch to fail, got %v", err)
+	}
+	if _, err := captureStdout(t, func() error { return Apply(ApplyOptions{Fuzz: true, MinContext: 2}, patch) }); err == nil {
+		t.Fatal("expected two lines of context to be required")
+	}
+	out, err := captureStdout(t, func() error { return Apply(ApplyOptions{Fuzz: true, MinContext: 1}, patch) })
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if out != "Context reduced to (1/1) to apply fragment at 9\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	if got := readFile(t, filepath.Join(dir, "n.txt")); got != numberedText(20, map[int]string{8: "eight", 10: "ten"}) {
+		t.Errorf("unexpected content %q", got)
+	}
+}
+
+func TestApply_CheckAndAllOrNothing(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "other.txt"), []byte("other\n"), 0644)
+	Add([]string{"other.txt"})
+	patch := writePatch(t, dir, worktreePatch(t, dir, map[string]string{"test.txt": "hello world\n", "other.txt": "changed\n"}))
+
+	if err := Apply(ApplyOptions{Check: true}, patch); err != nil {
+		t.Fatalf("Apply --check failed: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "test.txt")); got != "hello\n" {
+		t.Errorf("--check should change nothing, got %q", got)
+	}
+
+	// other.txt no longer matches, so test.txt is not patched either.
+	os.WriteFile(filepath.Join(dir, "other.txt"), []byte("something else\n"), 0644)
+	if err := Apply(ApplyOptions{}, patch); err == nil || !strings.Contains(err.Error(), "other.txt") {
+		t.Fatalf("expected other.txt to fail, got %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "test.txt")); got != "hello\n" {
+		t.Errorf("a failed patch should change nothing, got %q", got)
+	}
+}
+
+func TestApply_Cached(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	patch := writePatch(t, dir, worktreePatch(t, dir, map[string]string{"test.txt": "hello world\n"}))
+	// The working tree does not matter with --cached.
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("unrelated\n"), 0644)
+
+	if err := Apply(ApplyOptions{Cached: true}, patch); err != nil {
+		t.Fatalf("Apply --cached failed: %v", err)
+	}
+	idx, _ := index.ReadIndex(dir)
+	if e := idx.LookupEntry("test.txt"); e.Hash != object.HashBlob([]byte("hello world\n")) {
+		t.Error("the index should hold the patched content")
+	}
+	if got := readFile(t, filepath.Join(dir, "test.txt")); got != "unrelated\n" {
+		t.Errorf("--cached should leave the working tree alone, got %q", got)
+	}
+}
+
+func TestApply_Index(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	patch := writePatch(t, dir, worktreePatch(t, dir, map[string]string{"test.txt": "hello world\n"}))
+
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("unrelated\n"), 0644)
+	if err := Apply(ApplyOptions{Index: true}, patch); err == nil || err.Error() != "test.txt: does not match index" {
+		t.Fatalf("expected a mismatch with the index, got %v", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("hello\n"), 0644)
+	if err := Apply(ApplyOptions{Index: true}, patch); err != nil {
+		t.Fatalf("Apply --index failed: %v", err)
+	}
+	idx, _ := index.ReadIndex(dir)
+	e := idx.LookupEntry("test.txt")
+	if e.Hash != object.HashBlob([]byte("hello world\n")) {
+		t.Error("the index should hold the patched content")
+	}
+	if state, _, _ := checkWorktree(dir, idx, e); state != worktreeUnchanged {
+		t.Error("the working tree should match the index")
+	}
+}
+
+func TestApply_Reverse(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	patch := writePatch(t, dir, worktreePatch(t, dir, map[string]string{"test.txt": "hello world\n"}))
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("hello world\n"), 0644)
+
+	if err := Apply(ApplyOptions{Reverse: true}, patch); err != nil {
+		t.Fatalf("Apply -R failed: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "test.txt")); got != "hello\n" {
+		t.Errorf("unexpected content %q", got)
+	}
+	if err := Apply(ApplyOptions{Reverse: true}, patch); err == nil {
+		t.Error("expected an already reversed patch to fail")
+	}
+}
+
+func TestApply_ReverseCreation(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	patch := writePatch(t, dir, "diff --git a/n b/n\nnew file mode 100644\n--- /dev/null\n+++ b/n\n@@ -0,0 +1 @@\n+n\n")
+	if err := Apply(ApplyOptions{}, patch); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if err := Apply(ApplyOptions{}, patch); err == nil || err.Error() != "n: already exists in working directory" {
+		t.Errorf("expected the file to exist, got %v", err)
+	}
+	if err := Apply(ApplyOptions{Reverse: true}, patch); err != nil {
+		t.Fatalf("Apply -R failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "n")); !os.IsNotExist(err) {
+		t.Error("reversing a creation should remove the file")
+	}
+}
+
+func TestApply_ThreeWay(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "n.txt"), []byte(numberedText(12, nil)), 0644)
+	Add([]string{"n.txt"})
+	Commit("numbers")
+	patch := writePatch(t, dir, worktreePatch(t, dir, map[string]string{"n.txt": numberedText(12, map[int]string{3: "three"})}))
+
+	ours := numberedText(12, map[int]string{6: "six"})
+	os.WriteFile(filepath.Join(dir, "n.txt"), []byte(ours), 0644)
+	Add([]string{"n.txt"})
+	Commit("six")
+
+	if _, err := captureStdout(t, func() error { return Apply(ApplyOptions{}, patch) }); err == nil {
+		t.Fatal("expected the hunk not to apply")
+	}
+	out, err := captureStdout(t, func() error { return Apply(ApplyOptions{ThreeWay: true}, patch) })
+	if err != nil {
+		t.Fatalf("Apply --3way failed: %v", err)
+	}
+	if out != "patch failed: n.txt:1\nFalling back to three-way merge...\nApplied patch to 'n.txt' cleanly.\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	want := numberedText(12, map[int]string{3: "three", 6: "six"})
+	if got := readFile(t, filepath.Join(dir, "n.txt")); got != want {
+		t.Errorf("unexpected merge %q", got)
+	}
+	idx, _ := index.ReadIndex(dir)
+	if e := idx.LookupEntry("n.txt"); e == nil || e.Hash != object.HashBlob([]byte(want)) {
+		t.Error("--3way should stage the merged content")
+	}
+}
+
+func TestApply_ThreeWayConflict(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	patch := writePatch(t, dir, worktreePatch(t, dir, map[string]string{"test.txt": "patched\n"}))
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("ours\n"), 0644)
+	Add([]string{"test.txt"})
+	Commit("ours")
+
+	out, err := captureStdout(t, func() error { return Apply(ApplyOptions{ThreeWay: true}, patch) })
+	if err == nil || err.Error() != "patch applied with conflicts" {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if !strings.HasSuffix(out, "Applied patch to 'test.txt' with conflicts.\nU test.txt\n") {
+		t.Errorf("unexpected output %q", out)
+	}
+	want := "<<<<<<< ours\nours\n=======\npatched\n>>>>>>> theirs\n"
+	if got := readFile(t, filepath.Join(dir, "test.txt")); got != want {
+		t.Errorf("unexpected conflict %q", got)
+	}
+	idx, _ := index.ReadIndex(dir)
+	base, ourEntry, theirs := idx.ConflictStages("test.txt")
+	if base == nil || ourEntry == nil || theirs == nil ||
+		base.Hash != object.HashBlob([]byte("hello\n")) || ourEntry.Hash != object.HashBlob([]byte("ours\n")) ||
+		theirs.Hash != object.HashBlob([]byte("patched\n")) {
+		t.Errorf("expected the three stages, got %+v %+v %+v", base, ourEntry, theirs)
+	}
+}
+
+func TestApply_ThreeWayMissingBlob(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	patch := worktreePatch(t, dir, map[string]string{"test.txt": "patched\n"})
+	patch = strings.Replace(patch, "index "+object.HashBlob([]byte("hello\n"))[:shortHashLen], "index 1234567", 1)
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("ours\n"), 0644)
+	Add([]string{"test.txt"})
+
+	out, err := captureStdout(t, func() error { return Apply(ApplyOptions{ThreeWay: true}, writePatch(t, dir, patch)) })
+	if err == nil || err.Error() != "patch failed: test.txt:1" {
+		t.Fatalf("expected the patch to fail, got %v", err)
+	}
+	if !strings.Contains(out, "repository lacks the necessary blob") {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestApply_Binary(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "x.bin"), []byte("a\x00b"), 0644)
+	Add([]string{"x.bin"})
+	Commit("binary")
+	os.WriteFile(filepath.Join(dir, "x.bin"), []byte("a\x00c"), 0644)
+	binary, _ := captureStdout(t, func() error { return Diff(DiffOptions{Binary: true}) })
+	plain, _ := captureStdout(t, func() error { return Diff(DiffOptions{}) })
+	os.WriteFile(filepath.Join(dir, "x.bin"), []byte("a\x00b"), 0644)
+
+	if err := Apply(ApplyOptions{}, writePatch(t, dir, plain)); err == nil || err.Error() != "cannot apply binary patch to 'x.bin' without full index line" {
+		t.Errorf("expected the patch to need the data, got %v", err)
+	}
+	patch := writePatch(t, dir, binary)
+	if err := Apply(ApplyOptions{}, patch); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "x.bin")); got != "a\x00c" {
+		t.Errorf("unexpected content %q", got)
+	}
+	if err := Apply(ApplyOptions{}, patch); err == nil || !strings.Contains(err.Error(), "does not match the current contents") {
+		t.Errorf("expected the applied patch to be refused, got %v", err)
+	}
+	if err := Apply(ApplyOptions{Reverse: true}, patch); err != nil {
+		t.Fatalf("Apply -R failed: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "x.bin")); got != "a\x00b" {
+		t.Errorf("unexpected content %q", got)
+	}
+}
+
+func TestApply_Errors(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	tests := []struct{ patch, want string }{
+		{"diff --git a/missing b/missing\n--- a/missing\n+++ b/missing\n@@ -1 +1 @@\n-a\n+b\n",
+			"missing: does not exist in working directory"},
+		{"diff --git a/test.txt b/test.txt\ndeleted file mode 100644\n--- a/test.txt\n+++ /dev/null\n",
+			"test.txt: removal patch leaves file contents"},
+		{"--- a/../outside\n+++ b/../outside\n@@ -0,0 +1 @@\n+x\n", "invalid path '../outside'"},
+		{"diff --git a/.gogit/HEAD b/.gogit/HEAD\n--- a/.gogit/HEAD\n+++ b/.gogit/HEAD\n@@ -1 +1 @@\n-a\n+b\n",
+			"invalid path '.gogit/HEAD'"},
+	}
+	for _, tt := range tests {
+		if err := Apply(ApplyOptions{}, writePatch(t, dir, tt.patch)); err == nil || err.Error() != tt.want {
+			t.Errorf("expected %q, got %v", tt.want, err)
+		}
+	}
+}
+
+func TestApply_Stdin(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	orig := stdin
+	defer func() { stdin = orig }()
+	stdin = strings.NewReader(worktreePatch(t, dir, map[string]string{"test.txt": "from stdin\n"}))
+
+	if err := Apply(ApplyOptions{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "test.txt")); got != "from stdin\n" {
+		t.Errorf("unexpected content %q", got)
+	}
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+)
+
+// binaryCheckLen is how much of a file isBinary looks at, as in git.
+const binaryCheckLen = 8000
+
+// isBinary reports whether content looks like binary data rather than
+// text: its first 8000 bytes hold a NUL byte, or more than one control
+// character for every 128 printable ones.
+func isBinary(content []byte) bool {
+	if len(content) > binaryCheckLen {
+		content = content[:binaryCheckLen]
+	}
+	if bytes.IndexByte(content, 0) >= 0 {
+		return true
+	}
+	printable, nonprintable := 0, 0
+	for _, c := range content {
+		switch {
+		case c == 127:
+			nonprintable++
+		case c >= 32, c == '\t', c == '\n', c == '\r', c == '\b', c == '\f', c == '\x1b':
+			printable++
+		default:
+			nonprintable++
+		}
+	}
+	return printable/128 < nonprintable
+}
+
+// base85Alphabet holds the digits of git's base85, which avoids the quote
+// characters of other variants.
+const base85Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz!#$%&()*+-;<=>?@^_`{|}~"
+
+// binaryLineLen is the most bytes one line of a binary patch encodes.
+const binaryLineLen = 52
+
+// encodeBase85 encodes each four bytes of data, big-endian, as five base85
+// digits, padding a final partial group with zeros.
+func encodeBase85(data []byte) string {
+	var out []byte
+	for len(data) > 0 {
+		var group [4]byte
+		n := copy(group[:], data)
+		data = data[n:]
+		acc := uint32(group[0])<<24 | uint32(group[1])<<16 | uint32(group[2])<<8 | uint32(group[3])
+		var digits [5]byte
+		for i := 4; i >= 0; i-- {
+			digits[i] = base85Alphabet[acc%85]
+			acc /= 85
+		}
+		out = append(out, digits[:]...)
+	}
+	return string(out)
+}
+
+// decodeBase85 decodes the digits of one line of a binary patch, which
+// hold n bytes followed by the zeros that padded them to a group of four.
+func decodeBase85(s string, n int) ([]byte, error) {
+	if len(s) != (n+3)/4*5 {
+		return nil, fmt.Errorf("%d base85 digits cannot hold %d bytes", len(s), n)
+	}
+	out := make([]byte, 0, len(s)/5*4)
+	for i := 0; i < len(s); i += 5 {
+		var acc uint64
+		for _, c := range []byte(s[i : i+5]) {
+			digit := strings.IndexByte(base85Alphabet, c)
+			if digit < 0 {
+				return nil, fmt.Errorf("invalid base85 digit %q", c)
+			}
+			acc = acc*85 + uint64(digit)
+		}
+		if acc > 0xffffffff {
+			return nil, fmt.Errorf("invalid base85 group %q", s[i:i+5])
+		}
+		out = append(out, byte(acc>>24), byte(acc>>16), byte(acc>>8), byte(acc))
+	}
+	return out[:n], nil
+}
+
+// printBinaryPatch prints a git binary patch that turns old into new: a
+// "literal" hunk with the new content, for applying it, and one with the
+// old content, for reversing it.
+func printBinaryPatch(old, new []byte) {
+	fmt.Println("GIT binary patch")
+	printBinaryLiteral(new)
+	printBinaryLiteral(old)
+}
+
+// printBinaryLiteral prints the size of data and then data deflated and
+// encoded in base85, binaryLineLen bytes to a line. Each line starts with
+// its byte count, 'A' to 'Z' for 1 to 26 and 'a' to 'z' for 27 to 52. A
+// blank line ends the hunk.
+func printBinaryLiteral(data []byte) {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+
+	fmt.Printf("literal %d\n", len(data))
+	deflated := buf.Bytes()
+	for len(deflated) > 0 {
+		n := min(len(deflated), binaryLineLen)
+		length := byte('A' + n - 1)
+		if n > 26 {
+			length = byte('a' + n - 27)
+		}
+		fmt.Printf("%c%s\n", length, encodeBase85(deflated[:n]))
+		deflated = deflated[n:]
+	}
+	fmt.Println()
+}
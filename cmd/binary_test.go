@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"empty", "", false},
+		{"text", "hello\nworld\n", false},
+		{"tabs and carriage returns", "a\tb\r\n", false},
+		{"escape sequences", "\x1b[1mbold\x1b[0m\n", false},
+		{"nul", "hello\x00world", true},
+		{"control characters", "\x01\x02\x03abc", true},
+		{"rare control character", strings.Repeat("x", 200) + "\x01", false},
+		{"nul past the checked prefix", strings.Repeat("x", binaryCheckLen) + "\x00", false},
+	}
+	for _, tt := range tests {
+		if got := isBinary([]byte(tt.content)); got != tt.want {
+			t.Errorf("%s: isBinary = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeBase85(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"\x00\x00\x00\x00", "00000"},
+		{"\xff\xff\xff\xff", "|NsC0"},
+		{"\x01\x02\x03", "0RjU6"},
+		{"gogit", "XK!a|bN~PV"},
+	}
+	for _, tt := range tests {
+		if got := encodeBase85([]byte(tt.in)); got != tt.want {
+			t.Errorf("encodeBase85(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// decodeLiteral reverses printBinaryLiteral for the hunk at the start of
+// lines and returns its data and the lines after it.
+func decodeLiteral(t *testing.T, lines []string) ([]byte, []string) {
+	t.Helper()
+	size, err := strconv.Atoi(strings.TrimPrefix(lines[0], "literal "))
+	if err != nil {
+		t.Fatalf("bad literal header %q", lines[0])
+	}
+	var deflated []byte
+	i := 1
+	for ; lines[i] != ""; i++ {
+		line := lines[i]
+		n := int(line[0]-'A') + 1
+		if line[0] >= 'a' {
+			n = int(line[0]-'a') + 27
+		}
+		var decoded []byte
+		for j := 1; j < len(line); j += 5 {
+			var acc uint32
+			for _, c := range []byte(line[j : j+5]) {
+				acc = acc*85 + uint32(strings.IndexByte(base85Alphabet, c))
+			}
+			decoded = append(decoded, byte(acc>>24), byte(acc>>16), byte(acc>>8), byte(acc))
+		}
+		if len(decoded) < n || len(line) > binaryLineLen/4*5+1 {
+			t.Fatalf("line %q does not hold %d bytes", line, n)
+		}
+		deflated = append(deflated, decoded[:n]...)
+	}
+	r, err := zlib.NewReader(bytes.NewReader(deflated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != size {
+		t.Errorf("literal says %d bytes, holds %d", size, len(data))
+	}
+	return data, lines[i+1:]
+}
+
+func TestPrintBinaryPatch(t *testing.T) {
+	old := []byte("old\x00content")
+	new := make([]byte, 5000)
+	for i := range new {
+		new[i] = byte(i * 7919 >> 3)
+	}
+	out, _ := captureStdout(t, func() error {
+		printBinaryPatch(old, new)
+		return nil
+	})
+	lines := strings.Split(out, "\n")
+	if lines[0] != "GIT binary patch" {
+		t.Fatalf("unexpected header in:\n%s", out)
+	}
+	gotNew, rest := decodeLiteral(t, lines[1:])
+	gotOld, rest := decodeLiteral(t, rest)
+	if !bytes.Equal(gotNew, new) || !bytes.Equal(gotOld, old) {
+		t.Error("binary patch does not round-trip")
+	}
+	if len(rest) != 1 || rest[0] != "" {
+		t.Errorf("unexpected trailing lines %q", rest)
+	}
+}
+
+func TestDecodeBase85(t *testing.T) {
+	for _, in := range []string{"\x00\x00\x00\x00", "\xff\xff\xff\xff", "\x01\x02\x03", "gogit"} {
+		got, err := decodeBase85(encodeBase85([]byte(in)), len(in))
+		if err != nil || string(got) != in {
+			t.Errorf("decodeBase85 of %q = %q, %v", in, got, err)
+		}
+	}
+	for _, tt := range []struct {
+		in string
+		n  int
+	}{{"0RjU6", 5}, {"0RjU", 3}, {"0Rj\"6", 3}, {"|NsC1", 4}} {
+		if _, err := decodeBase85(tt.in, tt.n); err == nil {
+			t.Errorf("decodeBase85(%q, %d): expected an error", tt.in, tt.n)
+		}
+	}
+}
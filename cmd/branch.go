@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
+	"gogit/object"
 	"gogit/refs"
 	"gogit/repo"
 )
@@ -14,13 +17,192 @@ func Branch(name string) error {
 	}
 
 	if name == "" {
-		return listBranches(root)
+		return listBranches(root, false)
 	}
 
-	return createBranch(root, name)
+	return createBranch(root, name, "")
 }
 
-func listBranches(root string) error {
+// BranchAt creates a branch pointing at the commit named by startPoint.
+func BranchAt(name, startPoint string) error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+	return createBranch(root, name, startPoint)
+}
+
+// BranchList lists branches; with verbose set each line also shows the
+// branch's tip commit and its subject.
+func BranchList(verbose bool) error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+	return listBranches(root, verbose)
+}
+
+// BranchForce points a branch at startPoint (HEAD when empty), creating it
+// or resetting it if it already exists. The current branch cannot be reset
+// this way, since that would leave the index and working tree behind.
+func BranchForce(name, startPoint string) error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+
+	existing, err := refs.ReadRef(root, refs.BranchRef(name))
+	if err != nil {
+		return err
+	}
+	if existing == "" {
+		return createBranch(root, name, startPoint)
+	}
+
+	current, err := refs.CurrentBranch(root)
+	if err != nil {
+		return err
+	}
+	if name == current {
+		return fmt.Errorf("cannot force update the current branch '%s'", name)
+	}
+
+	target := startPoint
+	if target == "" {
+		target = "HEAD"
+	}
+	hash, err := refs.ResolveCommit(root, target)
+	if err != nil {
+		return fmt.Errorf("not a valid start point '%s': %v", target, err)
+	}
+	if err := refs.UpdateRef(root, refs.BranchRef(name), hash, existing, "branch: Reset to "+target); err != nil {
+		return err
+	}
+
+	fmt.Printf("Reset branch '%s' to %s\n", name, hash[:7])
+	return nil
+}
+
+// BranchDelete deletes branches. Unless force is set, a branch is only
+// deleted if its tip is reachable from HEAD. The current branch is never
+// deleted. A branch that cannot be deleted does not stop the others; the
+// returned error joins the reasons of every one that failed.
+func BranchDelete(names []string, force bool) error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+
+	current, err := refs.CurrentBranch(root)
+	if err != nil {
+		return err
+	}
+	head, err := refs.ResolveHead(root)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, name := range names {
+		if err := deleteBranch(root, name, current, head, force); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deleteBranch deletes one branch for BranchDelete.
+func deleteBranch(root, name, current, head string, force bool) error {
+	if err := refs.ValidateBranchName(name); err != nil {
+		return err
+	}
+	if name == current {
+		return fmt.Errorf("cannot delete branch '%s' checked out", name)
+	}
+	refPath := refs.BranchRef(name)
+	hash, err := refs.ReadRef(root, refPath)
+	if err != nil {
+		return err
+	}
+	if hash == "" {
+		return fmt.Errorf("branch '%s' not found", name)
+	}
+
+	if !force {
+		merged := false
+		if head != "" {
+			if merged, err = object.IsAncestor(root, hash, head); err != nil {
+				return err
+			}
+		}
+		if !merged {
+			return fmt.Errorf("the branch '%s' is not fully merged; if you are sure you want to delete it, run 'gogit branch -D %s'", name, name)
+		}
+	}
+
+	if err := refs.DeleteRef(root, refPath, hash, "branch: deleted"); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted branch %s (was %s).\n", name, hash[:7])
+	return nil
+}
+
+// BranchRename renames a branch, or the current branch when oldName is
+// empty, moving its reflog along. Unless force is set, newName must not
+// name an existing branch.
+func BranchRename(oldName, newName string, force bool) error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+
+	current, err := refs.CurrentBranch(root)
+	if err != nil {
+		return err
+	}
+	if oldName == "" {
+		if current == "" {
+			return fmt.Errorf("no branch to rename: HEAD is detached")
+		}
+		oldName = current
+	}
+	if err := refs.ValidateBranchName(newName); err != nil {
+		return err
+	}
+	if oldName == newName {
+		return nil
+	}
+
+	oldRef, newRef := refs.BranchRef(oldName), refs.BranchRef(newName)
+	existing, err := refs.ReadRef(root, newRef)
+	if err != nil {
+		return err
+	}
+	if existing != "" {
+		if !force {
+			return fmt.Errorf("a branch named '%s' already exists", newName)
+		}
+		if newName == current {
+			return fmt.Errorf("cannot force update the current branch '%s'", newName)
+		}
+	}
+
+	hash, err := refs.ReadRef(root, oldRef)
+	if err != nil {
+		return err
+	}
+	reason := fmt.Sprintf("Branch: renamed %s to %s", oldRef, newRef)
+	if hash == "" && oldName == current {
+		// A branch without commits exists only as HEAD's target.
+		return refs.UpdateHead(root, "ref: "+newRef, reason)
+	}
+	if hash == "" {
+		return fmt.Errorf("branch '%s' not found", oldName)
+	}
+	return refs.RenameRef(root, oldRef, newRef, force, reason)
+}
+
+func listBranches(root string, verbose bool) error {
 	branches, err := refs.ListBranches(root)
 	if err != nil {
 		return err
@@ -31,17 +213,45 @@ func listBranches(root string) error {
 		return err
 	}
 
+	width := 0
+	for _, b := range branches {
+		width = max(width, len(b))
+	}
+
 	for _, b := range branches {
+		marker := "  "
 		if b == current {
-			fmt.Printf("* %s\n", b)
-		} else {
-			fmt.Printf("  %s\n", b)
+			marker = "* "
 		}
+		if !verbose {
+			fmt.Printf("%s%s\n", marker, b)
+			continue
+		}
+
+		hash, err := refs.ReadRef(root, refs.BranchRef(b))
+		if err != nil {
+			return err
+		}
+		commit, err := object.ReadCommit(root, hash)
+		if err != nil {
+			return err
+		}
+		subject, _, _ := strings.Cut(commit.Message, "\n")
+		fmt.Printf("%s%-*s %s %s\n", marker, width, b, hash[:7], subject)
 	}
 	return nil
 }
 
-func createBranch(root, name string) error {
+// createBranch creates a branch at startPoint, or at HEAD when it is empty.
+func createBranch(root, name, startPoint string) error {
+	if err := refs.ValidateBranchName(name); err != nil {
+		return err
+	}
+
+	if err := refs.CheckRefConflict(root, refs.BranchRef(name)); err != nil {
+		return err
+	}
+
 	// Check if branch already exists
 	existing, err := refs.ReadRef(root, refs.BranchRef(name))
 	if err != nil {
@@ -51,16 +261,26 @@ func createBranch(root, name string) error {
 		return fmt.Errorf("branch '%s' already exists", name)
 	}
 
-	// Get current HEAD commit
-	hash, err := refs.ResolveHead(root)
-	if err != nil {
-		return err
-	}
-	if hash == "" {
-		return fmt.Errorf("cannot create branch: no commits yet")
+	var hash string
+	reason := "branch: Created from HEAD"
+	if startPoint != "" {
+		reason = "branch: Created from " + startPoint
+		hash, err = refs.ResolveCommit(root, startPoint)
+		if err != nil {
+			return fmt.Errorf("not a valid start point '%s': %v", startPoint, err)
+		}
+	} else {
+		// Get current HEAD commit
+		hash, err = refs.ResolveHead(root)
+		if err != nil {
+			return err
+		}
+		if hash == "" {
+			return fmt.Errorf("cannot create branch: no commits yet")
+		}
 	}
 
-	if err := refs.WriteRef(root, refs.BranchRef(name), hash); err != nil {
+	if err := refs.UpdateRef(root, refs.BranchRef(name), hash, refs.ZeroHash, reason); err != nil {
 		return err
 	}
 
@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"gogit/refs"
@@ -135,7 +136,7 @@ func TestCreateBranch_WriteRefError(t *testing.T) {
 	os.Chmod(headsDir, 0555)
 	defer os.Chmod(headsDir, 0755)
 
-	err := createBranch(dir, "newbranch")
+	err := createBranch(dir, "newbranch", "")
 	if err == nil {
 		t.Fatal("expected error when cannot write ref")
 	}
@@ -143,12 +144,12 @@ func TestCreateBranch_WriteRefError(t *testing.T) {
 
 func TestCreateBranch_ReadRefError(t *testing.T) {
 	dir := setupTestRepoWithCommit(t)
-	// Make the ref path a directory with contents to cause ReadRef error (not ENOENT)
+	// Make the ref file unreadable to cause a ReadRef error (not ENOENT)
 	refPath := filepath.Join(dir, repo.GogitDir, "refs", "heads", "badref")
-	os.MkdirAll(filepath.Join(refPath, "sub"), 0755)
-	defer os.RemoveAll(refPath)
+	os.WriteFile(refPath, []byte("x\n"), 0000)
+	defer os.Remove(refPath)
 
-	err := createBranch(dir, "badref")
+	err := createBranch(dir, "badref", "")
 	if err == nil {
 		t.Fatal("expected error when ReadRef fails")
 	}
@@ -171,3 +172,325 @@ func TestBranch_CreateResolveHeadError(t *testing.T) {
 		t.Fatal("expected error when ResolveHead fails in createBranch")
 	}
 }
+
+func TestBranchAt_StartPoint(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	first, _ := refs.ResolveHead(dir)
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0644)
+	Add([]string{"b.txt"})
+	Commit("second")
+
+	if err := BranchAt("old", "HEAD~1"); err != nil {
+		t.Fatalf("BranchAt failed: %v", err)
+	}
+	hash, _ := refs.ReadRef(dir, refs.BranchRef("old"))
+	if hash != first {
+		t.Errorf("expected branch at %s, got %s", first, hash)
+	}
+}
+
+func TestBranchAt_Errors(t *testing.T) {
+	setupTestRepoWithCommit(t)
+	if err := BranchAt("x", "nope"); err == nil {
+		t.Error("expected error for unknown start point")
+	}
+	if err := BranchAt("main", "HEAD"); err == nil {
+		t.Error("expected error for existing branch")
+	}
+}
+
+func TestBranchAt_NoRepo(t *testing.T) {
+	dir := t.TempDir()
+	orig, _ := os.Getwd()
+	defer os.Chdir(orig)
+	os.Chdir(dir)
+
+	if err := BranchAt("x", "HEAD"); err == nil {
+		t.Fatal("expected error when not in a repo")
+	}
+}
+
+func TestBranch_CreateInvalidName(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+
+	for _, name := range []string{"a..b", "../../HEAD", "HEAD", "-x", "foo.lock"} {
+		err := Branch(name)
+		if err == nil || !strings.Contains(err.Error(), "not a valid branch name") {
+			t.Errorf("%q: expected invalid branch name error, got %v", name, err)
+		}
+	}
+	if head, _ := refs.ReadHead(dir); head != "ref: refs/heads/main" {
+		t.Errorf("HEAD should be untouched, got %q", head)
+	}
+}
+
+func TestBranch_Hierarchical(t *testing.T) {
+	setupTestRepoWithCommit(t)
+
+	if err := Branch("feature/login"); err != nil {
+		t.Fatalf("Branch create failed: %v", err)
+	}
+	out, err := captureStdout(t, func() error { return Branch("") })
+	if err != nil {
+		t.Fatalf("Branch list failed: %v", err)
+	}
+	if !strings.Contains(out, "  feature/login\n") {
+		t.Errorf("expected feature/login in listing, got %q", out)
+	}
+
+	err = Branch("feature")
+	if err == nil || !strings.Contains(err.Error(), "'refs/heads/feature/login' exists; cannot create 'refs/heads/feature'") {
+		t.Errorf("expected directory/file conflict, got %v", err)
+	}
+	err = Branch("main/sub")
+	if err == nil || !strings.Contains(err.Error(), "'refs/heads/main' exists; cannot create 'refs/heads/main/sub'") {
+		t.Errorf("expected directory/file conflict, got %v", err)
+	}
+}
+
+// setupUnmergedBranch creates a branch "feature" with a commit that main
+// does not contain, and leaves main checked out.
+func setupUnmergedBranch(t *testing.T) string {
+	t.Helper()
+	dir := setupTestRepoWithCommit(t)
+	Branch("feature")
+	if err := Checkout("feature"); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "feature.txt"), []byte("f\n"), 0644)
+	Add([]string{"feature.txt"})
+	if err := Commit("feature work"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if err := Checkout("main"); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	return dir
+}
+
+func TestBranchList_Verbose(t *testing.T) {
+	dir := setupUnmergedBranch(t)
+
+	out, err := captureStdout(t, func() error { return BranchList(true) })
+	if err != nil {
+		t.Fatalf("BranchList failed: %v", err)
+	}
+	mainHash, _ := refs.ReadRef(dir, "refs/heads/main")
+	featureHash, _ := refs.ReadRef(dir, "refs/heads/feature")
+	want := "  feature " + featureHash[:7] + " feature work\n" +
+		"* main    " + mainHash[:7] + " initial commit\n"
+	if out != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, out)
+	}
+}
+
+func TestBranchDelete_Merged(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	Branch("done")
+
+	out, err := captureStdout(t, func() error { return BranchDelete([]string{"done"}, false) })
+	if err != nil {
+		t.Fatalf("BranchDelete failed: %v", err)
+	}
+	if !strings.HasPrefix(out, "Deleted branch done (was ") {
+		t.Errorf("unexpected output: %q", out)
+	}
+	if hash, _ := refs.ReadRef(dir, "refs/heads/done"); hash != "" {
+		t.Error("branch should be deleted")
+	}
+	if _, err := os.Stat(refs.ReflogPath(dir, "refs/heads/done")); !os.IsNotExist(err) {
+		t.Error("branch reflog should be deleted")
+	}
+}
+
+func TestBranchDelete_Unmerged(t *testing.T) {
+	dir := setupUnmergedBranch(t)
+
+	err := BranchDelete([]string{"feature"}, false)
+	if err == nil || !strings.Contains(err.Error(), "not fully merged") {
+		t.Fatalf("expected not fully merged error, got %v", err)
+	}
+	if hash, _ := refs.ReadRef(dir, "refs/heads/feature"); hash == "" {
+		t.Fatal("unmerged branch should be kept")
+	}
+
+	if err := BranchDelete([]string{"feature"}, true); err != nil {
+		t.Fatalf("forced delete failed: %v", err)
+	}
+	if hash, _ := refs.ReadRef(dir, "refs/heads/feature"); hash != "" {
+		t.Error("branch should be deleted")
+	}
+}
+
+func TestBranchDelete_Errors(t *testing.T) {
+	setupTestRepoWithCommit(t)
+
+	err := BranchDelete([]string{"main"}, true)
+	if err == nil || !strings.Contains(err.Error(), "checked out") {
+		t.Errorf("expected current branch error, got %v", err)
+	}
+	err = BranchDelete([]string{"missing"}, false)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestBranchDelete_ContinuesPastFailures(t *testing.T) {
+	dir := setupUnmergedBranch(t)
+	Branch("a")
+	Branch("b")
+
+	out, err := captureStdout(t, func() error {
+		return BranchDelete([]string{"a", "missing", "feature", "b"}, false)
+	})
+	if err == nil || !strings.Contains(err.Error(), "'missing' not found") || !strings.Contains(err.Error(), "'feature' is not fully merged") {
+		t.Fatalf("expected both failures to be reported, got %v", err)
+	}
+	if !strings.Contains(out, "Deleted branch a ") || !strings.Contains(out, "Deleted branch b ") {
+		t.Errorf("expected the other branches to be deleted, got %q", out)
+	}
+	for name, kept := range map[string]bool{"a": false, "b": false, "feature": true} {
+		if hash, _ := refs.ReadRef(dir, "refs/heads/"+name); (hash != "") != kept {
+			t.Errorf("branch %s: expected kept=%v", name, kept)
+		}
+	}
+}
+
+func TestBranchDelete_InvalidName(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+
+	for _, force := range []bool{false, true} {
+		err := BranchDelete([]string{"../../HEAD"}, force)
+		if err == nil || !strings.Contains(err.Error(), "not a valid branch name") {
+			t.Errorf("expected invalid name error, got %v", err)
+		}
+	}
+	if head, _ := refs.ReadHead(dir); head != "ref: refs/heads/main" {
+		t.Errorf("HEAD should be intact, got %q", head)
+	}
+}
+
+func TestBranchManagement_NoRepo(t *testing.T) {
+	dir := t.TempDir()
+	orig, _ := os.Getwd()
+	defer os.Chdir(orig)
+	os.Chdir(dir)
+
+	if err := BranchList(true); err == nil {
+		t.Error("BranchList: expected error when not in a repo")
+	}
+	if err := BranchDelete([]string{"x"}, false); err == nil {
+		t.Error("BranchDelete: expected error when not in a repo")
+	}
+	if err := BranchRename("x", "y", false); err == nil {
+		t.Error("BranchRename: expected error when not in a repo")
+	}
+	if err := BranchForce("x", ""); err == nil {
+		t.Error("BranchForce: expected error when not in a repo")
+	}
+}
+
+func TestBranchRename_Current(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	hash, _ := refs.ReadRef(dir, "refs/heads/main")
+
+	if err := BranchRename("", "trunk", false); err != nil {
+		t.Fatalf("BranchRename failed: %v", err)
+	}
+	if head, _ := refs.ReadHead(dir); head != "ref: refs/heads/trunk" {
+		t.Errorf("HEAD should point at trunk, got %q", head)
+	}
+	if got, _ := refs.ReadRef(dir, "refs/heads/trunk"); got != hash {
+		t.Errorf("trunk should be at %s, got %s", hash, got)
+	}
+	log, _ := refs.ReadReflog(dir, "refs/heads/trunk")
+	if len(log) != 2 || !strings.HasPrefix(log[0].Message, "commit (initial)") {
+		t.Errorf("expected main's reflog to move to trunk, got %+v", log)
+	}
+}
+
+func TestBranchRename_Other(t *testing.T) {
+	dir := setupUnmergedBranch(t)
+
+	err := BranchRename("feature", "main", false)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected already exists error, got %v", err)
+	}
+	err = BranchRename("feature", "main", true)
+	if err == nil || !strings.Contains(err.Error(), "current branch") {
+		t.Errorf("expected current branch error, got %v", err)
+	}
+	err = BranchRename("missing", "other", false)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+	err = BranchRename("feature", "bad..name", false)
+	if err == nil || !strings.Contains(err.Error(), "not a valid branch name") {
+		t.Errorf("expected invalid name error, got %v", err)
+	}
+
+	if err := BranchRename("feature", "topic/feature", false); err != nil {
+		t.Fatalf("BranchRename failed: %v", err)
+	}
+	if hash, _ := refs.ReadRef(dir, "refs/heads/topic/feature"); hash == "" {
+		t.Error("renamed branch should exist")
+	}
+	if head, _ := refs.ReadHead(dir); head != "ref: refs/heads/main" {
+		t.Errorf("HEAD should stay on main, got %q", head)
+	}
+}
+
+func TestBranchRename_Unborn(t *testing.T) {
+	dir := setupTestRepo(t)
+
+	if err := BranchRename("", "trunk", false); err != nil {
+		t.Fatalf("BranchRename failed: %v", err)
+	}
+	if head, _ := refs.ReadHead(dir); head != "ref: refs/heads/trunk" {
+		t.Errorf("HEAD should point at trunk, got %q", head)
+	}
+}
+
+func TestBranchRename_Detached(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	hash, _ := refs.ResolveHead(dir)
+	refs.UpdateHead(dir, hash, "test")
+
+	if err := BranchRename("", "x", false); err == nil || !strings.Contains(err.Error(), "detached") {
+		t.Errorf("expected detached HEAD error, got %v", err)
+	}
+}
+
+func TestBranchForce(t *testing.T) {
+	dir := setupUnmergedBranch(t)
+	mainHash, _ := refs.ReadRef(dir, "refs/heads/main")
+
+	out, err := captureStdout(t, func() error { return BranchForce("feature", "main") })
+	if err != nil {
+		t.Fatalf("BranchForce failed: %v", err)
+	}
+	if out != "Reset branch 'feature' to "+mainHash[:7]+"\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+	if hash, _ := refs.ReadRef(dir, "refs/heads/feature"); hash != mainHash {
+		t.Errorf("feature should be reset to main, got %s", hash)
+	}
+	log, _ := refs.ReadReflog(dir, "refs/heads/feature")
+	if last := log[len(log)-1]; last.Message != "branch: Reset to main" {
+		t.Errorf("unexpected reflog message: %q", last.Message)
+	}
+
+	if err := BranchForce("fresh", ""); err != nil {
+		t.Fatalf("BranchForce create failed: %v", err)
+	}
+	if hash, _ := refs.ReadRef(dir, "refs/heads/fresh"); hash != mainHash {
+		t.Errorf("fresh should be created at HEAD, got %s", hash)
+	}
+
+	if err := BranchForce("main", "feature"); err == nil || !strings.Contains(err.Error(), "current branch") {
+		t.Errorf("expected current branch error, got %v", err)
+	}
+	if err := BranchForce("feature", "nope"); err == nil || !strings.Contains(err.Error(), "not a valid start point") {
+		t.Errorf("expected bad start point error, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gogit/ignore"
+	"gogit/index"
+	"gogit/repo"
+)
+
+// CheckIgnore prints each path that is ignored. With verbose set it also
+// prints the rule that decided, as "<source>:<line>:<pattern>\t<path>",
+// including negated rules that re-include a path. Tracked paths are never
+// reported as ignored unless noIndex is set. It reports whether any path
+// was ignored.
+func CheckIgnore(paths []string, verbose, noIndex bool) (bool, error) {
+	root, err := repo.Find()
+	if err != nil {
+		return false, err
+	}
+	matcher, err := ignore.New(root)
+	if err != nil {
+		return false, err
+	}
+	var idx *index.Index
+	if !noIndex {
+		if idx, err = index.ReadIndex(root); err != nil {
+			return false, err
+		}
+	}
+
+	anyIgnored := false
+	for _, p := range paths {
+		relPath, err := repoPath(root, p)
+		if err != nil {
+			return false, err
+		}
+		if idx != nil && idx.LookupEntry(relPath) != nil {
+			continue
+		}
+
+		isDir := strings.HasSuffix(p, "/")
+		if info, err := os.Stat(filepath.Join(root, relPath)); err == nil {
+			isDir = info.IsDir()
+		}
+		pat, err := matcher.Match(relPath, isDir)
+		if err != nil {
+			return false, err
+		}
+		if pat == nil || (pat.Negated() && !verbose) {
+			continue
+		}
+		if !pat.Negated() {
+			anyIgnored = true
+		}
+		if verbose {
+			fmt.Printf("%s\t%s\n", pat, p)
+		} else {
+			fmt.Println(p)
+		}
+	}
+	return anyIgnored, nil
+}
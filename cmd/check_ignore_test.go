@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setupIgnoreRepo(t *testing.T) string {
+	t.Helper()
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, ".gogitignore"), []byte("*.log\n!keep.log\nbuild/\ntest.txt\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "build"), 0755)
+	return dir
+}
+
+func TestCheckIgnore(t *testing.T) {
+	setupIgnoreRepo(t)
+
+	var ignored bool
+	out, err := captureStdout(t, func() error {
+		var err error
+		ignored, err = CheckIgnore([]string{"a.log", "keep.log", "main.go", "build", "build/x.o", "test.txt"}, false, false)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("CheckIgnore failed: %v", err)
+	}
+	if !ignored {
+		t.Error("expected some paths to be ignored")
+	}
+	if out != "a.log\nbuild\nbuild/x.o\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func TestCheckIgnore_Verbose(t *testing.T) {
+	setupIgnoreRepo(t)
+
+	out, err := captureStdout(t, func() error {
+		_, err := CheckIgnore([]string{"a.log", "keep.log", "main.go", "build/x.o"}, true, false)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("CheckIgnore failed: %v", err)
+	}
+	want := ".gogitignore:1:*.log\ta.log\n" +
+		".gogitignore:2:!keep.log\tkeep.log\n" +
+		".gogitignore:3:build/\tbuild/x.o\n"
+	if out != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestCheckIgnore_NothingIgnored(t *testing.T) {
+	setupIgnoreRepo(t)
+
+	out, err := captureStdout(t, func() error {
+		ignored, err := CheckIgnore([]string{"main.go", "keep.log"}, true, false)
+		if ignored {
+			t.Error("a negated match is not ignored")
+		}
+		return err
+	})
+	if err != nil || out != ".gogitignore:2:!keep.log\tkeep.log\n" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+}
+
+func TestCheckIgnore_TrackedFiles(t *testing.T) {
+	setupIgnoreRepo(t)
+
+	ignored, err := CheckIgnore([]string{"test.txt"}, false, false)
+	if err != nil || ignored {
+		t.Errorf("tracked file should not be reported, got %v, %v", ignored, err)
+	}
+	ignored, err = CheckIgnore([]string{"test.txt"}, false, true)
+	if err != nil || !ignored {
+		t.Errorf("with --no-index the tracked file should be reported, got %v, %v", ignored, err)
+	}
+}
+
+func TestCheckIgnore_Errors(t *testing.T) {
+	dir := setupIgnoreRepo(t)
+
+	if _, err := CheckIgnore([]string{"../outside"}, false, false); err == nil {
+		t.Error("expected error for path outside the repository")
+	}
+
+	os.WriteFile(filepath.Join(dir, ".gogit", "index"), []byte("garbage"), 0644)
+	if _, err := CheckIgnore([]string{"a.log"}, false, false); err == nil {
+		t.Error("expected error for corrupt index")
+	}
+
+	os.MkdirAll(filepath.Join(dir, ".gogit", "info", "exclude"), 0755)
+	if _, err := CheckIgnore([]string{"a.log"}, false, true); err == nil {
+		t.Error("expected error when ignore rules cannot be read")
+	}
+
+	os.MkdirAll(filepath.Join(dir, "sub", ".gogitignore"), 0755)
+	os.RemoveAll(filepath.Join(dir, ".gogit", "info"))
+	if _, err := CheckIgnore([]string{"sub/x"}, false, true); err == nil {
+		t.Error("expected error when a nested ignore file is unreadable")
+	}
+}
+
+func TestCheckIgnore_NoRepo(t *testing.T) {
+	dir := t.TempDir()
+	orig, _ := os.Getwd()
+	defer os.Chdir(orig)
+	os.Chdir(dir)
+
+	if _, err := CheckIgnore([]string{"x"}, false, false); err == nil {
+		t.Error("expected error when not in a repo")
+	}
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"fmt"
+
+	"gogit/refs"
+)
+
+// CheckRefFormat validates a ref name the way refs are validated when they
+// are created. With branch set, name is checked as a branch name and printed
+// when valid.
+func CheckRefFormat(name string, allowOneLevel, branch bool) error {
+	if branch {
+		if err := refs.ValidateBranchName(name); err != nil {
+			return err
+		}
+		fmt.Println(name)
+		return nil
+	}
+	return refs.CheckRefFormat(name, allowOneLevel)
+}
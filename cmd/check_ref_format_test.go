@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestCheckRefFormat(t *testing.T) {
+	if err := CheckRefFormat("refs/heads/main", false, false); err != nil {
+		t.Errorf("expected valid ref name: %v", err)
+	}
+	if err := CheckRefFormat("main", false, false); err == nil {
+		t.Error("expected one-level name to be rejected")
+	}
+	if err := CheckRefFormat("main", true, false); err != nil {
+		t.Errorf("expected one-level name to be allowed: %v", err)
+	}
+}
+
+func TestCheckRefFormat_Branch(t *testing.T) {
+	out, err := captureStdout(t, func() error { return CheckRefFormat("feature/login", false, true) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "feature/login\n" {
+		t.Errorf("expected branch name echoed, got %q", out)
+	}
+	if err := CheckRefFormat("a..b", false, true); err == nil {
+		t.Error("expected invalid branch name error")
+	}
+}
@@ -18,13 +18,38 @@ func Checkout(target string) error {
 		return err
 	}
 
-	// Check if target is a branch
-	branchHash, err := refs.ReadRef(root, refs.BranchRef(target))
+	idx, err := index.ReadIndex(root)
 	if err != nil {
 		return err
 	}
+	if idx.HasUnmerged() {
+		return fmt.Errorf("you need to resolve your current index first")
+	}
+	// A merge left in progress would be concluded on the other branch.
+	mergeHead, err := readMergeHead(root)
+	if err != nil {
+		return err
+	}
+	if mergeHead != "" {
+		return fmt.Errorf("you need to resolve your current index first (MERGE_HEAD exists); commit the merge or run 'gogit merge --abort'")
+	}
+
+	// A branch name switches to that branch; any other revision detaches HEAD
+	branchHash := ""
+	if refs.ValidateBranchName(target) == nil {
+		if branchHash, err = refs.ReadRef(root, refs.BranchRef(target)); err != nil {
+			return err
+		}
+	}
+	head := "ref: " + refs.BranchRef(target)
+	switched := fmt.Sprintf("Switched to branch '%s'", target)
 	if branchHash == "" {
-		return fmt.Errorf("branch '%s' not found", target)
+		branchHash, err = refs.ResolveCommit(root, target)
+		if err != nil {
+			return fmt.Errorf("'%s' did not match any branch or revision: %v", target, err)
+		}
+		head = branchHash
+		switched = fmt.Sprintf("HEAD is now at %s", branchHash[:7])
 	}
 
 	// Get current HEAD commit tree
@@ -33,12 +58,21 @@ func Checkout(target string) error {
 		return err
 	}
 
+	from, err := refs.CurrentBranch(root)
+	if err != nil {
+		return err
+	}
+	if from == "" {
+		from = currentHash
+	}
+	reason := fmt.Sprintf("checkout: moving from %s to %s", from, target)
+
 	if currentHash == branchHash {
 		// Already on the right commit, just switch HEAD
-		if err := refs.UpdateHead(root, "ref: refs/heads/"+target); err != nil {
+		if err := refs.UpdateHead(root, head, reason); err != nil {
 			return err
 		}
-		fmt.Printf("Switched to branch '%s'\n", target)
+		fmt.Println(switched)
 		return nil
 	}
 
@@ -66,15 +100,38 @@ func Checkout(target string) error {
 		return err
 	}
 
-	if err := updateWorkingTree(root, target, currentTree, targetTree); err != nil {
+	if err := updateWorkingTree(root, head, reason, currentTree, targetTree); err != nil {
+		return err
+	}
+
+	fmt.Println(switched)
+	return nil
+}
+
+// updateWorkingTree checks out targetTree over currentTree and then points
+// HEAD at head, either "ref: <branch ref>" or a detached commit hash,
+// logging reason in HEAD's reflog.
+func updateWorkingTree(root, head, reason string, currentTree, targetTree map[string]string) error {
+	if err := checkoutTree(root, currentTree, targetTree); err != nil {
+		return err
+	}
+
+	if err := refs.UpdateHead(root, head, reason); err != nil {
 		return err
 	}
 
-	fmt.Printf("Switched to branch '%s'\n", target)
 	return nil
 }
 
-func updateWorkingTree(root, target string, currentTree, targetTree map[string]string) error {
+// checkoutTree replaces the files of currentTree in the working tree with
+// those of targetTree and rewrites the index to match targetTree.
+func checkoutTree(root string, currentTree, targetTree map[string]string) error {
+	lock, err := index.LockIndex(root)
+	if err != nil {
+		return err
+	}
+	defer lock.Rollback()
+
 	// Remove files that are in current tree but not in target tree
 	for path := range currentTree {
 		if _, inTarget := targetTree[path]; !inTarget {
@@ -86,7 +143,7 @@ func updateWorkingTree(root, target string, currentTree, targetTree map[string]s
 	}
 
 	// Write/update files from target tree
-	idx := &index.Index{}
+	var entries []index.Entry
 	for path, hash := range targetTree {
 		absPath := filepath.Join(root, path)
 		if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
@@ -103,25 +160,14 @@ func updateWorkingTree(root, target string, currentTree, targetTree map[string]s
 		}
 
 		info, _ := os.Stat(absPath)
-		idx.AddEntry(index.Entry{
-			Ctime: uint32(info.ModTime().Unix()),
-			Mtime: uint32(info.ModTime().Unix()),
-			Size:  uint32(info.Size()),
-			Hash:  hash,
-			Mode:  0100644,
-			Path:  path,
-		})
-	}
-
-	if err := index.WriteIndex(root, idx); err != nil {
-		return err
-	}
-
-	if err := refs.UpdateHead(root, "ref: refs/heads/"+target); err != nil {
-		return err
+		entry := index.Entry{Hash: hash, Mode: 0100644, Path: path}
+		entry.SetStat(info)
+		entries = append(entries, entry)
 	}
+	idx := &index.Index{}
+	idx.AddEntries(entries)
 
-	return nil
+	return lock.Commit(idx)
 }
 
 func cleanEmptyDirs(root, dir string) {
@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"gogit/index"
@@ -119,15 +120,15 @@ func TestCheckout_SameCommitUpdateHeadError(t *testing.T) {
 	dir := setupTestRepoWithCommit(t)
 	Branch("feature")
 
-	// Make HEAD read-only: ResolveHead can still read it,
-	// but UpdateHead's WriteFile will fail
-	headPath := filepath.Join(dir, repo.GogitDir, "HEAD")
-	os.Chmod(headPath, 0444)
-	defer os.Chmod(headPath, 0644)
+	// Hold HEAD's lock: ResolveHead can still read it,
+	// but UpdateHead cannot acquire the lock
+	lockPath := filepath.Join(dir, repo.GogitDir, "HEAD.lock")
+	os.WriteFile(lockPath, nil, 0644)
+	defer os.Remove(lockPath)
 
 	err := Checkout("feature")
 	if err == nil {
-		t.Fatal("expected error when HEAD is read-only")
+		t.Fatal("expected error when HEAD is locked")
 	}
 }
 
@@ -253,7 +254,7 @@ func TestCheckout_BadCurrentCommit(t *testing.T) {
 	Checkout("main")
 
 	// Now corrupt main's commit by pointing it to a bad hash
-	refs.WriteRef(dir, "refs/heads/main", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	refs.WriteRef(dir, "refs/heads/main", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", "test")
 
 	err := Checkout("feature")
 	if err == nil {
@@ -264,7 +265,7 @@ func TestCheckout_BadCurrentCommit(t *testing.T) {
 func TestCheckout_BadTargetCommit(t *testing.T) {
 	dir := setupTestRepoWithCommit(t)
 	// Point feature to a bad hash
-	refs.WriteRef(dir, "refs/heads/feature", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	refs.WriteRef(dir, "refs/heads/feature", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", "test")
 
 	err := Checkout("feature")
 	if err == nil {
@@ -298,11 +299,11 @@ func TestCheckout_NoCurrentCommits(t *testing.T) {
 
 	// Alternative: directly test with a known setup
 	// Write HEAD as detached pointing to empty
-	refs.UpdateHead(dir, "ref: refs/heads/main")
+	refs.UpdateHead(dir, "ref: refs/heads/main", "test")
 	// Remove main ref so ResolveHead returns ""
 	os.Remove(filepath.Join(dir, repo.GogitDir, "refs", "heads", "main"))
 	// Put feature back
-	refs.WriteRef(dir, "refs/heads/feature", commitHash)
+	refs.WriteRef(dir, "refs/heads/feature", commitHash, "test")
 
 	// This checkout: currentHash="" (no commits on main), branchHash=commitHash
 	err := Checkout("feature")
@@ -475,7 +476,7 @@ func TestUpdateWorkingTree_WriteIndexError(t *testing.T) {
 	os.MkdirAll(idxPath, 0755)
 	defer os.RemoveAll(idxPath)
 
-	err := updateWorkingTree(dir, "test", map[string]string{}, targetTree)
+	err := updateWorkingTree(dir, "test", "test", map[string]string{}, targetTree)
 	if err == nil {
 		t.Fatal("expected error when cannot write index")
 	}
@@ -495,7 +496,7 @@ func TestUpdateWorkingTree_UpdateHeadError(t *testing.T) {
 	os.MkdirAll(headPath, 0755)
 	defer os.RemoveAll(headPath)
 
-	err := updateWorkingTree(dir, "test", map[string]string{}, targetTree)
+	err := updateWorkingTree(dir, "test", "test", map[string]string{}, targetTree)
 	if err == nil {
 		t.Fatal("expected error when cannot update HEAD")
 	}
@@ -508,7 +509,7 @@ func TestUpdateWorkingTree_ReadBlobError(t *testing.T) {
 		"file.txt": "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
 	}
 
-	err := updateWorkingTree(dir, "test", map[string]string{}, targetTree)
+	err := updateWorkingTree(dir, "test", "test", map[string]string{}, targetTree)
 	if err == nil {
 		t.Fatal("expected error when blob is missing")
 	}
@@ -527,7 +528,7 @@ func TestUpdateWorkingTree_MkdirError(t *testing.T) {
 	// Block sub dir creation
 	os.WriteFile(filepath.Join(dir, "sub"), []byte("blocker"), 0644)
 
-	err := updateWorkingTree(dir, "test", map[string]string{}, targetTree)
+	err := updateWorkingTree(dir, "test", "test", map[string]string{}, targetTree)
 	if err == nil {
 		t.Fatal("expected error when mkdir is blocked")
 	}
@@ -547,8 +548,94 @@ func TestUpdateWorkingTree_WriteFileError(t *testing.T) {
 	os.MkdirAll(filepath.Join(dir, "f.txt", "sub"), 0755)
 	defer os.RemoveAll(filepath.Join(dir, "f.txt"))
 
-	err := updateWorkingTree(dir, "test", map[string]string{}, targetTree)
+	err := updateWorkingTree(dir, "test", "test", map[string]string{}, targetTree)
 	if err == nil {
 		t.Fatal("expected error when file write is blocked")
 	}
 }
+
+func TestCheckout_DetachedAtRevision(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	first, _ := refs.ResolveHead(dir)
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("changed\n"), 0644)
+	Add([]string{"test.txt"})
+	Commit("second")
+
+	if err := Checkout("HEAD~1"); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	head, _ := refs.ReadHead(dir)
+	if head != first {
+		t.Errorf("expected detached HEAD at %s, got %s", first, head)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "test.txt"))
+	if string(data) != "hello\n" {
+		t.Errorf("expected first commit's content, got %q", data)
+	}
+
+	// Checking out the same commit by abbreviated hash only moves HEAD.
+	if err := Checkout(first[:8]); err != nil {
+		t.Fatalf("Checkout by abbreviated hash failed: %v", err)
+	}
+	if err := Checkout("main"); err != nil {
+		t.Fatalf("Checkout back to main failed: %v", err)
+	}
+	if head, _ := refs.ReadHead(dir); head != "ref: refs/heads/main" {
+		t.Errorf("expected HEAD to be attached to main, got %s", head)
+	}
+}
+
+func TestCheckout_UnknownRevision(t *testing.T) {
+	setupTestRepoWithCommit(t)
+	err := Checkout("nope~1")
+	if err == nil || !strings.Contains(err.Error(), "did not match any branch or revision") {
+		t.Fatalf("expected unknown revision error, got %v", err)
+	}
+}
+
+func TestCheckout_InvalidBranchName(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	head, _ := refs.ResolveHead(dir)
+	os.WriteFile(repo.OrigHeadPath(dir), []byte(head+"\n"), 0644)
+
+	// refs/heads/../../ORIG_HEAD is a file, but not a branch.
+	if err := Checkout("../../ORIG_HEAD"); err == nil {
+		t.Fatal("expected an invalid branch name not to be checked out")
+	}
+	if got, _ := refs.ReadHead(dir); got != "ref: refs/heads/main" {
+		t.Errorf("HEAD should be unchanged, got %q", got)
+	}
+}
+
+func TestCheckout_Tag(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	head, _ := refs.ResolveHead(dir)
+	TagCreate("v1.0", "", "release", false)
+
+	if err := Checkout("v1.0"); err != nil {
+		t.Fatalf("Checkout of tag failed: %v", err)
+	}
+	if got, _ := refs.ReadHead(dir); got != head {
+		t.Errorf("HEAD should be detached at the tagged commit, got %q", got)
+	}
+}
+
+func TestCheckout_IndexLocked(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	Branch("feature")
+	os.WriteFile(filepath.Join(dir, "main_only.txt"), []byte("main"), 0644)
+	Add([]string{"main_only.txt"})
+	Commit("main commit")
+	os.WriteFile(filepath.Join(dir, ".gogit", "index.lock"), nil, 0644)
+
+	if err := Checkout("feature"); err == nil {
+		t.Fatal("expected error while the index is locked")
+	}
+	// Nothing was touched.
+	if _, err := os.Stat(filepath.Join(dir, "main_only.txt")); err != nil {
+		t.Error("working tree should be unchanged")
+	}
+	if branch, _ := refs.CurrentBranch(dir); branch != "main" {
+		t.Errorf("expected to stay on main, got %s", branch)
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"gogit/index"
 	"gogit/object"
@@ -9,6 +10,9 @@ import (
 	"gogit/repo"
 )
 
+// Commit records the index as a new commit on the current branch. While a
+// merge is in progress the merged commit becomes the second parent, and an
+// empty message defaults to the prepared merge message.
 func Commit(message string) error {
 	root, err := repo.Find()
 	if err != nil {
@@ -23,6 +27,9 @@ func Commit(message string) error {
 	if len(idx.Entries) == 0 {
 		return fmt.Errorf("nothing to commit")
 	}
+	if idx.HasUnmerged() {
+		return fmt.Errorf("committing is not possible because you have unmerged files")
+	}
 
 	// Build tree from index
 	treeHash, err := object.BuildTreeFromIndex(root, idx)
@@ -36,18 +43,41 @@ func Commit(message string) error {
 	if err != nil {
 		return err
 	}
+	parentTree := ""
 	if headHash != "" {
 		parents = append(parents, headHash)
+		if parentTree, err = revisionTree(root, headHash); err != nil {
+			return err
+		}
+	}
+
+	// Conclude an in-progress merge with the merged commit as second parent
+	mergeHead, err := readMergeHead(root)
+	if err != nil {
+		return err
+	}
+	if mergeHead != "" {
+		parents = append(parents, mergeHead)
+		if message == "" {
+			if message, err = readMergeMsg(root); err != nil {
+				return err
+			}
+		}
+	}
+	if message == "" {
+		return fmt.Errorf("aborting commit due to empty commit message")
 	}
 
 	commitHash, err := writeCommitAndUpdateRef(root, treeHash, parents, message)
 	if err != nil {
 		return err
 	}
+	clearMergeState(root)
 
 	branch, _ := refs.CurrentBranch(root)
-	fmt.Printf("[%s %s] %s\n", branchDisplay(branch), commitHash[:7], message)
-	return nil
+	subject, _, _ := strings.Cut(message, "\n")
+	fmt.Printf("[%s %s] %s\n", branchDisplay(branch), commitHash[:7], subject)
+	return printCommitSummary(root, parentTree, treeHash)
 }
 
 var writeCommitFn = object.WriteCommit
@@ -64,13 +94,19 @@ func writeCommitAndUpdateRef(root, treeHash string, parents []string, message st
 	if err != nil {
 		return "", err
 	}
+	reason := commitReason(parents, message)
 	if branch != "" {
-		if err := refs.WriteRef(root, refs.BranchRef(branch), commitHash); err != nil {
+		// Fail rather than lose a commit made concurrently by another process
+		expectedOld := refs.ZeroHash
+		if len(parents) > 0 {
+			expectedOld = parents[0]
+		}
+		if err := refs.UpdateRef(root, refs.BranchRef(branch), commitHash, expectedOld, reason); err != nil {
 			return "", err
 		}
 	} else {
 		// Detached HEAD
-		if err := refs.UpdateHead(root, commitHash); err != nil {
+		if err := refs.UpdateHead(root, commitHash, reason); err != nil {
 			return "", err
 		}
 	}
@@ -78,6 +114,19 @@ func writeCommitAndUpdateRef(root, treeHash string, parents []string, message st
 	return commitHash, nil
 }
 
+// commitReason is the reflog message for a new commit, e.g.
+// "commit (initial): first".
+func commitReason(parents []string, message string) string {
+	subject, _, _ := strings.Cut(message, "\n")
+	switch {
+	case len(parents) == 0:
+		return "commit (initial): " + subject
+	case len(parents) > 1:
+		return "commit (merge): " + subject
+	}
+	return "commit: " + subject
+}
+
 func branchDisplay(branch string) string {
 	if branch == "" {
 		return "detached HEAD"
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"gogit/index"
@@ -86,7 +87,7 @@ func TestCommit_DetachedHead(t *testing.T) {
 	dir := setupTestRepoWithCommit(t)
 
 	hash, _ := refs.ResolveHead(dir)
-	refs.UpdateHead(dir, hash)
+	refs.UpdateHead(dir, hash, "test")
 
 	os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new"), 0644)
 	Add([]string{"new.txt"})
@@ -201,7 +202,7 @@ func TestCommit_CurrentBranchError(t *testing.T) {
 	}
 
 	// Detach HEAD
-	refs.UpdateHead(dir, hash)
+	refs.UpdateHead(dir, hash, "test")
 
 	os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new"), 0644)
 	Add([]string{"new.txt"})
@@ -277,7 +278,7 @@ func TestWriteCommitAndUpdateRef_WriteRefError(t *testing.T) {
 func TestWriteCommitAndUpdateRef_DetachedHead(t *testing.T) {
 	dir := setupTestRepoWithCommit(t)
 	hash, _ := refs.ResolveHead(dir)
-	refs.UpdateHead(dir, hash)
+	refs.UpdateHead(dir, hash, "test")
 
 	idx, _ := index.ReadIndex(dir)
 	treeHash, _ := object.BuildTreeFromIndex(dir, idx)
@@ -296,16 +297,16 @@ func TestWriteCommitAndUpdateRef_DetachedHead(t *testing.T) {
 func TestWriteCommitAndUpdateRef_DetachedHeadError(t *testing.T) {
 	dir := setupTestRepoWithCommit(t)
 	hash, _ := refs.ResolveHead(dir)
-	refs.UpdateHead(dir, hash) // detach HEAD
+	refs.UpdateHead(dir, hash, "test") // detach HEAD
 
 	idx, _ := index.ReadIndex(dir)
 	treeHash, _ := object.BuildTreeFromIndex(dir, idx)
 
-	// Make HEAD read-only: CurrentBranch can still read it (returns "")
-	// but UpdateHead's WriteFile will fail
-	headPath := filepath.Join(dir, repo.GogitDir, "HEAD")
-	os.Chmod(headPath, 0444)
-	defer os.Chmod(headPath, 0644)
+	// Hold HEAD's lock: CurrentBranch can still read it (returns "")
+	// but UpdateHead cannot acquire the lock
+	lockPath := filepath.Join(dir, repo.GogitDir, "HEAD.lock")
+	os.WriteFile(lockPath, nil, 0644)
+	defer os.Remove(lockPath)
 
 	_, err := writeCommitAndUpdateRef(dir, treeHash, []string{hash}, "detached fail")
 	if err == nil {
@@ -383,3 +384,57 @@ func TestWriteCommitAndUpdateRef_BadObjectDir(t *testing.T) {
 	}
 }
 
+
+func TestCommit_BranchMovedConcurrently(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	before, _ := refs.ResolveHead(dir)
+
+	// Simulate another process committing while this commit is being written.
+	orig := writeCommitFn
+	defer func() { writeCommitFn = orig }()
+	writeCommitFn = func(root, treeHash string, parents []string, message string) (string, error) {
+		other, err := orig(root, treeHash, parents, "other process")
+		if err != nil {
+			return "", err
+		}
+		refs.WriteRef(root, "refs/heads/main", other, "commit: other process")
+		return orig(root, treeHash, parents, message)
+	}
+
+	os.WriteFile(filepath.Join(dir, "race.txt"), []byte("race"), 0644)
+	Add([]string{"race.txt"})
+	err := Commit("mine")
+	if err == nil || !strings.Contains(err.Error(), "but expected "+before) {
+		t.Fatalf("expected compare-and-swap failure, got %v", err)
+	}
+	head, _ := refs.ResolveHead(dir)
+	commit, _ := object.ReadCommit(dir, head)
+	if commit.Message != "other process" {
+		t.Errorf("the concurrent commit must not be overwritten, HEAD is %q", commit.Message)
+	}
+}
+
+func TestCommit_PrintsSummary(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("hello\nworld\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh\n"), 0755)
+	Add([]string{"test.txt", "run.sh"})
+
+	out, err := captureStdout(t, func() error { return Commit("second") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, summary, _ := strings.Cut(out, "\n")
+	want := " 2 files changed, 2 insertions(+)\n create mode 100755 run.sh\n"
+	if summary != want {
+		t.Errorf("got summary %q, want %q", summary, want)
+	}
+
+	// A rename is shown as such.
+	os.Rename(filepath.Join(dir, "run.sh"), filepath.Join(dir, "start.sh"))
+	Add([]string{"run.sh", "start.sh"})
+	out, _ = captureStdout(t, func() error { return Commit("rename") })
+	if _, summary, _ = strings.Cut(out, "\n"); summary != " 1 file changed, 0 insertions(+), 0 deletions(-)\n rename run.sh => start.sh (100%)\n" {
+		t.Errorf("unexpected rename summary %q", summary)
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+
+	"gogit/config"
+	"gogit/repo"
+)
+
+// ConfigLocation selects the configuration file a config command works on:
+// one of the scopes "system", "global" and "local", or an explicit File.
+// When both are empty, reads see the merged configuration and writes go to
+// the repository's file.
+type ConfigLocation struct {
+	Scope string
+	File  string
+}
+
+// ConfigGet prints the value of key, or every value when all is set. It
+// reports whether the key was found.
+func ConfigGet(loc ConfigLocation, key string, all bool) (bool, error) {
+	if _, err := config.CanonicalKey(key); err != nil {
+		return false, err
+	}
+	cfg, err := loadConfig(loc)
+	if err != nil {
+		return false, err
+	}
+	values := cfg.GetAll(key)
+	if len(values) == 0 {
+		return false, nil
+	}
+	if !all {
+		values = values[len(values)-1:]
+	}
+	for _, v := range values {
+		fmt.Println(v)
+	}
+	return true, nil
+}
+
+// ConfigList prints every variable as key=value, in the order read.
+func ConfigList(loc ConfigLocation) error {
+	cfg, err := loadConfig(loc)
+	if err != nil {
+		return err
+	}
+	for _, e := range cfg.Entries() {
+		fmt.Printf("%s=%s\n", e.Key, e.Value)
+	}
+	return nil
+}
+
+// ConfigSet sets key to value, or adds value alongside existing ones when
+// add is set.
+func ConfigSet(loc ConfigLocation, key, value string, add bool) error {
+	path, err := configFile(loc)
+	if err != nil {
+		return err
+	}
+	if add {
+		return config.Add(path, key, value)
+	}
+	return config.Set(path, key, value)
+}
+
+// ConfigUnset removes key, or every value of it when all is set.
+func ConfigUnset(loc ConfigLocation, key string, all bool) error {
+	path, err := configFile(loc)
+	if err != nil {
+		return err
+	}
+	if all {
+		return config.UnsetAll(path, key)
+	}
+	return config.Unset(path, key)
+}
+
+func parseScope(name string) (config.Scope, error) {
+	switch name {
+	case "system":
+		return config.ScopeSystem, nil
+	case "global":
+		return config.ScopeGlobal, nil
+	case "local":
+		return config.ScopeLocal, nil
+	}
+	return 0, fmt.Errorf("unknown config scope '%s'", name)
+}
+
+// configFile returns the file a write should change.
+func configFile(loc ConfigLocation) (string, error) {
+	if loc.File != "" {
+		return loc.File, nil
+	}
+	if loc.Scope == "" {
+		loc.Scope = "local"
+	}
+	scope, err := parseScope(loc.Scope)
+	if err != nil {
+		return "", err
+	}
+	root := ""
+	if scope == config.ScopeLocal {
+		if root, err = repo.Find(); err != nil {
+			return "", err
+		}
+	}
+	return config.Path(root, scope)
+}
+
+// loadConfig reads the configuration a read should see. Outside a
+// repository the merged view has only the system and global files.
+func loadConfig(loc ConfigLocation) (*config.Config, error) {
+	if loc.File != "" {
+		return config.LoadFile(loc.File, config.ScopeLocal)
+	}
+	if loc.Scope == "" {
+		root, err := repo.Find()
+		if err != nil {
+			root = ""
+		}
+		return config.Load(root)
+	}
+	path, err := configFile(loc)
+	if err != nil {
+		return nil, err
+	}
+	scope, _ := parseScope(loc.Scope)
+	return config.LoadFile(path, scope)
+}
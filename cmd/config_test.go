@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gogit/object"
+	"gogit/refs"
+	"gogit/repo"
+)
+
+func TestConfigSetAndGet(t *testing.T) {
+	dir := setupTestRepo(t)
+
+	if err := ConfigSet(ConfigLocation{}, "user.name", "Local User", false); err != nil {
+		t.Fatalf("ConfigSet failed: %v", err)
+	}
+	data, _ := os.ReadFile(repo.ConfigPath(dir))
+	if string(data) != "[user]\n\tname = Local User\n" {
+		t.Errorf("unexpected config file: %q", data)
+	}
+
+	out, err := captureStdout(t, func() error {
+		found, err := ConfigGet(ConfigLocation{}, "user.name", false)
+		if !found {
+			t.Error("expected key to be found")
+		}
+		return err
+	})
+	if err != nil || out != "Local User\n" {
+		t.Errorf("unexpected get output %q, %v", out, err)
+	}
+
+	found, err := ConfigGet(ConfigLocation{}, "user.email", false)
+	if err != nil || found {
+		t.Errorf("expected missing key, got %v, %v", found, err)
+	}
+	if _, err := ConfigGet(ConfigLocation{}, "nosection", false); err == nil {
+		t.Error("expected error for invalid key")
+	}
+}
+
+func TestConfig_Scopes(t *testing.T) {
+	dir := setupTestRepo(t)
+	global := ConfigLocation{Scope: "global"}
+
+	ConfigSet(global, "user.name", "Global User", false)
+	ConfigSet(ConfigLocation{Scope: "local"}, "user.name", "Local User", false)
+
+	data, _ := os.ReadFile(filepath.Join(dir, ".gogitconfig"))
+	if !strings.Contains(string(data), "Global User") {
+		t.Errorf("expected global file to be written, got %q", data)
+	}
+
+	out, _ := captureStdout(t, func() error { _, err := ConfigGet(global, "user.name", false); return err })
+	if out != "Global User\n" {
+		t.Errorf("expected global value, got %q", out)
+	}
+	out, _ = captureStdout(t, func() error { _, err := ConfigGet(ConfigLocation{}, "user.name", true); return err })
+	if out != "Global User\nLocal User\n" {
+		t.Errorf("expected all values in precedence order, got %q", out)
+	}
+	out, _ = captureStdout(t, func() error { return ConfigList(ConfigLocation{}) })
+	if out != "user.name=Global User\nuser.name=Local User\n" {
+		t.Errorf("unexpected listing: %q", out)
+	}
+
+	if err := ConfigSet(ConfigLocation{Scope: "bogus"}, "a.b", "c", false); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+	if _, err := ConfigGet(ConfigLocation{Scope: "bogus"}, "a.b", false); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+}
+
+func TestConfig_FileAndMultiValued(t *testing.T) {
+	dir := setupTestRepo(t)
+	loc := ConfigLocation{File: filepath.Join(dir, "custom")}
+
+	ConfigSet(loc, "remote.origin.fetch", "a", true)
+	ConfigSet(loc, "remote.origin.fetch", "b", true)
+	out, _ := captureStdout(t, func() error { _, err := ConfigGet(loc, "remote.origin.fetch", true); return err })
+	if out != "a\nb\n" {
+		t.Errorf("unexpected values: %q", out)
+	}
+
+	if err := ConfigUnset(loc, "remote.origin.fetch", false); err == nil {
+		t.Error("expected error unsetting a multi-valued key")
+	}
+	if err := ConfigUnset(loc, "remote.origin.fetch", true); err != nil {
+		t.Fatalf("ConfigUnset failed: %v", err)
+	}
+	if found, _ := ConfigGet(loc, "remote.origin.fetch", true); found {
+		t.Error("key should be gone")
+	}
+	ConfigSet(loc, "core.x", "1", false)
+	if err := ConfigUnset(loc, "core.x", false); err != nil {
+		t.Errorf("ConfigUnset failed: %v", err)
+	}
+}
+
+func TestConfig_OutsideRepo(t *testing.T) {
+	dir := t.TempDir()
+	orig, _ := os.Getwd()
+	defer os.Chdir(orig)
+	os.Chdir(dir)
+	t.Setenv("GOGIT_CONFIG_GLOBAL", filepath.Join(dir, "global"))
+	t.Setenv("GOGIT_CONFIG_SYSTEM", filepath.Join(dir, "system"))
+
+	if err := ConfigSet(ConfigLocation{}, "user.name", "x", false); err == nil {
+		t.Error("expected error writing local config outside a repo")
+	}
+	if err := ConfigSet(ConfigLocation{Scope: "global"}, "user.name", "Global", false); err != nil {
+		t.Fatalf("global ConfigSet failed: %v", err)
+	}
+	out, err := captureStdout(t, func() error { _, err := ConfigGet(ConfigLocation{}, "user.name", false); return err })
+	if err != nil || out != "Global\n" {
+		t.Errorf("expected global value outside a repo, got %q, %v", out, err)
+	}
+	if err := ConfigList(ConfigLocation{Scope: "local"}); err == nil {
+		t.Error("expected error listing local config outside a repo")
+	}
+}
+
+func TestConfig_IdentityForCommits(t *testing.T) {
+	dir := setupTestRepo(t)
+	t.Setenv("GOGIT_AUTHOR_NAME", "")
+	t.Setenv("GOGIT_AUTHOR_EMAIL", "")
+	ConfigSet(ConfigLocation{}, "user.name", "Config Name", false)
+	ConfigSet(ConfigLocation{}, "user.email", "config@example.com", false)
+
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("x\n"), 0644)
+	Add([]string{"f.txt"})
+	if err := Commit("configured"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	head, _ := refs.ResolveHead(dir)
+	commit, _ := object.ReadCommit(dir, head)
+	if !strings.HasPrefix(commit.Author, "Config Name <config@example.com> ") {
+		t.Errorf("expected author from config, got %q", commit.Author)
+	}
+	log, _ := refs.ReadReflog(dir, "HEAD")
+	if !strings.HasPrefix(log[0].Identity, "Config Name <config@example.com> ") {
+		t.Errorf("expected reflog identity from config, got %q", log[0].Identity)
+	}
+}
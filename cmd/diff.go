@@ -4,226 +4,635 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"gogit/config"
 	"gogit/index"
 	"gogit/object"
+	"gogit/refs"
 	"gogit/repo"
 )
 
-func Diff() error {
+// DiffOptions controls how Diff compares files.
+type DiffOptions struct {
+	// Algorithm names the diff algorithm: myers, minimal, patience or
+	// histogram. Empty selects diff.algorithm from the configuration.
+	Algorithm string
+	// Cached compares the index, instead of the working tree, with a
+	// revision, HEAD unless one is named.
+	Cached bool
+	// Paths limits the diff to these files and directories, relative to
+	// the current directory.
+	Paths []string
+	// Renames overrides diff.renames: "true" pairs renamed files, "copies"
+	// copied ones as well and "false" turns detection off.
+	Renames string
+	// RenameThreshold is the similarity, in percent, from which two files
+	// are paired. Zero selects 50.
+	RenameThreshold int
+	// Binary prints binary files as git binary patches, which can be
+	// applied, instead of only noting that they differ.
+	Binary bool
+	// Text compares every file line by line, even those that look binary.
+	Text bool
+	// Format selects an overview of the changes, such as DiffStat, instead
+	// of patches.
+	Format DiffFormat
+	// StatWidth is the width DiffStat fits its lines to. Zero selects the
+	// width of the terminal.
+	StatWidth int
+}
+
+// diffPrinter prints the changes Diff finds.
+type diffPrinter struct {
+	root    string
+	algo    diffAlgorithm
+	renames *object.RenameOptions // nil when rename detection is off
+	binary  bool                  // print binary patches
+	text    bool                  // treat every file as text
+	format  DiffFormat
+	width   int // of DiffStat lines
+	// contents holds blob content by hash, for blobs already read and for
+	// working tree files that were never written to the object store.
+	contents map[string][]byte
+}
+
+// newDiffPrinter returns a printer for the diffs of the repository at root
+// with opts, looking up its defaults in the configuration.
+func newDiffPrinter(root string, opts DiffOptions) (*diffPrinter, error) {
+	cfg, err := config.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	algo, err := lookupDiffAlgorithm(root, opts.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	renames, err := lookupRenames(cfg, opts.Renames, opts.RenameThreshold)
+	if err != nil {
+		return nil, err
+	}
+	width := opts.StatWidth
+	if width <= 0 {
+		width = termColumns()
+	}
+	return &diffPrinter{
+		root:     root,
+		algo:     algo,
+		renames:  renames,
+		binary:   opts.Binary,
+		text:     opts.Text,
+		format:   opts.Format,
+		width:    width,
+		contents: make(map[string][]byte),
+	}, nil
+}
+
+// Diff shows changes between the index and the working tree. With one
+// revision it compares that revision with the working tree, or with the
+// index if opts.Cached is set; with two revisions, or a range "A..B", it
+// compares their trees. "A...B" compares B with the merge base of A and B.
+// Except between the index and the working tree, renamed files are shown
+// as such (see DiffOptions.Renames).
+func Diff(opts DiffOptions, revs ...string) error {
 	root, err := repo.Find()
 	if err != nil {
 		return err
 	}
+	out, err := newDiffPrinter(root, opts)
+	if err != nil {
+		return err
+	}
+	var paths []string
+	for _, p := range opts.Paths {
+		rel, err := repoPath(root, p)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, rel)
+	}
+
+	if len(revs) == 1 && strings.Contains(revs[0], "..") {
+		if opts.Cached {
+			return fmt.Errorf("diff --cached does not take a range")
+		}
+		oldTree, newTree, err := rangeTrees(root, revs[0])
+		if err != nil {
+			return err
+		}
+		return out.diffTrees(oldTree, newTree, paths)
+	}
+
+	if opts.Cached {
+		if len(revs) > 1 {
+			return fmt.Errorf("diff --cached takes at most one revision")
+		}
+		rev := ""
+		if len(revs) == 1 {
+			rev = revs[0]
+		}
+		return out.diffIndex(rev, paths)
+	}
+
+	switch len(revs) {
+	case 0:
+	case 1:
+		idx, err := index.ReadIndex(root)
+		if err != nil {
+			return err
+		}
+		return out.diffRevisionToWorktree(idx, revs[0], paths)
+	case 2:
+		oldTree, err := revisionTree(root, revs[0])
+		if err != nil {
+			return err
+		}
+		newTree, err := revisionTree(root, revs[1])
+		if err != nil {
+			return err
+		}
+		return out.diffTrees(oldTree, newTree, paths)
+	default:
+		return fmt.Errorf("diff takes at most two revisions")
+	}
 
 	idx, err := index.ReadIndex(root)
 	if err != nil {
 		return err
 	}
+	return out.diffWorktree(idx, paths)
+}
 
-	for _, e := range idx.Entries {
-		absPath := filepath.Join(root, e.Path)
-		content, err := os.ReadFile(absPath)
-		if err != nil {
-			if os.IsNotExist(err) {
-				// File deleted — show full removal
-				oldContent, err := object.ReadBlob(root, e.Hash)
-				if err != nil {
-					continue
-				}
-				printUnifiedDiff(e.Path, strings.Split(string(oldContent), "\n"), nil)
+// diffWorktree compares the index with the working tree. Renames are not
+// looked for, as git does not either.
+func (out *diffPrinter) diffWorktree(idx *index.Index, paths []string) error {
+	var changes []object.Change
+	for i := range idx.Entries {
+		e := &idx.Entries[i]
+		if !object.InPaths(e.Path, paths) {
+			continue
+		}
+		if e.Stage != index.StageMerged {
+			if n := len(changes); n == 0 || changes[n-1].Path != e.Path {
+				changes = append(changes, object.Change{Path: e.Path, Unmerged: true})
 			}
 			continue
 		}
-
-		currentHash := object.HashBlob(content)
-		if currentHash == e.Hash {
+		state, _, err := checkWorktree(out.root, idx, e)
+		if err != nil || state == worktreeUnchanged || state == worktreeDeleted && e.IntentToAdd() {
 			continue
 		}
 
-		oldContent, err := object.ReadBlob(root, e.Hash)
+		// An intent-to-add path shows its whole file as new.
+		c := object.Change{Path: e.Path}
+		if !e.IntentToAdd() {
+			c.OldMode, c.OldHash = fmt.Sprintf("%o", e.Mode), e.Hash
+			content, err := object.ReadBlob(out.root, e.Hash)
+			if err != nil {
+				continue
+			}
+			out.contents[e.Hash] = content
+		}
+		if state != worktreeDeleted {
+			absPath := filepath.Join(out.root, e.Path)
+			info, err := os.Stat(absPath)
+			if err != nil {
+				continue
+			}
+			content, err := os.ReadFile(absPath)
+			if err != nil {
+				continue
+			}
+			c.NewMode, c.NewHash = worktreeMode(info), object.HashBlob(content)
+			out.contents[c.NewHash] = content
+		}
+		changes = append(changes, c)
+	}
+	return out.printFiles(changes)
+}
+
+// diffRevisionToWorktree compares the tree of rev with the working tree for
+// every path within paths tracked by either the revision or the index.
+func (out *diffPrinter) diffRevisionToWorktree(idx *index.Index, rev string, paths []string) error {
+	treeHash, err := revisionTree(out.root, rev)
+	if err != nil {
+		return err
+	}
+	tree, err := object.FlattenTreeEntries(out.root, treeHash, paths)
+	if err != nil {
+		return err
+	}
+
+	tracked := make(map[string]bool)
+	for p := range tree {
+		tracked[p] = true
+	}
+	for _, e := range idx.Entries {
+		if object.InPaths(e.Path, paths) {
+			tracked[e.Path] = true
+		}
+	}
+	sorted := make([]string, 0, len(tracked))
+	for p := range tracked {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	var changes []object.Change
+	for _, path := range sorted {
+		te, inTree := tree[path]
+
+		// A file matching an up-to-date index entry for the same blob
+		// needs no reading.
+		if e := idx.LookupEntry(path); inTree && e != nil && e.Hash == te.Hash {
+			if info, err := os.Stat(filepath.Join(out.root, path)); err == nil && idx.UpToDate(e, info) {
+				continue
+			}
+		}
+
+		c := object.Change{Path: path}
+		if inTree {
+			c.OldMode, c.OldHash = te.Mode, te.Hash
+		}
+		absPath := filepath.Join(out.root, path)
+		info, err := os.Stat(absPath)
+		var content []byte
+		if err == nil {
+			content, err = os.ReadFile(absPath)
+		}
 		if err != nil {
+			if os.IsNotExist(err) && inTree {
+				changes = append(changes, c)
+			}
 			continue
 		}
+		c.NewMode, c.NewHash = worktreeMode(info), object.HashBlob(content)
+		if c.NewHash == c.OldHash {
+			continue
+		}
+		out.contents[c.NewHash] = content
+		changes = append(changes, c)
+	}
+	return out.printChanges(changes)
+}
 
-		oldLines := strings.Split(string(oldContent), "\n")
-		newLines := strings.Split(string(content), "\n")
-		printUnifiedDiff(e.Path, oldLines, newLines)
+// revisionTree returns the tree of the commit rev names.
+func revisionTree(root, rev string) (string, error) {
+	hash, err := refs.ResolveCommit(root, rev)
+	if err != nil {
+		return "", err
 	}
+	commit, err := object.ReadCommit(root, hash)
+	if err != nil {
+		return "", err
+	}
+	return commit.TreeHash, nil
+}
 
-	return nil
+// rangeTrees returns the trees to compare for "A..B", A and B, or for
+// "A...B", the merge base of A and B and B. An omitted side means HEAD.
+func rangeTrees(root, arg string) (string, string, error) {
+	rng, err := refs.ResolveRange(root, []string{arg})
+	if err != nil {
+		return "", "", err
+	}
+	if len(rng.Exclude) == 0 {
+		return "", "", fmt.Errorf("%s: no merge base", arg)
+	}
+	oldTree, err := revisionTree(root, rng.Exclude[0])
+	if err != nil {
+		return "", "", err
+	}
+	newTree, err := revisionTree(root, rng.Include[len(rng.Include)-1])
+	if err != nil {
+		return "", "", err
+	}
+	return oldTree, newTree, nil
 }
 
-func printUnifiedDiff(path string, oldLines, newLines []string) {
-	fmt.Printf("--- a/%s\n", path)
-	fmt.Printf("+++ b/%s\n", path)
+// headTree returns the tree of HEAD, or "" before the first commit.
+func headTree(root string) (string, error) {
+	head, err := refs.ResolveHead(root)
+	if err != nil || head == "" {
+		return "", err
+	}
+	return revisionTree(root, head)
+}
 
-	if newLines == nil {
-		// File deleted
-		fmt.Printf("@@ -1,%d +0,0 @@\n", len(oldLines))
-		for _, line := range oldLines {
-			fmt.Printf("-%s\n", line)
-		}
-		return
+// diffIndex compares the tree of rev, or of HEAD when rev is empty, with
+// the index. Before the first commit the index is compared with an empty
+// tree.
+func (out *diffPrinter) diffIndex(rev string, paths []string) error {
+	var treeHash string
+	var err error
+	if rev == "" {
+		treeHash, err = headTree(out.root)
+	} else {
+		treeHash, err = revisionTree(out.root, rev)
+	}
+	if err != nil {
+		return err
 	}
 
-	// LCS-based diff
-	lcs := computeLCS(oldLines, newLines)
-	hunks := buildHunks(oldLines, newLines, lcs)
+	idx, err := index.ReadIndex(out.root)
+	if err != nil {
+		return err
+	}
+	changes, err := object.DiffTreeIndex(out.root, treeHash, idx, paths)
+	if err != nil {
+		return err
+	}
+	return out.printChanges(changes)
+}
 
-	for _, hunk := range hunks {
-		fmt.Println(hunk)
+// diffTrees compares two trees.
+func (out *diffPrinter) diffTrees(oldTree, newTree string, paths []string) error {
+	changes, err := object.DiffTrees(out.root, oldTree, newTree, paths)
+	if err != nil {
+		return err
 	}
+	return out.printChanges(changes)
 }
 
-// computeLCS computes the longest common subsequence table.
-func computeLCS(a, b []string) [][]int {
-	m, n := len(a), len(b)
-	dp := make([][]int, m+1)
-	for i := range dp {
-		dp[i] = make([]int, n+1)
+// readBlob returns the content of a blob, which may be a working tree file
+// that was only hashed.
+func (out *diffPrinter) readBlob(hash string) ([]byte, error) {
+	if content, ok := out.contents[hash]; ok {
+		return content, nil
 	}
-	for i := 1; i <= m; i++ {
-		for j := 1; j <= n; j++ {
-			if a[i-1] == b[j-1] {
-				dp[i][j] = dp[i-1][j-1] + 1
-			} else if dp[i-1][j] >= dp[i][j-1] {
-				dp[i][j] = dp[i-1][j]
-			} else {
-				dp[i][j] = dp[i][j-1]
-			}
+	return object.ReadBlob(out.root, hash)
+}
+
+// printChanges pairs up renamed files, if enabled, and prints the changes.
+func (out *diffPrinter) printChanges(changes []object.Change) error {
+	if out.renames != nil {
+		var err error
+		if changes, err = object.DetectRenames(changes, out.readBlob, *out.renames); err != nil {
+			return err
 		}
 	}
-	return dp
+	return out.printFiles(changes)
 }
 
-// buildHunks generates unified diff hunks from the LCS table.
-func buildHunks(oldLines, newLines []string, dp [][]int) []string {
-	// Backtrack to find the diff operations
-	type diffLine struct {
-		op   byte // ' ', '+', '-'
-		text string
+// printFiles prints the changed files in the printer's format: a patch for
+// each, their names or their statistics.
+func (out *diffPrinter) printFiles(changes []object.Change) error {
+	switch out.format {
+	case DiffNameOnly:
+		for _, c := range changes {
+			fmt.Println(c.Path)
+		}
+		return nil
+	case DiffNameStatus:
+		printNameStatus(changes)
+		return nil
+	case DiffStat, DiffNumStat, DiffShortStat:
+		if len(changes) == 0 {
+			return nil
+		}
+		stats, err := out.diffStats(changes)
+		if err != nil {
+			return err
+		}
+		switch out.format {
+		case DiffStat:
+			printStat(stats, out.width)
+		case DiffNumStat:
+			printNumStat(stats)
+		default:
+			printShortStat(stats)
+		}
+		return nil
 	}
 
-	var diff []diffLine
-	i, j := len(oldLines), len(newLines)
-	for i > 0 || j > 0 {
-		if i > 0 && j > 0 && oldLines[i-1] == newLines[j-1] {
-			diff = append(diff, diffLine{' ', oldLines[i-1]})
-			i--
-			j--
-		} else if j > 0 && (i == 0 || dp[i][j-1] >= dp[i-1][j]) {
-			diff = append(diff, diffLine{'+', newLines[j-1]})
-			j--
-		} else {
-			diff = append(diff, diffLine{'-', oldLines[i-1]})
-			i--
+	for _, c := range changes {
+		if c.Unmerged {
+			fmt.Printf("* Unmerged path %s\n", c.Path)
+			continue
 		}
+		old, new, err := out.readChange(c)
+		if err != nil {
+			return err
+		}
+		out.printFileDiff(c, old, new)
 	}
+	return nil
+}
 
-	// Reverse the diff (we built it backwards)
-	for l, r := 0, len(diff)-1; l < r; l, r = l+1, r-1 {
-		diff[l], diff[r] = diff[r], diff[l]
+// readChange returns the old and new content of a changed file. Neither is
+// read when both sides are the same blob, as for an exact rename.
+func (out *diffPrinter) readChange(c object.Change) (old, new []byte, err error) {
+	if c.OldHash == c.NewHash {
+		return nil, nil, nil
 	}
-
-	// Build hunks with context
-	const contextLines = 3
-	var result []string
-	var hunkLines []string
-	hunkOldStart, hunkNewStart := 0, 0
-	hunkOldCount, hunkNewCount := 0, 0
-	oldLine, newLine := 1, 1
-	lastChange := -1
-
-	flushHunk := func() {
-		if len(hunkLines) > 0 {
-			header := fmt.Sprintf("@@ -%d,%d +%d,%d @@",
-				hunkOldStart, hunkOldCount, hunkNewStart, hunkNewCount)
-			result = append(result, header)
-			result = append(result, hunkLines...)
-			hunkLines = nil
-			hunkOldCount = 0
-			hunkNewCount = 0
-		}
-	}
-
-	for idx, d := range diff {
-		isChange := d.op != ' '
-
-		if isChange {
-			if lastChange < 0 || idx-lastChange > 2*contextLines {
-				flushHunk()
-				// Start new hunk with context
-				start := idx - contextLines
-				if start < 0 {
-					start = 0
-				}
-				tmpOld, tmpNew := 1, 1
-				for k := 0; k < start; k++ {
-					if diff[k].op != '+' {
-						tmpOld++
-					}
-					if diff[k].op != '-' {
-						tmpNew++
-					}
-				}
-				hunkOldStart = tmpOld
-				hunkNewStart = tmpNew
-				hunkOldCount = 0
-				hunkNewCount = 0
-				for k := start; k < idx; k++ {
-					line := fmt.Sprintf(" %s", diff[k].text)
-					hunkLines = append(hunkLines, line)
-					hunkOldCount++
-					hunkNewCount++
-				}
-			} else {
-				// Add intervening context
-				for k := lastChange + 1; k < idx; k++ {
-					line := fmt.Sprintf(" %s", diff[k].text)
-					hunkLines = append(hunkLines, line)
-					hunkOldCount++
-					hunkNewCount++
-				}
-			}
-			lastChange = idx
+	if c.OldHash != "" {
+		if old, err = out.readBlob(c.OldHash); err != nil {
+			return nil, nil, err
+		}
+	}
+	if c.NewHash != "" {
+		if new, err = out.readBlob(c.NewHash); err != nil {
+			return nil, nil, err
 		}
+	}
+	return old, new, nil
+}
 
-		if isChange {
-			hunkLines = append(hunkLines, fmt.Sprintf("%c%s", d.op, d.text))
-			if d.op == '-' {
-				hunkOldCount++
-			} else {
-				hunkNewCount++
-			}
+// printFileDiff prints the patch for one change, given the old and new
+// content of the file, in the format of git diff: a "diff --git" line, lines
+// for a created or deleted file, a mode change or a rename, an "index" line
+// with both blob ids, and the hunks. Binary content is only reported as
+// differing unless a binary patch was asked for.
+func (out *diffPrinter) printFileDiff(c object.Change, old, new []byte) {
+	oldPath := c.Path
+	if c.OldPath != "" {
+		oldPath = c.OldPath
+	}
+	fmt.Printf("diff --git a/%s b/%s\n", oldPath, c.Path)
+	switch {
+	case c.OldHash == "":
+		fmt.Printf("new file mode %s\n", c.NewMode)
+	case c.NewHash == "":
+		fmt.Printf("deleted file mode %s\n", c.OldMode)
+	case c.OldMode != c.NewMode:
+		fmt.Printf("old mode %s\n", c.OldMode)
+		fmt.Printf("new mode %s\n", c.NewMode)
+	}
+	if c.OldPath != "" {
+		verb := "rename"
+		if c.Copied {
+			verb = "copy"
 		}
+		fmt.Printf("similarity index %d%%\n", c.Similarity)
+		fmt.Printf("%s from %s\n", verb, c.OldPath)
+		fmt.Printf("%s to %s\n", verb, c.Path)
+	}
+	if c.OldHash == c.NewHash {
+		return
+	}
+
+	// A binary patch needs the full hashes to be applied.
+	binary := !out.text && (isBinary(old) || isBinary(new))
+	abbrev := shortHashLen
+	if binary && out.binary {
+		abbrev = len(refs.ZeroHash)
+	}
+	fmt.Printf("index %s..%s", abbrevHash(c.OldHash, abbrev), abbrevHash(c.NewHash, abbrev))
+	if c.OldMode == c.NewMode {
+		fmt.Printf(" %s", c.OldMode)
+	}
+	fmt.Println()
+
+	oldName, newName := "a/"+oldPath, "b/"+c.Path
+	if c.OldHash == "" {
+		oldName = "/dev/null"
+	}
+	if c.NewHash == "" {
+		newName = "/dev/null"
+	}
+	switch {
+	case binary && out.binary:
+		printBinaryPatch(old, new)
+	case binary:
+		fmt.Printf("Binary files %s and %s differ\n", oldName, newName)
+	default:
+		printUnifiedDiff(out.algo, oldName, newName, splitLines(string(old)), splitLines(string(new)))
+	}
+}
 
+// abbrevHash returns the first n digits of hash, or n zeros, the id git
+// gives a missing file.
+func abbrevHash(hash string, n int) string {
+	if hash == "" {
+		return strings.Repeat("0", n)
+	}
+	return hash[:n]
+}
+
+// worktreeMode returns the tree mode of a working tree file, executable or
+// not.
+func worktreeMode(info os.FileInfo) string {
+	if info.Mode()&0111 != 0 {
+		return "100755"
+	}
+	return "100644"
+}
+
+// contextLines is how many unchanged lines a hunk shows around changes.
+const contextLines = 3
+
+// printUnifiedDiff prints the "---" and "+++" lines, labelled oldName and
+// newName, and the hunks that turn oldLines into newLines, which keep their
+// line endings as splitLines returns them. Nothing is printed when the
+// lines are the same.
+func printUnifiedDiff(algo diffAlgorithm, oldName, newName string, oldLines, newLines []string) {
+	hunks := buildHunks(diffScript(algo, oldLines, newLines))
+	if len(hunks) == 0 {
+		return
+	}
+	fmt.Printf("--- %s\n", oldName)
+	fmt.Printf("+++ %s\n", newName)
+	for _, line := range hunks {
+		fmt.Println(line)
+	}
+}
+
+// buildHunks groups the changes of an edit script into unified diff hunks,
+// with contextLines unchanged lines on either side. Changes closer than
+// twice that share a hunk. Each hunk starts with its "@@" header, and a
+// line without a final newline is followed by a "\ No newline at end of
+// file" marker.
+func buildHunks(diff []diffLine) []string {
+	// oldBefore[i] and newBefore[i] count the old and new lines before
+	// diff[i].
+	oldBefore := make([]int, len(diff)+1)
+	newBefore := make([]int, len(diff)+1)
+	for i, d := range diff {
+		oldBefore[i+1], newBefore[i+1] = oldBefore[i], newBefore[i]
 		if d.op != '+' {
-			oldLine++
+			oldBefore[i+1]++
 		}
 		if d.op != '-' {
-			newLine++
+			newBefore[i+1]++
 		}
 	}
 
-	// Add trailing context after last change
-	if lastChange >= 0 {
-		end := lastChange + contextLines + 1
-		if end > len(diff) {
-			end = len(diff)
+	var result []string
+	funcLine, searched := "", 0
+	for i := 0; i < len(diff); {
+		if diff[i].op == ' ' {
+			i++
+			continue
 		}
-		for k := lastChange + 1; k < end; k++ {
-			line := fmt.Sprintf(" %s", diff[k].text)
-			hunkLines = append(hunkLines, line)
-			hunkOldCount++
-			hunkNewCount++
+		// Extend the hunk over every change that follows within reach.
+		end := i
+		for {
+			for end < len(diff) && diff[end].op != ' ' {
+				end++
+			}
+			next := end
+			for next < len(diff) && diff[next].op == ' ' {
+				next++
+			}
+			if next == len(diff) || next-end > 2*contextLines {
+				break
+			}
+			end = next
 		}
-		flushHunk()
+		start, stop := max(i-contextLines, 0), min(end+contextLines, len(diff))
+
+		// The header names the last line before the hunk that looks like
+		// the start of a function or section.
+		for ; searched < start; searched++ {
+			if d := diff[searched]; d.op != '+' {
+				if name, ok := funcName(d.text); ok {
+					funcLine = " " + name
+				}
+			}
+		}
+		result = append(result, fmt.Sprintf("@@ -%s +%s @@%s",
+			hunkRange(oldBefore[start], oldBefore[stop]-oldBefore[start]),
+			hunkRange(newBefore[start], newBefore[stop]-newBefore[start]),
+			funcLine))
+		for _, d := range diff[start:stop] {
+			text, ok := strings.CutSuffix(d.text, "\n")
+			result = append(result, string(d.op)+text)
+			if !ok {
+				result = append(result, "\\ No newline at end of file")
+			}
+		}
+		i = stop
 	}
+	return result
+}
 
-	_ = oldLine
-	_ = newLine
+// hunkRange formats the lines of one side of a hunk, count lines after the
+// first skip, as "start,count", or "start" alone for a single line. An
+// empty side gives the line it follows.
+func hunkRange(skip, count int) string {
+	start := skip + 1
+	if count == 0 {
+		start = skip
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
 
-	return result
+// funcName reports whether line starts a function or section as git sees
+// one by default, with a letter, '_' or '$', and returns its first 80
+// bytes without trailing space.
+func funcName(line string) (string, bool) {
+	if line == "" {
+		return "", false
+	}
+	if c := line[0]; !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$') {
+		return "", false
+	}
+	if len(line) > 80 {
+		line = line[:80]
+	}
+	return strings.TrimRight(line, " \t\n\v\f\r"), true
 }
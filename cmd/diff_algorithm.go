@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"gogit/config"
+)
+
+// defaultDiffAlgorithm is used when neither the command line nor the
+// diff.algorithm setting names one.
+const defaultDiffAlgorithm = "myers"
+
+// diffAlgorithm computes an edit script between two sequences of lines.
+// Lines are interned to integers, so equal lines have equal values.
+type diffAlgorithm interface {
+	// changes reports for each line of a whether the script removes it,
+	// and for each line of b whether it inserts it.
+	changes(a, b []int) (changedA, changedB []bool)
+}
+
+// diffAlgorithms maps the names accepted by --diff-algorithm and
+// diff.algorithm to their implementations.
+var diffAlgorithms = map[string]diffAlgorithm{
+	"default":   myersAlgorithm{},
+	"myers":     myersAlgorithm{},
+	"minimal":   myersAlgorithm{minimal: true},
+	"patience":  patienceAlgorithm{},
+	"histogram": histogramAlgorithm{},
+}
+
+// lookupDiffAlgorithm returns the algorithm called name. An empty name
+// selects diff.algorithm from the configuration, or myers.
+func lookupDiffAlgorithm(root, name string) (diffAlgorithm, error) {
+	if name == "" {
+		cfg, err := config.Load(root)
+		if err != nil {
+			return nil, err
+		}
+		name = cfg.GetString("diff.algorithm", defaultDiffAlgorithm)
+	}
+	algo, ok := diffAlgorithms[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown diff algorithm '%s': expected myers, minimal, patience or histogram", name)
+	}
+	return algo, nil
+}
+
+// diffLine is a single line of an edit script: ' ' keeps a line present in
+// both inputs, '-' removes an old line and '+' inserts a new one.
+type diffLine struct {
+	op   byte // ' ', '+', '-'
+	text string
+}
+
+// diffScript returns an edit script, computed by algo, that turns oldLines
+// into newLines.
+func diffScript(algo diffAlgorithm, oldLines, newLines []string) []diffLine {
+	a, b := internLines(oldLines, newLines)
+	changedA, changedB := algo.changes(a, b)
+	oldSide := &diffSide{lines: a, text: oldLines, changed: changedA}
+	newSide := &diffSide{lines: b, text: newLines, changed: changedB}
+	compactChanges(oldSide, newSide)
+	compactChanges(newSide, oldSide)
+
+	// List the removed lines of each change before the inserted ones.
+	var diff []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for i < len(a) && changedA[i] {
+			diff = append(diff, diffLine{'-', oldLines[i]})
+			i++
+		}
+		for j < len(b) && changedB[j] {
+			diff = append(diff, diffLine{'+', newLines[j]})
+			j++
+		}
+		if i < len(a) && j < len(b) && !changedA[i] && !changedB[j] {
+			diff = append(diff, diffLine{' ', oldLines[i]})
+			i++
+			j++
+		}
+	}
+	return diff
+}
+
+// internLines numbers the distinct lines of a and b, so that equal lines
+// on either side get the same integer.
+func internLines(a, b []string) ([]int, []int) {
+	ids := make(map[string]int)
+	intern := func(lines []string) []int {
+		out := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+	return intern(a), intern(b)
+}
+
+// markChanged flags lines lo through hi-1 as changed.
+func markChanged(changed []bool, lo, hi int) {
+	for i := lo; i < hi; i++ {
+		changed[i] = true
+	}
+}
+
+// diffRange runs algo on a[aLo:aHi] and b[bLo:bHi] and records the result
+// in changedA and changedB. Algorithms use it to hand off a region.
+func diffRange(algo diffAlgorithm, a, b []int, changedA, changedB []bool, aLo, aHi, bLo, bHi int) {
+	subA, subB := algo.changes(a[aLo:aHi], b[bLo:bHi])
+	copy(changedA[aLo:aHi], subA)
+	copy(changedB[bLo:bHi], subB)
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gogit/repo"
+)
+
+// lcsLength is a quadratic reference for the number of lines a minimal
+// edit script keeps.
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// checkScript verifies that diff turns a into b and returns the number of
+// lines it keeps.
+func checkScript(t *testing.T, a, b []string, diff []diffLine) int {
+	t.Helper()
+	var gotA, gotB []string
+	kept := 0
+	for _, d := range diff {
+		switch d.op {
+		case ' ':
+			gotA = append(gotA, d.text)
+			gotB = append(gotB, d.text)
+			kept++
+		case '-':
+			gotA = append(gotA, d.text)
+		case '+':
+			gotB = append(gotB, d.text)
+		}
+	}
+	if strings.Join(gotA, "\n") != strings.Join(a, "\n") || len(gotA) != len(a) {
+		t.Fatalf("script does not start from %q: %q", a, gotA)
+	}
+	if strings.Join(gotB, "\n") != strings.Join(b, "\n") || len(gotB) != len(b) {
+		t.Fatalf("script does not produce %q: %q", b, gotB)
+	}
+	return kept
+}
+
+// formatScript renders an edit script compactly, one word per line.
+func formatScript(diff []diffLine) string {
+	var parts []string
+	for _, d := range diff {
+		parts = append(parts, fmt.Sprintf("%c%s", d.op, d.text))
+	}
+	return strings.Join(parts, " ")
+}
+
+// randomLines returns up to 40 lines drawn from a small alphabet, so that
+// they repeat a lot.
+func randomLines(rng *rand.Rand, alphabet int) []string {
+	lines := make([]string, rng.Intn(40))
+	for i := range lines {
+		lines[i] = string(rune('a' + rng.Intn(alphabet)))
+	}
+	return lines
+}
+
+func TestDiffAlgorithms_ValidScripts(t *testing.T) {
+	for name, algo := range diffAlgorithms {
+		rng := rand.New(rand.NewSource(1))
+		for n := 0; n < 500; n++ {
+			alphabet := 2 + rng.Intn(30)
+			a, b := randomLines(rng, alphabet), randomLines(rng, alphabet)
+			kept := checkScript(t, a, b, diffScript(algo, a, b))
+			if _, ok := algo.(myersAlgorithm); ok && kept != lcsLength(a, b) {
+				t.Fatalf("%s: %q -> %q: kept %d lines, want %d", name, a, b, kept, lcsLength(a, b))
+			}
+		}
+	}
+}
+
+func TestDiffScript_SlidesChangesDown(t *testing.T) {
+	// Expected scripts are git's for the same inputs.
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"a } } } b", "a } } b", " a  }  } -}  b"},
+		{"a b", "a b b", " a  b +b"},
+		{"x a x", "x a x a x", " x  a  x +a +x"},
+	}
+	for _, tt := range tests {
+		for name, algo := range diffAlgorithms {
+			a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+			if got := formatScript(diffScript(algo, a, b)); got != tt.want {
+				t.Errorf("%s: %q -> %q: got %q, want %q", name, tt.a, tt.b, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestDiffScript_IndentHeuristic(t *testing.T) {
+	// A group that could start or end at several places keeps whole
+	// blocks together, as git places it.
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{
+			"func a() {\n\tx\n}\n\nfunc c() {\n\tz\n}\n",
+			"func a() {\n\tx\n}\n\nfunc b() {\n\ty\n}\n\nfunc c() {\n\tz\n}\n",
+			" func a() {| \tx| }| |+func b() {|+\ty|+}|+| func c() {| \tz| }",
+		},
+		{
+			"if a {\n\tx\n}\nif b {\n\tx\n}\n",
+			"if a {\n\tx\n}\nif c {\n\tx\n}\nif b {\n\tx\n}\n",
+			" if a {| \tx| }|+if c {|+\tx|+}| if b {| \tx| }",
+		},
+	}
+	for _, tt := range tests {
+		for name, algo := range diffAlgorithms {
+			var parts []string
+			for _, d := range diffScript(algo, splitLines(tt.a), splitLines(tt.b)) {
+				parts = append(parts, string(d.op)+strings.TrimSuffix(d.text, "\n"))
+			}
+			if got := strings.Join(parts, "|"); got != tt.want {
+				t.Errorf("%s: got %q, want %q", name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestLookupDiffAlgorithm(t *testing.T) {
+	dir := setupTestRepo(t)
+
+	tests := []struct {
+		name string
+		want diffAlgorithm
+	}{
+		{"myers", myersAlgorithm{}},
+		{"default", myersAlgorithm{}},
+		{"minimal", myersAlgorithm{minimal: true}},
+		{"patience", patienceAlgorithm{}},
+		{"Histogram", histogramAlgorithm{}},
+		{"", myersAlgorithm{}},
+	}
+	for _, tt := range tests {
+		got, err := lookupDiffAlgorithm(dir, tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+
+	_, err := lookupDiffAlgorithm(dir, "lcs")
+	if err == nil || !strings.Contains(err.Error(), "unknown diff algorithm 'lcs'") {
+		t.Errorf("expected unknown algorithm error, got %v", err)
+	}
+}
+
+func TestLookupDiffAlgorithm_Config(t *testing.T) {
+	dir := setupTestRepo(t)
+	configPath := filepath.Join(dir, repo.GogitDir, "config")
+	os.WriteFile(configPath, []byte("[diff]\n\talgorithm = patience\n"), 0644)
+
+	got, err := lookupDiffAlgorithm(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (patienceAlgorithm{}) {
+		t.Errorf("expected patience from diff.algorithm, got %#v", got)
+	}
+	if got, _ := lookupDiffAlgorithm(dir, "histogram"); got != (histogramAlgorithm{}) {
+		t.Errorf("the named algorithm should override the config, got %#v", got)
+	}
+
+	os.WriteFile(configPath, []byte("[diff]\n\talgorithm = bogus\n"), 0644)
+	if _, err := lookupDiffAlgorithm(dir, ""); err == nil {
+		t.Error("expected an error for an unknown diff.algorithm")
+	}
+	os.WriteFile(configPath, []byte("[diff\n"), 0644)
+	if _, err := lookupDiffAlgorithm(dir, ""); err == nil {
+		t.Error("expected an error for a malformed config")
+	}
+}
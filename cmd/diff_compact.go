@@ -0,0 +1,288 @@
+package cmd
+
+// diffSide is one input of an edit script: its interned lines, their text
+// and which of them the script changes.
+type diffSide struct {
+	lines   []int
+	text    []string
+	changed []bool
+}
+
+// changeGroup is a run of changed lines, start through end-1, on one side.
+// Every unchanged line ends a group, so the groups of both sides pair up
+// one to one, and a group is empty where only the other side changed.
+type changeGroup struct {
+	start, end int
+}
+
+func (s *diffSide) isChanged(i int) bool {
+	return i >= 0 && i < len(s.changed) && s.changed[i]
+}
+
+func (s *diffSide) firstGroup() changeGroup {
+	var g changeGroup
+	for s.isChanged(g.end) {
+		g.end++
+	}
+	return g
+}
+
+// nextGroup moves g to the group after it, reporting false at the end.
+func (s *diffSide) nextGroup(g *changeGroup) bool {
+	if g.end == len(s.changed) {
+		return false
+	}
+	g.start = g.end + 1
+	for g.end = g.start; s.isChanged(g.end); g.end++ {
+	}
+	return true
+}
+
+// prevGroup moves g to the group before it, reporting false at the start.
+func (s *diffSide) prevGroup(g *changeGroup) bool {
+	if g.start == 0 {
+		return false
+	}
+	g.end = g.start - 1
+	for g.start = g.end; s.isChanged(g.start - 1); g.start-- {
+	}
+	return true
+}
+
+// slideDown moves a non-empty group one line down, when the line after it
+// equals its first line, merging it with a group it runs into.
+func (s *diffSide) slideDown(g *changeGroup) bool {
+	if g.end >= len(s.lines) || s.lines[g.start] != s.lines[g.end] {
+		return false
+	}
+	s.changed[g.start], s.changed[g.end] = false, true
+	g.start, g.end = g.start+1, g.end+1
+	for s.isChanged(g.end) {
+		g.end++
+	}
+	return true
+}
+
+// slideUp moves a non-empty group one line up, when the line before it
+// equals its last line, merging it with a group it runs into.
+func (s *diffSide) slideUp(g *changeGroup) bool {
+	if g.start == 0 || s.lines[g.start-1] != s.lines[g.end-1] {
+		return false
+	}
+	g.start, g.end = g.start-1, g.end-1
+	s.changed[g.start], s.changed[g.end] = true, false
+	for s.isChanged(g.start - 1) {
+		g.start--
+	}
+	return true
+}
+
+// compactChanges moves the runs of changed lines of x to where git puts
+// them, keeping the other side o in step. When lines repeat, as blank lines
+// and closing braces do, a run can slide over equal lines without changing
+// the script's length. Each run is slid as far down as it goes, merging
+// with the runs it meets; then back up to line up with a change on the
+// other side, if it passed one, or else to the position the indent
+// heuristic scores best.
+func compactChanges(x, o *diffSide) {
+	g, og := x.firstGroup(), o.firstGroup()
+	for {
+		if g.end > g.start {
+			var earliestEnd, endMatchingOther int
+			for {
+				size := g.end - g.start
+				endMatchingOther = -1
+				for x.slideUp(&g) {
+					o.prevGroup(&og)
+				}
+				earliestEnd = g.end
+				if og.end > og.start {
+					endMatchingOther = g.end
+				}
+				for x.slideDown(&g) {
+					o.nextGroup(&og)
+					if og.end > og.start {
+						endMatchingOther = g.end
+					}
+				}
+				if size == g.end-g.start {
+					break
+				}
+			}
+
+			switch {
+			case g.end == earliestEnd:
+			case endMatchingOther != -1:
+				for og.end == og.start {
+					x.slideUp(&g)
+					o.prevGroup(&og)
+				}
+			default:
+				for best := x.bestShift(g, earliestEnd); g.end > best; {
+					x.slideUp(&g)
+					o.prevGroup(&og)
+				}
+			}
+		}
+		if !x.nextGroup(&g) {
+			break
+		}
+		o.nextGroup(&og)
+	}
+}
+
+// The indent heuristic, ported from git, scores the two places where a
+// group splits its unchanged surroundings, preferring splits at blank lines
+// and before lines indented less than what follows, so that a group
+// starts and ends with whole blocks.
+const (
+	indentMaxSliding = 100 // the furthest a group is slid back up
+	indentMax        = 200 // indentation counted at most
+	blanksMax        = 20  // blank lines counted at most
+
+	startOfFilePenalty              = 1
+	endOfFilePenalty                = 21
+	totalBlankWeight                = -30
+	postBlankWeight                 = 6
+	relativeIndentPenalty           = -4
+	relativeIndentWithBlankPenalty  = 10
+	relativeOutdentPenalty          = 24
+	relativeOutdentWithBlankPenalty = 17
+	relativeDedentPenalty           = 23
+	relativeDedentWithBlankPenalty  = 17
+	indentWeight                    = 60
+)
+
+// bestShift returns the end the group g, slid as far down as it goes,
+// scores best at, from earliestEnd, its highest, down to its current end.
+func (s *diffSide) bestShift(g changeGroup, earliestEnd int) int {
+	size := g.end - g.start
+	best := -1
+	var bestScore splitScore
+	for shift := max(earliestEnd, g.end-size-1, g.end-indentMaxSliding); shift <= g.end; shift++ {
+		var score splitScore
+		score.add(s.measureSplit(shift))
+		score.add(s.measureSplit(shift - size))
+		if best == -1 || score.cmp(bestScore) <= 0 {
+			best, bestScore = shift, score
+		}
+	}
+	return best
+}
+
+// splitMeasure describes the lines around a split before line split.
+type splitMeasure struct {
+	endOfFile  bool
+	indent     int // of the line after the split, -1 if blank
+	preBlank   int // blank lines just before the split
+	preIndent  int // of the first non-blank line before them, -1 if none
+	postBlank  int // blank lines after the line after the split
+	postIndent int // of the first non-blank line after them, -1 if none
+}
+
+func (s *diffSide) measureSplit(split int) splitMeasure {
+	m := splitMeasure{indent: -1, preIndent: -1, postIndent: -1}
+	if split >= len(s.text) {
+		m.endOfFile = true
+	} else {
+		m.indent = lineIndent(s.text[split])
+	}
+	for i := split - 1; i >= 0; i-- {
+		if m.preIndent = lineIndent(s.text[i]); m.preIndent != -1 {
+			break
+		}
+		if m.preBlank++; m.preBlank == blanksMax {
+			m.preIndent = 0
+			break
+		}
+	}
+	for i := split + 1; i < len(s.text); i++ {
+		if m.postIndent = lineIndent(s.text[i]); m.postIndent != -1 {
+			break
+		}
+		if m.postBlank++; m.postBlank == blanksMax {
+			m.postIndent = 0
+			break
+		}
+	}
+	return m
+}
+
+// lineIndent returns the width of the leading whitespace of line, with
+// tabs to multiples of 8, or -1 for a line of only whitespace.
+func lineIndent(line string) int {
+	n := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			n++
+		case '\t':
+			n += 8 - n%8
+		case '\n', '\v', '\f', '\r':
+		default:
+			return n
+		}
+		if n >= indentMax {
+			return indentMax
+		}
+	}
+	return -1
+}
+
+// splitScore sums the scores of the splits around a group; lower is
+// better.
+type splitScore struct {
+	effectiveIndent int
+	penalty         int
+}
+
+func (s *splitScore) add(m splitMeasure) {
+	if m.preIndent == -1 && m.preBlank == 0 {
+		s.penalty += startOfFilePenalty
+	}
+	if m.endOfFile {
+		s.penalty += endOfFilePenalty
+	}
+
+	postBlank := 0
+	if m.indent == -1 {
+		postBlank = 1 + m.postBlank
+	}
+	totalBlank := m.preBlank + postBlank
+	s.penalty += totalBlankWeight*totalBlank + postBlankWeight*postBlank
+
+	indent := m.indent
+	if indent == -1 {
+		indent = m.postIndent
+	}
+	s.effectiveIndent += indent
+
+	anyBlanks := totalBlank != 0
+	switch {
+	case indent == -1, m.preIndent == -1, indent == m.preIndent:
+	case indent > m.preIndent:
+		s.penalty += pick(anyBlanks, relativeIndentWithBlankPenalty, relativeIndentPenalty)
+	case m.postIndent != -1 && m.postIndent > indent:
+		s.penalty += pick(anyBlanks, relativeOutdentWithBlankPenalty, relativeOutdentPenalty)
+	default:
+		s.penalty += pick(anyBlanks, relativeDedentWithBlankPenalty, relativeDedentPenalty)
+	}
+}
+
+// cmp compares two scores, negative when s is better than t.
+func (s splitScore) cmp(t splitScore) int {
+	indents := 0
+	if s.effectiveIndent > t.effectiveIndent {
+		indents = 1
+	} else if s.effectiveIndent < t.effectiveIndent {
+		indents = -1
+	}
+	return indentWeight*indents + s.penalty - t.penalty
+}
+
+func pick(cond bool, a, b int) int {
+	if cond {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompactChanges(t *testing.T) {
+	// Expected scripts are git's for the same inputs. A deletion that could
+	// slide past an insertion lines up with it.
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"a b a b c", "a b x c", " a  b -a -b +x  c"},
+		{"p a a q", "p a b a q", " p  a +b  a  q"},
+		{"a a a", "a b a a", " a +b  a  a"},
+	}
+	for _, tt := range tests {
+		a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+		if got := formatScript(diffScript(myersAlgorithm{}, a, b)); got != tt.want {
+			t.Errorf("%q -> %q: got %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLineIndent(t *testing.T) {
+	tests := []struct {
+		line string
+		want int
+	}{
+		{"x\n", 0},
+		{"  x\n", 2},
+		{"\tx\n", 8},
+		{"  \tx\n", 8},
+		{"\t  x", 10},
+		{"\n", -1},
+		{" \t\r\n", -1},
+		{"", -1},
+		{strings.Repeat(" ", 300) + "x", indentMax},
+	}
+	for _, tt := range tests {
+		if got := lineIndent(tt.line); got != tt.want {
+			t.Errorf("lineIndent(%q) = %d, want %d", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestSplitScore_PrefersBlankLines(t *testing.T) {
+	s := &diffSide{text: splitLines("func a() {\n}\n\nfunc b() {\n}\n")}
+	var atBlank, inside splitScore
+	atBlank.add(s.measureSplit(3))
+	inside.add(s.measureSplit(1))
+	if atBlank.cmp(inside) >= 0 {
+		t.Errorf("expected a split after a blank line to score better: %+v vs %+v", atBlank, inside)
+	}
+}
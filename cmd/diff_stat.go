@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gogit/object"
+)
+
+// DiffFormat selects what Diff prints for the changed files.
+type DiffFormat int
+
+const (
+	DiffPatch      DiffFormat = iota // a unified diff per file
+	DiffStat                         // changed lines per file as a histogram, and a summary
+	DiffNumStat                      // added and deleted line counts per file
+	DiffShortStat                    // only the summary line of DiffStat
+	DiffNameOnly                     // the names of the changed files
+	DiffNameStatus                   // the names with a status letter, as in "M\tpath"
+)
+
+// defaultColumns is the width assumed when neither COLUMNS nor the
+// terminal tells one.
+const defaultColumns = 80
+
+// fileStat counts the changes to one file. For a binary file added and
+// deleted are the new and old sizes in bytes.
+type fileStat struct {
+	name     string // the path, or "old => new" for a rename or copy
+	added    int
+	deleted  int
+	binary   bool
+	unmerged bool
+}
+
+// diffStats counts the lines added and removed by each change.
+func (out *diffPrinter) diffStats(changes []object.Change) ([]fileStat, error) {
+	stats := make([]fileStat, 0, len(changes))
+	for _, c := range changes {
+		st := fileStat{name: c.Path, unmerged: c.Unmerged}
+		if c.OldPath != "" {
+			st.name = renameName(c.OldPath, c.Path)
+		}
+		if c.Unmerged || c.OldHash == c.NewHash {
+			stats = append(stats, st)
+			continue
+		}
+		old, new, err := out.readChange(c)
+		if err != nil {
+			return nil, err
+		}
+		if !out.text && (isBinary(old) || isBinary(new)) {
+			st.binary, st.added, st.deleted = true, len(new), len(old)
+			stats = append(stats, st)
+			continue
+		}
+		for _, d := range diffScript(out.algo, splitLines(string(old)), splitLines(string(new))) {
+			switch d.op {
+			case '+':
+				st.added++
+			case '-':
+				st.deleted++
+			}
+		}
+		stats = append(stats, st)
+	}
+	return stats, nil
+}
+
+// renameName shows a rename or copy from a to b, putting the parts that
+// differ in braces when the paths share leading or trailing directories, as
+// in "dir/{old => new}/file".
+func renameName(a, b string) string {
+	prefix := 0
+	for i := 0; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
+		if a[i] == '/' {
+			prefix = i + 1
+		}
+	}
+	// The suffix is found from the end of both paths, which compare
+	// equal, and may run one byte into the prefix to see its slash.
+	at := func(s string, i int) byte {
+		if i == len(s) {
+			return 0
+		}
+		return s[i]
+	}
+	stop := prefix
+	if prefix > 0 {
+		stop--
+	}
+	suffix := 0
+	for i, j := len(a), len(b); i >= stop && j >= stop && at(a, i) == at(b, j); i, j = i-1, j-1 {
+		if at(a, i) == '/' {
+			suffix = len(a) - i
+		}
+	}
+
+	aMid, bMid := max(len(a)-prefix-suffix, 0), max(len(b)-prefix-suffix, 0)
+	if prefix+suffix == 0 {
+		return a + " => " + b
+	}
+	return a[:prefix] + "{" + a[prefix:prefix+aMid] + " => " + b[prefix:prefix+bMid] + "}" + a[len(a)-suffix:]
+}
+
+// printNumStat prints the added and deleted line counts of each file, or
+// dashes for a binary file.
+func printNumStat(stats []fileStat) {
+	for _, st := range stats {
+		if st.binary {
+			fmt.Printf("-\t-\t%s\n", st.name)
+			continue
+		}
+		fmt.Printf("%d\t%d\t%s\n", st.added, st.deleted, st.name)
+	}
+}
+
+// printStat prints a line per file with its count of changed lines and a
+// bar of '+' and '-', scaled so that every line fits in width columns, and
+// then the summary line.
+func printStat(stats []fileStat, width int) {
+	maxName, maxChange, numberWidth, binWidth := 0, 0, 0, 0
+	for _, st := range stats {
+		maxName = max(maxName, len(st.name))
+		if st.binary {
+			// "Bin XXX -> YYY bytes", with the counts aligned with "Bin"
+			binWidth = max(binWidth, 14+len(strconv.Itoa(st.added))+len(strconv.Itoa(st.deleted)))
+			numberWidth = 3
+			continue
+		}
+		maxChange = max(maxChange, st.added+st.deleted)
+	}
+	numberWidth = max(numberWidth, len(strconv.Itoa(maxChange)))
+
+	// Each line is " name | count graph": the name and the graph get what
+	// they need if it fits, and otherwise the graph gets at most 3/8 of the
+	// width and the name the rest.
+	width = max(width, 16+6+numberWidth)
+	graphWidth := maxChange
+	if maxChange+4 <= binWidth {
+		graphWidth = binWidth - 4
+	}
+	nameWidth := maxName
+	if nameWidth+numberWidth+6+graphWidth > width {
+		if graphWidth > width*3/8-numberWidth-6 {
+			graphWidth = max(width*3/8-numberWidth-6, 6)
+		}
+		if nameWidth > width-numberWidth-6-graphWidth {
+			nameWidth = width - numberWidth - 6 - graphWidth
+		} else {
+			graphWidth = width - numberWidth - 6 - nameWidth
+		}
+	}
+
+	added, deleted := 0, 0
+	for _, st := range stats {
+		// Too long a name loses its leading directories to "...".
+		name, prefix := st.name, ""
+		if len(name) > nameWidth {
+			prefix = "..."
+			name = name[len(name)-max(nameWidth-3, 0):]
+			if slash := strings.IndexByte(name, '/'); slash >= 0 {
+				name = name[slash:]
+			}
+		}
+		label := fmt.Sprintf(" %s%-*s |", prefix, nameWidth-len(prefix), name)
+
+		switch {
+		case st.binary:
+			if st.added == 0 && st.deleted == 0 {
+				fmt.Printf("%s %*s\n", label, numberWidth, "Bin")
+			} else {
+				fmt.Printf("%s %*s %d -> %d bytes\n", label, numberWidth, "Bin", st.deleted, st.added)
+			}
+			continue
+		case st.unmerged:
+			fmt.Printf("%s %*s\n", label, numberWidth, "Unmerged")
+			continue
+		}
+		added += st.added
+		deleted += st.deleted
+
+		plus, minus := st.added, st.deleted
+		if graphWidth <= maxChange {
+			total := scaleLinear(plus+minus, graphWidth, maxChange)
+			if total < 2 && plus > 0 && minus > 0 {
+				total = 2
+			}
+			if plus < minus {
+				plus = scaleLinear(plus, graphWidth, maxChange)
+				minus = total - plus
+			} else {
+				minus = scaleLinear(minus, graphWidth, maxChange)
+				plus = total - minus
+			}
+		}
+		sep := ""
+		if st.added+st.deleted > 0 {
+			sep = " "
+		}
+		fmt.Printf("%s %*d%s%s%s\n", label, numberWidth, st.added+st.deleted, sep, strings.Repeat("+", plus), strings.Repeat("-", minus))
+	}
+	fmt.Println(statSummary(len(stats), added, deleted))
+}
+
+// scaleLinear scales n out of most to a bar of at most width, keeping any
+// change visible.
+func scaleLinear(n, width, most int) int {
+	if n == 0 {
+		return 0
+	}
+	return 1 + n*(width-1)/most
+}
+
+// printShortStat prints only the summary line of printStat.
+func printShortStat(stats []fileStat) {
+	added, deleted := 0, 0
+	for _, st := range stats {
+		if !st.binary {
+			added += st.added
+			deleted += st.deleted
+		}
+	}
+	fmt.Println(statSummary(len(stats), added, deleted))
+}
+
+// statSummary describes the number of files changed and lines inserted and
+// deleted, as in " 2 files changed, 3 insertions(+), 1 deletion(-)". A zero
+// count is left out unless both are zero.
+func statSummary(files, added, deleted int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, " %d file%s changed", files, plural(files))
+	if added > 0 || deleted == 0 {
+		fmt.Fprintf(&b, ", %d insertion%s(+)", added, plural(added))
+	}
+	if deleted > 0 || added == 0 {
+		fmt.Fprintf(&b, ", %d deletion%s(-)", deleted, plural(deleted))
+	}
+	return b.String()
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// printNameStatus prints a status letter and the path of each change: A,
+// D, M or U, or R and C with the similarity and both paths.
+func printNameStatus(changes []object.Change) {
+	for _, c := range changes {
+		switch {
+		case c.Unmerged:
+			fmt.Printf("U\t%s\n", c.Path)
+		case c.OldPath != "" && c.Copied:
+			fmt.Printf("C%03d\t%s\t%s\n", c.Similarity, c.OldPath, c.Path)
+		case c.OldPath != "":
+			fmt.Printf("R%03d\t%s\t%s\n", c.Similarity, c.OldPath, c.Path)
+		case c.OldHash == "":
+			fmt.Printf("A\t%s\n", c.Path)
+		case c.NewHash == "":
+			fmt.Printf("D\t%s\n", c.Path)
+		default:
+			fmt.Printf("M\t%s\n", c.Path)
+		}
+	}
+}
+
+// printSummary lists created and deleted files, renames, copies and mode
+// changes, as commit does after its summary line.
+func printSummary(changes []object.Change) {
+	for _, c := range changes {
+		switch {
+		case c.Unmerged:
+		case c.OldPath != "":
+			verb := "rename"
+			if c.Copied {
+				verb = "copy"
+			}
+			fmt.Printf(" %s %s (%d%%)\n", verb, renameName(c.OldPath, c.Path), c.Similarity)
+		case c.OldHash == "":
+			fmt.Printf(" create mode %s %s\n", c.NewMode, c.Path)
+		case c.NewHash == "":
+			fmt.Printf(" delete mode %s %s\n", c.OldMode, c.Path)
+		case c.OldMode != c.NewMode:
+			fmt.Printf(" mode change %s => %s %s\n", c.OldMode, c.NewMode, c.Path)
+		}
+	}
+}
+
+// printCommitSummary prints what a commit changed from oldTree to newTree:
+// the summary line of DiffStat and then the files created, deleted, renamed
+// or changed in mode.
+func printCommitSummary(root, oldTree, newTree string) error {
+	out, err := newDiffPrinter(root, DiffOptions{Format: DiffShortStat})
+	if err != nil {
+		return err
+	}
+	changes, err := object.DiffTrees(root, oldTree, newTree, nil)
+	if err != nil {
+		return err
+	}
+	if out.renames != nil {
+		if changes, err = object.DetectRenames(changes, out.readBlob, *out.renames); err != nil {
+			return err
+		}
+	}
+	if err := out.printFiles(changes); err != nil {
+		return err
+	}
+	printSummary(changes)
+	return nil
+}
+
+// termColumns returns the width to fit output to: COLUMNS if set, else the
+// width of the terminal on standard output, else 80.
+func termColumns() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	if n := terminalWidth(os.Stdout); n > 0 {
+		return n
+	}
+	return defaultColumns
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"gogit/object"
+)
+
+func TestRenameName(t *testing.T) {
+	// Expected names are those git prints for the same renames.
+	tests := []struct{ a, b, want string }{
+		{"a/b/c", "a/b/d", "a/b/{c => d}"},
+		{"x", "y", "x => y"},
+		{"src/x.go", "lib/x.go", "{src => lib}/x.go"},
+		{"a/b/c.go", "a/c.go", "a/{b => }/c.go"},
+		{"a/c.go", "a/b/c.go", "a/{ => b}/c.go"},
+		{"ab/c", "abc/c", "{ab => abc}/c"},
+		{"d/a.txt", "d/a.txt.bak", "d/{a.txt => a.txt.bak}"},
+		{"p/q/r/s", "p/r/s", "p/{q => }/r/s"},
+	}
+	for _, tt := range tests {
+		if got := renameName(tt.a, tt.b); got != tt.want {
+			t.Errorf("renameName(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestPrintStat(t *testing.T) {
+	stats := []fileStat{
+		{name: "a.txt", added: 30, deleted: 66},
+		{name: "bin.dat", deleted: 3, binary: true},
+		{name: "del.txt", deleted: 1},
+		{name: "src/very/long/{directory/name => other}/file_with_long_name.go", added: 1},
+	}
+	// Expected output is git's for the same changes.
+	tests := []struct {
+		width int
+		want  string
+	}{
+		{80, "" +
+			" a.txt                                              |  96 +++++++--------------\n" +
+			" bin.dat                                            | Bin 3 -> 0 bytes\n" +
+			" del.txt                                            |   1 -\n" +
+			" .../name => other}/file_with_long_name.go          |   1 +\n"},
+		{40, "" +
+			" a.txt                     |  96 ++----\n" +
+			" bin.dat                   | Bin 3 -> 0 bytes\n" +
+			" del.txt                   |   1 -\n" +
+			" ...file_with_long_name.go |   1 +\n"},
+		{25, "" +
+			" a.txt      |  96 ++----\n" +
+			" bin.dat    | Bin 3 -> 0 bytes\n" +
+			" del.txt    |   1 -\n" +
+			" ...name.go |   1 +\n"},
+	}
+	for _, tt := range tests {
+		out, _ := captureStdout(t, func() error {
+			printStat(stats, tt.width)
+			return nil
+		})
+		want := tt.want + " 4 files changed, 31 insertions(+), 67 deletions(-)\n"
+		if out != want {
+			t.Errorf("width %d: got\n%s\nwant\n%s", tt.width, out, want)
+		}
+	}
+}
+
+func TestPrintStat_FitsGraph(t *testing.T) {
+	stats := []fileStat{{name: "a", added: 3, deleted: 1}, {name: "b", added: 1000}, {name: "c", deleted: 1}}
+	out, _ := captureStdout(t, func() error {
+		printStat(stats, 40)
+		return nil
+	})
+	lines := strings.Split(out, "\n")
+	// A small change keeps one mark of each kind; the largest fills the
+	// graph, leaving the last column free as git does.
+	if lines[0] != " a |    4 +-" || lines[2] != " c |    1 -" {
+		t.Errorf("unexpected small changes in:\n%s", out)
+	}
+	if len(lines[1]) != 39 || !strings.HasSuffix(lines[1], "++++") {
+		t.Errorf("expected the graph to fill 39 columns, got %q", lines[1])
+	}
+}
+
+func TestPrintNumStat(t *testing.T) {
+	out, _ := captureStdout(t, func() error {
+		printNumStat([]fileStat{{name: "a", added: 2, deleted: 1}, {name: "b", added: 9, binary: true}})
+		return nil
+	})
+	if out != "2\t1\ta\n-\t-\tb\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestStatSummary(t *testing.T) {
+	tests := []struct {
+		files, added, deleted int
+		want                  string
+	}{
+		{1, 1, 0, " 1 file changed, 1 insertion(+)"},
+		{2, 0, 3, " 2 files changed, 3 deletions(-)"},
+		{3, 2, 1, " 3 files changed, 2 insertions(+), 1 deletion(-)"},
+		{1, 0, 0, " 1 file changed, 0 insertions(+), 0 deletions(-)"},
+	}
+	for _, tt := range tests {
+		if got := statSummary(tt.files, tt.added, tt.deleted); got != tt.want {
+			t.Errorf("statSummary(%d, %d, %d) = %q, want %q", tt.files, tt.added, tt.deleted, got, tt.want)
+		}
+	}
+}
+
+func TestPrintNameStatusAndSummary(t *testing.T) {
+	changes := []object.Change{
+		{Path: "added", NewMode: "100644", NewHash: "1"},
+		{Path: "copy", OldPath: "src", OldHash: "2", NewHash: "2", OldMode: "100644", NewMode: "100644", Copied: true, Similarity: 100},
+		{Path: "deleted", OldMode: "100755", OldHash: "3"},
+		{Path: "dir/new", OldPath: "dir/old", OldHash: "4", NewHash: "5", OldMode: "100644", NewMode: "100644", Similarity: 87},
+		{Path: "exec", OldMode: "100644", OldHash: "6", NewMode: "100755", NewHash: "6"},
+		{Path: "modified", OldMode: "100644", OldHash: "7", NewMode: "100644", NewHash: "8"},
+		{Path: "unmerged", Unmerged: true},
+	}
+	out, _ := captureStdout(t, func() error {
+		printNameStatus(changes)
+		return nil
+	})
+	want := "A\tadded\nC100\tsrc\tcopy\nD\tdeleted\nR087\tdir/old\tdir/new\nM\texec\nM\tmodified\nU\tunmerged\n"
+	if out != want {
+		t.Errorf("name status: got\n%s\nwant\n%s", out, want)
+	}
+
+	out, _ = captureStdout(t, func() error {
+		printSummary(changes)
+		return nil
+	})
+	want = " create mode 100644 added\n copy src => copy (100%)\n delete mode 100755 deleted\n" +
+		" rename dir/{old => new} (87%)\n mode change 100644 => 100755 exec\n"
+	if out != want {
+		t.Errorf("summary: got\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestTermColumns(t *testing.T) {
+	t.Setenv("COLUMNS", "123")
+	if got := termColumns(); got != 123 {
+		t.Errorf("expected COLUMNS to be used, got %d", got)
+	}
+	// Tests do not run with a terminal on standard output.
+	t.Setenv("COLUMNS", "")
+	if got := termColumns(); got != defaultColumns {
+		t.Errorf("expected the default width, got %d", got)
+	}
+}
@@ -1,17 +1,20 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"gogit/index"
+	"gogit/object"
 	"gogit/repo"
 )
 
 func TestDiff_NoChanges(t *testing.T) {
 	setupTestRepoWithCommit(t)
-	if err := Diff(); err != nil {
+	if err := Diff(DiffOptions{}); err != nil {
 		t.Fatalf("Diff failed: %v", err)
 	}
 }
@@ -20,7 +23,7 @@ func TestDiff_ModifiedFile(t *testing.T) {
 	dir := setupTestRepoWithCommit(t)
 	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("hello\nworld\n"), 0644)
 
-	if err := Diff(); err != nil {
+	if err := Diff(DiffOptions{}); err != nil {
 		t.Fatalf("Diff failed: %v", err)
 	}
 }
@@ -29,7 +32,7 @@ func TestDiff_DeletedFile(t *testing.T) {
 	dir := setupTestRepoWithCommit(t)
 	os.Remove(filepath.Join(dir, "test.txt"))
 
-	if err := Diff(); err != nil {
+	if err := Diff(DiffOptions{}); err != nil {
 		t.Fatalf("Diff failed: %v", err)
 	}
 }
@@ -46,7 +49,7 @@ func TestDiff_MultipleChanges(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("aaa_modified"), 0644)
 	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("bbb_modified"), 0644)
 
-	if err := Diff(); err != nil {
+	if err := Diff(DiffOptions{}); err != nil {
 		t.Fatalf("Diff failed: %v", err)
 	}
 }
@@ -73,7 +76,7 @@ func TestDiff_LargeFile(t *testing.T) {
 	}
 	os.WriteFile(filepath.Join(dir, "big.txt"), []byte(b2.String()), 0644)
 
-	if err := Diff(); err != nil {
+	if err := Diff(DiffOptions{}); err != nil {
 		t.Fatalf("Diff failed: %v", err)
 	}
 }
@@ -88,7 +91,7 @@ func TestDiff_NewFileInIndex(t *testing.T) {
 
 	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("modified"), 0644)
 
-	if err := Diff(); err != nil {
+	if err := Diff(DiffOptions{}); err != nil {
 		t.Fatalf("Diff failed: %v", err)
 	}
 }
@@ -99,7 +102,7 @@ func TestDiff_NoRepo(t *testing.T) {
 	defer os.Chdir(orig)
 	os.Chdir(dir)
 
-	err := Diff()
+	err := Diff(DiffOptions{})
 	if err == nil {
 		t.Fatal("expected error when not in a repo")
 	}
@@ -107,7 +110,7 @@ func TestDiff_NoRepo(t *testing.T) {
 
 func TestDiff_EmptyIndex(t *testing.T) {
 	setupTestRepo(t)
-	if err := Diff(); err != nil {
+	if err := Diff(DiffOptions{}); err != nil {
 		t.Fatalf("Diff failed: %v", err)
 	}
 }
@@ -116,7 +119,7 @@ func TestDiff_CorruptIndex(t *testing.T) {
 	dir := setupTestRepo(t)
 	os.WriteFile(filepath.Join(dir, repo.GogitDir, "index"), []byte("corrupt data that is long enough"), 0644)
 
-	err := Diff()
+	err := Diff(DiffOptions{})
 	if err == nil {
 		t.Fatal("expected error for corrupt index")
 	}
@@ -138,50 +141,15 @@ func TestDiff_BadBlobInIndex(t *testing.T) {
 	})
 
 	// Diff should not return error, it just skips files with bad blobs (continue)
-	if err := Diff(); err != nil {
+	if err := Diff(DiffOptions{}); err != nil {
 		t.Fatalf("Diff should handle bad blobs gracefully: %v", err)
 	}
 }
 
-func TestComputeLCS(t *testing.T) {
-	a := []string{"a", "b", "c"}
-	b := []string{"a", "c"}
-	dp := computeLCS(a, b)
-	if dp[3][2] != 2 {
-		t.Errorf("expected LCS length 2, got %d", dp[3][2])
-	}
-}
-
-func TestComputeLCS_Empty(t *testing.T) {
-	dp := computeLCS(nil, nil)
-	if dp[0][0] != 0 {
-		t.Error("empty LCS should be 0")
-	}
-}
-
-func TestComputeLCS_OneEmpty(t *testing.T) {
-	a := []string{"a", "b"}
-	dp := computeLCS(a, nil)
-	if dp[2][0] != 0 {
-		t.Error("LCS with empty should be 0")
-	}
-}
-
-func TestComputeLCS_DpRightBranch(t *testing.T) {
-	// Force the dp[i][j-1] > dp[i-1][j] path
-	a := []string{"x", "y"}
-	b := []string{"y", "x", "y"}
-	dp := computeLCS(a, b)
-	if dp[2][3] != 2 {
-		t.Errorf("expected LCS length 2, got %d", dp[2][3])
-	}
-}
-
 func TestBuildHunks_AllNew(t *testing.T) {
 	old := []string{}
 	new := []string{"a", "b"}
-	dp := computeLCS(old, new)
-	hunks := buildHunks(old, new, dp)
+	hunks := buildHunks(diffScript(myersAlgorithm{}, old, new))
 	if len(hunks) == 0 {
 		t.Error("expected hunks for all-new content")
 	}
@@ -190,8 +158,7 @@ func TestBuildHunks_AllNew(t *testing.T) {
 func TestBuildHunks_AllRemoved(t *testing.T) {
 	old := []string{"a", "b"}
 	new := []string{}
-	dp := computeLCS(old, new)
-	hunks := buildHunks(old, new, dp)
+	hunks := buildHunks(diffScript(myersAlgorithm{}, old, new))
 	if len(hunks) == 0 {
 		t.Error("expected hunks for all-removed content")
 	}
@@ -199,8 +166,7 @@ func TestBuildHunks_AllRemoved(t *testing.T) {
 
 func TestBuildHunks_NoChanges(t *testing.T) {
 	lines := []string{"a", "b", "c"}
-	dp := computeLCS(lines, lines)
-	hunks := buildHunks(lines, lines, dp)
+	hunks := buildHunks(diffScript(myersAlgorithm{}, lines, lines))
 	if len(hunks) != 0 {
 		t.Error("expected no hunks for identical content")
 	}
@@ -217,8 +183,7 @@ func TestBuildHunks_MultipleSeparateChanges(t *testing.T) {
 	old[19] = "old_last"
 	new[19] = "new_last"
 
-	dp := computeLCS(old, new)
-	hunks := buildHunks(old, new, dp)
+	hunks := buildHunks(diffScript(myersAlgorithm{}, old, new))
 	if len(hunks) == 0 {
 		t.Error("expected hunks for changes")
 	}
@@ -236,19 +201,146 @@ func TestBuildHunks_CloseChanges(t *testing.T) {
 	old[6] = "old_b"
 	new[6] = "new_b"
 
-	dp := computeLCS(old, new)
-	hunks := buildHunks(old, new, dp)
+	hunks := buildHunks(diffScript(myersAlgorithm{}, old, new))
 	if len(hunks) == 0 {
 		t.Error("expected hunks")
 	}
 }
 
 func TestPrintUnifiedDiff_DeletedFile(t *testing.T) {
-	printUnifiedDiff("test.txt", []string{"line1", "line2"}, nil)
+	out, _ := captureStdout(t, func() error {
+		printUnifiedDiff(myersAlgorithm{}, "a/test.txt", "/dev/null", []string{"line1\n", "line2\n"}, nil)
+		return nil
+	})
+	if want := "--- a/test.txt\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-line1\n-line2\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
 }
 
 func TestPrintUnifiedDiff_ModifiedFile(t *testing.T) {
-	printUnifiedDiff("test.txt", []string{"old"}, []string{"new"})
+	out, _ := captureStdout(t, func() error {
+		printUnifiedDiff(myersAlgorithm{}, "a/test.txt", "b/test.txt", []string{"old\n"}, []string{"new\n"})
+		return nil
+	})
+	if want := "--- a/test.txt\n+++ b/test.txt\n@@ -1 +1 @@\n-old\n+new\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestPrintUnifiedDiff_NoChanges(t *testing.T) {
+	out, _ := captureStdout(t, func() error {
+		printUnifiedDiff(myersAlgorithm{}, "a/x", "b/x", []string{"same\n"}, []string{"same\n"})
+		return nil
+	})
+	if out != "" {
+		t.Errorf("expected nothing for equal lines, got %q", out)
+	}
+}
+
+// numberedLines returns the lines "1\n" to "n\n", with the lines named in
+// edits, by number, replaced.
+func numberedLines(n int, edits map[int]string) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%d\n", i+1)
+		if edit, ok := edits[i+1]; ok {
+			lines[i] = edit + "\n"
+		}
+	}
+	return lines
+}
+
+func TestBuildHunks_Format(t *testing.T) {
+	// Expected hunks are git's for the same changes.
+	tests := []struct {
+		name     string
+		old, new []string
+		want     string
+	}{
+		{
+			"added to an empty file", nil, []string{"a\n", "b\n"},
+			"@@ -0,0 +1,2 @@|+a|+b",
+		},
+		{
+			"inserted at the top", []string{"b\n"}, []string{"a\n", "b\n"},
+			"@@ -1 +1,2 @@|+a| b",
+		},
+		{
+			"missing newline", []string{"a\n", "b"}, []string{"a\n", "b\n"},
+			"@@ -1,2 +1,2 @@| a|-b|\\ No newline at end of file|+b",
+		},
+		{
+			"unchanged line without newline", []string{"a\n", "b"}, []string{"x\n", "b"},
+			"@@ -1,2 +1,2 @@|-a|+x| b|\\ No newline at end of file",
+		},
+		{
+			// Six unchanged lines between changes keep them in one hunk.
+			"changes six lines apart",
+			numberedLines(20, nil), numberedLines(20, map[int]string{5: "five", 12: "twelve"}),
+			"@@ -2,14 +2,14 @@| 2| 3| 4|-5|+five| 6| 7| 8| 9| 10| 11|-12|+twelve| 13| 14| 15",
+		},
+		{
+			// Seven split them, each hunk with its own trailing context.
+			"changes seven lines apart",
+			numberedLines(20, nil), numberedLines(20, map[int]string{5: "five", 13: "thirteen"}),
+			"@@ -2,7 +2,7 @@| 2| 3| 4|-5|+five| 6| 7| 8|@@ -10,7 +10,7 @@| 10| 11| 12|-13|+thirteen| 14| 15| 16",
+		},
+	}
+	for _, tt := range tests {
+		got := strings.Join(buildHunks(diffScript(myersAlgorithm{}, tt.old, tt.new)), "|")
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildHunks_FunctionName(t *testing.T) {
+	old := splitLines("package main\n\nfunc main() {\n\ta := 1\n\tb := 2\n\tc := 3\n\td := 4\n\te := 5\n}\n")
+	new := splitLines("package main\n\nfunc main() {\n\ta := 1\n\tb := 2\n\tc := 3\n\td := 4\n\te := 6\n}\n")
+	hunks := buildHunks(diffScript(myersAlgorithm{}, old, new))
+	if len(hunks) == 0 || hunks[0] != "@@ -5,5 +5,5 @@ func main() {" {
+		t.Errorf("expected the enclosing function in the header, got %q", hunks)
+	}
+	if name, ok := funcName("\tindented\n"); ok {
+		t.Errorf("indented line taken as a function name: %q", name)
+	}
+	if name, _ := funcName(strings.Repeat("x", 100) + "\n"); len(name) != 80 {
+		t.Errorf("expected the name cut to 80 bytes, got %d", len(name))
+	}
+}
+
+func TestDiff_GitHeaders(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "run.sh"), []byte("echo hi"), 0644)
+	os.WriteFile(filepath.Join(dir, "empty.txt"), nil, 0644)
+	Add([]string{"run.sh", "empty.txt"})
+	Commit("second")
+	os.Chmod(filepath.Join(dir, "run.sh"), 0755)
+	os.Remove(filepath.Join(dir, "test.txt"))
+	os.Remove(filepath.Join(dir, "empty.txt"))
+	os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new"), 0644)
+	Add([]string{"new.txt"})
+
+	short := func(content string) string { return object.HashBlob([]byte(content))[:7] }
+	// Expected output is git's for the same changes.
+	want := "diff --git a/empty.txt b/empty.txt\ndeleted file mode 100644\nindex " + short("") + "..0000000\n" +
+		"diff --git a/run.sh b/run.sh\nold mode 100644\nnew mode 100755\n" +
+		"diff --git a/test.txt b/test.txt\ndeleted file mode 100644\nindex " + short("hello\n") + "..0000000\n" +
+		"--- a/test.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-hello\n"
+	out, err := captureStdout(t, func() error { return Diff(DiffOptions{}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != want {
+		t.Errorf("worktree: got\n%s\nwant\n%s", out, want)
+	}
+
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Cached: true}) })
+	want = "diff --git a/new.txt b/new.txt\nnew file mode 100644\nindex 0000000.." + short("new") + "\n" +
+		"--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1 @@\n+new\n\\ No newline at end of file\n"
+	if out != want {
+		t.Errorf("cached: got\n%s\nwant\n%s", out, want)
+	}
 }
 
 func TestDiff_DeletedFileWithBadBlob(t *testing.T) {
@@ -265,7 +357,540 @@ func TestDiff_DeletedFileWithBadBlob(t *testing.T) {
 	})
 
 	// Diff should not error (ReadBlob error causes continue)
-	if err := Diff(); err != nil {
+	if err := Diff(DiffOptions{}); err != nil {
 		t.Fatalf("Diff should handle bad blob for deleted file: %v", err)
 	}
 }
+
+func TestDiff_AgainstRevision(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("second\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new\n"), 0644)
+	Add([]string{"test.txt", "new.txt"})
+	Commit("second")
+
+	// Working tree matches HEAD, so only the diff against HEAD~1 is non-empty.
+	out, err := captureStdout(t, func() error { return Diff(DiffOptions{}, "HEAD") })
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if out != "" {
+		t.Errorf("expected no diff against HEAD, got:\n%s", out)
+	}
+
+	os.Remove(filepath.Join(dir, "test.txt"))
+	out, err = captureStdout(t, func() error { return Diff(DiffOptions{}, "HEAD~1") })
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if !strings.Contains(out, "+++ b/new.txt") || !strings.Contains(out, "+new") {
+		t.Errorf("expected new.txt as added, got:\n%s", out)
+	}
+	if !strings.Contains(out, "--- a/test.txt") || !strings.Contains(out, "-hello") {
+		t.Errorf("expected test.txt as deleted, got:\n%s", out)
+	}
+}
+
+func TestDiff_RevisionErrors(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	if err := Diff(DiffOptions{}, "nope"); err == nil {
+		t.Error("expected error for unknown revision")
+	}
+	if err := Diff(DiffOptions{}, "HEAD", "HEAD", "HEAD"); err == nil {
+		t.Error("expected error for three revisions")
+	}
+
+	// A missing blob in the revision's tree is reported once the file
+	// differs and its old content is needed.
+	h := object.HashBlob([]byte("hello\n"))
+	os.Remove(filepath.Join(dir, repo.GogitDir, "objects", h[:2], h[2:]))
+	backdate(t, filepath.Join(dir, "test.txt"))
+	if err := Diff(DiffOptions{}, "HEAD"); err != nil {
+		t.Errorf("unchanged file should not need its blob, got %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("changed\n"), 0644)
+	if err := Diff(DiffOptions{}, "HEAD"); err == nil {
+		t.Error("expected error for missing blob")
+	}
+}
+
+func TestDiff_TrustsStatData(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	backdate(t, filepath.Join(dir, "test.txt"))
+	Add([]string{"test.txt"})
+	stageWithHash(t, dir)
+
+	out, err := captureStdout(t, func() error { return Diff(DiffOptions{}) })
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if out != "" {
+		t.Errorf("file with matching stat data should not be read, got:\n%s", out)
+	}
+
+	// Against a revision, an up-to-date entry for the same blob is trusted.
+	Commit("restaged")
+	out, err = captureStdout(t, func() error { return Diff(DiffOptions{}, "HEAD") })
+	if err != nil || out != "" {
+		t.Errorf("expected no diff against HEAD, got %q, %v", out, err)
+	}
+}
+
+func TestDiff_Algorithm(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	path := filepath.Join(dir, "test.txt")
+	os.WriteFile(path, []byte(movedFunctionOld), 0644)
+	Add([]string{"test.txt"})
+	os.WriteFile(path, []byte(movedFunctionNew), 0644)
+
+	out, err := captureStdout(t, func() error { return Diff(DiffOptions{Algorithm: "patience"}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "\n+int fib(int n)\n") || strings.Contains(out, "\n-int frobnitz(int foo)\n") {
+		t.Errorf("expected patience to keep frobnitz, got:\n%s", out)
+	}
+
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Algorithm: "myers"}) })
+	if !strings.Contains(out, "\n-int frobnitz(int foo)\n") {
+		t.Errorf("expected myers to interleave the functions, got:\n%s", out)
+	}
+
+	// diff.algorithm sets the default.
+	os.WriteFile(filepath.Join(dir, repo.GogitDir, "config"), []byte("[diff]\n\talgorithm = histogram\n"), 0644)
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{}) })
+	if !strings.Contains(out, "\n+int fib(int n)\n") || strings.Contains(out, "\n-int frobnitz(int foo)\n") {
+		t.Errorf("expected diff.algorithm to select histogram, got:\n%s", out)
+	}
+}
+
+func TestDiff_UnknownAlgorithm(t *testing.T) {
+	setupTestRepoWithCommit(t)
+	err := Diff(DiffOptions{Algorithm: "lcs"})
+	if err == nil || !strings.Contains(err.Error(), "unknown diff algorithm") {
+		t.Errorf("expected unknown algorithm error, got %v", err)
+	}
+}
+
+func TestDiff_Cached(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("staged\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new\n"), 0644)
+	Add([]string{"test.txt", "new.txt"})
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("unstaged\n"), 0644)
+
+	out, err := captureStdout(t, func() error { return Diff(DiffOptions{Cached: true}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"+++ b/new.txt", "+new", "-hello", "+staged"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in staged diff, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "unstaged") {
+		t.Errorf("staged diff should not show the working tree, got:\n%s", out)
+	}
+	if strings.Index(out, "new.txt") > strings.Index(out, "test.txt") {
+		t.Errorf("expected files in path order, got:\n%s", out)
+	}
+
+	// The plain diff shows only what is not staged.
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{}) })
+	if !strings.Contains(out, "-staged") || !strings.Contains(out, "+unstaged") || strings.Contains(out, "new.txt") {
+		t.Errorf("unexpected unstaged diff:\n%s", out)
+	}
+
+	// A named revision replaces HEAD.
+	Commit("second")
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Cached: true}) })
+	if out != "" {
+		t.Errorf("expected nothing staged after commit, got:\n%s", out)
+	}
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Cached: true}, "HEAD~1") })
+	if !strings.Contains(out, "+staged") || !strings.Contains(out, "+++ b/new.txt") {
+		t.Errorf("expected the diff against HEAD~1, got:\n%s", out)
+	}
+}
+
+func TestDiff_CachedBeforeFirstCommit(t *testing.T) {
+	dir := setupTestRepo(t)
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0644)
+	Add([]string{"a.txt"})
+
+	out, err := captureStdout(t, func() error { return Diff(DiffOptions{Cached: true}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "+++ b/a.txt") || !strings.Contains(out, "+a") {
+		t.Errorf("expected a.txt as added, got:\n%s", out)
+	}
+}
+
+func TestDiff_CachedIntentToAdd(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "planned.txt"), []byte("later\n"), 0644)
+	if err := UpdateIndex([]string{"planned.txt"}, UpdateIndexOptions{Set: index.FlagIntentToAdd}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := captureStdout(t, func() error { return Diff(DiffOptions{Cached: true}) })
+	if err != nil || out != "" {
+		t.Errorf("intent-to-add should not be staged, got %q, %v", out, err)
+	}
+}
+
+func TestDiff_TwoRevisions(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("second\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "sub", "s.txt"), []byte("s\n"), 0644)
+	Add([]string{"test.txt", "sub"})
+	Commit("second")
+	// Working tree changes do not affect a diff between revisions.
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("dirty\n"), 0644)
+
+	for _, revs := range [][]string{{"HEAD~1", "HEAD"}, {"HEAD~1..HEAD"}, {"HEAD~1.."}, {"HEAD~1...HEAD"}} {
+		out, err := captureStdout(t, func() error { return Diff(DiffOptions{}, revs...) })
+		if err != nil {
+			t.Fatalf("%v: %v", revs, err)
+		}
+		if !strings.Contains(out, "-hello\n+second") || !strings.Contains(out, "+++ b/sub/s.txt") || strings.Contains(out, "dirty") {
+			t.Errorf("%v: unexpected diff:\n%s", revs, out)
+		}
+	}
+
+	out, _ := captureStdout(t, func() error { return Diff(DiffOptions{}, "HEAD", "HEAD~1") })
+	if !strings.Contains(out, "-second\n+hello") || !strings.Contains(out, "--- a/sub/s.txt") {
+		t.Errorf("expected the reverse diff, got:\n%s", out)
+	}
+
+	// A...B starts from the merge base, so changes only on A do not show.
+	if err := Checkout("HEAD~1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Branch("other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Checkout("other"); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "other.txt"), []byte("other\n"), 0644)
+	Add([]string{"other.txt"})
+	Commit("other")
+
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{}, "other...main") })
+	if strings.Contains(out, "other.txt") || !strings.Contains(out, "+second") {
+		t.Errorf("expected only the changes on main, got:\n%s", out)
+	}
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{}, "other..main") })
+	if !strings.Contains(out, "--- a/other.txt") || !strings.Contains(out, "+second") {
+		t.Errorf("expected the changes of both sides, got:\n%s", out)
+	}
+}
+
+func TestDiff_Paths(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "sub", "s.txt"), []byte("s\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "sub.txt"), []byte("x\n"), 0644)
+	Add([]string{"sub", "sub.txt"})
+	Commit("second")
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("changed\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "sub", "s.txt"), []byte("changed\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "sub.txt"), []byte("changed\n"), 0644)
+
+	onlySub := func(label, out string) {
+		t.Helper()
+		if !strings.Contains(out, "sub/s.txt") || strings.Contains(out, "test.txt") || strings.Contains(out, "sub.txt") {
+			t.Errorf("%s: expected only sub/s.txt, got:\n%s", label, out)
+		}
+	}
+	out, _ := captureStdout(t, func() error { return Diff(DiffOptions{Paths: []string{"sub"}}) })
+	onlySub("worktree", out)
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Paths: []string{"sub"}}, "HEAD") })
+	onlySub("revision", out)
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Paths: []string{"sub"}}, "HEAD~1", "HEAD") })
+	if !strings.Contains(out, "+++ b/sub/s.txt") || strings.Contains(out, "sub.txt") {
+		t.Errorf("expected only sub/s.txt between revisions, got:\n%s", out)
+	}
+
+	Add([]string{"."})
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Cached: true, Paths: []string{"sub"}}) })
+	onlySub("cached", out)
+
+	// Paths are relative to the current directory.
+	os.Chdir(filepath.Join(dir, "sub"))
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Cached: true, Paths: []string{"s.txt"}}) })
+	onlySub("subdirectory", out)
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Cached: true, Paths: []string{"../test.txt"}}) })
+	if !strings.Contains(out, "test.txt") || strings.Contains(out, "sub/s.txt") {
+		t.Errorf("expected only test.txt, got:\n%s", out)
+	}
+	if err := Diff(DiffOptions{Paths: []string{"../../elsewhere"}}); err == nil {
+		t.Error("expected error for a path outside the repository")
+	}
+}
+
+func TestDiff_CachedErrors(t *testing.T) {
+	setupTestRepoWithCommit(t)
+	if err := Diff(DiffOptions{Cached: true}, "HEAD~1..HEAD"); err == nil {
+		t.Error("expected error for a range with --cached")
+	}
+	if err := Diff(DiffOptions{Cached: true}, "HEAD", "HEAD"); err == nil {
+		t.Error("expected error for two revisions with --cached")
+	}
+	if err := Diff(DiffOptions{Cached: true}, "nope"); err == nil {
+		t.Error("expected error for unknown revision")
+	}
+	if err := Diff(DiffOptions{}, "HEAD", "nope"); err == nil {
+		t.Error("expected error for unknown second revision")
+	}
+	if err := Diff(DiffOptions{}, "nope..HEAD"); err == nil {
+		t.Error("expected error for unknown range start")
+	}
+}
+
+func TestDiff_Renames(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	var b strings.Builder
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	os.WriteFile(filepath.Join(dir, "old.txt"), []byte(b.String()), 0644)
+	Add([]string{"old.txt"})
+	Commit("second")
+	os.Rename(filepath.Join(dir, "old.txt"), filepath.Join(dir, "new.txt"))
+	os.WriteFile(filepath.Join(dir, "new.txt"), []byte(b.String()+"line 10\n"), 0644)
+	Add([]string{"old.txt", "new.txt"})
+
+	out, err := captureStdout(t, func() error { return Diff(DiffOptions{Cached: true}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "diff --git a/old.txt b/new.txt\nsimilarity index 89%\nrename from old.txt\nrename to new.txt\n" +
+		"index " + object.HashBlob([]byte(b.String()))[:7] + ".." + object.HashBlob([]byte(b.String() + "line 10\n"))[:7] + " 100644\n" +
+		"--- a/old.txt\n+++ b/new.txt\n"
+	if !strings.HasPrefix(out, want) || !strings.Contains(out, "+line 10\n") || strings.Contains(out, "-line 0") {
+		t.Errorf("expected a rename with its changes, got:\n%s", out)
+	}
+
+	// The same pairing applies between revisions and to the working tree.
+	Commit("rename")
+	for _, revs := range [][]string{{"HEAD~1", "HEAD"}, {"HEAD~1"}} {
+		out, _ = captureStdout(t, func() error { return Diff(DiffOptions{}, revs...) })
+		if !strings.HasPrefix(out, want) {
+			t.Errorf("%v: expected a rename, got:\n%s", revs, out)
+		}
+	}
+
+	// Below the threshold, or with detection off, the files stay apart.
+	for _, opts := range []DiffOptions{{RenameThreshold: 95}, {Renames: "false"}} {
+		out, _ = captureStdout(t, func() error { return Diff(opts, "HEAD~1", "HEAD") })
+		if strings.Contains(out, "rename") || !strings.Contains(out, "deleted file mode 100644\n") || !strings.Contains(out, "--- a/old.txt\n+++ /dev/null\n") {
+			t.Errorf("%+v: expected a delete and an add, got:\n%s", opts, out)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, ".gogit", "config"), []byte("[diff]\n\trenames = false\n"), 0644)
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{}, "HEAD~1", "HEAD") })
+	if strings.Contains(out, "rename") {
+		t.Errorf("expected diff.renames to turn detection off, got:\n%s", out)
+	}
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Renames: "true"}, "HEAD~1", "HEAD") })
+	if !strings.HasPrefix(out, want) {
+		t.Errorf("expected the option to override diff.renames, got:\n%s", out)
+	}
+}
+
+func TestDiff_ExactRenameAndCopy(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.Rename(filepath.Join(dir, "test.txt"), filepath.Join(dir, "moved.txt"))
+	Add([]string{"test.txt", "moved.txt"})
+
+	out, err := captureStdout(t, func() error { return Diff(DiffOptions{Cached: true}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "diff --git a/test.txt b/moved.txt\nsimilarity index 100%\nrename from test.txt\nrename to moved.txt\n" {
+		t.Errorf("expected an exact rename without a patch, got:\n%s", out)
+	}
+
+	Commit("moved")
+	os.WriteFile(filepath.Join(dir, "moved.txt"), []byte("hello\nworld\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "copy.txt"), []byte("hello\n"), 0644)
+	Add([]string{"moved.txt", "copy.txt"})
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Cached: true, Renames: "copies"}) })
+	if !strings.HasPrefix(out, "diff --git a/moved.txt b/copy.txt\nsimilarity index 100%\ncopy from moved.txt\ncopy to copy.txt\ndiff --git a/moved.txt b/moved.txt\n") {
+		t.Errorf("expected copy.txt as a copy, got:\n%s", out)
+	}
+	if err := Diff(DiffOptions{Cached: true, Renames: "sometimes"}); err == nil {
+		t.Error("expected error for a bad rename setting")
+	}
+}
+
+func TestDiff_BinaryFiles(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "image.bin"), []byte("PNG\x00\x01"), 0644)
+	os.WriteFile(filepath.Join(dir, "gone.bin"), []byte("\x00"), 0644)
+	Add([]string{"image.bin", "gone.bin"})
+	Commit("binaries")
+	os.WriteFile(filepath.Join(dir, "image.bin"), []byte("PNG\x00\x02"), 0644)
+	os.WriteFile(filepath.Join(dir, "new.bin"), []byte("\x00new"), 0644)
+	os.Remove(filepath.Join(dir, "gone.bin"))
+
+	out, err := captureStdout(t, func() error { return Diff(DiffOptions{}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	short := func(content string) string { return object.HashBlob([]byte(content))[:7] }
+	gone := "diff --git a/gone.bin b/gone.bin\ndeleted file mode 100644\nindex " + short("\x00") + "..0000000\n" +
+		"Binary files a/gone.bin and /dev/null differ\n"
+	image := "diff --git a/image.bin b/image.bin\nindex " + short("PNG\x00\x01") + ".." + short("PNG\x00\x02") + " 100644\n" +
+		"Binary files a/image.bin and b/image.bin differ\n"
+	want := gone + image
+	if out != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+
+	Add([]string{"image.bin", "new.bin", "gone.bin"})
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Cached: true}) })
+	want = gone + image + "diff --git a/new.bin b/new.bin\nnew file mode 100644\nindex 0000000.." + short("\x00new") + "\n" +
+		"Binary files /dev/null and b/new.bin differ\n"
+	if out != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+
+	// Text that turns binary is binary too.
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("hello\x00\n"), 0644)
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{}, "HEAD") })
+	if !strings.Contains(out, "Binary files a/test.txt and b/test.txt differ\n") || strings.Contains(out, "+hello") {
+		t.Errorf("expected test.txt as binary, got:\n%s", out)
+	}
+
+	// --text compares them line by line anyway.
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Text: true}) })
+	if !strings.Contains(out, "-hello\n+hello\x00\n") || strings.Contains(out, "Binary files") {
+		t.Errorf("expected a text diff, got:\n%s", out)
+	}
+}
+
+func TestDiff_BinaryPatch(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "image.bin"), []byte("PNG\x00\x01"), 0644)
+	Add([]string{"image.bin"})
+	Commit("binary")
+	os.WriteFile(filepath.Join(dir, "image.bin"), []byte("PNG\x00\x02"), 0644)
+	Add([]string{"image.bin"})
+
+	out, err := captureStdout(t, func() error { return Diff(DiffOptions{Cached: true, Binary: true}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldHash := object.HashBlob([]byte("PNG\x00\x01"))
+	newHash := object.HashBlob([]byte("PNG\x00\x02"))
+	header := "diff --git a/image.bin b/image.bin\nindex " + oldHash + ".." + newHash + " 100644\nGIT binary patch\nliteral 5\n"
+	if !strings.HasPrefix(out, header) {
+		t.Fatalf("expected a binary patch, got:\n%s", out)
+	}
+	lines := strings.Split(out, "\n")
+	gotNew, rest := decodeLiteral(t, lines[3:])
+	gotOld, _ := decodeLiteral(t, rest)
+	if string(gotNew) != "PNG\x00\x02" || string(gotOld) != "PNG\x00\x01" {
+		t.Errorf("binary patch holds %q and %q", gotNew, gotOld)
+	}
+
+	// Text files keep their unified diff.
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("changed\n"), 0644)
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Binary: true}) })
+	if !strings.Contains(out, "+changed") || strings.Contains(out, "GIT binary patch") {
+		t.Errorf("expected a text diff, got:\n%s", out)
+	}
+}
+
+func TestDiff_Formats(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	t.Setenv("COLUMNS", "80")
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("hello\nworld\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "new.txt"), []byte("a\nb\n"), 0644)
+	Add([]string{"new.txt"})
+
+	// The working tree, the index and revisions are summarized alike.
+	tests := []struct {
+		name string
+		opts DiffOptions
+		revs []string
+		want string
+	}{
+		{"stat", DiffOptions{Format: DiffStat}, nil, " test.txt | 1 +\n 1 file changed, 1 insertion(+)\n"},
+		{"numstat", DiffOptions{Format: DiffNumStat}, nil, "1\t0\ttest.txt\n"},
+		{"shortstat", DiffOptions{Format: DiffShortStat}, nil, " 1 file changed, 1 insertion(+)\n"},
+		{"name-only", DiffOptions{Format: DiffNameOnly}, nil, "test.txt\n"},
+		{"name-status", DiffOptions{Format: DiffNameStatus}, nil, "M\ttest.txt\n"},
+		{"cached stat", DiffOptions{Format: DiffStat, Cached: true}, nil, " new.txt | 2 ++\n 1 file changed, 2 insertions(+)\n"},
+		{"cached name-status", DiffOptions{Format: DiffNameStatus, Cached: true}, nil, "A\tnew.txt\n"},
+		{"revision to worktree", DiffOptions{Format: DiffNumStat}, []string{"HEAD"}, "2\t0\tnew.txt\n1\t0\ttest.txt\n"},
+	}
+	for _, tt := range tests {
+		out, err := captureStdout(t, func() error { return Diff(tt.opts, tt.revs...) })
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, out, tt.want)
+		}
+	}
+
+	// Without changes nothing is printed, not even a summary.
+	out, _ := captureStdout(t, func() error { return Diff(DiffOptions{Format: DiffStat}, "HEAD", "HEAD") })
+	if out != "" {
+		t.Errorf("expected no output, got %q", out)
+	}
+}
+
+func TestDiff_FormatsBetweenRevisions(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	t.Setenv("COLUMNS", "80")
+	os.MkdirAll(filepath.Join(dir, "src"), 0755)
+	content := "one\ntwo\nthree\nfour\nfive\n"
+	os.WriteFile(filepath.Join(dir, "src", "a.go"), []byte(content), 0644)
+	Add([]string{"src/a.go"})
+	Commit("add a")
+	os.MkdirAll(filepath.Join(dir, "lib"), 0755)
+	os.Rename(filepath.Join(dir, "src", "a.go"), filepath.Join(dir, "lib", "a.go"))
+	os.WriteFile(filepath.Join(dir, "lib", "a.go"), []byte(content+"six\n"), 0644)
+	os.Remove(filepath.Join(dir, "test.txt"))
+	Add([]string{"src/a.go", "lib/a.go", "test.txt"})
+	Commit("move a")
+
+	out, err := captureStdout(t, func() error { return Diff(DiffOptions{Format: DiffStat}, "HEAD~1", "HEAD") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := " {src => lib}/a.go | 1 +\n test.txt          | 1 -\n 2 files changed, 1 insertion(+), 1 deletion(-)\n"
+	if out != want {
+		t.Errorf("stat: got %q, want %q", out, want)
+	}
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Format: DiffNameStatus}, "HEAD~1..HEAD") })
+	if out != "R085\tsrc/a.go\tlib/a.go\nD\ttest.txt\n" {
+		t.Errorf("name-status: got %q", out)
+	}
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Format: DiffNameOnly}, "HEAD~1", "HEAD") })
+	if out != "lib/a.go\ntest.txt\n" {
+		t.Errorf("name-only: got %q", out)
+	}
+}
+
+func TestDiff_StatUnmerged(t *testing.T) {
+	setupConflictedMerge(t)
+	out, err := captureStdout(t, func() error { return Diff(DiffOptions{Format: DiffStat, StatWidth: 80}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != " test.txt | Unmerged\n 1 file changed, 0 insertions(+), 0 deletions(-)\n" {
+		t.Errorf("unexpected stat %q", out)
+	}
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{Format: DiffNameStatus}) })
+	if out != "U\ttest.txt\n" {
+		t.Errorf("unexpected name-status %q", out)
+	}
+}
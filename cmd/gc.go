@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gogit/object"
+	"gogit/refs"
+	"gogit/repo"
+)
+
+// reflogExpiry is how long reflog entries are kept before gc drops them.
+const reflogExpiry = 90 * 24 * time.Hour
+
+// GC performs repository housekeeping: it expires old reflog entries and
+// packs all objects.
+func GC() error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+	expired, err := refs.ExpireReflogs(root, time.Now().Add(-reflogExpiry))
+	if err != nil {
+		return err
+	}
+	if expired > 0 {
+		fmt.Printf("Expired %d reflog entries\n", expired)
+	}
+	return repackAll(root)
+}
+
+// Repack packs all loose objects and existing packs into a single pack.
+func Repack() error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+	return repackAll(root)
+}
+
+func repackAll(root string) error {
+	loose, err := object.LooseObjects(root)
+	if err != nil {
+		return err
+	}
+	packed, err := object.PackedObjects(root)
+	if err != nil {
+		return err
+	}
+	oldPacks, err := object.PackFiles(root)
+	if err != nil {
+		return err
+	}
+
+	if len(loose) == 0 && len(oldPacks) <= 1 {
+		fmt.Println("Nothing new to pack.")
+		return nil
+	}
+
+	all := append(append([]string{}, loose...), packed...)
+	stats, err := object.WritePack(root, all)
+	if err != nil {
+		return err
+	}
+
+	// The new pack now holds everything; drop the redundant copies.
+	packDir := repo.PackPath(root)
+	for _, name := range oldPacks {
+		if name == stats.Name {
+			continue
+		}
+		os.Remove(filepath.Join(packDir, name+".idx"))
+		os.Remove(filepath.Join(packDir, name+".pack"))
+	}
+	objDir := repo.ObjectsPath(root)
+	for _, h := range loose {
+		os.Remove(filepath.Join(objDir, h[:2], h[2:]))
+		os.Remove(filepath.Join(objDir, h[:2])) // only succeeds once empty
+	}
+
+	fmt.Printf("Packed %d objects (%d deltas) into %s.pack\n", stats.Objects, stats.Deltas, stats.Name)
+	return nil
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gogit/object"
+	"gogit/refs"
+	"gogit/repo"
+)
+
+func TestGC_PacksLooseObjects(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("second file\n"), 0644)
+	Add([]string{"b.txt"})
+	Commit("second")
+
+	if err := GC(); err != nil {
+		t.Fatalf("GC failed: %v", err)
+	}
+
+	loose, _ := object.LooseObjects(dir)
+	if len(loose) != 0 {
+		t.Errorf("expected no loose objects after gc, got %d", len(loose))
+	}
+	packs, _ := object.PackFiles(dir)
+	if len(packs) != 1 {
+		t.Fatalf("expected 1 pack, got %d", len(packs))
+	}
+
+	// History must still be readable from the pack.
+	if err := Log(LogOptions{}); err != nil {
+		t.Fatalf("Log after gc failed: %v", err)
+	}
+	head, _ := refs.ResolveHead(dir)
+	commit, err := object.ReadCommit(dir, head)
+	if err != nil || commit.Message != "second" {
+		t.Errorf("unexpected commit after gc: %v %v", commit, err)
+	}
+}
+
+func TestRepack_ConsolidatesPacks(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	if err := Repack(); err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("more\n"), 0644)
+	Add([]string{"b.txt"})
+	Commit("second")
+
+	if err := Repack(); err != nil {
+		t.Fatalf("Repack failed: %v", err)
+	}
+	packs, _ := object.PackFiles(dir)
+	if len(packs) != 1 {
+		t.Errorf("expected old packs to be consolidated, got %d", len(packs))
+	}
+	entries, _ := os.ReadDir(repo.ObjectsPath(dir))
+	for _, e := range entries {
+		if e.Name() != "pack" {
+			t.Errorf("unexpected leftover in objects dir: %s", e.Name())
+		}
+	}
+}
+
+func TestRepack_NothingToPack(t *testing.T) {
+	setupTestRepo(t)
+	if err := Repack(); err != nil {
+		t.Fatalf("Repack on empty repo failed: %v", err)
+	}
+	if err := Repack(); err != nil {
+		t.Fatalf("second Repack failed: %v", err)
+	}
+}
+
+func TestGC_NoRepo(t *testing.T) {
+	dir := t.TempDir()
+	orig, _ := os.Getwd()
+	defer os.Chdir(orig)
+	os.Chdir(dir)
+
+	if err := GC(); err == nil {
+		t.Fatal("expected error when not in a repo")
+	}
+	if err := Repack(); err == nil {
+		t.Fatal("expected error when not in a repo")
+	}
+}
+
+func TestRepack_CorruptPackIndex(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.MkdirAll(repo.PackPath(dir), 0755)
+	os.WriteFile(filepath.Join(repo.PackPath(dir), "pack-bad.idx"), []byte("bad"), 0644)
+
+	// The unreadable index is skipped, and left alone since its objects
+	// were not repacked.
+	if err := Repack(); err != nil {
+		t.Fatalf("Repack failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.PackPath(dir), "pack-bad.idx")); err != nil {
+		t.Error("the unreadable index should be kept")
+	}
+	if err := Log(LogOptions{}); err != nil {
+		t.Errorf("Log after repack failed: %v", err)
+	}
+}
+
+func TestGC_ExpiresOldReflogEntries(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	head, _ := refs.ResolveHead(dir)
+	logPath := refs.ReflogPath(dir, "HEAD")
+	recent, _ := os.ReadFile(logPath)
+	old := refs.ReflogEntry{Old: refs.ZeroHash, New: head, Identity: "Test <test@test.com> 100 +0000", Message: "ancient"}
+	os.WriteFile(logPath, append([]byte(old.String()), recent...), 0644)
+
+	if err := GC(); err != nil {
+		t.Fatalf("GC failed: %v", err)
+	}
+	entries, _ := refs.ReadReflog(dir, "HEAD")
+	if len(entries) != 1 || entries[0].Message == "ancient" {
+		t.Errorf("expected only the recent entry to remain, got %+v", entries)
+	}
+}
+
+func TestGC_ReflogError(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(refs.ReflogPath(dir, "HEAD"), []byte("garbage\n"), 0644)
+	if err := GC(); err == nil {
+		t.Fatal("expected error for malformed reflog")
+	}
+}
@@ -1,16 +1,20 @@
 package cmd
 
 import (
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
 )
 
 // setupTestRepo creates a temp directory, chdirs into it, inits a gogit repo,
-// and returns the directory path. Cleanup restores the original cwd.
+// and returns the directory path. Cleanup restores the original cwd. The
+// global and system config files are redirected into the temp directory.
 func setupTestRepo(t *testing.T) string {
 	t.Helper()
 	dir := t.TempDir()
+	t.Setenv("GOGIT_CONFIG_GLOBAL", filepath.Join(dir, ".gogitconfig"))
+	t.Setenv("GOGIT_CONFIG_SYSTEM", filepath.Join(dir, ".gogitconfig-system"))
 	orig, err := os.Getwd()
 	if err != nil {
 		t.Fatal(err)
@@ -41,3 +45,23 @@ func setupTestRepoWithCommit(t *testing.T) string {
 	}
 	return dir
 }
+
+// captureStdout runs fn and returns everything it printed to stdout.
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	fnErr := fn()
+	os.Stdout = orig
+	w.Close()
+	return string(<-done), fnErr
+}
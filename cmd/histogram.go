@@ -0,0 +1,121 @@
+package cmd
+
+// histogramMaxChain is the number of times a line may occur on the old side
+// of a region before histogramAlgorithm gives the region to myers.
+const histogramMaxChain = 64
+
+// histogramAlgorithm extends patience to lines that are not unique: in each
+// region it finds the longest common run of lines that contains the line
+// occurring least often on the old side, takes it as matched and recurses
+// on both sides of it. Rare lines make good anchors, so the result reads
+// like patience output while still matching repeated lines. Regions whose
+// lines all repeat too often fall back to myers.
+type histogramAlgorithm struct{}
+
+func (histogramAlgorithm) changes(a, b []int) ([]bool, []bool) {
+	h := &histogram{
+		a:        a,
+		b:        b,
+		changedA: make([]bool, len(a)),
+		changedB: make([]bool, len(b)),
+	}
+	h.diff(0, len(a), 0, len(b))
+	return h.changedA, h.changedB
+}
+
+type histogram struct {
+	a, b               []int
+	changedA, changedB []bool
+}
+
+// diff marks the changes between a[aLo:aHi] and b[bLo:bHi].
+func (h *histogram) diff(aLo, aHi, bLo, bHi int) {
+	for {
+		for aLo < aHi && bLo < bHi && h.a[aLo] == h.b[bLo] {
+			aLo++
+			bLo++
+		}
+		for aLo < aHi && bLo < bHi && h.a[aHi-1] == h.b[bHi-1] {
+			aHi--
+			bHi--
+		}
+		if aLo == aHi || bLo == bHi {
+			markChanged(h.changedA, aLo, aHi)
+			markChanged(h.changedB, bLo, bHi)
+			return
+		}
+
+		run, ok, common := h.longestRun(aLo, aHi, bLo, bHi)
+		if !ok {
+			if common {
+				diffRange(myersAlgorithm{}, h.a, h.b, h.changedA, h.changedB, aLo, aHi, bLo, bHi)
+			} else {
+				markChanged(h.changedA, aLo, aHi)
+				markChanged(h.changedB, bLo, bHi)
+			}
+			return
+		}
+		h.diff(aLo, run.a, bLo, run.b)
+		// Continue with the region after the run instead of recursing.
+		aLo, bLo = run.a+run.n, run.b+run.n
+	}
+}
+
+// commonRun is a run of n equal lines starting at a and b.
+type commonRun struct {
+	a, b, n int
+}
+
+// longestRun picks the run of lines shared by both ranges whose rarest line
+// occurs least often in a[aLo:aHi], preferring longer runs on ties. ok is
+// false when no line is rare enough, and common reports whether the ranges
+// share any line at all.
+func (h *histogram) longestRun(aLo, aHi, bLo, bHi int) (best commonRun, ok, common bool) {
+	// positions lists where each line occurs in the old range, in order.
+	positions := make(map[int][]int)
+	for i := aLo; i < aHi; i++ {
+		occ := append(positions[h.a[i]], i)
+		if len(occ) > histogramMaxChain {
+			return best, false, true
+		}
+		positions[h.a[i]] = occ
+	}
+
+	bestCount := histogramMaxChain + 1
+	for j := bLo; j < bHi; {
+		occ := positions[h.b[j]]
+		next := j + 1
+		if len(occ) > 0 {
+			common = true
+		}
+		if len(occ) > bestCount {
+			j = next
+			continue
+		}
+		for k := 0; k < len(occ); {
+			as, bs := occ[k], j
+			count := len(occ)
+			for as > aLo && bs > bLo && h.a[as-1] == h.b[bs-1] {
+				as--
+				bs--
+				count = min(count, len(positions[h.a[as]]))
+			}
+			ae, be := occ[k]+1, j+1
+			for ae < aHi && be < bHi && h.a[ae] == h.b[be] {
+				count = min(count, len(positions[h.a[ae]]))
+				ae++
+				be++
+			}
+			next = max(next, be)
+			if ae-as > best.n || count < bestCount {
+				best, bestCount, ok = commonRun{as, bs, ae - as}, count, true
+			}
+			// Skip occurrences already inside this run.
+			for k < len(occ) && occ[k] < ae {
+				k++
+			}
+		}
+		j = next
+	}
+	return best, ok, common
+}
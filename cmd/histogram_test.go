@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHistogramDiff_MovedFunction(t *testing.T) {
+	a := strings.Split(strings.TrimSuffix(movedFunctionOld, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(movedFunctionNew, "\n"), "\n")
+	if got := renderScript(diffScript(histogramAlgorithm{}, a, b)); got != movedFunctionDiff {
+		t.Errorf("unexpected histogram diff:\n%s", got)
+	}
+}
+
+func TestHistogramDiff(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"", "", ""},
+		{"a b", "c d", "-a -b +c +d"},
+		{"a b c", "a x c", " a -b +x  c"},
+		// Repeated lines still anchor when nothing rarer is shared.
+		{"x y x y", "y x y x", "-x  y  x  y +x"},
+		// The rare line r anchors ahead of a longer run of common lines.
+		{"x y x y r", "r x y x y", "-x -y -x -y  r +x +y +x +y"},
+	}
+	for _, tt := range tests {
+		a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+		if got := formatScript(diffScript(histogramAlgorithm{}, a, b)); got != tt.want {
+			t.Errorf("%q -> %q: got %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestHistogramDiff_LongChainsFallBack(t *testing.T) {
+	var a, b []string
+	for i := 0; i < histogramMaxChain+10; i++ {
+		a = append(a, "same", "old")
+		b = append(b, "same", "new")
+	}
+	ia, ib := internLines(a, b)
+	h := &histogram{a: ia, b: ib, changedA: make([]bool, len(ia)), changedB: make([]bool, len(ib))}
+	if _, ok, common := h.longestRun(0, len(ia), 0, len(ib)); ok || !common {
+		t.Errorf("expected no run but common lines, got ok=%v common=%v", ok, common)
+	}
+
+	kept := checkScript(t, a, b, diffScript(histogramAlgorithm{}, a, b))
+	if kept != histogramMaxChain+10 {
+		t.Errorf("expected the repeated lines to be kept, got %d", kept)
+	}
+}
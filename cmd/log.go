@@ -8,39 +8,94 @@ import (
 	"gogit/repo"
 )
 
-func Log() error {
+// LogOptions controls what Log shows of each commit.
+type LogOptions struct {
+	// Stat adds the files each commit changed, as diff --stat shows them,
+	// compared with its first parent. Merge commits show none.
+	Stat bool
+}
+
+// Log shows the commits selected by revs (revisions and ranges such as
+// A..B), or the history of HEAD when none are given.
+func Log(opts LogOptions, revs ...string) error {
 	root, err := repo.Find()
 	if err != nil {
 		return err
 	}
 
-	hash, err := refs.ResolveHead(root)
+	var rng *refs.RevRange
+	if len(revs) == 0 {
+		hash, err := refs.ResolveHead(root)
+		if err != nil {
+			return err
+		}
+		if hash == "" {
+			fmt.Println("No commits yet")
+			return nil
+		}
+		rng = &refs.RevRange{Include: []string{hash}}
+	} else {
+		rng, err = refs.ResolveRange(root, revs)
+		if err != nil {
+			return err
+		}
+	}
+
+	hashes, err := object.RevList(root, rng.Include, rng.Exclude)
 	if err != nil {
 		return err
 	}
-	if hash == "" {
-		fmt.Println("No commits yet")
-		return nil
+	var stat *diffPrinter
+	if opts.Stat {
+		if stat, err = newDiffPrinter(root, DiffOptions{Format: DiffStat}); err != nil {
+			return err
+		}
 	}
-
-	for hash != "" {
+	for _, hash := range hashes {
 		commit, err := object.ReadCommit(root, hash)
 		if err != nil {
 			return err
 		}
 
 		fmt.Printf("commit %s\n", hash)
+		if len(commit.Parents) > 1 {
+			fmt.Print("Merge:")
+			for _, p := range commit.Parents {
+				fmt.Printf(" %s", p[:7])
+			}
+			fmt.Println()
+		}
 		fmt.Printf("Author: %s\n", commit.Author)
 		fmt.Println()
 		fmt.Printf("    %s\n", commit.Message)
 		fmt.Println()
-
-		if len(commit.Parents) > 0 {
-			hash = commit.Parents[0]
-		} else {
-			hash = ""
+		if stat != nil && len(commit.Parents) < 2 {
+			if err := logStat(root, stat, commit); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
+
+// logStat prints the files commit changed from its parent, or all its files
+// for a root commit, followed by a blank line.
+func logStat(root string, out *diffPrinter, commit *object.Commit) error {
+	parentTree := ""
+	if len(commit.Parents) == 1 {
+		parent, err := object.ReadCommit(root, commit.Parents[0])
+		if err != nil {
+			return err
+		}
+		parentTree = parent.TreeHash
+	}
+	if parentTree == commit.TreeHash {
+		return nil
+	}
+	if err := out.diffTrees(parentTree, commit.TreeHash, nil); err != nil {
+		return err
+	}
+	fmt.Println()
+	return nil
+}
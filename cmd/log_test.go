@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"gogit/refs"
@@ -11,14 +12,14 @@ import (
 
 func TestLog_NoCommits(t *testing.T) {
 	setupTestRepo(t)
-	if err := Log(); err != nil {
+	if err := Log(LogOptions{}); err != nil {
 		t.Fatalf("Log should not fail with no commits: %v", err)
 	}
 }
 
 func TestLog_SingleCommit(t *testing.T) {
 	setupTestRepoWithCommit(t)
-	if err := Log(); err != nil {
+	if err := Log(LogOptions{}); err != nil {
 		t.Fatalf("Log failed: %v", err)
 	}
 }
@@ -30,7 +31,7 @@ func TestLog_MultipleCommits(t *testing.T) {
 	Add([]string{"file2.txt"})
 	Commit("second commit")
 
-	if err := Log(); err != nil {
+	if err := Log(LogOptions{}); err != nil {
 		t.Fatalf("Log failed: %v", err)
 	}
 }
@@ -41,7 +42,7 @@ func TestLog_NoRepo(t *testing.T) {
 	defer os.Chdir(orig)
 	os.Chdir(dir)
 
-	err := Log()
+	err := Log(LogOptions{})
 	if err == nil {
 		t.Fatal("expected error when not in a repo")
 	}
@@ -50,9 +51,9 @@ func TestLog_NoRepo(t *testing.T) {
 func TestLog_BadCommitHash(t *testing.T) {
 	dir := setupTestRepo(t)
 	// Write a bad commit hash to the branch ref
-	refs.WriteRef(dir, "refs/heads/main", "0000000000000000000000000000000000000000")
+	refs.WriteRef(dir, "refs/heads/main", "0000000000000000000000000000000000000000", "test")
 
-	err := Log()
+	err := Log(LogOptions{})
 	if err == nil {
 		t.Fatal("expected error for bad commit hash")
 	}
@@ -65,8 +66,99 @@ func TestLog_ResolveHeadError(t *testing.T) {
 	os.MkdirAll(filepath.Join(refPath, "subdir"), 0755)
 	defer os.RemoveAll(refPath)
 
-	err := Log()
+	err := Log(LogOptions{})
 	if err == nil {
 		t.Fatal("expected error when ResolveHead fails")
 	}
 }
+
+func TestLog_Range(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	Branch("feature")
+	os.WriteFile(filepath.Join(dir, "file2.txt"), []byte("more"), 0644)
+	Add([]string{"file2.txt"})
+	Commit("second commit")
+	head, _ := refs.ResolveHead(dir)
+
+	out, err := captureStdout(t, func() error { return Log(LogOptions{}, "feature..main") })
+	if err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+	if strings.Count(out, "commit ") != 1 || !strings.Contains(out, "commit "+head) {
+		t.Errorf("expected only the second commit, got:\n%s", out)
+	}
+}
+
+func TestLog_ShowsMergeParents(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	Branch("feature")
+	Checkout("feature")
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("f"), 0644)
+	Add([]string{"f.txt"})
+	Commit("feat")
+	Checkout("main")
+	os.WriteFile(filepath.Join(dir, "m.txt"), []byte("m"), 0644)
+	Add([]string{"m.txt"})
+	Commit("main work")
+	Merge("feature")
+
+	out, err := captureStdout(t, func() error { return Log(LogOptions{}) })
+	if err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+	if !strings.Contains(out, "Merge: ") || strings.Count(out, "commit ") != 4 {
+		t.Errorf("expected merge header and all four commits, got:\n%s", out)
+	}
+}
+
+func TestLog_UnknownRevision(t *testing.T) {
+	setupTestRepoWithCommit(t)
+	if err := Log(LogOptions{}, "nope"); err == nil {
+		t.Fatal("expected error for unknown revision")
+	}
+}
+
+func TestLog_Stat(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	t.Setenv("COLUMNS", "80")
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("hello\nworld\n"), 0644)
+	Add([]string{"test.txt"})
+	Commit("second")
+
+	out, err := captureStdout(t, func() error { return Log(LogOptions{Stat: true}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each commit is compared with its parent, the first with nothing.
+	second := "    second\n\n test.txt | 1 +\n 1 file changed, 1 insertion(+)\n\n"
+	initial := "    initial commit\n\n test.txt | 1 +\n 1 file changed, 1 insertion(+)\n\n"
+	if !strings.Contains(out, second) || !strings.HasSuffix(out, initial) {
+		t.Errorf("unexpected log:\n%s", out)
+	}
+}
+
+func TestLog_StatSkipsMerges(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	Branch("feature")
+	Checkout("feature")
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("f\n"), 0644)
+	Add([]string{"f.txt"})
+	Commit("feat")
+	Checkout("main")
+	os.WriteFile(filepath.Join(dir, "m.txt"), []byte("m\n"), 0644)
+	Add([]string{"m.txt"})
+	Commit("main work")
+	Merge("feature")
+
+	out, err := captureStdout(t, func() error { return Log(LogOptions{Stat: true}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	merge, rest, _ := strings.Cut(strings.TrimPrefix(out, "commit "), "commit ")
+	if !strings.Contains(merge, "Merge: ") || strings.Contains(merge, "changed") {
+		t.Errorf("expected the merge without a stat, got:\n%s", merge)
+	}
+	if strings.Count(rest, "changed") != 3 {
+		t.Errorf("expected a stat for each other commit, got:\n%s", rest)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"gogit/index"
 	"gogit/object"
@@ -34,13 +35,24 @@ func Merge(branchName string) error {
 		return fmt.Errorf("no commits on current branch")
 	}
 
-	// Resolve merge target
+	mergeHead, err := readMergeHead(root)
+	if err != nil {
+		return err
+	}
+	if mergeHead != "" {
+		return fmt.Errorf("you have not concluded your merge (MERGE_HEAD exists); commit your changes or run 'gogit merge --abort'")
+	}
+
+	// Resolve merge target: a branch name or any other revision
 	targetHash, err := refs.ReadRef(root, refs.BranchRef(branchName))
 	if err != nil {
 		return err
 	}
 	if targetHash == "" {
-		return fmt.Errorf("branch '%s' not found", branchName)
+		targetHash, err = refs.ResolveCommit(root, branchName)
+		if err != nil {
+			return fmt.Errorf("%s - not something we can merge: %v", branchName, err)
+		}
 	}
 
 	if currentHash == targetHash {
@@ -50,7 +62,7 @@ func Merge(branchName string) error {
 
 	// Check if fast-forward is possible (current is ancestor of target)
 	if isAncestor(root, currentHash, targetHash) {
-		return fastForwardMerge(root, currentBranch, branchName, targetHash)
+		return fastForwardMerge(root, currentBranch, branchName, currentHash, targetHash)
 	}
 
 	// Check if target is ancestor of current (already merged)
@@ -63,29 +75,25 @@ func Merge(branchName string) error {
 	return fileLevelMerge(root, currentBranch, branchName, currentHash, targetHash)
 }
 
-// isAncestor checks if `ancestor` is an ancestor of `descendant`.
+// isAncestor checks if `ancestor` is an ancestor of `descendant`, following
+// every parent of merge commits.
 func isAncestor(root, ancestor, descendant string) bool {
-	hash := descendant
-	for hash != "" {
-		if hash == ancestor {
-			return true
-		}
-		commit, err := object.ReadCommit(root, hash)
-		if err != nil {
-			return false
-		}
-		if len(commit.Parents) > 0 {
-			hash = commit.Parents[0]
-		} else {
-			hash = ""
-		}
-	}
-	return false
+	ok, err := object.IsAncestor(root, ancestor, descendant)
+	return err == nil && ok
 }
 
-func fastForwardMerge(root, currentBranch, targetBranch, targetHash string) error {
+func fastForwardMerge(root, currentBranch, targetBranch, currentHash, targetHash string) error {
+	// Take the index lock before touching anything, so that a concurrent
+	// command makes the merge fail cleanly.
+	lock, err := index.LockIndex(root)
+	if err != nil {
+		return err
+	}
+	defer lock.Rollback()
+
 	// Update current branch to point to target
-	if err := refs.WriteRef(root, refs.BranchRef(currentBranch), targetHash); err != nil {
+	reason := fmt.Sprintf("merge %s: Fast-forward", targetBranch)
+	if err := refs.UpdateRef(root, refs.BranchRef(currentBranch), targetHash, currentHash, reason); err != nil {
 		return err
 	}
 
@@ -99,7 +107,7 @@ func fastForwardMerge(root, currentBranch, targetBranch, targetHash string) erro
 		return err
 	}
 
-	idx := &index.Index{}
+	var entries []index.Entry
 	for path, hash := range targetTree {
 		absPath := filepath.Join(root, path)
 		if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
@@ -113,17 +121,14 @@ func fastForwardMerge(root, currentBranch, targetBranch, targetHash string) erro
 			return err
 		}
 		info, _ := os.Stat(absPath)
-		idx.AddEntry(index.Entry{
-			Ctime: uint32(info.ModTime().Unix()),
-			Mtime: uint32(info.ModTime().Unix()),
-			Size:  uint32(info.Size()),
-			Hash:  hash,
-			Mode:  0100644,
-			Path:  path,
-		})
+		entry := index.Entry{Hash: hash, Mode: 0100644, Path: path}
+		entry.SetStat(info)
+		entries = append(entries, entry)
 	}
+	idx := &index.Index{}
+	idx.AddEntries(entries)
 
-	if err := index.WriteIndex(root, idx); err != nil {
+	if err := lock.Commit(idx); err != nil {
 		return err
 	}
 
@@ -131,158 +136,146 @@ func fastForwardMerge(root, currentBranch, targetBranch, targetHash string) erro
 	return nil
 }
 
-// findMergeBase finds the common ancestor of two commits.
+// findMergeBase returns the best common ancestor of two commits, or "" if
+// there is none.
 func findMergeBase(root, hash1, hash2 string) string {
-	// Collect all ancestors of hash1
-	ancestors := make(map[string]bool)
-	h := hash1
-	for h != "" {
-		ancestors[h] = true
-		commit, err := object.ReadCommit(root, h)
+	bases, err := object.MergeBases(root, hash1, hash2)
+	if err != nil || len(bases) == 0 {
+		return ""
+	}
+	return bases[0]
+}
+
+// commitTree flattens the tree of a commit into a path→blob map.
+func commitTree(root, commitHash string) (map[string]string, error) {
+	commit, err := object.ReadCommit(root, commitHash)
+	if err != nil {
+		return nil, err
+	}
+	return object.FlattenTree(root, commit.TreeHash, "")
+}
+
+// virtualBaseTree returns the tree to use as the merge base. With a single
+// best common ancestor that is simply its tree; with several (criss-cross
+// merges) the bases are merged recursively into a virtual base, keeping any
+// conflict markers in its content as git's recursive strategy does.
+func virtualBaseTree(root string, bases []string) (map[string]string, error) {
+	if len(bases) == 0 {
+		return make(map[string]string), nil
+	}
+	tree, err := commitTree(root, bases[0])
+	if err != nil {
+		return nil, err
+	}
+	merged := []string{bases[0]}
+	for _, next := range bases[1:] {
+		inner, err := object.MergeBasesOf(root, merged, []string{next})
 		if err != nil {
-			break
+			return nil, err
 		}
-		if len(commit.Parents) > 0 {
-			h = commit.Parents[0]
-		} else {
-			h = ""
+		innerTree, err := virtualBaseTree(root, inner)
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	// Walk hash2's ancestors to find first common one
-	h = hash2
-	for h != "" {
-		if ancestors[h] {
-			return h
+		nextTree, err := commitTree(root, next)
+		if err != nil {
+			return nil, err
 		}
-		commit, err := object.ReadCommit(root, h)
+		m, err := mergeTrees(root, innerTree, tree, nextTree, "Temporary merge branch 1", "Temporary merge branch 2")
 		if err != nil {
-			break
+			return nil, err
 		}
-		if len(commit.Parents) > 0 {
-			h = commit.Parents[0]
-		} else {
-			h = ""
+		for path, content := range m.content {
+			hash, err := object.WriteBlob(root, content)
+			if err != nil {
+				return nil, err
+			}
+			m.tree[path] = hash
 		}
+		tree = m.tree
+		merged = append(merged, next)
 	}
-	return ""
+	return tree, nil
 }
 
-func fileLevelMerge(root, currentBranch, targetBranch, currentHash, targetHash string) error {
-	baseHash := findMergeBase(root, currentHash, targetHash)
+// treeMerge is the result of merging two flattened trees against a base.
+type treeMerge struct {
+	tree      map[string]string    // path → blob to check out
+	content   map[string][]byte    // conflicted files written with markers
+	stages    map[string][3]string // base, ours, theirs for unmerged paths
+	conflicts []string             // unmerged paths
+	messages  []string             // progress and conflict reports
+}
 
-	var baseTree map[string]string
-	if baseHash != "" {
-		baseCommit, err := object.ReadCommit(root, baseHash)
-		if err != nil {
-			return err
-		}
-		baseTree, err = object.FlattenTree(root, baseCommit.TreeHash, "")
-		if err != nil {
-			return err
-		}
-	} else {
-		baseTree = make(map[string]string)
+func fileLevelMerge(root, currentBranch, targetBranch, currentHash, targetHash string) error {
+	lock, err := index.LockIndex(root)
+	if err != nil {
+		return err
 	}
+	defer lock.Rollback()
 
-	currentCommit, err := object.ReadCommit(root, currentHash)
+	bases, err := object.MergeBases(root, currentHash, targetHash)
 	if err != nil {
 		return err
 	}
-	currentTree, err := object.FlattenTree(root, currentCommit.TreeHash, "")
+	baseTree, err := virtualBaseTree(root, bases)
 	if err != nil {
 		return err
 	}
 
-	targetCommit, err := object.ReadCommit(root, targetHash)
+	currentTree, err := commitTree(root, currentHash)
 	if err != nil {
 		return err
 	}
-	targetTree, err := object.FlattenTree(root, targetCommit.TreeHash, "")
+	targetTree, err := commitTree(root, targetHash)
 	if err != nil {
 		return err
 	}
 
-	// Collect all paths
-	allPaths := make(map[string]bool)
-	for p := range baseTree {
-		allPaths[p] = true
-	}
-	for p := range currentTree {
-		allPaths[p] = true
-	}
-	for p := range targetTree {
-		allPaths[p] = true
-	}
-
-	// Merge each file
-	mergedTree := make(map[string]string)
-	hasConflict := false
-
-	for path := range allPaths {
-		baseH := baseTree[path]
-		curH := currentTree[path]
-		tarH := targetTree[path]
-
-		switch {
-		case curH == tarH:
-			// Both same (or both deleted)
-			if curH != "" {
-				mergedTree[path] = curH
-			}
-		case curH == baseH:
-			// Only target changed
-			if tarH != "" {
-				mergedTree[path] = tarH
-			}
-			// else: target deleted, don't include
-		case tarH == baseH:
-			// Only current changed
-			if curH != "" {
-				mergedTree[path] = curH
-			}
-			// else: current deleted, don't include
-		default:
-			// Both changed differently — conflict at file level
-			fmt.Printf("CONFLICT (content): Merge conflict in %s\n", path)
-			hasConflict = true
-			// Keep current version
-			if curH != "" {
-				mergedTree[path] = curH
-			}
-		}
+	m, err := mergeTrees(root, baseTree, currentTree, targetTree, "HEAD", targetBranch)
+	if err != nil {
+		return err
 	}
-
-	if hasConflict {
-		return fmt.Errorf("automatic merge failed; fix conflicts and then commit")
+	for _, msg := range m.messages {
+		fmt.Println(msg)
 	}
+	mergedTree, conflicted, stages, conflictPaths := m.tree, m.content, m.stages, m.conflicts
 
 	// Write merged files to working tree and index
-	idx := &index.Index{}
+	var entries []index.Entry
 	for path, hash := range mergedTree {
 		absPath := filepath.Join(root, path)
 		if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
 			return err
 		}
-		content, err := object.ReadBlob(root, hash)
-		if err != nil {
-			return err
+		content, hasMarkers := conflicted[path]
+		if !hasMarkers {
+			content, err = object.ReadBlob(root, hash)
+			if err != nil {
+				return err
+			}
 		}
 		if err := os.WriteFile(absPath, content, 0644); err != nil {
 			return err
 		}
+		if st, unmerged := stages[path]; unmerged {
+			// Record each side as a conflict stage until the user resolves it
+			for i, h := range st {
+				if h != "" {
+					entries = append(entries, index.Entry{Hash: h, Mode: 0100644, Path: path, Stage: index.StageBase + i})
+				}
+			}
+			continue
+		}
 		info, _ := os.Stat(absPath)
-		idx.AddEntry(index.Entry{
-			Ctime: uint32(info.ModTime().Unix()),
-			Mtime: uint32(info.ModTime().Unix()),
-			Size:  uint32(info.Size()),
-			Hash:  hash,
-			Mode:  0100644,
-			Path:  path,
-		})
+		entry := index.Entry{Hash: hash, Mode: 0100644, Path: path}
+		entry.SetStat(info)
+		entries = append(entries, entry)
 	}
+	idx := &index.Index{}
+	idx.AddEntries(entries)
 
-	if err := index.WriteIndex(root, idx); err != nil {
+	if err := lock.Commit(idx); err != nil {
 		return err
 	}
 
@@ -294,6 +287,14 @@ func fileLevelMerge(root, currentBranch, targetBranch, currentHash, targetHash s
 		}
 	}
 
+	message := mergeMessage(root, targetBranch, currentBranch)
+	if len(conflictPaths) > 0 {
+		if err := writeMergeState(root, currentHash, targetHash, message, conflictPaths); err != nil {
+			return err
+		}
+		return fmt.Errorf("automatic merge failed; fix conflicts and then commit")
+	}
+
 	// Build tree and create merge commit
 	treeHash, err := object.BuildTreeFromIndex(root, idx)
 	if err != nil {
@@ -301,17 +302,148 @@ func fileLevelMerge(root, currentBranch, targetBranch, currentHash, targetHash s
 	}
 
 	parents := []string{currentHash, targetHash}
-	message := fmt.Sprintf("Merge branch '%s' into %s", targetBranch, currentBranch)
 	commitHash, err := writeCommitFn(root, treeHash, parents, message)
 	if err != nil {
 		return err
 	}
 
-	if err := refs.WriteRef(root, refs.BranchRef(currentBranch), commitHash); err != nil {
+	reason := fmt.Sprintf("merge %s: Merge made by the 'three-way' strategy.", targetBranch)
+	if err := refs.UpdateRef(root, refs.BranchRef(currentBranch), commitHash, currentHash, reason); err != nil {
 		return err
 	}
 
-	fmt.Printf("Merge made by the 'file-level' strategy.\n")
+	fmt.Printf("Merge made by the 'three-way' strategy.\n")
 	fmt.Printf("[%s %s] %s\n", currentBranch, commitHash[:7], message)
 	return nil
 }
+
+// mergeMessage describes a merge the way git does: branches are named as
+// such and any other revision as a commit.
+func mergeMessage(root, target, currentBranch string) string {
+	kind := "commit"
+	if hash, _ := refs.ReadRef(root, refs.BranchRef(target)); hash != "" {
+		kind = "branch"
+	}
+	return fmt.Sprintf("Merge %s '%s' into %s", kind, target, currentBranch)
+}
+
+// mergeBlobs runs a line-level three-way merge of two blobs against their
+// base, matching lines with the diff.algorithm setting. A missing base (both
+// sides added the file) merges against empty content. Binary files cannot
+// be merged by line: when any side looks binary, merged is nil and the
+// merge conflicts.
+func mergeBlobs(root, baseH, curH, tarH, oursLabel, theirsLabel string) ([]byte, bool, error) {
+	algo, err := lookupDiffAlgorithm(root, "")
+	if err != nil {
+		return nil, false, err
+	}
+	var base []byte
+	if baseH != "" {
+		if base, err = object.ReadBlob(root, baseH); err != nil {
+			return nil, false, err
+		}
+	}
+	ours, err := object.ReadBlob(root, curH)
+	if err != nil {
+		return nil, false, err
+	}
+	theirs, err := object.ReadBlob(root, tarH)
+	if err != nil {
+		return nil, false, err
+	}
+	if isBinary(base) || isBinary(ours) || isBinary(theirs) {
+		return nil, true, nil
+	}
+	merged, conflict := mergeLines(algo, string(base), string(ours), string(theirs), oursLabel, theirsLabel)
+	return []byte(merged), conflict, nil
+}
+
+// mergeTrees merges ours and theirs against base path by path. Files changed
+// on both sides are merged line by line; unresolvable files are reported as
+// conflicts with their marker-annotated content and conflict stages.
+func mergeTrees(root string, baseTree, currentTree, targetTree map[string]string, oursLabel, theirsLabel string) (*treeMerge, error) {
+	// Collect all paths
+	allPaths := make(map[string]bool)
+	for p := range baseTree {
+		allPaths[p] = true
+	}
+	for p := range currentTree {
+		allPaths[p] = true
+	}
+	for p := range targetTree {
+		allPaths[p] = true
+	}
+	sorted := make([]string, 0, len(allPaths))
+	for p := range allPaths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	m := &treeMerge{
+		tree:    make(map[string]string),
+		content: make(map[string][]byte),
+		stages:  make(map[string][3]string),
+	}
+
+	for _, path := range sorted {
+		baseH := baseTree[path]
+		curH := currentTree[path]
+		tarH := targetTree[path]
+
+		switch {
+		case curH == tarH:
+			// Both same (or both deleted)
+			if curH != "" {
+				m.tree[path] = curH
+			}
+		case curH == baseH:
+			// Only target changed
+			if tarH != "" {
+				m.tree[path] = tarH
+			}
+			// else: target deleted, don't include
+		case tarH == baseH:
+			// Only current changed
+			if curH != "" {
+				m.tree[path] = curH
+			}
+			// else: current deleted, don't include
+		case curH == "" || tarH == "":
+			// One side deleted, the other modified — keep the surviving version
+			m.messages = append(m.messages, fmt.Sprintf("CONFLICT (modify/delete): %s deleted in one branch and modified in the other", path))
+			m.conflicts = append(m.conflicts, path)
+			m.stages[path] = [3]string{baseH, curH, tarH}
+			if curH != "" {
+				m.tree[path] = curH
+			} else {
+				m.tree[path] = tarH
+			}
+		default:
+			// Both changed differently — merge line by line
+			m.messages = append(m.messages, fmt.Sprintf("Auto-merging %s", path))
+			merged, conflict, err := mergeBlobs(root, baseH, curH, tarH, oursLabel, theirsLabel)
+			if err != nil {
+				return nil, err
+			}
+			if conflict {
+				if merged == nil {
+					// Leave our version in the working tree.
+					m.messages = append(m.messages, fmt.Sprintf("warning: Cannot merge binary files: %s (%s vs. %s)", path, oursLabel, theirsLabel))
+				} else {
+					m.content[path] = merged
+				}
+				m.messages = append(m.messages, fmt.Sprintf("CONFLICT (content): Merge conflict in %s", path))
+				m.conflicts = append(m.conflicts, path)
+				m.stages[path] = [3]string{baseH, curH, tarH}
+				m.tree[path] = curH
+				continue
+			}
+			hash, err := object.WriteBlob(root, merged)
+			if err != nil {
+				return nil, err
+			}
+			m.tree[path] = hash
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"strings"
+)
+
+const (
+	conflictMarkerOurs   = "<<<<<<<"
+	conflictMarkerSep    = "======="
+	conflictMarkerTheirs = ">>>>>>>"
+)
+
+// splitLines splits content into lines that keep their trailing newline, so
+// joining them back reproduces the input exactly.
+func splitLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// matchLines maps each line of a to the index of the line it is paired with
+// in b by the edit script, or -1 when the line was removed.
+func matchLines(algo diffAlgorithm, a, b []string) []int {
+	matches := make([]int, len(a))
+	i, j := 0, 0
+	for _, d := range diffScript(algo, a, b) {
+		switch d.op {
+		case ' ':
+			matches[i] = j
+			i++
+			j++
+		case '-':
+			matches[i] = -1
+			i++
+		case '+':
+			j++
+		}
+	}
+	return matches
+}
+
+// mergeLines performs a diff3-style three-way merge of ours and theirs
+// against their common base. Regions changed on only one side are taken
+// from that side; regions changed identically on both sides are taken once;
+// anything else is written out between conflict markers. The boolean result
+// reports whether any conflict remained.
+func mergeLines(algo diffAlgorithm, base, ours, theirs, oursLabel, theirsLabel string) (string, bool) {
+	b, a, c := splitLines(base), splitLines(ours), splitLines(theirs)
+	matchA := matchLines(algo, b, a)
+	matchC := matchLines(algo, b, c)
+
+	var out strings.Builder
+	conflict := false
+	ib, ia, ic := 0, 0, 0
+	for {
+		// Find the next base line that survives unchanged on both sides.
+		j := ib
+		for j < len(b) && (matchA[j] < 0 || matchC[j] < 0) {
+			j++
+		}
+		endA, endC := len(a), len(c)
+		if j < len(b) {
+			endA, endC = matchA[j], matchC[j]
+		}
+
+		if j > ib || endA > ia || endC > ic {
+			chunkB, chunkA, chunkC := b[ib:j], a[ia:endA], c[ic:endC]
+			switch {
+			case equalLines(chunkA, chunkB):
+				writeLines(&out, chunkC)
+			case equalLines(chunkC, chunkB), equalLines(chunkA, chunkC):
+				writeLines(&out, chunkA)
+			default:
+				conflict = true
+				writeConflict(&out, chunkA, chunkC, oursLabel, theirsLabel)
+			}
+		}
+
+		if j == len(b) {
+			break
+		}
+		out.WriteString(b[j])
+		ib, ia, ic = j+1, endA+1, endC+1
+	}
+	return out.String(), conflict
+}
+
+func equalLines(x, y []string) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeLines(out *strings.Builder, lines []string) {
+	for _, l := range lines {
+		out.WriteString(l)
+	}
+}
+
+func writeConflict(out *strings.Builder, ours, theirs []string, oursLabel, theirsLabel string) {
+	out.WriteString(conflictMarkerOurs + " " + oursLabel + "\n")
+	writeSide(out, ours)
+	out.WriteString(conflictMarkerSep + "\n")
+	writeSide(out, theirs)
+	out.WriteString(conflictMarkerTheirs + " " + theirsLabel + "\n")
+}
+
+// writeSide writes one side of a conflict, terminating a final line that
+// lacks a newline so the following marker starts on its own line.
+func writeSide(out *strings.Builder, lines []string) {
+	writeLines(out, lines)
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		out.WriteString("\n")
+	}
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitLines(t *testing.T) {
+	lines := splitLines("a\nb\nc")
+	if len(lines) != 3 || lines[0] != "a\n" || lines[2] != "c" {
+		t.Errorf("unexpected lines: %q", lines)
+	}
+	if len(splitLines("")) != 0 {
+		t.Error("empty content should have no lines")
+	}
+	if strings.Join(splitLines("x\ny\n"), "") != "x\ny\n" {
+		t.Error("lines should join back to the original")
+	}
+}
+
+func TestMatchLines(t *testing.T) {
+	m := matchLines(myersAlgorithm{}, []string{"a", "b", "c"}, []string{"a", "c", "d"})
+	want := []int{0, -1, 1}
+	for i := range want {
+		if m[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, m)
+		}
+	}
+}
+
+func TestMergeLines_NonOverlapping(t *testing.T) {
+	base := "1\n2\n3\n4\n5\n6\n7\n"
+	ours := "one\n2\n3\n4\n5\n6\n7\n"
+	theirs := "1\n2\n3\n4\n5\n6\nseven\n"
+
+	merged, conflict := mergeLines(myersAlgorithm{}, base, ours, theirs, "HEAD", "feature")
+	if conflict {
+		t.Fatalf("unexpected conflict:\n%s", merged)
+	}
+	if merged != "one\n2\n3\n4\n5\n6\nseven\n" {
+		t.Errorf("unexpected merge result:\n%s", merged)
+	}
+}
+
+func TestMergeLines_Conflict(t *testing.T) {
+	base := "a\nb\nc\n"
+	ours := "a\nours\nc\n"
+	theirs := "a\ntheirs\nc\n"
+
+	merged, conflict := mergeLines(myersAlgorithm{}, base, ours, theirs, "HEAD", "feature")
+	if !conflict {
+		t.Fatal("expected conflict")
+	}
+	want := "a\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> feature\nc\n"
+	if merged != want {
+		t.Errorf("unexpected conflict output:\n%s", merged)
+	}
+}
+
+func TestMergeLines_SameChangeBothSides(t *testing.T) {
+	merged, conflict := mergeLines(myersAlgorithm{}, "a\nb\n", "a\nX\n", "a\nX\n", "HEAD", "f")
+	if conflict || merged != "a\nX\n" {
+		t.Errorf("identical changes should merge cleanly, got %q (conflict=%v)", merged, conflict)
+	}
+}
+
+func TestMergeLines_InsertionsAtDifferentPlaces(t *testing.T) {
+	base := "a\nb\nc\nd\n"
+	ours := "top\na\nb\nc\nd\n"
+	theirs := "a\nb\nc\nd\nbottom\n"
+
+	merged, conflict := mergeLines(myersAlgorithm{}, base, ours, theirs, "HEAD", "f")
+	if conflict || merged != "top\na\nb\nc\nd\nbottom\n" {
+		t.Errorf("unexpected result %q (conflict=%v)", merged, conflict)
+	}
+}
+
+func TestMergeLines_AddAddConflict(t *testing.T) {
+	merged, conflict := mergeLines(myersAlgorithm{}, "", "ours", "theirs", "HEAD", "f")
+	if !conflict {
+		t.Fatal("expected conflict for differing additions")
+	}
+	want := "<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> f\n"
+	if merged != want {
+		t.Errorf("unexpected output %q", merged)
+	}
+}
+
+func TestMergeLines_OneSideDeletesLine(t *testing.T) {
+	merged, conflict := mergeLines(myersAlgorithm{}, "a\nb\nc\n", "a\nc\n", "a\nb\nc\nd\n", "HEAD", "f")
+	if conflict || merged != "a\nc\nd\n" {
+		t.Errorf("unexpected result %q (conflict=%v)", merged, conflict)
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+
+	"gogit/object"
+	"gogit/refs"
+	"gogit/repo"
+)
+
+// MergeBase prints the best common ancestor of two commits, or all of them
+// when all is set.
+func MergeBase(rev1, rev2 string, all bool) error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+	a, err := refs.ResolveCommit(root, rev1)
+	if err != nil {
+		return err
+	}
+	b, err := refs.ResolveCommit(root, rev2)
+	if err != nil {
+		return err
+	}
+
+	bases, err := object.MergeBases(root, a, b)
+	if err != nil {
+		return err
+	}
+	if len(bases) == 0 {
+		return fmt.Errorf("no common ancestor between %s and %s", rev1, rev2)
+	}
+	if !all {
+		bases = bases[:1]
+	}
+	for _, h := range bases {
+		fmt.Println(h)
+	}
+	return nil
+}
+
+// MergeBaseIsAncestor reports whether rev1 is an ancestor of rev2.
+func MergeBaseIsAncestor(rev1, rev2 string) (bool, error) {
+	root, err := repo.Find()
+	if err != nil {
+		return false, err
+	}
+	a, err := refs.ResolveCommit(root, rev1)
+	if err != nil {
+		return false, err
+	}
+	b, err := refs.ResolveCommit(root, rev2)
+	if err != nil {
+		return false, err
+	}
+	return object.IsAncestor(root, a, b)
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gogit/index"
+	"gogit/object"
+	"gogit/refs"
+)
+
+// writeFilesCommit writes a commit whose tree holds exactly the given files.
+func writeFilesCommit(t *testing.T, dir, msg string, files map[string]string, parents ...string) string {
+	t.Helper()
+	idx := &index.Index{}
+	for path, content := range files {
+		h, err := object.WriteBlob(dir, []byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx.AddEntry(index.Entry{Hash: h, Mode: 0100644, Path: path})
+	}
+	tree, err := object.BuildTreeFromIndex(dir, idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := object.WriteCommit(dir, tree, parents, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestMergeBase_Command(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	base, _ := refs.ResolveHead(dir)
+	Branch("feature")
+
+	os.WriteFile(filepath.Join(dir, "m.txt"), []byte("m"), 0644)
+	Add([]string{"m.txt"})
+	Commit("main")
+
+	if err := MergeBase("main", "feature", false); err != nil {
+		t.Fatalf("MergeBase failed: %v", err)
+	}
+	if err := MergeBase("main", base, true); err != nil {
+		t.Fatalf("MergeBase with hash failed: %v", err)
+	}
+
+	ok, err := MergeBaseIsAncestor("feature", "main")
+	if err != nil || !ok {
+		t.Errorf("feature should be an ancestor of main (err=%v)", err)
+	}
+	ok, err = MergeBaseIsAncestor("main", "feature")
+	if err != nil || ok {
+		t.Errorf("main should not be an ancestor of feature (err=%v)", err)
+	}
+}
+
+func TestMergeBase_Errors(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	if err := MergeBase("main", "nope", false); err == nil {
+		t.Error("expected error for unknown revision")
+	}
+	if err := MergeBase("nope", "main", false); err == nil {
+		t.Error("expected error for unknown revision")
+	}
+	if _, err := MergeBaseIsAncestor("nope", "main"); err == nil {
+		t.Error("expected error for unknown revision")
+	}
+	if _, err := MergeBaseIsAncestor("main", "nope"); err == nil {
+		t.Error("expected error for unknown revision")
+	}
+
+	unrelated := writeFilesCommit(t, dir, "orphan", map[string]string{"o.txt": "o"})
+	refs.WriteRef(dir, refs.BranchRef("orphan"), unrelated, "test")
+	if err := MergeBase("main", "orphan", false); err == nil {
+		t.Error("expected error for unrelated histories")
+	}
+}
+
+func TestMergeBase_NoRepo(t *testing.T) {
+	dir := t.TempDir()
+	orig, _ := os.Getwd()
+	defer os.Chdir(orig)
+	os.Chdir(dir)
+
+	if err := MergeBase("a", "b", false); err == nil {
+		t.Fatal("expected error when not in a repo")
+	}
+	if _, err := MergeBaseIsAncestor("a", "b"); err == nil {
+		t.Fatal("expected error when not in a repo")
+	}
+}
+
+func TestMerge_FastForwardAfterMergeCommit(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	Branch("feature")
+
+	os.WriteFile(filepath.Join(dir, "m.txt"), []byte("m"), 0644)
+	Add([]string{"m.txt"})
+	Commit("main")
+
+	Checkout("feature")
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("f"), 0644)
+	Add([]string{"f.txt"})
+	Commit("feature")
+
+	// Merge main into feature, then fast-forward main onto the merge commit.
+	if err := Merge("main"); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	featHash, _ := refs.ResolveHead(dir)
+	Checkout("main")
+	if err := Merge("feature"); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	mainHash, _ := refs.ReadRef(dir, refs.BranchRef("main"))
+	if mainHash != featHash {
+		t.Error("main should fast-forward to the merge commit through its second parent")
+	}
+}
+
+func TestMerge_CrissCrossVirtualBase(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+
+	base := writeFilesCommit(t, dir, "base", map[string]string{"f": "1\n2\n3\n"})
+	a1 := writeFilesCommit(t, dir, "a1", map[string]string{"f": "A\n2\n3\n"}, base)
+	b1 := writeFilesCommit(t, dir, "b1", map[string]string{"f": "1\n2\nB\n"}, base)
+	// Both criss-cross merges resolve to "A 2 B"; ours then edits line 2.
+	a2 := writeFilesCommit(t, dir, "a2", map[string]string{"f": "A\na2\nB\n"}, a1, b1)
+	b2 := writeFilesCommit(t, dir, "b2", map[string]string{"f": "A\n2\nB\n", "g": "new\n"}, b1, a1)
+	refs.WriteRef(dir, refs.BranchRef("main"), a2, "test")
+	refs.WriteRef(dir, refs.BranchRef("feature"), b2, "test")
+
+	// Against either single base the edits overlap; against the virtual
+	// base (a1 merged with b1) only ours touched line 2.
+	if err := fileLevelMerge(dir, "main", "feature", a2, b2); err != nil {
+		t.Fatalf("criss-cross merge should resolve cleanly: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "f"))
+	if string(data) != "A\na2\nB\n" {
+		t.Errorf("unexpected merged content %q", data)
+	}
+}
+
+func TestVirtualBaseTree_ConflictingBases(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	base := writeFilesCommit(t, dir, "base", map[string]string{"f": "x\n"})
+	a1 := writeFilesCommit(t, dir, "a1", map[string]string{"f": "a\n"}, base)
+	b1 := writeFilesCommit(t, dir, "b1", map[string]string{"f": "b\n"}, base)
+
+	tree, err := virtualBaseTree(dir, []string{a1, b1})
+	if err != nil {
+		t.Fatalf("virtualBaseTree failed: %v", err)
+	}
+	content, _ := object.ReadBlob(dir, tree["f"])
+	want := "<<<<<<< Temporary merge branch 1\na\n=======\nb\n>>>>>>> Temporary merge branch 2\n"
+	if string(content) != want {
+		t.Errorf("virtual base should keep conflict markers, got %q", content)
+	}
+}
+
+func TestVirtualBaseTree_Errors(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	a := writeFilesCommit(t, dir, "a", map[string]string{"f": "a\n"})
+	missing := "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"
+
+	if _, err := virtualBaseTree(dir, []string{missing}); err == nil {
+		t.Error("expected error for missing first base")
+	}
+	if _, err := virtualBaseTree(dir, []string{a, missing}); err == nil {
+		t.Error("expected error for missing second base")
+	}
+}
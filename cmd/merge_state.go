@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gogit/index"
+	"gogit/object"
+	"gogit/refs"
+	"gogit/repo"
+)
+
+// writeMergeState records an interrupted merge so that a later commit can
+// conclude it and `merge --abort` can undo it.
+func writeMergeState(root, origHash, mergeHash, message string, conflicts []string) error {
+	sort.Strings(conflicts)
+	var msg strings.Builder
+	msg.WriteString(message + "\n")
+	if len(conflicts) > 0 {
+		msg.WriteString("\nConflicts:\n")
+		for _, p := range conflicts {
+			fmt.Fprintf(&msg, "\t%s\n", p)
+		}
+	}
+
+	if err := os.WriteFile(repo.OrigHeadPath(root), []byte(origHash+"\n"), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(repo.MergeMsgPath(root), []byte(msg.String()), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(repo.MergeHeadPath(root), []byte(mergeHash+"\n"), 0644)
+}
+
+// readMergeHead returns the commit being merged, or "" when no merge is in
+// progress.
+func readMergeHead(root string) (string, error) {
+	data, err := os.ReadFile(repo.MergeHeadPath(root))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// readMergeMsg returns the prepared merge commit message.
+func readMergeMsg(root string) (string, error) {
+	data, err := os.ReadFile(repo.MergeMsgPath(root))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
+// clearMergeState removes the merge bookkeeping files. ORIG_HEAD is kept, as
+// git does, so the previous tip stays reachable by name.
+func clearMergeState(root string) {
+	os.Remove(repo.MergeHeadPath(root))
+	os.Remove(repo.MergeMsgPath(root))
+}
+
+// MergeContinue concludes a conflicted merge once all conflicts have been
+// resolved and staged, using the prepared merge message.
+func MergeContinue() error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+	mergeHead, err := readMergeHead(root)
+	if err != nil {
+		return err
+	}
+	if mergeHead == "" {
+		return fmt.Errorf("there is no merge in progress (MERGE_HEAD missing)")
+	}
+	return Commit("")
+}
+
+// MergeInProgress reports whether the repository has a merge waiting to be
+// concluded by a commit.
+func MergeInProgress() bool {
+	root, err := repo.Find()
+	if err != nil {
+		return false
+	}
+	mergeHead, err := readMergeHead(root)
+	return err == nil && mergeHead != ""
+}
+
+// MergeAbort abandons a conflicted merge, restoring the working tree and
+// index to the pre-merge commit.
+func MergeAbort() error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+	mergeHead, err := readMergeHead(root)
+	if err != nil {
+		return err
+	}
+	if mergeHead == "" {
+		return fmt.Errorf("there is no merge to abort (MERGE_HEAD missing)")
+	}
+
+	headHash, err := refs.ResolveHead(root)
+	if err != nil {
+		return err
+	}
+	commit, err := object.ReadCommit(root, headHash)
+	if err != nil {
+		return err
+	}
+	headTree, err := object.FlattenTree(root, commit.TreeHash, "")
+	if err != nil {
+		return err
+	}
+
+	// Everything the merge put in the index is what may need undoing.
+	idx, err := index.ReadIndex(root)
+	if err != nil {
+		return err
+	}
+	mergedTree := make(map[string]string)
+	for _, e := range idx.Entries {
+		mergedTree[e.Path] = e.Hash
+	}
+
+	if err := checkoutTree(root, mergedTree, headTree); err != nil {
+		return err
+	}
+	clearMergeState(root)
+
+	fmt.Println("Merge aborted.")
+	return nil
+}
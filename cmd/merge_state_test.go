@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gogit/index"
+	"gogit/object"
+	"gogit/refs"
+	"gogit/repo"
+)
+
+// setupConflictedMerge creates diverging edits to test.txt on main and
+// feature and runs a merge that stops on the conflict.
+func setupConflictedMerge(t *testing.T) (dir, mainHash, featHash string) {
+	t.Helper()
+	dir = setupTestRepoWithCommit(t)
+	Branch("feature")
+
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("main version\n"), 0644)
+	Add([]string{"test.txt"})
+	Commit("main change")
+	mainHash, _ = refs.ResolveHead(dir)
+
+	Checkout("feature")
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("feature version\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "feature_only.txt"), []byte("feature\n"), 0644)
+	Add([]string{"test.txt", "feature_only.txt"})
+	Commit("feature change")
+	featHash, _ = refs.ResolveHead(dir)
+
+	Checkout("main")
+	if err := Merge("feature"); err == nil {
+		t.Fatal("expected merge conflict")
+	}
+	return dir, mainHash, featHash
+}
+
+func TestMerge_ConflictWritesMergeState(t *testing.T) {
+	dir, mainHash, featHash := setupConflictedMerge(t)
+
+	mergeHead, _ := os.ReadFile(repo.MergeHeadPath(dir))
+	if strings.TrimSpace(string(mergeHead)) != featHash {
+		t.Errorf("MERGE_HEAD should be %s, got %q", featHash, mergeHead)
+	}
+	origHead, _ := os.ReadFile(repo.OrigHeadPath(dir))
+	if strings.TrimSpace(string(origHead)) != mainHash {
+		t.Errorf("ORIG_HEAD should be %s, got %q", mainHash, origHead)
+	}
+	msg, _ := os.ReadFile(repo.MergeMsgPath(dir))
+	if !strings.HasPrefix(string(msg), "Merge branch 'feature' into main") || !strings.Contains(string(msg), "\ttest.txt") {
+		t.Errorf("unexpected MERGE_MSG:\n%s", msg)
+	}
+}
+
+func TestMerge_RefusesWhileMergeInProgress(t *testing.T) {
+	setupConflictedMerge(t)
+	err := Merge("feature")
+	if err == nil || !strings.Contains(err.Error(), "MERGE_HEAD exists") {
+		t.Fatalf("expected MERGE_HEAD error, got %v", err)
+	}
+}
+
+func TestCommit_ConcludesMerge(t *testing.T) {
+	dir, mainHash, featHash := setupConflictedMerge(t)
+
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("resolved\n"), 0644)
+	Add([]string{"test.txt"})
+	if err := Commit("resolved merge"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	hash, _ := refs.ResolveHead(dir)
+	commit, _ := object.ReadCommit(dir, hash)
+	if len(commit.Parents) != 2 || commit.Parents[0] != mainHash || commit.Parents[1] != featHash {
+		t.Errorf("expected parents [%s %s], got %v", mainHash[:7], featHash[:7], commit.Parents)
+	}
+	if _, err := os.Stat(repo.MergeHeadPath(dir)); !os.IsNotExist(err) {
+		t.Error("MERGE_HEAD should be removed after commit")
+	}
+	if _, err := os.Stat(repo.MergeMsgPath(dir)); !os.IsNotExist(err) {
+		t.Error("MERGE_MSG should be removed after commit")
+	}
+}
+
+func TestCommit_ConcludesMergeWithPreparedMessage(t *testing.T) {
+	dir, _, featHash := setupConflictedMerge(t)
+	prepared, _ := readMergeMsg(dir)
+
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("resolved\n"), 0644)
+	Add([]string{"test.txt"})
+	if err := Commit(""); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	hash, _ := refs.ResolveHead(dir)
+	commit, _ := object.ReadCommit(dir, hash)
+	if len(commit.Parents) != 2 || commit.Parents[1] != featHash {
+		t.Errorf("expected a merge commit, got parents %v", commit.Parents)
+	}
+	if commit.Message != prepared {
+		t.Errorf("expected the prepared message %q, got %q", prepared, commit.Message)
+	}
+}
+
+func TestCommit_EmptyMessage(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	head, _ := refs.ResolveHead(dir)
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("changed\n"), 0644)
+	Add([]string{"test.txt"})
+
+	if err := Commit(""); err == nil || !strings.Contains(err.Error(), "empty commit message") {
+		t.Fatalf("expected empty message error, got %v", err)
+	}
+	if now, _ := refs.ResolveHead(dir); now != head {
+		t.Error("no commit should be made without a message")
+	}
+}
+
+func TestMergeContinue(t *testing.T) {
+	dir, _, _ := setupConflictedMerge(t)
+
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("resolved\n"), 0644)
+	Add([]string{"test.txt"})
+	if err := MergeContinue(); err != nil {
+		t.Fatalf("MergeContinue failed: %v", err)
+	}
+
+	hash, _ := refs.ResolveHead(dir)
+	commit, _ := object.ReadCommit(dir, hash)
+	if len(commit.Parents) != 2 {
+		t.Errorf("expected merge commit, got %d parents", len(commit.Parents))
+	}
+	if !strings.HasPrefix(commit.Message, "Merge branch 'feature' into main") {
+		t.Errorf("expected prepared message, got %q", commit.Message)
+	}
+}
+
+func TestMergeContinue_NoMerge(t *testing.T) {
+	setupTestRepoWithCommit(t)
+	if err := MergeContinue(); err == nil {
+		t.Fatal("expected error when no merge is in progress")
+	}
+}
+
+func TestMergeAbort(t *testing.T) {
+	dir, mainHash, _ := setupConflictedMerge(t)
+
+	if err := MergeAbort(); err != nil {
+		t.Fatalf("MergeAbort failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "test.txt"))
+	if string(data) != "main version\n" {
+		t.Errorf("test.txt should be restored, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "feature_only.txt")); !os.IsNotExist(err) {
+		t.Error("files brought in by the merge should be removed")
+	}
+	idx, _ := index.ReadIndex(dir)
+	if len(idx.Entries) != 1 || idx.Entries[0].Path != "test.txt" {
+		t.Errorf("index should match HEAD, got %v", idx.Entries)
+	}
+	if _, err := os.Stat(repo.MergeHeadPath(dir)); !os.IsNotExist(err) {
+		t.Error("MERGE_HEAD should be removed after abort")
+	}
+	if hash, _ := refs.ResolveHead(dir); hash != mainHash {
+		t.Error("HEAD should not move on abort")
+	}
+}
+
+func TestMergeAbort_NoMerge(t *testing.T) {
+	setupTestRepoWithCommit(t)
+	if err := MergeAbort(); err == nil {
+		t.Fatal("expected error when no merge is in progress")
+	}
+}
+
+func TestMergeAbort_NoRepo(t *testing.T) {
+	dir := t.TempDir()
+	orig, _ := os.Getwd()
+	defer os.Chdir(orig)
+	os.Chdir(dir)
+
+	if err := MergeAbort(); err == nil {
+		t.Fatal("expected error when not in a repo")
+	}
+	if err := MergeContinue(); err == nil {
+		t.Fatal("expected error when not in a repo")
+	}
+}
+
+func TestReadMergeHead_Unreadable(t *testing.T) {
+	dir := setupTestRepo(t)
+	os.MkdirAll(filepath.Join(repo.MergeHeadPath(dir), "sub"), 0755)
+
+	if _, err := readMergeHead(dir); err == nil {
+		t.Fatal("expected error when MERGE_HEAD is a directory")
+	}
+	if err := Status(); err == nil {
+		t.Fatal("expected Status to surface the MERGE_HEAD error")
+	}
+}
+
+func TestStatus_DuringMerge(t *testing.T) {
+	setupConflictedMerge(t)
+	if err := Status(); err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+}
+
+func TestMerge_ConflictRecordsStages(t *testing.T) {
+	dir, _, _ := setupConflictedMerge(t)
+
+	idx, _ := index.ReadIndex(dir)
+	base, ours, theirs := idx.ConflictStages("test.txt")
+	if base == nil || ours == nil || theirs == nil {
+		t.Fatalf("expected base/ours/theirs stages, got %v", idx.Entries)
+	}
+	oursBlob, _ := object.ReadBlob(dir, ours.Hash)
+	theirsBlob, _ := object.ReadBlob(dir, theirs.Hash)
+	if string(oursBlob) != "main version\n" || string(theirsBlob) != "feature version\n" {
+		t.Errorf("unexpected stage contents: %q / %q", oursBlob, theirsBlob)
+	}
+	if e := idx.LookupEntry("feature_only.txt"); e == nil {
+		t.Error("cleanly merged paths should be staged at stage 0")
+	}
+}
+
+func TestCommit_RefusesUnmerged(t *testing.T) {
+	setupConflictedMerge(t)
+	err := Commit("too early")
+	if err == nil || !strings.Contains(err.Error(), "unmerged") {
+		t.Fatalf("expected unmerged error, got %v", err)
+	}
+}
+
+func TestCheckout_RefusesUnmerged(t *testing.T) {
+	setupConflictedMerge(t)
+	if err := Checkout("feature"); err == nil {
+		t.Fatal("expected checkout to refuse with unmerged entries")
+	}
+}
+
+func TestCheckout_RefusesDuringMerge(t *testing.T) {
+	dir, mainHash, _ := setupConflictedMerge(t)
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("resolved\n"), 0644)
+	Add([]string{"test.txt"})
+
+	err := Checkout("feature")
+	if err == nil || !strings.Contains(err.Error(), "MERGE_HEAD exists") {
+		t.Fatalf("expected checkout to refuse during a merge, got %v", err)
+	}
+	if branch, _ := refs.CurrentBranch(dir); branch != "main" {
+		t.Errorf("expected to stay on main, got %q", branch)
+	}
+	if head, _ := refs.ResolveHead(dir); head != mainHash {
+		t.Error("HEAD should not move")
+	}
+	if _, err := os.Stat(repo.MergeHeadPath(dir)); err != nil {
+		t.Error("MERGE_HEAD should be kept")
+	}
+}
+
+func TestStatus_AllConflictsFixed(t *testing.T) {
+	dir, _, _ := setupConflictedMerge(t)
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("resolved\n"), 0644)
+	Add([]string{"test.txt"})
+
+	idx, _ := index.ReadIndex(dir)
+	if idx.HasUnmerged() {
+		t.Fatal("add should collapse conflict stages")
+	}
+	if err := Status(); err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+}
+
+func TestDiff_UnmergedPath(t *testing.T) {
+	setupConflictedMerge(t)
+	if err := Diff(DiffOptions{}); err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+}
+
+func TestUnmergedLabel(t *testing.T) {
+	h := "1111111111111111111111111111111111111111"
+	tests := []struct {
+		stages []int
+		want   string
+	}{
+		{[]int{1, 2, 3}, "both modified"},
+		{[]int{2, 3}, "both added"},
+		{[]int{1, 2}, "deleted by them"},
+		{[]int{1, 3}, "deleted by us"},
+		{[]int{2}, "added by us"},
+		{[]int{3}, "added by them"},
+		{[]int{1}, "both deleted"},
+	}
+	for _, tt := range tests {
+		idx := &index.Index{}
+		for _, s := range tt.stages {
+			idx.AddEntry(index.Entry{Hash: h, Path: "f", Stage: s})
+		}
+		if got := unmergedLabel(idx, "f"); got != tt.want {
+			t.Errorf("stages %v: expected %q, got %q", tt.stages, tt.want, got)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"gogit/index"
@@ -129,7 +130,7 @@ func TestMerge_DetachedHead(t *testing.T) {
 	Branch("feature")
 
 	hash, _ := refs.ResolveHead(dir)
-	refs.UpdateHead(dir, hash)
+	refs.UpdateHead(dir, hash, "test")
 
 	err := Merge("feature")
 	if err == nil {
@@ -801,7 +802,7 @@ func TestFastForwardMerge_WriteRefError(t *testing.T) {
 	os.MkdirAll(mainRef, 0755)
 	defer os.RemoveAll(mainRef)
 
-	err := fastForwardMerge(dir, "main", "feature", targetHash)
+	err := fastForwardMerge(dir, "main", "feature", "", targetHash)
 	if err == nil {
 		t.Fatal("expected error when WriteRef fails")
 	}
@@ -809,8 +810,9 @@ func TestFastForwardMerge_WriteRefError(t *testing.T) {
 
 func TestFastForwardMerge_ReadCommitError(t *testing.T) {
 	dir := setupTestRepoWithCommit(t)
+	head, _ := refs.ResolveHead(dir)
 
-	err := fastForwardMerge(dir, "main", "feature", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	err := fastForwardMerge(dir, "main", "feature", head, "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
 	if err == nil {
 		t.Fatal("expected error when ReadCommit fails")
 	}
@@ -929,3 +931,248 @@ func TestFileLevelMerge_DirectReadCommitError(t *testing.T) {
 		t.Fatal("expected error when ReadCommit fails for current hash")
 	}
 }
+
+func TestMerge_LineLevelAutoResolve(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("1\n2\n3\n4\n5\n6\n7\n8\n"), 0644)
+	Add([]string{"test.txt"})
+	Commit("numbers")
+
+	Branch("feature")
+
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("one\n2\n3\n4\n5\n6\n7\n8\n"), 0644)
+	Add([]string{"test.txt"})
+	Commit("main edits top")
+
+	Checkout("feature")
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("1\n2\n3\n4\n5\n6\n7\neight\n"), 0644)
+	Add([]string{"test.txt"})
+	Commit("feature edits bottom")
+
+	Checkout("main")
+	if err := Merge("feature"); err != nil {
+		t.Fatalf("non-overlapping edits should merge cleanly: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "test.txt"))
+	if string(data) != "one\n2\n3\n4\n5\n6\n7\neight\n" {
+		t.Errorf("unexpected merged content:\n%s", data)
+	}
+
+	hash, _ := refs.ResolveHead(dir)
+	commit, _ := object.ReadCommit(dir, hash)
+	tree, _ := object.FlattenTree(dir, commit.TreeHash, "")
+	blob, _ := object.ReadBlob(dir, tree["test.txt"])
+	if string(blob) != string(data) {
+		t.Error("merge commit should record the merged content")
+	}
+}
+
+func TestMerge_ConflictMarkersInWorkingTree(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	Branch("feature")
+
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("main version\n"), 0644)
+	Add([]string{"test.txt"})
+	Commit("main change")
+
+	Checkout("feature")
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("feature version\n"), 0644)
+	Add([]string{"test.txt"})
+	Commit("feature change")
+
+	Checkout("main")
+	if err := Merge("feature"); err == nil {
+		t.Fatal("expected conflict error")
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "test.txt"))
+	want := "<<<<<<< HEAD\nmain version\n=======\nfeature version\n>>>>>>> feature\n"
+	if string(data) != want {
+		t.Errorf("expected conflict markers, got:\n%s", data)
+	}
+}
+
+func TestMerge_BinaryConflict(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	path := filepath.Join(dir, "image.bin")
+	os.WriteFile(path, []byte("base\x00\n"), 0644)
+	Add([]string{"image.bin"})
+	Commit("add image")
+	Branch("feature")
+
+	os.WriteFile(path, []byte("ours\x00\n"), 0644)
+	Add([]string{"image.bin"})
+	Commit("main change")
+
+	Checkout("feature")
+	os.WriteFile(path, []byte("theirs\x00\n"), 0644)
+	Add([]string{"image.bin"})
+	Commit("feature change")
+
+	Checkout("main")
+	out, err := captureStdout(t, func() error { return Merge("feature") })
+	if err == nil {
+		t.Fatal("expected conflict error")
+	}
+	if !strings.Contains(out, "warning: Cannot merge binary files: image.bin (HEAD vs. feature)") ||
+		!strings.Contains(out, "CONFLICT (content): Merge conflict in image.bin") {
+		t.Errorf("expected a binary conflict, got:\n%s", out)
+	}
+
+	// Our version stays in the working tree, without markers, and all
+	// three versions are staged.
+	if data, _ := os.ReadFile(path); string(data) != "ours\x00\n" {
+		t.Errorf("expected our version, got %q", data)
+	}
+	idx, _ := index.ReadIndex(dir)
+	base, ours, theirs := idx.ConflictStages("image.bin")
+	if base == nil || ours == nil || theirs == nil || theirs.Hash != object.HashBlob([]byte("theirs\x00\n")) {
+		t.Errorf("expected all conflict stages, got %v %v %v", base, ours, theirs)
+	}
+}
+
+func TestMergeBlobs_Binary(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	text, _ := object.WriteBlob(dir, []byte("a\n"))
+	binary, _ := object.WriteBlob(dir, []byte("a\x00\n"))
+	for _, hashes := range [][3]string{{binary, text, text}, {text, binary, text}, {"", text, binary}} {
+		merged, conflict, err := mergeBlobs(dir, hashes[0], hashes[1], hashes[2], "HEAD", "f")
+		if err != nil || !conflict || merged != nil {
+			t.Errorf("expected a binary conflict, got %q, %v, %v", merged, conflict, err)
+		}
+	}
+}
+
+func TestMerge_ModifyDeleteConflict(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "keep.txt"), []byte("keep"), 0644)
+	Add([]string{"keep.txt"})
+	Commit("add keeper")
+	Branch("feature")
+
+	os.Remove(filepath.Join(dir, "test.txt"))
+	Add([]string{"test.txt"})
+	Commit("main deletes")
+
+	Checkout("feature")
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("changed\n"), 0644)
+	Add([]string{"test.txt"})
+	Commit("feature modifies")
+
+	Checkout("main")
+	if err := Merge("feature"); err == nil {
+		t.Fatal("expected modify/delete conflict")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "test.txt"))
+	if err != nil || string(data) != "changed\n" {
+		t.Errorf("modified version should be kept in the working tree, got %q (%v)", data, err)
+	}
+}
+
+func TestMergeBlobs_MissingBlob(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	good, _ := object.WriteBlob(dir, []byte("x"))
+	bad := "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"
+
+	if _, _, err := mergeBlobs(dir, bad, good, good, "HEAD", "f"); err == nil {
+		t.Error("expected error for missing base")
+	}
+	if _, _, err := mergeBlobs(dir, "", bad, good, "HEAD", "f"); err == nil {
+		t.Error("expected error for missing ours")
+	}
+	if _, _, err := mergeBlobs(dir, "", good, bad, "HEAD", "f"); err == nil {
+		t.Error("expected error for missing theirs")
+	}
+}
+
+func TestMergeBlobs_DiffAlgorithmConfig(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	base, _ := object.WriteBlob(dir, []byte("a\n"))
+	ours, _ := object.WriteBlob(dir, []byte("a\nb\n"))
+	theirs, _ := object.WriteBlob(dir, []byte("z\na\n"))
+	configPath := filepath.Join(dir, repo.GogitDir, "config")
+
+	os.WriteFile(configPath, []byte("[diff]\n\talgorithm = histogram\n"), 0644)
+	merged, conflict, err := mergeBlobs(dir, base, ours, theirs, "HEAD", "f")
+	if err != nil || conflict || string(merged) != "z\na\nb\n" {
+		t.Errorf("expected a clean merge, got %q, %v, %v", merged, conflict, err)
+	}
+
+	os.WriteFile(configPath, []byte("[diff]\n\talgorithm = bogus\n"), 0644)
+	if _, _, err := mergeBlobs(dir, base, ours, theirs, "HEAD", "f"); err == nil {
+		t.Error("expected error for an unknown diff.algorithm")
+	}
+}
+
+func TestMerge_ByRevision(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	Branch("feature")
+	Checkout("feature")
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("f"), 0644)
+	Add([]string{"f.txt"})
+	Commit("feat")
+	featHash, _ := refs.ResolveHead(dir)
+	Checkout("main")
+	os.WriteFile(filepath.Join(dir, "m.txt"), []byte("m"), 0644)
+	Add([]string{"m.txt"})
+	Commit("main work")
+
+	if err := Merge(featHash[:8]); err != nil {
+		t.Fatalf("Merge by abbreviated hash failed: %v", err)
+	}
+	head, _ := refs.ResolveHead(dir)
+	commit, _ := object.ReadCommit(dir, head)
+	if len(commit.Parents) != 2 || commit.Parents[1] != featHash {
+		t.Errorf("expected merge commit with %s as second parent, got %v", featHash, commit.Parents)
+	}
+	if commit.Message != "Merge commit '"+featHash[:8]+"' into main" {
+		t.Errorf("unexpected merge message: %q", commit.Message)
+	}
+}
+
+func TestMerge_UnknownRevision(t *testing.T) {
+	setupTestRepoWithCommit(t)
+	err := Merge("feature~1")
+	if err == nil || !strings.Contains(err.Error(), "not something we can merge") {
+		t.Fatalf("expected error for unknown revision, got %v", err)
+	}
+}
+
+func TestMerge_IndexLocked(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	Branch("feature")
+	Checkout("feature")
+	os.WriteFile(filepath.Join(dir, "feature.txt"), []byte("feature\n"), 0644)
+	Add([]string{"feature.txt"})
+	Commit("feature commit")
+	Checkout("main")
+	lockPath := filepath.Join(dir, ".gogit", "index.lock")
+
+	// Fast-forward
+	mainHead, _ := refs.ResolveHead(dir)
+	os.WriteFile(lockPath, nil, 0644)
+	if err := Merge("feature"); err == nil {
+		t.Error("expected error for a fast-forward while the index is locked")
+	}
+	if head, _ := refs.ResolveHead(dir); head != mainHead {
+		t.Error("HEAD should be unchanged")
+	}
+
+	// File-level merge
+	os.Remove(lockPath)
+	os.WriteFile(filepath.Join(dir, "main.txt"), []byte("main\n"), 0644)
+	Add([]string{"main.txt"})
+	Commit("main commit")
+	mainHead, _ = refs.ResolveHead(dir)
+	os.WriteFile(lockPath, nil, 0644)
+	if err := Merge("feature"); err == nil {
+		t.Error("expected error for a file-level merge while the index is locked")
+	}
+	if head, _ := refs.ResolveHead(dir); head != mainHead {
+		t.Error("HEAD should be unchanged")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "feature.txt")); !os.IsNotExist(err) {
+		t.Error("working tree should be unchanged")
+	}
+}
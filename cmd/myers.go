@@ -0,0 +1,231 @@
+package cmd
+
+import "math"
+
+// myersMinCost is the smallest number of edits the middle-snake search
+// explores before myersAlgorithm settles for a heuristic split. Larger
+// inputs get a limit of about the square root of their combined length.
+const myersMinCost = 256
+
+// myersAlgorithm is the default diff algorithm. It runs the divide and
+// conquer variant of Myers' O(ND) algorithm, which finds the middle snake
+// of an optimal path and recurses on both halves, so memory stays linear in
+// the input size. Lines that occur only on one side are marked changed up
+// front, and when the search for a middle snake exceeds a cost limit the
+// best partial path is used instead, so the script stays minimal for
+// ordinary inputs and pathological ones finish in reasonable time. The
+// minimal variant has no cost limit.
+type myersAlgorithm struct {
+	minimal bool
+}
+
+func (alg myersAlgorithm) changes(a, b []int) ([]bool, []bool) {
+	m := &myers{
+		a:         a,
+		b:         b,
+		changedA:  make([]bool, len(a)),
+		changedB:  make([]bool, len(b)),
+		costLimit: myersMinCost,
+	}
+	for n := len(a) + len(b); m.costLimit*m.costLimit < n; {
+		m.costLimit *= 2
+	}
+	if alg.minimal {
+		m.costLimit = math.MaxInt
+	}
+	m.diff()
+	return m.changedA, m.changedB
+}
+
+// myers holds the state of one linear-space Myers diff, recording the
+// result as a changed flag per line of each side.
+type myers struct {
+	a, b        []int
+	changedA    []bool
+	changedB    []bool
+	fwd, bwd    []int // furthest reaching x per diagonal, reused by each split
+	costLimit   int
+	approximate bool // whether a split settled for a heuristic point
+}
+
+// diff marks the changed lines of both sides.
+func (m *myers) diff() {
+	// Only lines present on both sides can be matched; run the search on
+	// those and map the result back.
+	inA := make(map[int]bool, len(m.a))
+	for _, id := range m.a {
+		inA[id] = true
+	}
+	inB := make(map[int]bool, len(m.b))
+	for _, id := range m.b {
+		inB[id] = true
+	}
+	a, mapA := m.discard(m.a, m.changedA, inB)
+	b, mapB := m.discard(m.b, m.changedB, inA)
+
+	sub := &myers{
+		a:         a,
+		b:         b,
+		changedA:  make([]bool, len(a)),
+		changedB:  make([]bool, len(b)),
+		costLimit: m.costLimit,
+	}
+	size := len(a) + len(b) + 4
+	sub.fwd = make([]int, size)
+	sub.bwd = make([]int, size)
+	sub.compare(0, len(a), 0, len(b))
+
+	for i, changed := range sub.changedA {
+		m.changedA[mapA[i]] = changed
+	}
+	for i, changed := range sub.changedB {
+		m.changedB[mapB[i]] = changed
+	}
+	m.approximate = sub.approximate
+}
+
+// discard returns the lines of side that occur in other, along with their
+// positions in side, and marks the rest changed.
+func (m *myers) discard(side []int, changed []bool, other map[int]bool) ([]int, []int) {
+	var kept, positions []int
+	for i, id := range side {
+		if other[id] {
+			kept = append(kept, id)
+			positions = append(positions, i)
+		} else {
+			changed[i] = true
+		}
+	}
+	return kept, positions
+}
+
+// compare marks the changes between a[aLo:aHi] and b[bLo:bHi].
+func (m *myers) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && m.a[aLo] == m.b[bLo] {
+		aLo++
+		bLo++
+	}
+	for aLo < aHi && bLo < bHi && m.a[aHi-1] == m.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	x, y, ok := aLo, bLo, false
+	if aLo < aHi && bLo < bHi {
+		x, y, ok = m.split(aLo, aHi, bLo, bHi)
+		// A split at a corner would not make progress.
+		ok = ok && (x > aLo || y > bLo) && (x < aHi || y < bHi)
+	}
+	if !ok {
+		markChanged(m.changedA, aLo, aHi)
+		markChanged(m.changedB, bLo, bHi)
+		return
+	}
+	m.compare(aLo, x, bLo, y)
+	m.compare(x, aHi, y, bHi)
+}
+
+// split finds a point where an optimal path from (aLo, bLo) to (aHi, bHi)
+// crosses the middle of its edits, by searching forward from the start and
+// backward from the end at once until the two searches overlap. Both ranges
+// must be non-empty. ok is false when no line can be matched at all.
+func (m *myers) split(aLo, aHi, bLo, bHi int) (x, y int, ok bool) {
+	n, mm := aHi-aLo, bHi-bLo
+	maxD := (n + mm + 1) / 2
+	off := maxD + 1
+	vf, vb := m.fwd[:2*maxD+3], m.bwd[:2*maxD+3]
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[off+1], vb[off+1] = 0, 0
+
+	// Diagonal k holds the points with x-y == k; the backward search runs
+	// in reversed coordinates, where the end lies on diagonal delta.
+	delta := n - mm
+	odd := delta&1 != 0
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+	for d := 0; d <= maxD; d++ {
+		if d > m.costLimit {
+			m.approximate = true
+			x, y = m.bestSplit(n, mm, d-1, off, fStart, fEnd, bStart, bEnd)
+			return aLo + x, bLo + y, true
+		}
+
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			i := off + k
+			var x int
+			if k == -d || k != d && vf[i-1] < vf[i+1] {
+				x = vf[i+1]
+			} else {
+				x = vf[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < mm && m.a[aLo+x] == m.b[bLo+y] {
+				x++
+				y++
+			}
+			vf[i] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > mm:
+				fStart += 2
+			case odd:
+				j := off + delta - k
+				if j >= 0 && j < len(vb) && vb[j] != -1 && x >= n-vb[j] {
+					return aLo + x, bLo + y, true
+				}
+			}
+		}
+
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			i := off + k
+			var x int
+			if k == -d || k != d && vb[i-1] < vb[i+1] {
+				x = vb[i+1]
+			} else {
+				x = vb[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < mm && m.a[aHi-x-1] == m.b[bHi-y-1] {
+				x++
+				y++
+			}
+			vb[i] = x
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > mm:
+				bStart += 2
+			case !odd:
+				j := off + delta - k
+				if j >= 0 && j < len(vf) && vf[j] != -1 {
+					fx := vf[j]
+					if fx >= n-x {
+						return aLo + fx, bLo + fx - (j - off), true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// bestSplit returns the point furthest from its corner among those reached
+// by the forward and backward searches after d edits.
+func (m *myers) bestSplit(n, mm, d, off, fStart, fEnd, bStart, bEnd int) (x, y int) {
+	best := -1
+	for k := -d + fStart; k <= d-fEnd; k += 2 {
+		fx := m.fwd[off+k]
+		if fy := fx - k; fx >= 0 && fx <= n && fy >= 0 && fy <= mm && fx+fy > best {
+			best, x, y = fx+fy, fx, fy
+		}
+	}
+	for k := -d + bStart; k <= d-bEnd; k += 2 {
+		bx := m.bwd[off+k]
+		if by := bx - k; bx >= 0 && bx <= n && by >= 0 && by <= mm && bx+by > best {
+			best, x, y = bx+by, n-bx, mm-by
+		}
+	}
+	return x, y
+}
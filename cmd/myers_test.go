@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMyersDiff(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"", "", ""},
+		{"a b", "", "-a -b"},
+		{"", "a b", "+a +b"},
+		{"a b c", "a b c", " a  b  c"},
+		{"a b c", "a c", " a -b  c"},
+		{"x y", "y x y", "+y  x  y"},
+		{"a x b", "a y b", " a -x +y  b"},
+		{"a b c d", "e f", "-a -b -c -d +e +f"},
+	}
+	for _, tt := range tests {
+		a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+		diff := diffScript(myersAlgorithm{}, a, b)
+		if kept := checkScript(t, a, b, diff); kept != lcsLength(a, b) {
+			t.Errorf("%q -> %q: kept %d lines, want %d", tt.a, tt.b, kept, lcsLength(a, b))
+		}
+		if got := formatScript(diff); got != tt.want {
+			t.Errorf("%q -> %q: got %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMyersDiff_CostLimit(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	var a, b []string
+	for i := 0; i < 300; i++ {
+		a = append(a, string(rune('a'+rng.Intn(4))))
+		b = append(b, string(rune('a'+rng.Intn(4))))
+	}
+	ia, ib := internLines(a, b)
+
+	m := &myers{
+		a:         ia,
+		b:         ib,
+		changedA:  make([]bool, len(ia)),
+		changedB:  make([]bool, len(ib)),
+		costLimit: 3,
+	}
+	m.diff()
+	if !m.approximate {
+		t.Error("expected a heuristic split with a low cost limit")
+	}
+
+	// The unchanged lines of both sides must still pair up.
+	kept := func(lines []int, changed []bool) []int {
+		var out []int
+		for i, c := range changed {
+			if !c {
+				out = append(out, lines[i])
+			}
+		}
+		return out
+	}
+	keptA, keptB := kept(ia, m.changedA), kept(ib, m.changedB)
+	if !slices.Equal(keptA, keptB) {
+		t.Errorf("unchanged lines differ: %v vs %v", keptA, keptB)
+	}
+	if len(keptA) == 0 {
+		t.Error("expected some lines to be matched")
+	}
+}
+
+func TestMyersDiff_MinimalHasNoCostLimit(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	var a, b []string
+	for i := 0; i < 2000; i++ {
+		a = append(a, string(rune('a'+rng.Intn(4))))
+		b = append(b, string(rune('a'+rng.Intn(4))))
+	}
+	kept := checkScript(t, a, b, diffScript(myersAlgorithm{minimal: true}, a, b))
+	if want := lcsLength(a, b); kept != want {
+		t.Errorf("kept %d lines, want %d", kept, want)
+	}
+}
+
+func TestMyersDiff_LargeInput(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 50000; i++ {
+		line := fmt.Sprintf("line %d", i)
+		a = append(a, line)
+		if i%10000 == 5000 {
+			b = append(b, "changed")
+			continue
+		}
+		b = append(b, line)
+	}
+	b = append(b, "appended")
+
+	hunks := buildHunks(diffScript(myersAlgorithm{}, a, b))
+	var headers []string
+	for _, h := range hunks {
+		if strings.HasPrefix(h, "@@") {
+			headers = append(headers, h)
+		}
+	}
+	if len(headers) != 6 {
+		t.Fatalf("expected 6 hunks, got %d: %v", len(headers), headers)
+	}
+	if headers[5] != "@@ -49998,3 +49998,4 @@ line 49996" {
+		t.Errorf("unexpected last hunk %q", headers[5])
+	}
+}
+
+func TestMyersDiff_NothingInCommon(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 20000; i++ {
+		a = append(a, fmt.Sprintf("old %d", i))
+		b = append(b, fmt.Sprintf("new %d", i))
+	}
+	if kept := checkScript(t, a, b, diffScript(myersAlgorithm{}, a, b)); kept != 0 {
+		t.Errorf("expected no common lines, got %d", kept)
+	}
+}
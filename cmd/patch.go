@@ -0,0 +1,437 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// filePatch is what a patch changes in one file: its name, mode and
+// existence, and its content through text hunks or a binary patch.
+type filePatch struct {
+	oldPath, newPath string // the same unless the file is renamed or copied
+	oldMode, newMode string // empty when the patch does not tell
+	oldHash, newHash string // from the index line, often abbreviated; empty for a missing side
+	isNew, isDelete  bool
+	isRename, isCopy bool
+	hunks            []patchHunk
+	// binary is set for a binary patch. Its forward hunk turns the old
+	// content into the new one and its reverse hunk turns it back; both are
+	// nil when the patch only says that the files differ.
+	binary           bool
+	forward, reverse *binaryHunk
+}
+
+// patchHunk is one "@@" section of a text patch. Its lines keep their
+// newline, except for a last line the patch marks as having none.
+type patchHunk struct {
+	oldStart, oldCount int
+	newStart, newCount int
+	lines              []diffLine
+}
+
+// binaryHunk is one hunk of a git binary patch: the whole new content, or a
+// delta against the old content.
+type binaryHunk struct {
+	delta bool
+	data  []byte
+}
+
+// patchParser reads a patch line by line.
+type patchParser struct {
+	lines []string // keeping their newlines
+	pos   int      // the next line to read
+}
+
+// parsePatch reads the file patches in data, in git's format, with a
+// "diff --git" line starting each file, or as plain unified diffs. Other
+// text around them, such as the message of a mailed patch, is skipped.
+func parsePatch(data []byte) ([]*filePatch, error) {
+	p := &patchParser{lines: splitLines(string(data))}
+	var patches []*filePatch
+	for p.pos < len(p.lines) {
+		var f *filePatch
+		line := p.lines[p.pos]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			f = p.parseGitHeader()
+		case strings.HasPrefix(line, "--- ") && p.traditionalHeader():
+			f = p.parseTraditionalHeader()
+		default:
+			p.pos++
+			continue
+		}
+		if err := p.parseBody(f); err != nil {
+			return nil, err
+		}
+		if f.oldPath == "" || f.newPath == "" {
+			return nil, fmt.Errorf("git diff header lacks filename information (line %d)", p.pos)
+		}
+		patches = append(patches, f)
+	}
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no valid patches in input")
+	}
+	return patches, nil
+}
+
+// corrupt reports that the line at pos cannot be read.
+func (p *patchParser) corrupt() error {
+	return fmt.Errorf("corrupt patch at line %d", p.pos+1)
+}
+
+// gitHeaderFields are the names of the extended header lines that may
+// follow "diff --git", each followed by a space and its value.
+var gitHeaderFields = []string{
+	"old mode", "new mode", "deleted file mode", "new file mode",
+	"rename from", "rename to", "rename old", "rename new", "copy from", "copy to",
+	"similarity index", "dissimilarity index", "index", "---", "+++",
+}
+
+// parseGitHeader reads a "diff --git a/<old> b/<new>" line and the extended
+// header lines after it. The names on the first line are replaced by those
+// of any rename or copy lines.
+func (p *patchParser) parseGitHeader() *filePatch {
+	f := &filePatch{}
+	f.oldPath, f.newPath = gitHeaderNames(strings.TrimSuffix(p.lines[p.pos], "\n")[len("diff --git "):])
+	for p.pos++; p.pos < len(p.lines); p.pos++ {
+		line := strings.TrimSuffix(p.lines[p.pos], "\n")
+		field, value := "", ""
+		for _, name := range gitHeaderFields {
+			if v, ok := strings.CutPrefix(line, name+" "); ok {
+				field, value = name, v
+				break
+			}
+		}
+		switch field {
+		case "":
+			return f
+		case "old mode":
+			f.oldMode = value
+		case "new mode":
+			f.newMode = value
+		case "deleted file mode":
+			f.isDelete, f.oldMode = true, value
+		case "new file mode":
+			f.isNew, f.newMode = true, value
+		case "rename from", "rename old":
+			f.isRename, f.oldPath = true, unquotePath(value)
+		case "rename to", "rename new":
+			f.isRename, f.newPath = true, unquotePath(value)
+		case "copy from":
+			f.isCopy, f.oldPath = true, unquotePath(value)
+		case "copy to":
+			f.isCopy, f.newPath = true, unquotePath(value)
+		case "index":
+			hashes, mode, _ := strings.Cut(value, " ")
+			oldHash, newHash, _ := strings.Cut(hashes, "..")
+			f.oldHash, f.newHash = blobID(oldHash), blobID(newHash)
+			if mode != "" && f.oldMode == "" && f.newMode == "" {
+				f.oldMode, f.newMode = mode, mode
+			}
+		}
+	}
+	return f
+}
+
+// gitHeaderNames splits the names of a "diff --git" line and strips their
+// "a/" and "b/" prefixes. Unquoted names may hold spaces, so the line is
+// split where it gives the same name twice, and otherwise before the first
+// " b/".
+func gitHeaderNames(s string) (oldPath, newPath string) {
+	if strings.HasPrefix(s, `"`) {
+		end := quotedEnd(s)
+		oldPath, s = unquotePath(s[:end]), strings.TrimLeft(s[end:], " ")
+		return stripComponent(oldPath), stripComponent(unquotePath(s))
+	}
+	split := -1
+	for i := 0; i < len(s); i++ {
+		if !strings.HasPrefix(s[i:], " b/") {
+			continue
+		}
+		if split < 0 {
+			split = i
+		}
+		if stripComponent(s[:i]) == s[i+3:] {
+			split = i
+			break
+		}
+	}
+	if split < 0 {
+		return "", ""
+	}
+	return stripComponent(s[:split]), stripComponent(unquotePath(s[split+1:]))
+}
+
+// quotedEnd returns the index just past the closing quote of the
+// C-style quoted string s starts with.
+func quotedEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// unquotePath undoes the C-style quoting git gives a name holding special
+// characters, as in "\"t\\303\\244st\"".
+func unquotePath(s string) string {
+	if !strings.HasPrefix(s, `"`) {
+		return s
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return s
+}
+
+// stripComponent removes the leading directory of a name in a patch, the
+// "a/" or "b/" that diffs put before it.
+func stripComponent(name string) string {
+	if _, rest, ok := strings.Cut(name, "/"); ok {
+		return rest
+	}
+	return name
+}
+
+// blobID returns the blob id of an index line, or "" for the zeros that
+// stand for a missing file.
+func blobID(hash string) string {
+	if strings.Trim(hash, "0") == "" {
+		return ""
+	}
+	return hash
+}
+
+// traditionalHeader reports whether the line at pos starts a plain unified
+// diff: a "---" line, a "+++" line and a hunk header.
+func (p *patchParser) traditionalHeader() bool {
+	return p.pos+2 < len(p.lines) &&
+		strings.HasPrefix(p.lines[p.pos+1], "+++ ") &&
+		strings.HasPrefix(p.lines[p.pos+2], "@@ -")
+}
+
+// parseTraditionalHeader reads the "---" and "+++" lines of a plain unified
+// diff. The file is created when the old name is /dev/null and deleted when
+// the new one is; otherwise the new name is patched.
+func (p *patchParser) parseTraditionalHeader() *filePatch {
+	oldName := traditionalName(p.lines[p.pos][len("--- "):])
+	newName := traditionalName(p.lines[p.pos+1][len("+++ "):])
+	p.pos += 2
+	f := &filePatch{oldPath: newName, newPath: newName}
+	switch {
+	case oldName == "":
+		f.isNew = true
+	case newName == "":
+		f.isDelete = true
+		f.oldPath, f.newPath = oldName, oldName
+	}
+	return f
+}
+
+// traditionalName returns the path of a "---" or "+++" line without its
+// leading directory, or "" for /dev/null. A tab ends the name, as diff
+// may put a timestamp after it.
+func traditionalName(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	if name, _, ok := strings.Cut(s, "\t"); ok {
+		s = name
+	}
+	if s == "/dev/null" {
+		return ""
+	}
+	return stripComponent(unquotePath(s))
+}
+
+// parseBody reads the hunks or the binary patch that follow the header of
+// f, up to the first line that belongs to neither.
+func (p *patchParser) parseBody(f *filePatch) error {
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		switch {
+		case strings.HasPrefix(line, "@@ -"):
+			h, err := p.parseHunk()
+			if err != nil {
+				return err
+			}
+			f.hunks = append(f.hunks, h)
+		case line == "GIT binary patch\n":
+			p.pos++
+			f.binary = true
+			var err error
+			if f.forward, err = p.parseBinaryHunk(); err != nil {
+				return err
+			}
+			if f.forward == nil {
+				return p.corrupt()
+			}
+			f.reverse, err = p.parseBinaryHunk()
+			return err
+		case strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, " differ\n"):
+			p.pos++
+			f.binary = true
+			return nil
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// parseHunk reads a "@@ -<start>,<count> +<start>,<count> @@" header and
+// the lines it counts. An empty line is read as an empty context line, as
+// mail and editors tend to strip the space of one.
+func (p *patchParser) parseHunk() (patchHunk, error) {
+	var h patchHunk
+	ranges, _, _ := strings.Cut(strings.TrimPrefix(p.lines[p.pos], "@@ -"), " @@")
+	oldRange, newRange, ok := strings.Cut(ranges, " +")
+	if !ok || !parseHunkRange(oldRange, &h.oldStart, &h.oldCount) || !parseHunkRange(newRange, &h.newStart, &h.newCount) {
+		return h, p.corrupt()
+	}
+	p.pos++
+
+	oldLeft, newLeft := h.oldCount, h.newCount
+	for oldLeft > 0 || newLeft > 0 {
+		if p.pos == len(p.lines) {
+			return h, p.corrupt()
+		}
+		line := p.lines[p.pos]
+		if line == "\n" {
+			line = " \n"
+		}
+		switch line[0] {
+		case ' ':
+			oldLeft--
+			newLeft--
+		case '-':
+			oldLeft--
+		case '+':
+			newLeft--
+		case '\\':
+			if len(h.lines) == 0 {
+				return h, p.corrupt()
+			}
+			last := &h.lines[len(h.lines)-1]
+			last.text = strings.TrimSuffix(last.text, "\n")
+			p.pos++
+			continue
+		default:
+			return h, p.corrupt()
+		}
+		if oldLeft < 0 || newLeft < 0 {
+			return h, p.corrupt()
+		}
+		h.lines = append(h.lines, diffLine{line[0], line[1:]})
+		p.pos++
+	}
+	// The marker for a last line without a newline follows the lines the
+	// header counts.
+	if p.pos < len(p.lines) && strings.HasPrefix(p.lines[p.pos], "\\") && len(h.lines) > 0 {
+		last := &h.lines[len(h.lines)-1]
+		last.text = strings.TrimSuffix(last.text, "\n")
+		p.pos++
+	}
+	return h, nil
+}
+
+// parseHunkRange parses one side of a hunk header, "start,count" or
+// "start" alone for a single line.
+func parseHunkRange(s string, start, count *int) bool {
+	startText, countText, hasCount := strings.Cut(s, ",")
+	var err error
+	if *start, err = strconv.Atoi(startText); err != nil || *start < 0 {
+		return false
+	}
+	*count = 1
+	if hasCount {
+		if *count, err = strconv.Atoi(countText); err != nil || *count < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// parseBinaryHunk reads a "literal <size>" or "delta <size>" hunk of a
+// binary patch, as printBinaryLiteral writes one, and inflates its data.
+// It returns nil when the next line starts no such hunk.
+func (p *patchParser) parseBinaryHunk() (*binaryHunk, error) {
+	if p.pos == len(p.lines) {
+		return nil, nil
+	}
+	h := &binaryHunk{}
+	header := strings.TrimSuffix(p.lines[p.pos], "\n")
+	sizeText, ok := strings.CutPrefix(header, "literal ")
+	if !ok {
+		if sizeText, ok = strings.CutPrefix(header, "delta "); !ok {
+			return nil, nil
+		}
+		h.delta = true
+	}
+	start := p.pos + 1
+	corrupt := fmt.Errorf("corrupt binary patch at line %d", start)
+	size, err := strconv.Atoi(sizeText)
+	if err != nil {
+		return nil, corrupt
+	}
+
+	var deflated []byte
+	for p.pos++; p.pos < len(p.lines) && p.lines[p.pos] != "\n"; p.pos++ {
+		line := strings.TrimSuffix(p.lines[p.pos], "\n")
+		var n int
+		switch c := line[0]; {
+		case c >= 'A' && c <= 'Z':
+			n = int(c-'A') + 1
+		case c >= 'a' && c <= 'z':
+			n = int(c-'a') + 27
+		default:
+			return nil, corrupt
+		}
+		decoded, err := decodeBase85(line[1:], n)
+		if err != nil {
+			return nil, corrupt
+		}
+		deflated = append(deflated, decoded...)
+	}
+	p.pos++ // the blank line that ends the hunk
+
+	r, err := zlib.NewReader(bytes.NewReader(deflated))
+	if err != nil {
+		return nil, corrupt
+	}
+	if h.data, err = io.ReadAll(r); err != nil || len(h.data) != size {
+		return nil, corrupt
+	}
+	return h, nil
+}
+
+// reversed returns the patch that undoes f.
+func (f *filePatch) reversed() *filePatch {
+	r := *f
+	r.oldPath, r.newPath = f.newPath, f.oldPath
+	r.oldMode, r.newMode = f.newMode, f.oldMode
+	r.oldHash, r.newHash = f.newHash, f.oldHash
+	r.isNew, r.isDelete = f.isDelete, f.isNew
+	r.forward, r.reverse = f.reverse, f.forward
+	r.hunks = make([]patchHunk, len(f.hunks))
+	for i, h := range f.hunks {
+		rh := patchHunk{oldStart: h.newStart, oldCount: h.newCount, newStart: h.oldStart, newCount: h.oldCount}
+		for _, l := range h.lines {
+			switch l.op {
+			case '-':
+				l.op = '+'
+			case '+':
+				l.op = '-'
+			}
+			rh.lines = append(rh.lines, l)
+		}
+		r.hunks[i] = rh
+	}
+	return &r
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+// gitPatch is what git diff prints for a modification, a creation with a
+// mode, a deletion, a rename with changes and a mode change.
+const gitPatch = `From: someone
+Subject: a change
+
+diff --git a/file.txt b/file.txt
+index ce01362..94954ab 100644
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,3 @@ func main
+ one
+-two
++TWO
+ three
+diff --git a/run.sh b/run.sh
+new file mode 100755
+index 0000000..e69de29
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+index 8baef1b..0000000
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-abc
+\ No newline at end of file
+diff --git a/src/a b/lib/b
+similarity index 90%
+rename from src/a
+rename to lib/b
+index 1111111..2222222 100644
+--- a/src/a
++++ b/lib/b
+@@ -10,0 +11 @@
++added
+diff --git a/tool b/tool
+old mode 100644
+new mode 100755
+--
+2.39.0
+`
+
+func TestParsePatch_Git(t *testing.T) {
+	patches, err := parsePatch([]byte(gitPatch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 5 {
+		t.Fatalf("expected 5 file patches, got %d", len(patches))
+	}
+
+	mod := patches[0]
+	if mod.oldPath != "file.txt" || mod.newPath != "file.txt" || mod.oldHash != "ce01362" || mod.newHash != "94954ab" ||
+		mod.oldMode != "100644" || mod.newMode != "100644" || mod.isNew || mod.isDelete || mod.isRename {
+		t.Errorf("unexpected modification %+v", mod)
+	}
+	if len(mod.hunks) != 1 {
+		t.Fatalf("expected one hunk, got %d", len(mod.hunks))
+	}
+	h := mod.hunks[0]
+	if h.oldStart != 1 || h.oldCount != 3 || h.newStart != 1 || h.newCount != 3 {
+		t.Errorf("unexpected hunk range %+v", h)
+	}
+	if got := formatScript(h.lines); got != " one\n -two\n +TWO\n  three\n" {
+		t.Errorf("unexpected hunk lines %q", got)
+	}
+
+	if create := patches[1]; !create.isNew || create.newMode != "100755" || create.oldHash != "" || len(create.hunks) != 0 {
+		t.Errorf("unexpected creation %+v", create)
+	}
+
+	del := patches[2]
+	if !del.isDelete || del.oldMode != "100644" || del.newHash != "" {
+		t.Errorf("unexpected deletion %+v", del)
+	}
+	if lines := del.hunks[0].lines; len(lines) != 1 || lines[0].text != "abc" {
+		t.Errorf("expected the last line to lose its newline, got %q", lines)
+	}
+
+	rename := patches[3]
+	if !rename.isRename || rename.oldPath != "src/a" || rename.newPath != "lib/b" {
+		t.Errorf("unexpected rename %+v", rename)
+	}
+	if h := rename.hunks[0]; h.oldStart != 10 || h.oldCount != 0 || h.newStart != 11 || h.newCount != 1 {
+		t.Errorf("unexpected hunk range %+v", h)
+	}
+
+	if mode := patches[4]; mode.oldMode != "100644" || mode.newMode != "100755" || len(mode.hunks) != 0 {
+		t.Errorf("unexpected mode change %+v", mode)
+	}
+}
+
+func TestParsePatch_Traditional(t *testing.T) {
+	patch := "--- a/dir/f.c\t2024-01-01 00:00:00\n+++ b/dir/f.c\t2024-01-02 00:00:00\n@@ -1,2 +1,2 @@\n-x\n+y\n\n" +
+		"--- /dev/null\n+++ b/new.c\n@@ -0,0 +1 @@\n+n\n"
+	patches, err := parsePatch([]byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 2 {
+		t.Fatalf("expected 2 file patches, got %d", len(patches))
+	}
+	if f := patches[0]; f.oldPath != "dir/f.c" || f.newPath != "dir/f.c" || f.isNew {
+		t.Errorf("unexpected modification %+v", f)
+	}
+	// The empty line is an empty context line that lost its space.
+	if got := formatScript(patches[0].hunks[0].lines); got != "-x\n +y\n  \n" {
+		t.Errorf("unexpected hunk lines %q", got)
+	}
+	if f := patches[1]; !f.isNew || f.newPath != "new.c" {
+		t.Errorf("unexpected creation %+v", f)
+	}
+}
+
+func TestGitHeaderNames(t *testing.T) {
+	tests := []struct{ header, old, new string }{
+		{"a/f.txt b/f.txt", "f.txt", "f.txt"},
+		{"a/with b/space b/with b/space", "with b/space", "with b/space"},
+		{"a/old name b/new", "old name", "new"},
+		{`"a/t\303\244st" "b/t\303\244st"`, "täst", "täst"},
+	}
+	for _, tt := range tests {
+		if old, new := gitHeaderNames(tt.header); old != tt.old || new != tt.new {
+			t.Errorf("gitHeaderNames(%q) = %q, %q, want %q, %q", tt.header, old, new, tt.old, tt.new)
+		}
+	}
+}
+
+func TestParsePatch_Errors(t *testing.T) {
+	tests := []struct{ patch, want string }{
+		{"just a message\n", "no valid patches in input"},
+		{"diff --git a/f b/f\n--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n-x\n", "corrupt patch at line 6"},
+		{"diff --git a/f b/f\n--- a/f\n+++ b/f\n@@ -1 +1 @@\n*x\n", "corrupt patch at line 5"},
+		{"diff --git a/f b/f\n--- a/f\n+++ b/f\n@@ -x +1 @@\n", "corrupt patch at line 4"},
+		{"diff --git a/f b/f\nGIT binary patch\nliteral 3\nzzz\n\n", "corrupt binary patch at line 3"},
+	}
+	for _, tt := range tests {
+		if _, err := parsePatch([]byte(tt.patch)); err == nil || err.Error() != tt.want {
+			t.Errorf("parsePatch(%q): expected %q, got %v", tt.patch, tt.want, err)
+		}
+	}
+}
+
+func TestParsePatch_BinaryRoundTrip(t *testing.T) {
+	old, new := []byte("old\x00content"), bytes.Repeat([]byte("new\x00"), 100)
+	out, _ := captureStdout(t, func() error {
+		printBinaryPatch(old, new)
+		return nil
+	})
+	patches, err := parsePatch([]byte("diff --git a/x.bin b/x.bin\n" + out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := patches[0]
+	if !f.binary || f.forward == nil || f.reverse == nil || f.forward.delta || f.reverse.delta {
+		t.Fatalf("expected two literal hunks, got %+v", f)
+	}
+	if !bytes.Equal(f.forward.data, new) || !bytes.Equal(f.reverse.data, old) {
+		t.Error("binary patch does not round-trip")
+	}
+	if r := f.reversed(); !bytes.Equal(r.forward.data, old) {
+		t.Error("reversing the patch should swap its hunks")
+	}
+}
+
+func TestFilePatch_Reversed(t *testing.T) {
+	patches, err := parsePatch([]byte(gitPatch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := patches[0].reversed()
+	if got := formatScript(r.hunks[0].lines); got != " one\n +two\n -TWO\n  three\n" {
+		t.Errorf("unexpected reversed lines %q", got)
+	}
+	if r.oldHash != "94954ab" || r.newHash != "ce01362" {
+		t.Errorf("expected the blob ids to swap, got %s..%s", r.oldHash, r.newHash)
+	}
+	if r := patches[2].reversed(); !r.isNew || r.isDelete || r.newMode != "100644" {
+		t.Errorf("a reversed deletion should create the file, got %+v", r)
+	}
+	rename := patches[3].reversed()
+	if rename.oldPath != "lib/b" || rename.newPath != "src/a" {
+		t.Errorf("a reversed rename should swap the paths, got %s -> %s", rename.oldPath, rename.newPath)
+	}
+	if h := rename.hunks[0]; h.oldStart != 11 || h.oldCount != 1 || h.newStart != 10 || h.newCount != 0 {
+		t.Errorf("unexpected reversed hunk range %+v", h)
+	}
+	if got := formatScript(rename.hunks[0].lines); got != "-added\n" {
+		t.Errorf("unexpected reversed lines %q", got)
+	}
+}
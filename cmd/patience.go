@@ -0,0 +1,122 @@
+package cmd
+
+import "sort"
+
+// patienceAlgorithm matches the lines that occur exactly once on each side,
+// keeps the longest run of them that appears in the same order on both, and
+// recurses on the regions between those anchors. Anchoring on unique lines
+// such as function signatures keeps moved or rewritten blocks together
+// instead of pairing up stray braces and blank lines. Regions without any
+// unique common line fall back to myers.
+type patienceAlgorithm struct{}
+
+func (patienceAlgorithm) changes(a, b []int) ([]bool, []bool) {
+	p := &patience{
+		a:        a,
+		b:        b,
+		changedA: make([]bool, len(a)),
+		changedB: make([]bool, len(b)),
+	}
+	p.diff(0, len(a), 0, len(b))
+	return p.changedA, p.changedB
+}
+
+type patience struct {
+	a, b               []int
+	changedA, changedB []bool
+}
+
+// diff marks the changes between a[aLo:aHi] and b[bLo:bHi].
+func (p *patience) diff(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && p.a[aLo] == p.b[bLo] {
+		aLo++
+		bLo++
+	}
+	for aLo < aHi && bLo < bHi && p.a[aHi-1] == p.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+	if aLo == aHi || bLo == bHi {
+		markChanged(p.changedA, aLo, aHi)
+		markChanged(p.changedB, bLo, bHi)
+		return
+	}
+
+	anchors := p.anchors(aLo, aHi, bLo, bHi)
+	if len(anchors) == 0 {
+		diffRange(myersAlgorithm{}, p.a, p.b, p.changedA, p.changedB, aLo, aHi, bLo, bHi)
+		return
+	}
+	for _, m := range anchors {
+		p.diff(aLo, m.a, bLo, m.b)
+		aLo, bLo = m.a+1, m.b+1
+	}
+	p.diff(aLo, aHi, bLo, bHi)
+}
+
+// lineMatch pairs line a of the old side with line b of the new side.
+type lineMatch struct {
+	a, b int
+}
+
+// anchors returns the longest sequence of lines unique to both ranges that
+// appear in the same order on both sides.
+func (p *patience) anchors(aLo, aHi, bLo, bHi int) []lineMatch {
+	type occurrence struct {
+		countA, countB int
+		posA, posB     int
+	}
+	lines := make(map[int]*occurrence)
+	for i := aLo; i < aHi; i++ {
+		o := lines[p.a[i]]
+		if o == nil {
+			o = &occurrence{}
+			lines[p.a[i]] = o
+		}
+		o.countA++
+		o.posA = i
+	}
+	for j := bLo; j < bHi; j++ {
+		if o := lines[p.b[j]]; o != nil {
+			o.countB++
+			o.posB = j
+		}
+	}
+
+	var unique []lineMatch
+	for i := aLo; i < aHi; i++ {
+		if o := lines[p.a[i]]; o.countA == 1 && o.countB == 1 {
+			unique = append(unique, lineMatch{i, o.posB})
+		}
+	}
+	return longestIncreasing(unique)
+}
+
+// longestIncreasing returns the longest subsequence of matches, which are
+// ordered by a, that is also increasing in b. It uses patience sorting:
+// each match goes on the leftmost pile whose top has a larger b, linked
+// to the top of the pile before it.
+func longestIncreasing(matches []lineMatch) []lineMatch {
+	var tops []int // index into matches of each pile's top
+	prev := make([]int, len(matches))
+	for i, m := range matches {
+		pile := sort.Search(len(tops), func(k int) bool { return matches[tops[k]].b > m.b })
+		prev[i] = -1
+		if pile > 0 {
+			prev[i] = tops[pile-1]
+		}
+		if pile == len(tops) {
+			tops = append(tops, i)
+		} else {
+			tops[pile] = i
+		}
+	}
+	if len(tops) == 0 {
+		return nil
+	}
+	seq := make([]lineMatch, len(tops))
+	for i, k := len(seq)-1, tops[len(tops)-1]; i >= 0; i, k = i-1, prev[k] {
+		seq[i] = matches[k]
+	}
+	return seq
+}
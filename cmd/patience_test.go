@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// movedFunctionOld and movedFunctionNew replace one function with another
+// placed before a function that is kept, which myers interleaves line by
+// line.
+const movedFunctionOld = `#include <stdio.h>
+
+// Frobs foo heartily
+int frobnitz(int foo)
+{
+    int i;
+    for(i = 0; i < 10; i++)
+    {
+        printf("Your answer is: ");
+        printf("%d\n", foo);
+    }
+}
+
+int fact(int n)
+{
+    if(n > 1)
+    {
+        return fact(n-1) * n;
+    }
+    return 1;
+}
+
+int main(int argc, char **argv)
+{
+    frobnitz(fact(10));
+}
+`
+
+const movedFunctionNew = `#include <stdio.h>
+
+int fib(int n)
+{
+    if(n > 2)
+    {
+        return fib(n-1) + fib(n-2);
+    }
+    return 1;
+}
+
+// Frobs foo heartily
+int frobnitz(int foo)
+{
+    int i;
+    for(i = 0; i < 10; i++)
+    {
+        printf("%d\n", foo);
+    }
+}
+
+int main(int argc, char **argv)
+{
+    frobnitz(fib(10));
+}
+`
+
+// movedFunctionDiff is the script patience and histogram produce for the
+// moved function, as git's do.
+const movedFunctionDiff = ` #include <stdio.h>
+ 
++int fib(int n)
++{
++    if(n > 2)
++    {
++        return fib(n-1) + fib(n-2);
++    }
++    return 1;
++}
++
+ // Frobs foo heartily
+ int frobnitz(int foo)
+ {
+     int i;
+     for(i = 0; i < 10; i++)
+     {
+-        printf("Your answer is: ");
+         printf("%d\n", foo);
+     }
+ }
+ 
+-int fact(int n)
+-{
+-    if(n > 1)
+-    {
+-        return fact(n-1) * n;
+-    }
+-    return 1;
+-}
+-
+ int main(int argc, char **argv)
+ {
+-    frobnitz(fact(10));
++    frobnitz(fib(10));
+ }
+`
+
+// renderScript prints an edit script one line per entry.
+func renderScript(diff []diffLine) string {
+	var sb strings.Builder
+	for _, d := range diff {
+		fmt.Fprintf(&sb, "%c%s\n", d.op, d.text)
+	}
+	return sb.String()
+}
+
+func TestPatienceDiff_MovedFunction(t *testing.T) {
+	a := strings.Split(strings.TrimSuffix(movedFunctionOld, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(movedFunctionNew, "\n"), "\n")
+
+	if got := renderScript(diffScript(patienceAlgorithm{}, a, b)); got != movedFunctionDiff {
+		t.Errorf("unexpected patience diff:\n%s", got)
+	}
+	if got := renderScript(diffScript(myersAlgorithm{}, a, b)); got == movedFunctionDiff {
+		t.Error("expected myers to interleave the functions")
+	}
+}
+
+func TestPatienceDiff(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"", "", ""},
+		{"a b", "", "-a -b"},
+		{"a b c", "a x c", " a -b +x  c"},
+		// No line is unique, so the region falls back to myers.
+		{"x y x y", "y x y x", "-x  y  x  y +x"},
+		// The unique lines c and a cross; the longer ordered run wins.
+		{"a b c d", "c d a b", "-a -b  c  d +a +b"},
+	}
+	for _, tt := range tests {
+		a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+		if got := formatScript(diffScript(patienceAlgorithm{}, a, b)); got != tt.want {
+			t.Errorf("%q -> %q: got %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLongestIncreasing(t *testing.T) {
+	matches := []lineMatch{{0, 4}, {1, 1}, {2, 5}, {3, 2}, {4, 3}, {5, 0}}
+	got := longestIncreasing(matches)
+	want := []lineMatch{{1, 1}, {3, 2}, {4, 3}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if longestIncreasing(nil) != nil {
+		t.Error("expected no matches for empty input")
+	}
+}
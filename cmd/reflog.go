@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+
+	"gogit/refs"
+	"gogit/repo"
+)
+
+// Reflog prints the reflog of a ref (HEAD when name is empty), newest entry
+// first, in the form "<hash> <name>@{<n>}: <message>".
+func Reflog(name string) error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = "HEAD"
+	}
+
+	refPath, err := refs.FullRefName(root, name)
+	if err != nil {
+		return err
+	}
+	if refPath == "" {
+		return fmt.Errorf("unknown ref '%s'", name)
+	}
+	entries, err := refs.ReadReflog(root, refPath)
+	if err != nil {
+		return err
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		fmt.Printf("%s %s@{%d}: %s\n", e.New[:7], name, len(entries)-1-i, e.Message)
+	}
+	return nil
+}
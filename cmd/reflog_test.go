@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gogit/refs"
+	"gogit/repo"
+)
+
+func TestReflog_RecordsCommands(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	Branch("feature")
+	Checkout("feature")
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("f"), 0644)
+	Add([]string{"f.txt"})
+	Commit("feat")
+	Checkout("main")
+	Merge("feature")
+
+	out, err := captureStdout(t, func() error { return Reflog("") })
+	if err != nil {
+		t.Fatalf("Reflog failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	want := []string{
+		"HEAD@{0}: merge feature: Fast-forward",
+		"HEAD@{1}: checkout: moving from feature to main",
+		"HEAD@{2}: commit: feat",
+		"HEAD@{3}: checkout: moving from main to feature",
+		"HEAD@{4}: commit (initial): initial commit",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d entries, got:\n%s", len(want), out)
+	}
+	for i, w := range want {
+		if !strings.HasSuffix(lines[i], w) {
+			t.Errorf("entry %d: expected %q, got %q", i, w, lines[i])
+		}
+	}
+
+	out, err = captureStdout(t, func() error { return Reflog("feature") })
+	if err != nil {
+		t.Fatalf("Reflog failed: %v", err)
+	}
+	if !strings.Contains(out, "feature@{0}: commit: feat") || !strings.Contains(out, "feature@{1}: branch: Created from HEAD") {
+		t.Errorf("unexpected feature reflog:\n%s", out)
+	}
+}
+
+func TestReflog_DetachedCommit(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	head, _ := refs.ResolveHead(dir)
+	Checkout(head)
+	os.WriteFile(filepath.Join(dir, "d.txt"), []byte("d"), 0644)
+	Add([]string{"d.txt"})
+	Commit("detached work")
+
+	entries, _ := refs.ReadReflog(dir, "HEAD")
+	last := entries[len(entries)-1]
+	if last.Old != head || last.Message != "commit: detached work" {
+		t.Errorf("unexpected detached commit entry: %+v", last)
+	}
+	if entries[len(entries)-2].Message != "checkout: moving from main to "+head {
+		t.Errorf("unexpected checkout entry: %+v", entries[len(entries)-2])
+	}
+}
+
+func TestReflog_UnknownRef(t *testing.T) {
+	setupTestRepoWithCommit(t)
+	if err := Reflog("nope"); err == nil {
+		t.Fatal("expected error for unknown ref")
+	}
+}
+
+func TestReflog_ReadError(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, repo.GogitDir, "logs", "HEAD"), []byte("garbage\n"), 0644)
+	if err := Reflog("HEAD"); err == nil {
+		t.Fatal("expected error for malformed reflog")
+	}
+}
+
+func TestReflog_NoRepo(t *testing.T) {
+	dir := t.TempDir()
+	orig, _ := os.Getwd()
+	defer os.Chdir(orig)
+	os.Chdir(dir)
+
+	if err := Reflog(""); err == nil {
+		t.Fatal("expected error when not in a repo")
+	}
+}
+
+func TestCommitReason(t *testing.T) {
+	tests := []struct {
+		parents []string
+		message string
+		want    string
+	}{
+		{nil, "first", "commit (initial): first"},
+		{[]string{"a"}, "subject\n\nbody", "commit: subject"},
+		{[]string{"a", "b"}, "Merge branch 'x'", "commit (merge): Merge branch 'x'"},
+	}
+	for _, tt := range tests {
+		if got := commitReason(tt.parents, tt.message); got != tt.want {
+			t.Errorf("commitReason(%v, %q) = %q, want %q", tt.parents, tt.message, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"gogit/config"
+	"gogit/object"
+)
+
+// lookupRenames returns the rename detection selected by setting, "true",
+// "copies" or "false", or nil when it is turned off. An empty setting
+// selects diff.renames from the configuration, which defaults to true. A
+// threshold of zero selects object.DefaultRenameThreshold.
+func lookupRenames(cfg *config.Config, setting string, threshold int) (*object.RenameOptions, error) {
+	if setting == "" {
+		setting = cfg.GetString("diff.renames", "true")
+	}
+	opts := &object.RenameOptions{Threshold: threshold}
+	if opts.Threshold == 0 {
+		opts.Threshold = object.DefaultRenameThreshold
+	}
+	switch strings.ToLower(setting) {
+	case "copies", "copy":
+		opts.Copies = true
+	default:
+		on, err := config.ParseBool(setting)
+		if err != nil {
+			return nil, fmt.Errorf("bad rename setting '%s': expected a boolean or copies", setting)
+		}
+		if !on {
+			return nil, nil
+		}
+	}
+	limit, err := cfg.GetInt("diff.renameLimit", object.DefaultRenameLimit)
+	if err != nil {
+		return nil, err
+	}
+	opts.Limit = limit
+	return opts, nil
+}
+
+// renameLabel describes a change in the status and name-status style,
+// "old -> new" for a rename or copy.
+func renameLabel(c object.Change) string {
+	if c.OldPath == "" {
+		return c.Path
+	}
+	return c.OldPath + " -> " + c.Path
+}
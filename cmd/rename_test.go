@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gogit/config"
+	"gogit/object"
+	"gogit/repo"
+)
+
+func TestLookupRenames(t *testing.T) {
+	dir := setupTestRepo(t)
+	load := func(content string) *config.Config {
+		t.Helper()
+		os.WriteFile(filepath.Join(dir, repo.GogitDir, "config"), []byte(content), 0644)
+		cfg, err := config.Load(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+
+	cfg := load("")
+	opts, err := lookupRenames(cfg, "", 0)
+	if err != nil || opts == nil || opts.Copies || opts.Threshold != object.DefaultRenameThreshold || opts.Limit != object.DefaultRenameLimit {
+		t.Errorf("expected renames on by default, got %+v, %v", opts, err)
+	}
+	if opts, _ := lookupRenames(cfg, "copies", 70); opts == nil || !opts.Copies || opts.Threshold != 70 {
+		t.Errorf("expected copies at 70%%, got %+v", opts)
+	}
+	if opts, _ := lookupRenames(cfg, "off", 0); opts != nil {
+		t.Errorf("expected detection off, got %+v", opts)
+	}
+
+	cfg = load("[diff]\n\trenames = Copies\n\trenameLimit = 5\n")
+	if opts, _ := lookupRenames(cfg, "", 0); opts == nil || !opts.Copies || opts.Limit != 5 {
+		t.Errorf("expected diff.renames and diff.renameLimit to apply, got %+v", opts)
+	}
+	if opts, _ := lookupRenames(cfg, "no", 0); opts != nil {
+		t.Errorf("expected the setting to override diff.renames, got %+v", opts)
+	}
+
+	if _, err := lookupRenames(load("[diff]\n\trenames = maybe\n"), "", 0); err == nil {
+		t.Error("expected error for a bad diff.renames")
+	}
+	if _, err := lookupRenames(load("[diff]\n\trenameLimit = lots\n"), "", 0); err == nil {
+		t.Error("expected error for a bad diff.renameLimit")
+	}
+}
+
+func TestRenameLabel(t *testing.T) {
+	if got := renameLabel(object.Change{Path: "a"}); got != "a" {
+		t.Errorf("got %q", got)
+	}
+	if got := renameLabel(object.Change{Path: "b", OldPath: "a"}); got != "a -> b" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+
+	"gogit/object"
+	"gogit/refs"
+	"gogit/repo"
+)
+
+// shortHashLen is the minimum length of abbreviated hashes in output.
+const shortHashLen = 7
+
+// RevParse prints the object name of each revision argument. Ranges print
+// their included commits followed by the excluded ones prefixed with '^'.
+// With verify, exactly one revision is accepted and ranges are rejected;
+// with short, hashes are abbreviated to a unique prefix.
+func RevParse(args []string, verify, short bool) error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+	if verify && len(args) != 1 {
+		return fmt.Errorf("needed a single revision")
+	}
+
+	format := func(hash string) (string, error) {
+		if !short {
+			return hash, nil
+		}
+		return object.UniqueAbbrev(root, hash, shortHashLen)
+	}
+
+	var lines []string
+	for _, arg := range args {
+		if !refs.IsRange(arg) {
+			hash, err := refs.ResolveRevision(root, arg)
+			if err != nil {
+				return err
+			}
+			out, err := format(hash)
+			if err != nil {
+				return err
+			}
+			lines = append(lines, out)
+			continue
+		}
+		if verify {
+			return fmt.Errorf("needed a single revision")
+		}
+		rng, err := refs.ResolveRange(root, []string{arg})
+		if err != nil {
+			return err
+		}
+		for _, h := range rng.Include {
+			out, err := format(h)
+			if err != nil {
+				return err
+			}
+			lines = append(lines, out)
+		}
+		for _, h := range rng.Exclude {
+			out, err := format(h)
+			if err != nil {
+				return err
+			}
+			lines = append(lines, "^"+out)
+		}
+	}
+
+	for _, l := range lines {
+		fmt.Println(l)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gogit/object"
+	"gogit/refs"
+)
+
+func TestRevParse_Revisions(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	first, _ := refs.ResolveHead(dir)
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b\n"), 0644)
+	Add([]string{"b.txt"})
+	Commit("second")
+	second, _ := refs.ResolveHead(dir)
+
+	out, err := captureStdout(t, func() error {
+		return RevParse([]string{"HEAD", "main~1", second[:8]}, false, false)
+	})
+	if err != nil {
+		t.Fatalf("RevParse failed: %v", err)
+	}
+	want := second + "\n" + first + "\n" + second + "\n"
+	if out != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestRevParse_TreePath(t *testing.T) {
+	setupTestRepoWithCommit(t)
+	out, err := captureStdout(t, func() error {
+		return RevParse([]string{"HEAD:test.txt"}, true, false)
+	})
+	if err != nil {
+		t.Fatalf("RevParse failed: %v", err)
+	}
+	if strings.TrimSpace(out) != object.HashBlob([]byte("hello\n")) {
+		t.Errorf("expected blob hash, got %q", out)
+	}
+}
+
+func TestRevParse_Range(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	first, _ := refs.ResolveHead(dir)
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b\n"), 0644)
+	Add([]string{"b.txt"})
+	Commit("second")
+	second, _ := refs.ResolveHead(dir)
+
+	out, err := captureStdout(t, func() error {
+		return RevParse([]string{"HEAD~1..HEAD"}, false, false)
+	})
+	if err != nil {
+		t.Fatalf("RevParse failed: %v", err)
+	}
+	if out != second+"\n^"+first+"\n" {
+		t.Errorf("unexpected range output: %q", out)
+	}
+}
+
+func TestRevParse_Short(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	head, _ := refs.ResolveHead(dir)
+	out, err := captureStdout(t, func() error {
+		return RevParse([]string{"HEAD", "^HEAD"}, false, true)
+	})
+	if err != nil {
+		t.Fatalf("RevParse failed: %v", err)
+	}
+	if out != head[:7]+"\n^"+head[:7]+"\n" {
+		t.Errorf("unexpected short output: %q", out)
+	}
+}
+
+func TestRevParse_Verify(t *testing.T) {
+	setupTestRepoWithCommit(t)
+	if err := RevParse([]string{"HEAD", "HEAD"}, true, false); err == nil {
+		t.Error("expected error verifying two revisions")
+	}
+	if err := RevParse([]string{"HEAD..HEAD"}, true, false); err == nil {
+		t.Error("expected error verifying a range")
+	}
+}
+
+func TestRevParse_Unknown(t *testing.T) {
+	setupTestRepoWithCommit(t)
+	if err := RevParse([]string{"nope"}, false, false); err == nil {
+		t.Error("expected error for unknown revision")
+	}
+	if err := RevParse([]string{"nope..HEAD"}, false, false); err == nil {
+		t.Error("expected error for unknown range endpoint")
+	}
+}
+
+func TestRevParse_NoRepo(t *testing.T) {
+	dir := t.TempDir()
+	orig, _ := os.Getwd()
+	defer os.Chdir(orig)
+	os.Chdir(dir)
+
+	if err := RevParse([]string{"HEAD"}, false, false); err == nil {
+		t.Fatal("expected error when not in a repo")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"gogit/config"
+	"gogit/ignore"
 	"gogit/index"
 	"gogit/object"
 	"gogit/refs"
@@ -27,91 +29,152 @@ func Status() error {
 		fmt.Println("HEAD detached")
 	}
 
-	idx, err := index.ReadIndex(root)
-	if err != nil {
+	// Hold the index lock if possible so refreshed stat data can be saved;
+	// when another process holds it, status still works read-only.
+	idx, lock, err := index.ReadIndexLocked(root)
+	if err == nil {
+		defer lock.Rollback()
+	} else if idx, err = index.ReadIndex(root); err != nil {
 		return err
 	}
 
-	// Get HEAD tree
-	headTree := make(map[string]string)
-	headHash, err := refs.ResolveHead(root)
+	mergeHead, err := readMergeHead(root)
 	if err != nil {
 		return err
 	}
-	if headHash != "" {
-		commit, err := object.ReadCommit(root, headHash)
-		if err != nil {
-			return err
-		}
-		headTree, err = object.FlattenTree(root, commit.TreeHash, "")
-		if err != nil {
-			return err
+	unmergedPaths := idx.Unmerged()
+	if len(unmergedPaths) > 0 {
+		fmt.Println("\nYou have unmerged paths.")
+		fmt.Println("  (fix conflicts and run \"gogit commit\")")
+		if mergeHead != "" {
+			fmt.Println("  (use \"gogit merge --abort\" to abort the merge)")
 		}
+	} else if mergeHead != "" {
+		fmt.Println("\nAll conflicts fixed but you are still merging.")
+		fmt.Println("  (use \"gogit commit\" to conclude merge)")
+	}
+	unmergedSet := make(map[string]bool)
+	var unmerged []string
+	for _, p := range unmergedPaths {
+		unmergedSet[p] = true
+		unmerged = append(unmerged, fmt.Sprintf("\t%-16s%s", unmergedLabel(idx, p)+":", p))
 	}
 
 	// Build index map
 	indexMap := make(map[string]string)
 	for _, e := range idx.Entries {
-		indexMap[e.Path] = e.Hash
+		if e.Stage == index.StageMerged {
+			indexMap[e.Path] = e.Hash
+		}
 	}
 
-	// Staged changes (HEAD vs index)
-	var staged []string
-	for path, idxHash := range indexMap {
-		headHash, inHead := headTree[path]
-		if !inHead {
-			staged = append(staged, fmt.Sprintf("\tnew file:   %s", path))
-		} else if idxHash != headHash {
-			staged = append(staged, fmt.Sprintf("\tmodified:   %s", path))
+	// Staged changes (HEAD vs index); intent-to-add paths are not staged
+	// yet and unmerged ones are listed on their own
+	treeHash, err := headTree(root)
+	if err != nil {
+		return err
+	}
+	changes, err := object.DiffTreeIndex(root, treeHash, idx, nil)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(root)
+	if err != nil {
+		return err
+	}
+	renames, err := lookupRenames(cfg, cfg.GetString("status.renames", ""), 0)
+	if err != nil {
+		return err
+	}
+	if renames != nil {
+		readBlob := func(hash string) ([]byte, error) { return object.ReadBlob(root, hash) }
+		if changes, err = object.DetectRenames(changes, readBlob, *renames); err != nil {
+			return err
 		}
 	}
-	for path := range headTree {
-		if _, inIndex := indexMap[path]; !inIndex {
-			staged = append(staged, fmt.Sprintf("\tdeleted:    %s", path))
+	var staged []string
+	for _, c := range changes {
+		switch {
+		case c.Unmerged:
+		case c.OldPath != "" && c.Copied:
+			staged = append(staged, fmt.Sprintf("\tcopied:     %s", renameLabel(c)))
+		case c.OldPath != "":
+			staged = append(staged, fmt.Sprintf("\trenamed:    %s", renameLabel(c)))
+		case c.OldHash == "":
+			staged = append(staged, fmt.Sprintf("\tnew file:   %s", c.Path))
+		case c.NewHash == "":
+			staged = append(staged, fmt.Sprintf("\tdeleted:    %s", c.Path))
+		default:
+			staged = append(staged, fmt.Sprintf("\tmodified:   %s", c.Path))
 		}
 	}
 
 	// Unstaged changes (index vs working tree)
 	var unstaged []string
-	for _, e := range idx.Entries {
-		absPath := filepath.Join(root, e.Path)
-		info, err := os.Stat(absPath)
-		if err != nil {
-			if os.IsNotExist(err) {
-				unstaged = append(unstaged, fmt.Sprintf("\tdeleted:    %s", e.Path))
-			}
+	refreshed := false
+	for i := range idx.Entries {
+		e := &idx.Entries[i]
+		if e.Stage != index.StageMerged {
 			continue
 		}
-		content, err := os.ReadFile(absPath)
+		state, updated, err := checkWorktree(root, idx, e)
 		if err != nil {
 			continue
 		}
-		currentHash := object.HashBlob(content)
-		if currentHash != e.Hash {
-			_ = info
+		refreshed = refreshed || updated
+		switch {
+		case state == worktreeDeleted:
+			unstaged = append(unstaged, fmt.Sprintf("\tdeleted:    %s", e.Path))
+		case e.IntentToAdd():
+			unstaged = append(unstaged, fmt.Sprintf("\tnew file:   %s", e.Path))
+		case state == worktreeModified:
 			unstaged = append(unstaged, fmt.Sprintf("\tmodified:   %s", e.Path))
 		}
 	}
+	if refreshed && lock != nil {
+		// Saving the refreshed stat data is only an optimization; like git,
+		// status still succeeds when the index cannot be written.
+		lock.Commit(idx)
+	}
 
-	// Untracked files
+	// Untracked files, leaving out those matched by ignore rules
+	matcher, err := ignore.New(root)
+	if err != nil {
+		return err
+	}
 	var untracked []string
-	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil
 		}
+		if info.IsDir() && info.Name() == repo.GogitDir {
+			return filepath.SkipDir
+		}
+		relPath, _ := filepath.Rel(root, path)
+		relPath = filepath.ToSlash(relPath)
+		if _, inIndex := indexMap[relPath]; inIndex || unmergedSet[relPath] {
+			return nil
+		}
+		ignored, err := matcher.Ignored(relPath, info.IsDir())
+		if err != nil {
+			return err
+		}
 		if info.IsDir() {
-			if info.Name() == repo.GogitDir {
+			// Tracked files below an ignored directory are still
+			// compared above; only untracked ones are hidden.
+			if ignored {
 				return filepath.SkipDir
 			}
 			return nil
 		}
-		relPath, _ := filepath.Rel(root, path)
-		relPath = filepath.ToSlash(relPath)
-		if _, inIndex := indexMap[relPath]; !inIndex {
+		if !ignored {
 			untracked = append(untracked, fmt.Sprintf("\t%s", relPath))
 		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
 
 	if len(staged) > 0 {
 		fmt.Println("\nChanges to be committed:")
@@ -120,6 +183,14 @@ func Status() error {
 		}
 	}
 
+	if len(unmerged) > 0 {
+		fmt.Println("\nUnmerged paths:")
+		fmt.Println("  (use \"gogit add <file>...\" to mark resolution)")
+		for _, s := range unmerged {
+			fmt.Println(s)
+		}
+	}
+
 	if len(unstaged) > 0 {
 		fmt.Println("\nChanges not staged for commit:")
 		for _, s := range unstaged {
@@ -134,9 +205,31 @@ func Status() error {
 		}
 	}
 
-	if len(staged) == 0 && len(unstaged) == 0 && len(untracked) == 0 {
+	if len(staged) == 0 && len(unmerged) == 0 && len(unstaged) == 0 && len(untracked) == 0 {
 		fmt.Println("nothing to commit, working tree clean")
 	}
 
 	return nil
 }
+
+// unmergedLabel describes how a conflicted path differs between the sides,
+// based on which conflict stages are present.
+func unmergedLabel(idx *index.Index, path string) string {
+	base, ours, theirs := idx.ConflictStages(path)
+	switch {
+	case ours != nil && theirs != nil && base != nil:
+		return "both modified"
+	case ours != nil && theirs != nil:
+		return "both added"
+	case ours != nil && base != nil:
+		return "deleted by them"
+	case theirs != nil && base != nil:
+		return "deleted by us"
+	case ours != nil:
+		return "added by us"
+	case theirs != nil:
+		return "added by them"
+	default:
+		return "both deleted"
+	}
+}
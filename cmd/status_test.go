@@ -3,8 +3,11 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"gogit/index"
 	"gogit/object"
 	"gogit/refs"
 	"gogit/repo"
@@ -88,7 +91,7 @@ func TestStatus_NoCommitsYet(t *testing.T) {
 func TestStatus_DetachedHead(t *testing.T) {
 	dir := setupTestRepoWithCommit(t)
 	hash, _ := refs.ResolveHead(dir)
-	refs.UpdateHead(dir, hash)
+	refs.UpdateHead(dir, hash, "test")
 
 	if err := Status(); err != nil {
 		t.Fatalf("Status failed: %v", err)
@@ -127,7 +130,7 @@ func TestStatus_CorruptIndex(t *testing.T) {
 func TestStatus_BadHeadRef(t *testing.T) {
 	dir := setupTestRepo(t)
 	// Write a HEAD pointing to a ref, then write a bad hash in that ref
-	refs.WriteRef(dir, "refs/heads/main", "0000000000000000000000000000000000000000")
+	refs.WriteRef(dir, "refs/heads/main", "0000000000000000000000000000000000000000", "test")
 
 	err := Status()
 	if err == nil {
@@ -163,7 +166,7 @@ func TestStatus_ResolveHeadError(t *testing.T) {
 func TestStatus_BadHeadCommit(t *testing.T) {
 	dir := setupTestRepoWithCommit(t)
 	// Point HEAD to a non-existent commit
-	refs.WriteRef(dir, "refs/heads/main", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	refs.WriteRef(dir, "refs/heads/main", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", "test")
 
 	err := Status()
 	if err == nil {
@@ -225,3 +228,151 @@ func TestStatus_WalkError(t *testing.T) {
 		t.Fatalf("Status should handle walk error: %v", err)
 	}
 }
+
+func TestStatus_IgnoredFiles(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, ".gogitignore"), []byte("*.log\nbuild/\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "debug.log"), []byte("x"), 0644)
+	os.MkdirAll(filepath.Join(dir, "build", "obj"), 0755)
+	os.WriteFile(filepath.Join(dir, "build", "obj", "a.o"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644)
+
+	out, err := captureStdout(t, Status)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if !strings.Contains(out, "\tnotes.txt\n") || !strings.Contains(out, "\t.gogitignore\n") {
+		t.Errorf("expected untracked files to be listed, got:\n%s", out)
+	}
+	if strings.Contains(out, "debug.log") || strings.Contains(out, "a.o") {
+		t.Errorf("ignored files should not be listed, got:\n%s", out)
+	}
+}
+
+func TestStatus_TrackedFileMatchingIgnore(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, ".gogitignore"), []byte("test.txt\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("changed\n"), 0644)
+
+	out, err := captureStdout(t, Status)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if !strings.Contains(out, "modified:   test.txt") {
+		t.Errorf("tracked files should still be compared, got:\n%s", out)
+	}
+}
+
+func TestStatus_IgnoreFileError(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.MkdirAll(filepath.Join(dir, ".gogit", "info", "exclude"), 0755)
+
+	if err := Status(); err == nil {
+		t.Error("expected error when info/exclude is unreadable")
+	}
+
+	os.RemoveAll(filepath.Join(dir, ".gogit", "info"))
+	os.MkdirAll(filepath.Join(dir, "sub", ".gogitignore"), 0755)
+	os.WriteFile(filepath.Join(dir, "sub", "f"), []byte("x"), 0644)
+	if err := Status(); err == nil {
+		t.Error("expected error when a nested ignore file is unreadable")
+	}
+}
+
+func TestStatus_RefreshesStatData(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	path := filepath.Join(dir, "test.txt")
+	touched := time.Now().Add(-time.Minute)
+	os.Chtimes(path, touched, touched)
+
+	out, err := captureStdout(t, Status)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if !strings.Contains(out, "nothing to commit") {
+		t.Errorf("touched file should not be reported, got:\n%s", out)
+	}
+	idx, _ := index.ReadIndex(dir)
+	if e := idx.LookupEntry("test.txt"); e.Mtime != touched.Unix() {
+		t.Errorf("expected stat data refreshed to %d, got %d", touched.Unix(), e.Mtime)
+	}
+}
+
+func TestStatus_TrustsStatData(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	backdate(t, filepath.Join(dir, "test.txt"))
+	Add([]string{"test.txt"})
+	stageWithHash(t, dir)
+
+	out, err := captureStdout(t, Status)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if strings.Contains(out, "Changes not staged") {
+		t.Errorf("file with matching stat data should not be read, got:\n%s", out)
+	}
+}
+
+func TestStatus_IndexLocked(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, ".gogit", "index.lock"), nil, 0644)
+	touched := time.Now().Add(-time.Minute)
+	os.Chtimes(filepath.Join(dir, "test.txt"), touched, touched)
+
+	out, err := captureStdout(t, Status)
+	if err != nil {
+		t.Fatalf("Status should work while the index is locked: %v", err)
+	}
+	if !strings.Contains(out, "nothing to commit") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	idx, _ := index.ReadIndex(dir)
+	if idx.LookupEntry("test.txt").Mtime == touched.Unix() {
+		t.Error("stat data should not be saved while the index is locked")
+	}
+}
+
+func TestStatus_StagedRename(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.Rename(filepath.Join(dir, "test.txt"), filepath.Join(dir, "moved.txt"))
+	Add([]string{"test.txt", "moved.txt"})
+
+	out, err := captureStdout(t, Status)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if !strings.Contains(out, "\trenamed:    test.txt -> moved.txt\n") {
+		t.Errorf("expected a rename, got:\n%s", out)
+	}
+	if strings.Contains(out, "deleted:") || strings.Contains(out, "new file:") {
+		t.Errorf("rename should not show as a delete and an add, got:\n%s", out)
+	}
+
+	// status.renames takes precedence over diff.renames.
+	config := filepath.Join(dir, ".gogit", "config")
+	os.WriteFile(config, []byte("[diff]\n\trenames = copies\n[status]\n\trenames = false\n"), 0644)
+	out, _ = captureStdout(t, Status)
+	if !strings.Contains(out, "\tdeleted:    test.txt\n") || !strings.Contains(out, "\tnew file:   moved.txt\n") {
+		t.Errorf("expected rename detection to be off, got:\n%s", out)
+	}
+	os.WriteFile(config, []byte("[diff]\n\trenames = maybe\n"), 0644)
+	if err := Status(); err == nil {
+		t.Error("expected error for a bad rename setting")
+	}
+}
+
+func TestStatus_StagedCopy(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, ".gogit", "config"), []byte("[status]\n\trenames = copies\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("hello\nagain\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "copy.txt"), []byte("hello\n"), 0644)
+	Add([]string{"test.txt", "copy.txt"})
+
+	out, err := captureStdout(t, Status)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if !strings.Contains(out, "\tcopied:     test.txt -> copy.txt\n") || !strings.Contains(out, "\tmodified:   test.txt\n") {
+		t.Errorf("expected a copy of the modified file, got:\n%s", out)
+	}
+}
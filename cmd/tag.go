@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"gogit/object"
+	"gogit/refs"
+	"gogit/repo"
+)
+
+// TagCreate tags the object named by target (HEAD when empty). With a
+// message an annotated tag object is written and the tag points at it;
+// otherwise the tag is lightweight and points at the object directly. An
+// existing tag is only replaced when force is set.
+func TagCreate(name, target, message string, force bool) error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+	if err := refs.ValidateTagName(name); err != nil {
+		return err
+	}
+
+	refPath := refs.TagRef(name)
+	if err := refs.CheckRefConflict(root, refPath); err != nil {
+		return err
+	}
+	existing, err := refs.ReadRef(root, refPath)
+	if err != nil {
+		return err
+	}
+	if existing != "" && !force {
+		return fmt.Errorf("tag '%s' already exists", name)
+	}
+
+	if target == "" {
+		target = "HEAD"
+	}
+	hash, err := refs.ResolveRevision(root, target)
+	if err != nil {
+		return fmt.Errorf("failed to resolve '%s' as a valid ref: %v", target, err)
+	}
+
+	if message != "" {
+		objType, _, err := object.ReadObject(root, hash)
+		if err != nil {
+			return err
+		}
+		if hash, err = object.WriteTag(root, hash, objType, name, message); err != nil {
+			return err
+		}
+	}
+
+	expectedOld := existing
+	if expectedOld == "" {
+		expectedOld = refs.ZeroHash
+	}
+	if err := refs.UpdateRef(root, refPath, hash, expectedOld, "tag: tagging "+target); err != nil {
+		return err
+	}
+
+	if existing != "" && existing != hash {
+		fmt.Printf("Updated tag '%s' (was %s)\n", name, existing[:7])
+	}
+	return nil
+}
+
+// TagList prints the names of all tags, or of those matching a shell glob
+// pattern when it is not empty.
+func TagList(pattern string) error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+	tags, err := refs.ListTags(root)
+	if err != nil {
+		return err
+	}
+	for _, name := range tags {
+		if pattern != "" {
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				return fmt.Errorf("invalid pattern '%s': %v", pattern, err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		fmt.Println(name)
+	}
+	return nil
+}
+
+// TagDelete deletes tags. The tag objects of annotated tags stay in the
+// object store. A tag that cannot be deleted does not stop the others; the
+// returned error joins the reasons of every one that failed.
+func TagDelete(names []string) error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, name := range names {
+		if err := deleteTag(root, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deleteTag deletes one tag for TagDelete.
+func deleteTag(root, name string) error {
+	if err := refs.ValidateTagName(name); err != nil {
+		return err
+	}
+	refPath := refs.TagRef(name)
+	hash, err := refs.ReadRef(root, refPath)
+	if err != nil {
+		return err
+	}
+	if hash == "" {
+		return fmt.Errorf("tag '%s' not found", name)
+	}
+	if err := refs.DeleteRef(root, refPath, hash, "tag: deleted"); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted tag '%s' (was %s)\n", name, hash[:7])
+	return nil
+}
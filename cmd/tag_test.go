@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gogit/object"
+	"gogit/refs"
+)
+
+func TestTagCreate_Lightweight(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	head, _ := refs.ResolveHead(dir)
+
+	if err := TagCreate("v1.0", "", "", false); err != nil {
+		t.Fatalf("TagCreate failed: %v", err)
+	}
+	hash, _ := refs.ReadRef(dir, "refs/tags/v1.0")
+	if hash != head {
+		t.Errorf("lightweight tag should point at HEAD, got %s", hash)
+	}
+	if _, err := os.Stat(refs.ReflogPath(dir, "refs/tags/v1.0")); !os.IsNotExist(err) {
+		t.Error("tags should not have a reflog")
+	}
+}
+
+func TestTagCreate_Annotated(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	head, _ := refs.ResolveHead(dir)
+
+	if err := TagCreate("v1.0", "main", "first release", false); err != nil {
+		t.Fatalf("TagCreate failed: %v", err)
+	}
+	hash, _ := refs.ReadRef(dir, "refs/tags/v1.0")
+	tag, err := object.ReadTag(dir, hash)
+	if err != nil {
+		t.Fatalf("tag ref should point at a tag object: %v", err)
+	}
+	if tag.Object != head || tag.Type != "commit" || tag.Name != "v1.0" || tag.Message != "first release" {
+		t.Errorf("unexpected tag: %+v", tag)
+	}
+	if !strings.HasPrefix(tag.Tagger, "Test <test@test.com> ") {
+		t.Errorf("unexpected tagger: %q", tag.Tagger)
+	}
+
+	peeled, err := refs.ResolveCommit(dir, "v1.0")
+	if err != nil || peeled != head {
+		t.Errorf("tag should peel to HEAD, got %s, %v", peeled, err)
+	}
+}
+
+func TestTagCreate_Exists(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	TagCreate("v1.0", "", "", false)
+	first, _ := refs.ReadRef(dir, "refs/tags/v1.0")
+
+	err := TagCreate("v1.0", "", "", false)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected already exists error, got %v", err)
+	}
+
+	out, err := captureStdout(t, func() error { return TagCreate("v1.0", "", "moved", true) })
+	if err != nil {
+		t.Fatalf("forced TagCreate failed: %v", err)
+	}
+	if out != "Updated tag 'v1.0' (was "+first[:7]+")\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+	if hash, _ := refs.ReadRef(dir, "refs/tags/v1.0"); hash == first {
+		t.Error("forced tag should point at the new tag object")
+	}
+}
+
+func TestTagCreate_Errors(t *testing.T) {
+	setupTestRepoWithCommit(t)
+
+	if err := TagCreate("bad..name", "", "", false); err == nil || !strings.Contains(err.Error(), "not a valid tag name") {
+		t.Errorf("expected invalid name error, got %v", err)
+	}
+	if err := TagCreate("v1", "nope", "", false); err == nil || !strings.Contains(err.Error(), "failed to resolve") {
+		t.Errorf("expected bad revision error, got %v", err)
+	}
+	TagCreate("rel", "", "", false)
+	if err := TagCreate("rel/1", "", "", false); err == nil || !strings.Contains(err.Error(), "'refs/tags/rel' exists") {
+		t.Errorf("expected directory/file conflict, got %v", err)
+	}
+}
+
+func TestTagCreate_NoCommits(t *testing.T) {
+	setupTestRepo(t)
+
+	if err := TagCreate("v1", "", "", false); err == nil {
+		t.Error("expected error tagging an unborn HEAD")
+	}
+}
+
+func TestTagList(t *testing.T) {
+	setupTestRepoWithCommit(t)
+	TagCreate("v2.0", "", "", false)
+	TagCreate("v1.0", "", "annotated", false)
+	TagCreate("beta", "", "", false)
+
+	out, err := captureStdout(t, func() error { return TagList("") })
+	if err != nil {
+		t.Fatalf("TagList failed: %v", err)
+	}
+	if out != "beta\nv1.0\nv2.0\n" {
+		t.Errorf("unexpected listing: %q", out)
+	}
+
+	out, err = captureStdout(t, func() error { return TagList("v*") })
+	if err != nil {
+		t.Fatalf("TagList failed: %v", err)
+	}
+	if out != "v1.0\nv2.0\n" {
+		t.Errorf("unexpected filtered listing: %q", out)
+	}
+
+	if _, err := captureStdout(t, func() error { return TagList("[") }); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+}
+
+func TestTagDelete(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	TagCreate("v1.0", "", "annotated", false)
+	hash, _ := refs.ReadRef(dir, "refs/tags/v1.0")
+
+	out, err := captureStdout(t, func() error { return TagDelete([]string{"v1.0"}) })
+	if err != nil {
+		t.Fatalf("TagDelete failed: %v", err)
+	}
+	if out != "Deleted tag 'v1.0' (was "+hash[:7]+")\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+	if got, _ := refs.ReadRef(dir, "refs/tags/v1.0"); got != "" {
+		t.Error("tag should be deleted")
+	}
+	if !object.HasObject(dir, hash) {
+		t.Error("tag object should be kept")
+	}
+
+	if err := TagDelete([]string{"v1.0"}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestTagDelete_InvalidNamesAndFailures(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	Branch("feat")
+	TagCreate("a", "", "", false)
+	TagCreate("b", "", "", false)
+
+	out, err := captureStdout(t, func() error {
+		return TagDelete([]string{"a", "../heads/feat", "missing", "b"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "'../heads/feat' is not a valid tag name") || !strings.Contains(err.Error(), "tag 'missing' not found") {
+		t.Fatalf("expected both failures to be reported, got %v", err)
+	}
+	if !strings.Contains(out, "Deleted tag 'a'") || !strings.Contains(out, "Deleted tag 'b'") {
+		t.Errorf("expected the other tags to be deleted, got %q", out)
+	}
+	if hash, _ := refs.ReadRef(dir, "refs/heads/feat"); hash == "" {
+		t.Error("branch feat should be intact")
+	}
+}
+
+func TestTag_NoRepo(t *testing.T) {
+	dir := t.TempDir()
+	orig, _ := os.Getwd()
+	defer os.Chdir(orig)
+	os.Chdir(dir)
+
+	if err := TagCreate("v1", "", "", false); err == nil {
+		t.Error("TagCreate: expected error when not in a repo")
+	}
+	if err := TagList(""); err == nil {
+		t.Error("TagList: expected error when not in a repo")
+	}
+	if err := TagDelete([]string{"v1"}); err == nil {
+		t.Error("TagDelete: expected error when not in a repo")
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package cmd
+
+import "os"
+
+// terminalWidth cannot ask the terminal for its size on this platform, so
+// output is fitted to COLUMNS or the default width.
+func terminalWidth(f *os.File) int {
+	return 0
+}
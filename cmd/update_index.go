@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gogit/index"
+	"gogit/object"
+	"gogit/repo"
+)
+
+// UpdateIndexOptions describes the changes UpdateIndex makes. The flags
+// in Set are turned on and those in Clear turned off for every path; a
+// non-zero Version switches the on-disk index format.
+type UpdateIndexOptions struct {
+	Set     uint16
+	Clear   uint16
+	Version uint32
+}
+
+// UpdateIndex changes the flags of index entries. Setting intent-to-add
+// records an untracked file with no content staged yet, and clearing it
+// drops such an entry again; tracked files are left alone. The other flags
+// require the path to be tracked.
+func UpdateIndex(paths []string, opts UpdateIndexOptions) error {
+	root, err := repo.Find()
+	if err != nil {
+		return err
+	}
+	idx, lock, err := index.ReadIndexLocked(root)
+	if err != nil {
+		return err
+	}
+	defer lock.Rollback()
+
+	if opts.Version != 0 {
+		if opts.Version != index.IndexVersion1 && opts.Version != index.IndexVersion2 {
+			return fmt.Errorf("index-version %d not in range: %d..%d", opts.Version, index.IndexVersion1, index.IndexVersion2)
+		}
+		idx.Version = opts.Version
+	}
+
+	for _, p := range paths {
+		relPath, err := repoPath(root, p)
+		if err != nil {
+			return err
+		}
+		e := idx.LookupEntry(relPath)
+		if e == nil && opts.Set&index.FlagIntentToAdd != 0 {
+			if err := addIntentToAdd(root, idx, relPath); err != nil {
+				return err
+			}
+			e = idx.LookupEntry(relPath)
+		}
+		if e == nil {
+			return fmt.Errorf("unable to mark file %s", p)
+		}
+		if opts.Clear&index.FlagIntentToAdd != 0 && e.IntentToAdd() {
+			idx.RemoveEntry(relPath)
+			continue
+		}
+		e.Flags = e.Flags&^opts.Clear | opts.Set&^index.FlagIntentToAdd
+	}
+
+	return lock.Commit(idx)
+}
+
+// addIntentToAdd records an intent-to-add entry for the file at relPath,
+// holding the empty blob.
+func addIntentToAdd(root string, idx *index.Index, relPath string) error {
+	info, err := os.Stat(filepath.Join(root, relPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s: does not exist", relPath)
+		}
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s: is a directory", relPath)
+	}
+	hash, err := object.WriteBlob(root, nil)
+	if err != nil {
+		return err
+	}
+	mode := uint32(0100644)
+	if info.Mode()&0111 != 0 {
+		mode = 0100755
+	}
+	idx.AddEntry(index.Entry{Hash: hash, Mode: mode, Flags: index.FlagIntentToAdd, Path: relPath})
+	return nil
+}
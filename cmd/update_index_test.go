@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gogit/index"
+	"gogit/object"
+)
+
+func TestUpdateIndex_AssumeUnchanged(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	if err := UpdateIndex([]string{"test.txt"}, UpdateIndexOptions{Set: index.FlagAssumeUnchanged}); err != nil {
+		t.Fatalf("UpdateIndex failed: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("changed\n"), 0644)
+
+	out, _ := captureStdout(t, Status)
+	if !strings.Contains(out, "nothing to commit") {
+		t.Errorf("assume-unchanged file should not be reported, got:\n%s", out)
+	}
+
+	UpdateIndex([]string{"test.txt"}, UpdateIndexOptions{Clear: index.FlagAssumeUnchanged})
+	out, _ = captureStdout(t, Status)
+	if !strings.Contains(out, "modified:   test.txt") {
+		t.Errorf("expected modification after clearing the flag, got:\n%s", out)
+	}
+}
+
+func TestUpdateIndex_SkipWorktree(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	UpdateIndex([]string{"test.txt"}, UpdateIndexOptions{Set: index.FlagSkipWorktree})
+	os.Remove(filepath.Join(dir, "test.txt"))
+
+	out, _ := captureStdout(t, Status)
+	if !strings.Contains(out, "nothing to commit") {
+		t.Errorf("skip-worktree file should not be reported deleted, got:\n%s", out)
+	}
+	idx, _ := index.ReadIndex(dir)
+	if idx.LookupEntry("test.txt").Flags != index.FlagSkipWorktree {
+		t.Errorf("expected skip-worktree flag, got %d", idx.LookupEntry("test.txt").Flags)
+	}
+}
+
+func TestUpdateIndex_IntentToAdd(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new\n"), 0644)
+	if err := UpdateIndex([]string{"new.txt", "test.txt"}, UpdateIndexOptions{Set: index.FlagIntentToAdd}); err != nil {
+		t.Fatalf("UpdateIndex failed: %v", err)
+	}
+
+	idx, _ := index.ReadIndex(dir)
+	e := idx.LookupEntry("new.txt")
+	if e == nil || !e.IntentToAdd() || e.Hash != object.HashBlob(nil) {
+		t.Fatalf("expected intent-to-add entry with the empty blob, got %+v", e)
+	}
+	if idx.LookupEntry("test.txt").Flags != 0 {
+		t.Error("tracked file should not become intent-to-add")
+	}
+
+	out, _ := captureStdout(t, Status)
+	if strings.Contains(out, "Changes to be committed") || strings.Contains(out, "Untracked files") {
+		t.Errorf("intent-to-add file is neither staged nor untracked, got:\n%s", out)
+	}
+	if !strings.Contains(out, "Changes not staged for commit:\n\tnew file:   new.txt") {
+		t.Errorf("expected unstaged new file, got:\n%s", out)
+	}
+
+	out, _ = captureStdout(t, func() error { return Diff(DiffOptions{}) })
+	if !strings.Contains(out, "+++ b/new.txt") || !strings.Contains(out, "+new") {
+		t.Errorf("expected diff to show the whole file, got:\n%s", out)
+	}
+
+	// The file is left out of commits until it is added.
+	Commit("without new.txt")
+	idx, _ = index.ReadIndex(dir)
+	tree, _ := object.BuildTreeFromIndex(dir, idx)
+	if files, _ := object.FlattenTree(dir, tree, ""); len(files) != 1 {
+		t.Errorf("expected only test.txt in the tree, got %v", files)
+	}
+
+	if err := UpdateIndex([]string{"new.txt"}, UpdateIndexOptions{Clear: index.FlagIntentToAdd}); err != nil {
+		t.Fatalf("UpdateIndex failed: %v", err)
+	}
+	idx, _ = index.ReadIndex(dir)
+	if idx.LookupEntry("new.txt") != nil {
+		t.Error("clearing intent-to-add should drop the entry")
+	}
+}
+
+func TestUpdateIndex_IntentToAddDeleted(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new\n"), 0644)
+	UpdateIndex([]string{"new.txt"}, UpdateIndexOptions{Set: index.FlagIntentToAdd})
+	os.Remove(filepath.Join(dir, "new.txt"))
+
+	out, _ := captureStdout(t, func() error { return Diff(DiffOptions{}) })
+	if out != "" {
+		t.Errorf("expected no diff for a vanished intent-to-add file, got:\n%s", out)
+	}
+}
+
+func TestUpdateIndex_Version(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	if err := UpdateIndex(nil, UpdateIndexOptions{Version: index.IndexVersion1}); err != nil {
+		t.Fatalf("UpdateIndex failed: %v", err)
+	}
+	idx, _ := index.ReadIndex(dir)
+	if idx.Version != index.IndexVersion1 {
+		t.Errorf("expected version 1, got %d", idx.Version)
+	}
+
+	// Later writes keep the version.
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b\n"), 0644)
+	Add([]string{"b.txt"})
+	idx, _ = index.ReadIndex(dir)
+	if idx.Version != index.IndexVersion1 || len(idx.Entries) != 2 {
+		t.Errorf("expected version 1 with 2 entries, got %d with %d", idx.Version, len(idx.Entries))
+	}
+
+	if err := UpdateIndex(nil, UpdateIndexOptions{Version: 3}); err == nil {
+		t.Error("expected error for unsupported version")
+	}
+}
+
+func TestUpdateIndex_Errors(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	if err := UpdateIndex([]string{"missing.txt"}, UpdateIndexOptions{Set: index.FlagAssumeUnchanged}); err == nil {
+		t.Error("expected error for untracked path")
+	}
+	if err := UpdateIndex([]string{"missing.txt"}, UpdateIndexOptions{Set: index.FlagIntentToAdd}); err == nil {
+		t.Error("expected error for missing file")
+	}
+	os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+	if err := UpdateIndex([]string{"sub"}, UpdateIndexOptions{Set: index.FlagIntentToAdd}); err == nil {
+		t.Error("expected error for directory")
+	}
+	if err := UpdateIndex([]string{"../x"}, UpdateIndexOptions{Set: index.FlagSkipWorktree}); err == nil {
+		t.Error("expected error for path outside the repository")
+	}
+
+	os.WriteFile(filepath.Join(dir, ".gogit", "index.lock"), nil, 0644)
+	if err := UpdateIndex([]string{"test.txt"}, UpdateIndexOptions{Set: index.FlagSkipWorktree}); err == nil {
+		t.Error("expected error while the index is locked")
+	}
+}
+
+func TestUpdateIndex_CorruptIndex(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, ".gogit", "index"), []byte("garbage"), 0644)
+	if err := UpdateIndex([]string{"test.txt"}, UpdateIndexOptions{}); err == nil {
+		t.Error("expected error for corrupt index")
+	}
+}
+
+func TestUpdateIndex_NoRepo(t *testing.T) {
+	dir := t.TempDir()
+	orig, _ := os.Getwd()
+	defer os.Chdir(orig)
+	os.Chdir(dir)
+
+	if err := UpdateIndex([]string{"x"}, UpdateIndexOptions{}); err == nil {
+		t.Error("expected error when not in a repo")
+	}
+}
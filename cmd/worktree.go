@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gogit/index"
+	"gogit/object"
+)
+
+// worktreeState describes how a working tree file compares with its index
+// entry.
+type worktreeState int
+
+const (
+	worktreeUnchanged worktreeState = iota
+	worktreeModified
+	worktreeDeleted
+)
+
+// checkWorktree compares the working tree file of a stage 0 entry with it.
+// Entries marked assume-unchanged or skip-worktree are reported unchanged
+// without looking at the file. Otherwise the file is only read when its
+// stat data differs from the entry or the entry is racily clean. A file
+// that gained or lost its executable bit is modified even when its content
+// is not. A file whose content turns out unchanged gets its stat data
+// refreshed in e, and refreshed reports that the index should be written
+// back so the next check can skip it.
+func checkWorktree(root string, idx *index.Index, e *index.Entry) (state worktreeState, refreshed bool, err error) {
+	if e.Flags&(index.FlagAssumeUnchanged|index.FlagSkipWorktree) != 0 {
+		return worktreeUnchanged, false, nil
+	}
+	absPath := filepath.Join(root, e.Path)
+	info, err := os.Stat(absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return worktreeDeleted, false, nil
+		}
+		return worktreeUnchanged, false, err
+	}
+	if idx.UpToDate(e, info) {
+		return worktreeUnchanged, false, nil
+	}
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return worktreeUnchanged, false, err
+	}
+	if object.HashBlob(content) != e.Hash || (e.Mode&0111 != 0) != (info.Mode()&0111 != 0) {
+		return worktreeModified, false, nil
+	}
+	if e.StatMatches(info) {
+		return worktreeUnchanged, false, nil
+	}
+	e.SetStat(info)
+	return worktreeUnchanged, true, nil
+}
+
+// repoPath converts a path given on the command line, relative to the
+// current directory, to a slash-separated path relative to root.
+func repoPath(root, p string) (string, error) {
+	absPath, err := absFunc(p)
+	if err != nil {
+		return "", err
+	}
+	relPath, err := relFunc(root, absPath)
+	if err != nil {
+		return "", err
+	}
+	relPath = filepath.ToSlash(relPath)
+	if relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return "", fmt.Errorf("%s: '%s' is outside repository", p, relPath)
+	}
+	return relPath, nil
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gogit/index"
+	"gogit/object"
+)
+
+// backdate sets a file's times an hour into the past, so that its index
+// entry is not racily clean once the index is written.
+func backdate(t *testing.T, path string) {
+	t.Helper()
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// stageWithHash stages test.txt in the index under a hash that does not
+// match its content, keeping its stat data, so only a content check can
+// notice the difference.
+func stageWithHash(t *testing.T, dir string) {
+	t.Helper()
+	idx, _ := index.ReadIndex(dir)
+	idx.LookupEntry("test.txt").Hash = object.HashBlob([]byte("something else\n"))
+	if err := index.WriteIndex(dir, idx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckWorktree(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	path := filepath.Join(dir, "test.txt")
+	backdate(t, path)
+	Add([]string{"test.txt"})
+
+	idx, _ := index.ReadIndex(dir)
+	e := idx.LookupEntry("test.txt")
+	state, refreshed, err := checkWorktree(dir, idx, e)
+	if err != nil || state != worktreeUnchanged || refreshed {
+		t.Errorf("expected unchanged, got %v %v %v", state, refreshed, err)
+	}
+
+	os.WriteFile(path, []byte("changed\n"), 0644)
+	if state, _, _ := checkWorktree(dir, idx, e); state != worktreeModified {
+		t.Errorf("expected modified, got %v", state)
+	}
+
+	os.Remove(path)
+	if state, _, _ := checkWorktree(dir, idx, e); state != worktreeDeleted {
+		t.Errorf("expected deleted, got %v", state)
+	}
+}
+
+func TestCheckWorktree_TrustsStatData(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	backdate(t, filepath.Join(dir, "test.txt"))
+	Add([]string{"test.txt"})
+	stageWithHash(t, dir)
+
+	idx, _ := index.ReadIndex(dir)
+	state, _, err := checkWorktree(dir, idx, idx.LookupEntry("test.txt"))
+	if err != nil || state != worktreeUnchanged {
+		t.Errorf("matching stat data should be trusted, got %v, %v", state, err)
+	}
+}
+
+func TestCheckWorktree_RacilyClean(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	// A file modified after the index was written is racy: its stat data
+	// may match even though the content changed.
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(dir, "test.txt"), future, future)
+	Add([]string{"test.txt"})
+	stageWithHash(t, dir)
+
+	idx, _ := index.ReadIndex(dir)
+	state, _, err := checkWorktree(dir, idx, idx.LookupEntry("test.txt"))
+	if err != nil || state != worktreeModified {
+		t.Errorf("racy entry should be compared by content, got %v, %v", state, err)
+	}
+}
+
+func TestCheckWorktree_Refresh(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	path := filepath.Join(dir, "test.txt")
+	backdate(t, path)
+	Add([]string{"test.txt"})
+	touched := time.Now().Add(-time.Minute)
+	os.Chtimes(path, touched, touched)
+
+	idx, _ := index.ReadIndex(dir)
+	e := idx.LookupEntry("test.txt")
+	state, refreshed, err := checkWorktree(dir, idx, e)
+	if err != nil || state != worktreeUnchanged || !refreshed {
+		t.Errorf("expected refreshed unchanged entry, got %v %v %v", state, refreshed, err)
+	}
+	if e.Mtime != touched.Unix() {
+		t.Errorf("expected refreshed mtime %d, got %d", touched.Unix(), e.Mtime)
+	}
+}
+
+func TestCheckWorktree_ModeChange(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	path := filepath.Join(dir, "test.txt")
+	os.Chmod(path, 0755)
+
+	idx, _ := index.ReadIndex(dir)
+	state, refreshed, err := checkWorktree(dir, idx, idx.LookupEntry("test.txt"))
+	if err != nil || state != worktreeModified || refreshed {
+		t.Errorf("expected an executable bit change to be modified, got %v %v %v", state, refreshed, err)
+	}
+}
+
+func TestCheckWorktree_Errors(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	idx, _ := index.ReadIndex(dir)
+	e := idx.LookupEntry("test.txt")
+
+	os.Chmod(filepath.Join(dir, "test.txt"), 0000)
+	defer os.Chmod(filepath.Join(dir, "test.txt"), 0644)
+	e.Size = 0
+	if _, _, err := checkWorktree(dir, idx, e); err == nil {
+		t.Error("expected error for unreadable file")
+	}
+
+	os.Chmod(dir, 0644)
+	defer os.Chmod(dir, 0755)
+	if _, _, err := checkWorktree(dir, idx, e); err == nil {
+		t.Error("expected error when the file cannot be examined")
+	}
+}
+
+func TestCheckWorktree_Flags(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.Remove(filepath.Join(dir, "test.txt"))
+
+	idx, _ := index.ReadIndex(dir)
+	e := idx.LookupEntry("test.txt")
+	for _, flag := range []uint16{index.FlagAssumeUnchanged, index.FlagSkipWorktree} {
+		e.Flags = flag
+		if state, _, err := checkWorktree(dir, idx, e); err != nil || state != worktreeUnchanged {
+			t.Errorf("flag %d: expected unchanged, got %v, %v", flag, state, err)
+		}
+	}
+}
+
+func TestRepoPath(t *testing.T) {
+	dir := setupTestRepo(t)
+	os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+	os.Chdir(filepath.Join(dir, "sub"))
+
+	if p, err := repoPath(dir, "a.txt"); err != nil || p != "sub/a.txt" {
+		t.Errorf("expected sub/a.txt, got %q, %v", p, err)
+	}
+	if p, err := repoPath(dir, ".."); err != nil || p != "." {
+		t.Errorf("expected ., got %q, %v", p, err)
+	}
+	if _, err := repoPath(dir, "../.."); err == nil {
+		t.Error("expected error for path outside the repository")
+	}
+}
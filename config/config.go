@@ -0,0 +1,309 @@
+// Package config reads and writes git-style INI configuration files. The
+// effective configuration merges the system, global (per-user) and
+// repository files, later files overriding earlier ones.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gogit/repo"
+)
+
+// Scope identifies one of the standard configuration files.
+type Scope int
+
+const (
+	ScopeSystem Scope = iota
+	ScopeGlobal
+	ScopeLocal
+)
+
+func (s Scope) String() string {
+	switch s {
+	case ScopeSystem:
+		return "system"
+	case ScopeGlobal:
+		return "global"
+	case ScopeLocal:
+		return "local"
+	}
+	return fmt.Sprintf("Scope(%d)", int(s))
+}
+
+// maxIncludeDepth bounds nested include.path directives, catching cycles.
+const maxIncludeDepth = 10
+
+// Entry is a single variable read from a configuration file. Key is in
+// canonical form: section and variable name lowercased, subsection as
+// written.
+type Entry struct {
+	Key   string
+	Value string
+	Scope Scope
+	File  string
+}
+
+// Config is the merged view of one or more configuration files.
+type Config struct {
+	entries []Entry
+}
+
+// Path returns the file backing a scope. The system file is
+// $GOGIT_CONFIG_SYSTEM or /etc/gogitconfig; the global file is
+// $GOGIT_CONFIG_GLOBAL or ~/.gogitconfig; the local file lives in the
+// repository at root.
+func Path(root string, scope Scope) (string, error) {
+	switch scope {
+	case ScopeSystem:
+		if p := os.Getenv("GOGIT_CONFIG_SYSTEM"); p != "" {
+			return p, nil
+		}
+		return "/etc/gogitconfig", nil
+	case ScopeGlobal:
+		if p := os.Getenv("GOGIT_CONFIG_GLOBAL"); p != "" {
+			return p, nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, ".gogitconfig"), nil
+	case ScopeLocal:
+		if root == "" {
+			return "", fmt.Errorf("local configuration requires a repository")
+		}
+		return repo.ConfigPath(root), nil
+	}
+	return "", fmt.Errorf("unknown config scope %v", scope)
+}
+
+// Load reads the system, global and (when root is not empty) repository
+// configuration files. Missing files are skipped.
+func Load(root string) (*Config, error) {
+	scopes := []Scope{ScopeSystem, ScopeGlobal}
+	if root != "" {
+		scopes = append(scopes, ScopeLocal)
+	}
+	c := &Config{}
+	for _, scope := range scopes {
+		path, err := Path(root, scope)
+		if err != nil {
+			// Without a home directory there is simply no global file.
+			continue
+		}
+		if err := c.readFile(path, scope, 0); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// LoadFile reads a single configuration file, attributing its entries to
+// scope. A missing file yields an empty configuration.
+func LoadFile(path string, scope Scope) (*Config, error) {
+	c := &Config{}
+	if err := c.readFile(path, scope, 0); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readFile appends the entries of path, expanding include.path directives
+// in place.
+func (c *Config) readFile(path string, scope Scope, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("exceeded maximum include depth (%d) while including %s", maxIncludeDepth, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	lines, err := parseLines(path, data)
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if l.key == "" {
+			continue
+		}
+		c.entries = append(c.entries, Entry{Key: l.key, Value: l.value, Scope: scope, File: path})
+		if l.key == "include.path" && l.value != "" {
+			if err := c.readFile(includePath(path, l.value), scope, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// includePath resolves an include.path value: "~/" is the home directory
+// and relative paths are relative to the including file.
+func includePath(from, p string) string {
+	if strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[2:])
+		}
+	}
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(filepath.Dir(from), p)
+}
+
+// Entries returns every entry in the order it was read.
+func (c *Config) Entries() []Entry {
+	return c.entries
+}
+
+// Get returns the value of key. When the key is set more than once the
+// last value read wins.
+func (c *Config) Get(key string) (string, bool) {
+	values := c.GetAll(key)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// GetAll returns every value of a multi-valued key, in the order read.
+func (c *Config) GetAll(key string) []string {
+	canonical, err := CanonicalKey(key)
+	if err != nil {
+		return nil
+	}
+	var values []string
+	for _, e := range c.entries {
+		if e.Key == canonical {
+			values = append(values, e.Value)
+		}
+	}
+	return values
+}
+
+// GetString returns the value of key, or def if it is not set.
+func (c *Config) GetString(key, def string) string {
+	if v, ok := c.Get(key); ok {
+		return v
+	}
+	return def
+}
+
+// GetBool returns key interpreted as a boolean (true/yes/on/1 or
+// false/no/off/0/empty), or def if it is not set.
+func (c *Config) GetBool(key string, def bool) (bool, error) {
+	v, ok := c.Get(key)
+	if !ok {
+		return def, nil
+	}
+	b, err := ParseBool(v)
+	if err != nil {
+		return def, fmt.Errorf("bad boolean config value '%s' for '%s'", v, key)
+	}
+	return b, nil
+}
+
+// GetInt returns key interpreted as an integer with an optional k, m or g
+// suffix, or def if it is not set.
+func (c *Config) GetInt(key string, def int) (int, error) {
+	v, ok := c.Get(key)
+	if !ok {
+		return def, nil
+	}
+	n, err := ParseInt(v)
+	if err != nil {
+		return def, fmt.Errorf("bad numeric config value '%s' for '%s'", v, key)
+	}
+	return n, nil
+}
+
+// ParseBool parses a config boolean.
+func ParseBool(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean '%s'", v)
+}
+
+// ParseInt parses a config integer with an optional k, m or g suffix.
+func ParseInt(v string) (int, error) {
+	num, factor := v, 1
+	if n := len(v); n > 0 {
+		switch v[n-1] {
+		case 'k', 'K':
+			num, factor = v[:n-1], 1<<10
+		case 'm', 'M':
+			num, factor = v[:n-1], 1<<20
+		case 'g', 'G':
+			num, factor = v[:n-1], 1<<30
+		}
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer '%s'", v)
+	}
+	return n * factor, nil
+}
+
+// CanonicalKey validates a "section.name" or "section.subsection.name" key
+// and returns it with section and name lowercased.
+func CanonicalKey(key string) (string, error) {
+	first := strings.IndexByte(key, '.')
+	last := strings.LastIndexByte(key, '.')
+	if first <= 0 || last == len(key)-1 {
+		return "", fmt.Errorf("key does not contain a section: %s", key)
+	}
+	section, name := key[:first], key[last+1:]
+	if !validSection(section) {
+		return "", fmt.Errorf("invalid key (bad section): %s", key)
+	}
+	if !validName(name) {
+		return "", fmt.Errorf("invalid key (bad variable name): %s", key)
+	}
+	canonical := strings.ToLower(section)
+	if first != last {
+		canonical += "." + key[first+1:last]
+	}
+	return canonical + "." + strings.ToLower(name), nil
+}
+
+// splitKey splits a canonical key into its section part ("core" or
+// "remote.origin") and variable name.
+func splitKey(canonical string) (section, name string) {
+	i := strings.LastIndexByte(canonical, '.')
+	return canonical[:i], canonical[i+1:]
+}
+
+func validSection(s string) bool {
+	for _, c := range s {
+		if !isAlnum(c) && c != '-' && c != '.' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func validName(s string) bool {
+	for i, c := range s {
+		if !isAlnum(c) && (c != '-' || i == 0) {
+			return false
+		}
+		if i == 0 && c >= '0' && c <= '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isAlnum(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
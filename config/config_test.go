@@ -0,0 +1,231 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gogit/repo"
+)
+
+// setupScopes creates a repository and points the system and global files
+// into a temp directory, returning the repository root.
+func setupScopes(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("GOGIT_CONFIG_SYSTEM", filepath.Join(dir, "system"))
+	t.Setenv("GOGIT_CONFIG_GLOBAL", filepath.Join(dir, "global"))
+	root := filepath.Join(dir, "repo")
+	os.MkdirAll(repo.GogitPath(root), 0755)
+	return root
+}
+
+func writeScope(t *testing.T, root string, scope Scope, content string) string {
+	t.Helper()
+	path, err := Path(root, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	root := setupScopes(t)
+	writeScope(t, root, ScopeSystem, "[user]\n\tname = System\n\temail = sys@example.com\n[core]\n\teditor = vi\n")
+	writeScope(t, root, ScopeGlobal, "[user]\n\tname = Global\n")
+	writeScope(t, root, ScopeLocal, "[user]\n\tname = Local\n")
+
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if v, _ := cfg.Get("user.name"); v != "Local" {
+		t.Errorf("expected local value to win, got %q", v)
+	}
+	if v, _ := cfg.Get("user.email"); v != "sys@example.com" {
+		t.Errorf("expected system value, got %q", v)
+	}
+	if all := cfg.GetAll("user.name"); strings.Join(all, ",") != "System,Global,Local" {
+		t.Errorf("expected values in precedence order, got %v", all)
+	}
+	if _, ok := cfg.Get("core.missing"); ok {
+		t.Error("missing key should not be found")
+	}
+
+	entries := cfg.Entries()
+	if entries[0].Scope != ScopeSystem || entries[len(entries)-1].Scope != ScopeLocal {
+		t.Errorf("unexpected entry scopes: %+v", entries)
+	}
+
+	global, err := Load("")
+	if err != nil {
+		t.Fatalf("Load without repository failed: %v", err)
+	}
+	if v, _ := global.Get("user.name"); v != "Global" {
+		t.Errorf("expected global value outside a repository, got %q", v)
+	}
+}
+
+func TestLoad_NoFiles(t *testing.T) {
+	root := setupScopes(t)
+
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cfg.Entries()) != 0 {
+		t.Errorf("expected no entries, got %v", cfg.Entries())
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	root := setupScopes(t)
+	writeScope(t, root, ScopeLocal, "[user]\nname = \"unterminated\n")
+
+	_, err := Load(root)
+	if err == nil || !strings.Contains(err.Error(), "bad config line 2") {
+		t.Errorf("expected bad config line error, got %v", err)
+	}
+
+	os.Remove(repo.ConfigPath(root))
+	os.Mkdir(repo.ConfigPath(root), 0755)
+	if _, err := Load(root); err == nil {
+		t.Error("expected error when config is a directory")
+	}
+}
+
+func TestLoad_Includes(t *testing.T) {
+	root := setupScopes(t)
+	dir := filepath.Dir(root)
+	os.WriteFile(filepath.Join(dir, "extra"), []byte("[user]\n\temail = included@example.com\n\tname = Included\n"), 0644)
+	writeScope(t, root, ScopeGlobal, "[user]\n\tname = Before\n[include]\n\tpath = extra\n\tpath = missing\n[core]\n\tx = after\n")
+	writeScope(t, root, ScopeLocal, "[user]\n\tname = Local\n")
+
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if v, _ := cfg.Get("user.email"); v != "included@example.com" {
+		t.Errorf("expected included value, got %q", v)
+	}
+	if all := cfg.GetAll("user.name"); strings.Join(all, ",") != "Before,Included,Local" {
+		t.Errorf("expected included values in place, got %v", all)
+	}
+	for _, e := range cfg.Entries() {
+		if e.Key == "user.email" && e.Scope != ScopeGlobal {
+			t.Errorf("included entries should take the including file's scope, got %v", e.Scope)
+		}
+	}
+}
+
+func TestLoad_IncludeCycle(t *testing.T) {
+	root := setupScopes(t)
+	path := writeScope(t, root, ScopeLocal, "")
+	os.WriteFile(path, []byte("[include]\n\tpath = "+path+"\n"), 0644)
+
+	_, err := Load(root)
+	if err == nil || !strings.Contains(err.Error(), "maximum include depth") {
+		t.Errorf("expected include depth error, got %v", err)
+	}
+}
+
+func TestGetTyped(t *testing.T) {
+	root := setupScopes(t)
+	writeScope(t, root, ScopeLocal, "[core]\n\tbare\n\tfilemode = off\n\tsize = 2k\n\tbig = 3M\n\tcount = 12\n\tbad = maybe\n")
+	cfg, _ := Load(root)
+
+	if b, err := cfg.GetBool("core.bare", false); err != nil || !b {
+		t.Errorf("bare variable should be true, got %v, %v", b, err)
+	}
+	if b, err := cfg.GetBool("core.fileMode", true); err != nil || b {
+		t.Errorf("expected false, got %v, %v", b, err)
+	}
+	if b, _ := cfg.GetBool("core.none", true); !b {
+		t.Error("expected default for missing bool")
+	}
+	if _, err := cfg.GetBool("core.bad", false); err == nil {
+		t.Error("expected error for bad boolean")
+	}
+	if n, err := cfg.GetInt("core.size", 0); err != nil || n != 2048 {
+		t.Errorf("expected 2048, got %d, %v", n, err)
+	}
+	if n, _ := cfg.GetInt("core.big", 0); n != 3<<20 {
+		t.Errorf("expected 3M, got %d", n)
+	}
+	if n, _ := cfg.GetInt("core.count", 0); n != 12 {
+		t.Errorf("expected 12, got %d", n)
+	}
+	if n, _ := cfg.GetInt("core.none", 7); n != 7 {
+		t.Errorf("expected default, got %d", n)
+	}
+	if _, err := cfg.GetInt("core.bad", 0); err == nil {
+		t.Error("expected error for bad integer")
+	}
+	if s := cfg.GetString("core.none", "dflt"); s != "dflt" {
+		t.Errorf("expected default string, got %q", s)
+	}
+}
+
+func TestParseInt_Empty(t *testing.T) {
+	if _, err := ParseInt(""); err == nil {
+		t.Error("expected error for empty integer")
+	}
+}
+
+func TestCanonicalKey(t *testing.T) {
+	tests := []struct {
+		key, want string
+	}{
+		{"user.name", "user.name"},
+		{"User.Name", "user.name"},
+		{"remote.Origin.URL", "remote.Origin.url"},
+		{"a.b.c.d", "a.b.c.d"},
+		{"core.file-mode", "core.file-mode"},
+	}
+	for _, tt := range tests {
+		got, err := CanonicalKey(tt.key)
+		if err != nil || got != tt.want {
+			t.Errorf("CanonicalKey(%q) = %q, %v; want %q", tt.key, got, err, tt.want)
+		}
+	}
+	for _, key := range []string{"", "user", ".name", "user.", "us er.name", "user.1name", "user.-x", "user.na_me"} {
+		if _, err := CanonicalKey(key); err == nil {
+			t.Errorf("CanonicalKey(%q): expected error", key)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Setenv("GOGIT_CONFIG_SYSTEM", "")
+	t.Setenv("GOGIT_CONFIG_GLOBAL", "")
+	t.Setenv("HOME", "/home/test")
+
+	if p, _ := Path("/r", ScopeSystem); p != "/etc/gogitconfig" {
+		t.Errorf("unexpected system path: %s", p)
+	}
+	if p, _ := Path("/r", ScopeGlobal); p != filepath.Join("/home/test", ".gogitconfig") {
+		t.Errorf("unexpected global path: %s", p)
+	}
+	if p, _ := Path("/r", ScopeLocal); p != repo.ConfigPath("/r") {
+		t.Errorf("unexpected local path: %s", p)
+	}
+	if _, err := Path("", ScopeLocal); err == nil {
+		t.Error("expected error for local scope without a repository")
+	}
+	if _, err := Path("/r", Scope(9)); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+}
+
+func TestScopeString(t *testing.T) {
+	if ScopeSystem.String() != "system" || ScopeGlobal.String() != "global" || ScopeLocal.String() != "local" {
+		t.Error("unexpected scope names")
+	}
+	if Scope(9).String() != "Scope(9)" {
+		t.Errorf("unexpected unknown scope name: %s", Scope(9))
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gogit/lockfile"
+)
+
+// Set sets key to value in the file at path, replacing its existing value.
+// A key with several values is not changed; use UnsetAll first.
+func Set(path, key, value string) error {
+	return edit(path, key, func(lines []line, matches []int, section, name string) ([]line, error) {
+		switch len(matches) {
+		case 0:
+			return insertVariable(lines, section, name, value), nil
+		case 1:
+			lines[matches[0]] = variableLine(section, name, value)
+			return lines, nil
+		}
+		return nil, fmt.Errorf("cannot overwrite multiple values of '%s' with a single value", key)
+	})
+}
+
+// Add adds another value for key to the file at path, keeping any values
+// it already has.
+func Add(path, key, value string) error {
+	return edit(path, key, func(lines []line, matches []int, section, name string) ([]line, error) {
+		return insertVariable(lines, section, name, value), nil
+	})
+}
+
+// Unset removes key from the file at path. It fails if the key is not set
+// or has several values.
+func Unset(path, key string) error {
+	return edit(path, key, func(lines []line, matches []int, section, name string) ([]line, error) {
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("key '%s' is not set", key)
+		case 1:
+			return removeLines(lines, matches), nil
+		}
+		return nil, fmt.Errorf("key '%s' has multiple values", key)
+	})
+}
+
+// UnsetAll removes every value of key from the file at path.
+func UnsetAll(path, key string) error {
+	return edit(path, key, func(lines []line, matches []int, section, name string) ([]line, error) {
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("key '%s' is not set", key)
+		}
+		return removeLines(lines, matches), nil
+	})
+}
+
+// edit rewrites the file at path under its lock. change receives the
+// parsed lines and the indexes of the lines setting key.
+func edit(path, key string, change func(lines []line, matches []int, section, name string) ([]line, error)) error {
+	canonical, err := CanonicalKey(key)
+	if err != nil {
+		return err
+	}
+	section, name := splitKey(canonical)
+
+	lock, err := lockfile.Acquire(path)
+	if err != nil {
+		return err
+	}
+	defer lock.Rollback()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	lines, err := parseLines(path, data)
+	if err != nil {
+		return err
+	}
+	var matches []int
+	for i, l := range lines {
+		if l.key == canonical {
+			matches = append(matches, i)
+		}
+	}
+
+	lines, err = change(lines, matches, section, name)
+	if err != nil {
+		return err
+	}
+	var buf strings.Builder
+	for _, l := range lines {
+		buf.WriteString(l.text)
+		buf.WriteByte('\n')
+	}
+	if _, err := lock.Write([]byte(buf.String())); err != nil {
+		return err
+	}
+	return lock.Commit()
+}
+
+func variableLine(section, name, value string) line {
+	return line{
+		text:    fmt.Sprintf("\t%s = %s", name, formatValue(value)),
+		section: section,
+		key:     section + "." + name,
+		value:   value,
+	}
+}
+
+// insertVariable adds a variable after the last line of the last block
+// of its section, or in a new section at the end of the file.
+func insertVariable(lines []line, section, name, value string) []line {
+	v := variableLine(section, name, value)
+	for i := len(lines) - 1; i >= 0; i-- {
+		if lines[i].section != section {
+			continue
+		}
+		// Keep trailing blank lines and comments after the new variable.
+		for i > 0 && lines[i].key == "" && !strings.HasPrefix(strings.TrimSpace(lines[i].text), "[") {
+			i--
+		}
+		return append(lines[:i+1], append([]line{v}, lines[i+1:]...)...)
+	}
+	header := line{text: formatSectionHeader(section), section: section}
+	return append(lines, header, v)
+}
+
+func removeLines(lines []line, indexes []int) []line {
+	drop := make(map[int]bool, len(indexes))
+	for _, i := range indexes {
+		drop[i] = true
+	}
+	var kept []line
+	for i, l := range lines {
+		if !drop[i] {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gogit/lockfile"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSet_NewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "config")
+
+	if err := Set(path, "user.name", "Jane Doe"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := Set(path, "remote.origin.url", "https://example.com"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := Set(path, "user.email", "jane@example.com"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	want := "[user]\n\tname = Jane Doe\n\temail = jane@example.com\n[remote \"origin\"]\n\turl = https://example.com\n"
+	if got := readFile(t, path); got != want {
+		t.Errorf("unexpected file:\n%s\nwant:\n%s", got, want)
+	}
+	if _, err := os.Stat(path + lockfile.Suffix); !os.IsNotExist(err) {
+		t.Error("lock should be released")
+	}
+}
+
+func TestSet_PreservesLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(path, []byte("# my settings\n[core]\n\teditor = vi ; favourite\n\n# user block\n[user]\n\tName = Old\n\n"), 0644)
+
+	if err := Set(path, "user.name", "New"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := Set(path, "core.pager", "less #1"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	want := "# my settings\n[core]\n\teditor = vi ; favourite\n\tpager = \"less #1\"\n\n# user block\n[user]\n\tname = New\n\n"
+	if got := readFile(t, path); got != want {
+		t.Errorf("unexpected file:\n%q\nwant:\n%q", got, want)
+	}
+
+	cfg, _ := LoadFile(path, ScopeLocal)
+	if v, _ := cfg.Get("core.pager"); v != "less #1" {
+		t.Errorf("expected value to round trip, got %q", v)
+	}
+}
+
+func TestAddAndUnset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+
+	Add(path, "remote.origin.fetch", "one")
+	Add(path, "remote.origin.fetch", "two")
+	cfg, _ := LoadFile(path, ScopeGlobal)
+	if all := cfg.GetAll("remote.origin.fetch"); strings.Join(all, ",") != "one,two" {
+		t.Fatalf("expected both values, got %v", all)
+	}
+
+	if err := Set(path, "remote.origin.fetch", "three"); err == nil || !strings.Contains(err.Error(), "multiple values") {
+		t.Errorf("expected multiple values error from Set, got %v", err)
+	}
+	if err := Unset(path, "remote.origin.fetch"); err == nil || !strings.Contains(err.Error(), "multiple values") {
+		t.Errorf("expected multiple values error from Unset, got %v", err)
+	}
+	if err := UnsetAll(path, "remote.origin.fetch"); err != nil {
+		t.Fatalf("UnsetAll failed: %v", err)
+	}
+	cfg, _ = LoadFile(path, ScopeGlobal)
+	if all := cfg.GetAll("remote.origin.fetch"); len(all) != 0 {
+		t.Errorf("expected no values, got %v", all)
+	}
+
+	Set(path, "user.name", "x")
+	if err := Unset(path, "User.Name"); err != nil {
+		t.Fatalf("Unset failed: %v", err)
+	}
+	if err := Unset(path, "user.name"); err == nil || !strings.Contains(err.Error(), "not set") {
+		t.Errorf("expected not set error, got %v", err)
+	}
+	if err := UnsetAll(path, "user.name"); err == nil || !strings.Contains(err.Error(), "not set") {
+		t.Errorf("expected not set error, got %v", err)
+	}
+}
+
+func TestEdit_Errors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+
+	if err := Set(path, "nosection", "x"); err == nil {
+		t.Error("expected error for invalid key")
+	}
+
+	os.WriteFile(path, []byte("[broken\n"), 0644)
+	if err := Set(path, "user.name", "x"); err == nil {
+		t.Error("expected error for malformed file")
+	}
+	if readFile(t, path) != "[broken\n" {
+		t.Error("malformed file should be left alone")
+	}
+
+	os.WriteFile(path, nil, 0644)
+	os.WriteFile(path+lockfile.Suffix, nil, 0644)
+	if err := Set(path, "user.name", "x"); err == nil || !strings.Contains(err.Error(), "file exists") {
+		t.Errorf("expected lock error, got %v", err)
+	}
+	os.Remove(path + lockfile.Suffix)
+
+	os.Remove(path)
+	os.Mkdir(path, 0755)
+	if err := Set(path, "user.name", "x"); err == nil {
+		t.Error("expected error when config is a directory")
+	}
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// line is one line of a configuration file. Lines are kept verbatim so a
+// file can be edited without disturbing comments and layout.
+type line struct {
+	text    string
+	section string // canonical section the line belongs to, e.g. "remote.origin"
+	key     string // canonical key for variable lines, "" otherwise
+	value   string
+}
+
+// parseLines splits a configuration file into lines, recording the section
+// of each line and the key and value of each variable.
+func parseLines(path string, data []byte) ([]line, error) {
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	section := ""
+	var lines []line
+	for i, raw := range strings.Split(text, "\n") {
+		l := line{text: raw}
+		trimmed := strings.TrimSpace(raw)
+		switch {
+		case trimmed == "" || trimmed[0] == '#' || trimmed[0] == ';':
+		case trimmed[0] == '[':
+			s, err := parseSectionHeader(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("bad config line %d in file %s", i+1, path)
+			}
+			section = s
+		default:
+			if section == "" {
+				return nil, fmt.Errorf("bad config line %d in file %s", i+1, path)
+			}
+			name, value, err := parseVariable(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("bad config line %d in file %s: %v", i+1, path, err)
+			}
+			l.key = section + "." + name
+			l.value = value
+		}
+		l.section = section
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+// parseSectionHeader parses `[section]` or `[section "subsection"]` into a
+// canonical section name. The older `[section.subsection]` form is read
+// with the subsection lowercased.
+func parseSectionHeader(s string) (string, error) {
+	end := strings.LastIndexByte(s, ']')
+	if end < 0 {
+		return "", fmt.Errorf("missing ']'")
+	}
+	if rest := strings.TrimSpace(s[end+1:]); rest != "" && rest[0] != '#' && rest[0] != ';' {
+		return "", fmt.Errorf("unexpected text after section header")
+	}
+	inner := s[1:end]
+
+	name, sub, quoted := strings.Cut(inner, " ")
+	if !validSection(name) {
+		return "", fmt.Errorf("invalid section name '%s'", name)
+	}
+	if !quoted {
+		return strings.ToLower(name), nil
+	}
+	sub = strings.TrimSpace(sub)
+	if len(sub) < 2 || sub[0] != '"' || sub[len(sub)-1] != '"' {
+		return "", fmt.Errorf("invalid subsection")
+	}
+	var b strings.Builder
+	for i := 1; i < len(sub)-1; i++ {
+		c := sub[i]
+		if c == '\\' && i+1 < len(sub)-1 {
+			i++
+			c = sub[i]
+		} else if c == '"' {
+			return "", fmt.Errorf("invalid subsection")
+		}
+		b.WriteByte(c)
+	}
+	return strings.ToLower(name) + "." + b.String(), nil
+}
+
+// parseVariable parses `name = value` or a bare `name`, which means true.
+func parseVariable(s string) (string, string, error) {
+	name, value, hasValue := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if !validName(name) {
+		return "", "", fmt.Errorf("invalid variable name '%s'", name)
+	}
+	if !hasValue {
+		return strings.ToLower(name), "true", nil
+	}
+	v, err := parseValue(value)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.ToLower(name), v, nil
+}
+
+// parseValue unquotes a value: double quotes preserve whitespace and
+// comment characters, backslash escapes \n, \t, \b, \" and \\, and an
+// unquoted # or ; starts a comment.
+func parseValue(s string) (string, error) {
+	var b strings.Builder
+	inQuote := false
+	space := "" // unquoted whitespace, kept only if more value follows
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			i++
+			if i == len(s) {
+				return "", fmt.Errorf("trailing backslash")
+			}
+			switch s[i] {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case '"', '\\':
+				c = s[i]
+			default:
+				return "", fmt.Errorf("invalid escape '\\%c'", s[i])
+			}
+		case c == '"':
+			inQuote = !inQuote
+			b.WriteString(space)
+			space = ""
+			continue
+		case !inQuote && (c == '#' || c == ';'):
+			i = len(s)
+			continue
+		case !inQuote && (c == ' ' || c == '\t'):
+			if b.Len() > 0 {
+				space += string(c)
+			}
+			continue
+		}
+		b.WriteString(space)
+		space = ""
+		b.WriteByte(c)
+	}
+	if inQuote {
+		return "", fmt.Errorf("unterminated quote")
+	}
+	return b.String(), nil
+}
+
+// formatValue quotes and escapes a value so parseValue reads it back
+// unchanged.
+func formatValue(v string) string {
+	needsQuotes := v != strings.TrimSpace(v) || strings.ContainsAny(v, "#;")
+	var b strings.Builder
+	for _, c := range v {
+		switch c {
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	if needsQuotes {
+		return `"` + b.String() + `"`
+	}
+	return b.String()
+}
+
+// formatSectionHeader writes the header line for a canonical section.
+func formatSectionHeader(section string) string {
+	name, sub, ok := strings.Cut(section, ".")
+	if !ok {
+		return "[" + name + "]"
+	}
+	sub = strings.ReplaceAll(sub, `\`, `\\`)
+	sub = strings.ReplaceAll(sub, `"`, `\"`)
+	return fmt.Sprintf("[%s \"%s\"]", name, sub)
+}
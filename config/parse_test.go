@@ -0,0 +1,110 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestParseLines(t *testing.T) {
+	data := []byte(`# leading comment
+[core]
+	editor = vim  ; trailing comment
+	pager = "less -R" # quoted
+[remote "origin"]
+	url = https://example.com/repo.git
+[Branch "Main"]
+	Remote = origin
+[alias.Co]
+	x = y
+`)
+	lines, err := parseLines("config", data)
+	if err != nil {
+		t.Fatalf("parseLines failed: %v", err)
+	}
+	got := map[string]string{}
+	for _, l := range lines {
+		if l.key != "" {
+			got[l.key] = l.value
+		}
+	}
+	want := map[string]string{
+		"core.editor":        "vim",
+		"core.pager":         "less -R",
+		"remote.origin.url":  "https://example.com/repo.git",
+		"branch.Main.remote": "origin",
+		"alias.co.x":         "y",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("unexpected keys: %v", got)
+	}
+	if lines[0].section != "" || lines[2].section != "core" {
+		t.Errorf("unexpected sections: %+v", lines[:3])
+	}
+}
+
+func TestParseLines_Errors(t *testing.T) {
+	bad := []string{
+		"name = value\n",
+		"[core\n",
+		"[core] junk\n",
+		"[co re]\n",
+		"[core \"sub]\n",
+		"[core]\n\t1x = y\n",
+		"[core]\n\tx = \"open\n",
+		"[core]\n\tx = bad\\q\n",
+		"[core]\n\tx = trailing\\\n",
+	}
+	for _, data := range bad {
+		if _, err := parseLines("config", []byte(data)); err == nil {
+			t.Errorf("expected error for %q", data)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"  spaced  out  ", "spaced  out"},
+		{`"  kept  "`, "  kept  "},
+		{`a "b" c`, "a b c"},
+		{`"x # y" # comment`, "x # y"},
+		{`tab\there`, "tab\there"},
+		{`line\nbreak`, "line\nbreak"},
+		{`say \"hi\" \\o/`, `say "hi" \o/`},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := parseValue(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseValue(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormatValue_RoundTrip(t *testing.T) {
+	for _, v := range []string{"simple", " lead", "trail ", "a#b", "semi;colon", "q\"uote", `back\slash`, "multi\nline", "tab\tbed", ""} {
+		got, err := parseValue(formatValue(v))
+		if err != nil || got != v {
+			t.Errorf("round trip of %q gave %q, %v", v, got, err)
+		}
+	}
+}
+
+func TestFormatSectionHeader(t *testing.T) {
+	if h := formatSectionHeader("core"); h != "[core]" {
+		t.Errorf("unexpected header: %s", h)
+	}
+	if h := formatSectionHeader(`remote.my "odd" name`); h != `[remote "my \"odd\" name"]` {
+		t.Errorf("unexpected header: %s", h)
+	}
+	s, err := parseSectionHeader(formatSectionHeader(`remote.my "odd" name`))
+	if err != nil || s != `remote.my "odd" name` {
+		t.Errorf("header did not round trip: %q, %v", s, err)
+	}
+}
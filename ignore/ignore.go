@@ -0,0 +1,161 @@
+// Package ignore decides which working tree paths are ignored, following
+// gitignore rules read from .gogitignore files, .gogit/info/exclude and the
+// file named by core.excludesFile.
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gogit/config"
+	"gogit/repo"
+)
+
+// FileName is the name of the per-directory ignore file.
+const FileName = ".gogitignore"
+
+// Matcher matches paths against the ignore rules of one working tree.
+// Per-directory files are read the first time a path below them is
+// matched.
+type Matcher struct {
+	root   string
+	global []*Pattern            // info/exclude then core.excludesFile; lowest precedence
+	dirs   map[string][]*Pattern // rules from <dir>/.gogitignore, keyed by slash path
+}
+
+// New creates a Matcher for the repository at root.
+func New(root string) (*Matcher, error) {
+	m := &Matcher{root: root, dirs: make(map[string][]*Pattern)}
+
+	cfg, err := config.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	if excludes := cfg.GetString("core.excludesFile", ""); excludes != "" {
+		if strings.HasPrefix(excludes, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				excludes = filepath.Join(home, excludes[2:])
+			}
+		}
+		if m.global, err = readPatterns(excludes, "", excludes); err != nil {
+			return nil, err
+		}
+	}
+
+	exclude := filepath.Join(repo.GogitPath(root), "info", "exclude")
+	source := path.Join(repo.GogitDir, "info", "exclude")
+	patterns, err := readPatterns(exclude, "", source)
+	if err != nil {
+		return nil, err
+	}
+	// info/exclude takes precedence over core.excludesFile, so it goes last.
+	m.global = append(m.global, patterns...)
+	return m, nil
+}
+
+// Match returns the rule that decides whether path (slash-separated,
+// relative to the root) is ignored, or nil if no rule matches. The rule may
+// be a negation, in which case the path is not ignored. A path inside an
+// ignored directory is ignored by the directory's rule, since negations
+// cannot re-include files below an excluded directory.
+func (m *Matcher) Match(p string, isDir bool) (*Pattern, error) {
+	p = strings.Trim(p, "/")
+	if p == "" || p == "." {
+		return nil, nil
+	}
+	parts := strings.Split(p, "/")
+	for i := 1; i < len(parts); i++ {
+		pat, err := m.matchOne(strings.Join(parts[:i], "/"), true)
+		if err != nil {
+			return nil, err
+		}
+		if pat != nil && !pat.negate {
+			return pat, nil
+		}
+	}
+	return m.matchOne(p, isDir)
+}
+
+// Ignored reports whether path is ignored.
+func (m *Matcher) Ignored(p string, isDir bool) (bool, error) {
+	pat, err := m.Match(p, isDir)
+	if err != nil {
+		return false, err
+	}
+	return pat != nil && !pat.negate, nil
+}
+
+// matchOne applies the rules to path alone, without looking at its parent
+// directories. Files closer to the path win over those further up, and
+// within a file the last matching rule wins.
+func (m *Matcher) matchOne(p string, isDir bool) (*Pattern, error) {
+	dir := path.Dir(p)
+	for {
+		if dir == "." {
+			dir = ""
+		}
+		patterns, err := m.dirPatterns(dir)
+		if err != nil {
+			return nil, err
+		}
+		if pat := lastMatch(patterns, p, isDir); pat != nil {
+			return pat, nil
+		}
+		if dir == "" {
+			break
+		}
+		dir = path.Dir(dir)
+	}
+	return lastMatch(m.global, p, isDir), nil
+}
+
+func lastMatch(patterns []*Pattern, p string, isDir bool) *Pattern {
+	for i := len(patterns) - 1; i >= 0; i-- {
+		if patterns[i].matches(p, isDir) {
+			return patterns[i]
+		}
+	}
+	return nil
+}
+
+// dirPatterns returns the rules of dir's ignore file, reading it once.
+func (m *Matcher) dirPatterns(dir string) ([]*Pattern, error) {
+	if patterns, ok := m.dirs[dir]; ok {
+		return patterns, nil
+	}
+	source := path.Join(dir, FileName)
+	patterns, err := readPatterns(filepath.Join(m.root, filepath.FromSlash(source)), dir, source)
+	if err != nil {
+		return nil, err
+	}
+	m.dirs[dir] = patterns
+	return patterns, nil
+}
+
+// readPatterns reads the rules in file, which apply below base. A missing
+// file has no rules.
+func readPatterns(file, base, source string) ([]*Pattern, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []*Pattern
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if p := parsePattern(scanner.Text(), base, source, line); p != nil {
+			patterns = append(patterns, p)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return patterns, nil
+}
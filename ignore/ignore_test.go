@@ -0,0 +1,151 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gogit/repo"
+)
+
+// setupTree creates a repository with the given files, keyed by slash
+// path, and isolates it from the user's configuration.
+func setupTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("GOGIT_CONFIG_GLOBAL", filepath.Join(root, ".gogitconfig"))
+	t.Setenv("GOGIT_CONFIG_SYSTEM", filepath.Join(root, ".gogitconfig-system"))
+	os.MkdirAll(repo.GogitPath(root), 0755)
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(content), 0644)
+	}
+	return root
+}
+
+func TestMatcher(t *testing.T) {
+	root := setupTree(t, map[string]string{
+		".gogitignore":        "*.log\n!important.log\nbuild/\n/secret\n",
+		"sub/.gogitignore":    "!debug.log\nlocal.txt\n",
+		".gogit/info/exclude": "*.swp\n",
+	})
+	m, err := New(root)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"important.log", false, false},
+		{"deep/dir/app.log", false, true},
+		{"build", true, true},
+		{"build/out.bin", false, true},
+		{"src/build/out.bin", false, true},
+		{"secret", false, true},
+		{"sub/secret", false, false},
+		{"sub/debug.log", false, false},
+		{"sub/other.log", false, true},
+		{"sub/local.txt", false, true},
+		{"local.txt", false, false},
+		{".main.go.swp", false, true},
+		{"main.go.swp", false, true},
+		{"main.go", false, false},
+		{"", true, false},
+	}
+	for _, tt := range tests {
+		got, err := m.Ignored(tt.path, tt.isDir)
+		if err != nil {
+			t.Fatalf("Ignored(%q) failed: %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("Ignored(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMatcher_Sources(t *testing.T) {
+	root := setupTree(t, map[string]string{
+		".gogitignore":        "# comment\n*.log\n",
+		"sub/.gogitignore":    "!keep.log\n",
+		".gogit/info/exclude": "tmp/\n",
+	})
+	m, _ := New(root)
+
+	pat, _ := m.Match("sub/keep.log", false)
+	if pat == nil || pat.String() != "sub/.gogitignore:1:!keep.log" || !pat.Negated() {
+		t.Errorf("unexpected rule for negated path: %v", pat)
+	}
+	pat, _ = m.Match("a.log", false)
+	if pat == nil || pat.String() != ".gogitignore:2:*.log" {
+		t.Errorf("unexpected rule: %v", pat)
+	}
+	pat, _ = m.Match("tmp/x/y", false)
+	if pat == nil || pat.String() != ".gogit/info/exclude:1:tmp/" {
+		t.Errorf("expected parent directory's rule, got %v", pat)
+	}
+	if pat, _ := m.Match("none.txt", false); pat != nil {
+		t.Errorf("expected no rule, got %v", pat)
+	}
+}
+
+func TestMatcher_NoReincludeBelowIgnoredDir(t *testing.T) {
+	root := setupTree(t, map[string]string{
+		".gogitignore": "vendor/\n!vendor/keep.go\n",
+	})
+	m, _ := New(root)
+
+	if ignored, _ := m.Ignored("vendor/keep.go", false); !ignored {
+		t.Error("a file below an ignored directory cannot be re-included")
+	}
+}
+
+func TestMatcher_ExcludesFile(t *testing.T) {
+	root := setupTree(t, map[string]string{
+		"global-ignore":       "*.bak\n*.tmp\n",
+		".gogit/info/exclude": "!keep.tmp\n",
+	})
+	os.WriteFile(filepath.Join(root, ".gogitconfig"), []byte("[core]\n\texcludesFile = "+filepath.Join(root, "global-ignore")+"\n"), 0644)
+	m, err := New(root)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if ignored, _ := m.Ignored("x.bak", false); !ignored {
+		t.Error("expected core.excludesFile rules to apply")
+	}
+	if ignored, _ := m.Ignored("keep.tmp", false); ignored {
+		t.Error("info/exclude should take precedence over core.excludesFile")
+	}
+}
+
+func TestMatcher_Errors(t *testing.T) {
+	root := setupTree(t, nil)
+	os.WriteFile(repo.ConfigPath(root), []byte("[broken\n"), 0644)
+	if _, err := New(root); err == nil {
+		t.Error("expected error for malformed config")
+	}
+
+	root = setupTree(t, nil)
+	os.MkdirAll(filepath.Join(repo.GogitPath(root), "info", "exclude"), 0755)
+	if _, err := New(root); err == nil {
+		t.Error("expected error when info/exclude is a directory")
+	}
+
+	root = setupTree(t, nil)
+	os.MkdirAll(filepath.Join(root, "sub", FileName), 0755)
+	m, err := New(root)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := m.Ignored("sub/file", false); err == nil {
+		t.Error("expected error when an ignore file is a directory")
+	}
+	if _, err := m.Match("sub/deeper/file", false); err == nil {
+		t.Error("expected error from a parent directory's ignore file")
+	}
+}
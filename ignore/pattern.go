@@ -0,0 +1,177 @@
+package ignore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pattern is one rule from an ignore file.
+type Pattern struct {
+	Source string // file the rule was read from, relative to the repository root
+	Line   int    // 1-based line number in Source
+	Text   string // the rule as written, without trailing spaces
+
+	base     string // directory the rule applies under ("" for the root)
+	negate   bool
+	dirOnly  bool
+	anchored bool
+	re       *regexp.Regexp
+}
+
+// Negated reports whether the rule re-includes paths ("!pattern").
+func (p *Pattern) Negated() bool {
+	return p.negate
+}
+
+func (p *Pattern) String() string {
+	return fmt.Sprintf("%s:%d:%s", p.Source, p.Line, p.Text)
+}
+
+// parsePattern parses one line of an ignore file whose rules apply below
+// base. It returns nil for blank lines, comments and malformed globs.
+func parsePattern(text, base, source string, line int) *Pattern {
+	text = trimTrailingSpaces(text)
+	if text == "" || text[0] == '#' {
+		return nil
+	}
+	p := &Pattern{Source: source, Line: line, Text: text, base: base}
+
+	glob := text
+	switch {
+	case glob[0] == '!':
+		p.negate = true
+		glob = glob[1:]
+	case strings.HasPrefix(glob, `\!`), strings.HasPrefix(glob, `\#`):
+		glob = glob[1:]
+	}
+	if strings.HasSuffix(glob, "/") {
+		p.dirOnly = true
+		glob = strings.TrimRight(glob, "/")
+	}
+	// A slash anywhere but the end ties the rule to its directory.
+	if strings.Contains(glob, "/") {
+		p.anchored = true
+		glob = strings.TrimPrefix(glob, "/")
+	}
+	if glob == "" {
+		return nil
+	}
+
+	re, err := compileGlob(glob, p.anchored)
+	if err != nil {
+		return nil
+	}
+	p.re = re
+	return p
+}
+
+// trimTrailingSpaces removes trailing spaces that are not escaped with a
+// backslash.
+func trimTrailingSpaces(s string) string {
+	s = strings.TrimRight(s, "\r")
+	for strings.HasSuffix(s, " ") && !strings.HasSuffix(s, `\ `) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// matches reports whether the rule applies to path, a slash-separated path
+// relative to the repository root.
+func (p *Pattern) matches(path string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	rel := path
+	if p.base != "" {
+		if !strings.HasPrefix(path, p.base+"/") {
+			return false
+		}
+		rel = path[len(p.base)+1:]
+	}
+	return p.re.MatchString(rel)
+}
+
+// compileGlob turns a gitignore glob into a regular expression over paths
+// relative to the rule's directory. "*" and "?" never match "/"; "**/",
+// "/**/" and "/**" match across directories. An unanchored glob may match
+// at any depth.
+func compileGlob(glob string, anchored bool) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(glob); {
+		atSegmentStart := i == 0 || glob[i-1] == '/'
+		switch c := glob[i]; {
+		case atSegmentStart && strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 3
+		case atSegmentStart && glob[i:] == "**":
+			b.WriteString(".*")
+			i += 2
+		case c == '*':
+			b.WriteString("[^/]*")
+			i++
+		case c == '?':
+			b.WriteString("[^/]")
+			i++
+		case c == '[':
+			class, n := bracketClass(glob[i:])
+			if n == 0 {
+				b.WriteString(`\[`)
+				i++
+				continue
+			}
+			b.WriteString(class)
+			i += n
+		case c == '\\' && i+1 < len(glob):
+			b.WriteString(regexp.QuoteMeta(glob[i+1 : i+2]))
+			i += 2
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			i++
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// bracketClass converts a "[...]" class at the start of s to a regular
+// expression class and returns it with the number of bytes consumed, or 0
+// if the class is not terminated.
+func bracketClass(s string) (string, int) {
+	i := 1
+	negate := false
+	if i < len(s) && (s[i] == '!' || s[i] == '^') {
+		negate = true
+		i++
+	}
+	start := i
+	// A ']' right after the opening bracket is a literal member.
+	if i < len(s) && s[i] == ']' {
+		i++
+	}
+	for i < len(s) && s[i] != ']' {
+		if s[i] == '[' && i+1 < len(s) && s[i+1] == ':' {
+			if end := strings.Index(s[i+2:], ":]"); end >= 0 {
+				i += end + 4
+				continue
+			}
+		}
+		i++
+	}
+	if i >= len(s) {
+		return "", 0
+	}
+
+	members := strings.ReplaceAll(s[start:i], `\`, `\\`)
+	if strings.HasPrefix(members, "]") {
+		members = `\]` + members[1:]
+	}
+	if negate {
+		return "[^/" + members + "]", i + 1
+	}
+	return "[" + members + "]", i + 1
+}
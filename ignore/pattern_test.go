@@ -0,0 +1,109 @@
+package ignore
+
+import (
+	"testing"
+)
+
+func TestPatternMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		// Unanchored names match at any depth.
+		{"*.o", "main.o", false, true},
+		{"*.o", "src/lib/util.o", false, true},
+		{"*.o", "main.c", false, false},
+		{"node_modules", "node_modules", true, true},
+		{"node_modules", "web/node_modules", true, true},
+		{".*.swp", "src/.main.go.swp", false, true},
+		{"?.txt", "a.txt", false, true},
+		{"?.txt", "ab.txt", false, false},
+		{"*.txt", "dir/sub.txt", false, true},
+		{"[abc].log", "b.log", false, true},
+		{"[abc].log", "d.log", false, false},
+		{"[!abc].log", "d.log", false, true},
+		{"[a-c]x", "bx", false, true},
+		{"[[:digit:]]*", "7up", false, true},
+
+		// Anchored patterns match relative to the ignore file.
+		{"/build", "build", true, true},
+		{"/build", "src/build", true, false},
+		{"doc/*.html", "doc/index.html", false, true},
+		{"doc/*.html", "doc/api/index.html", false, false},
+		{"doc/*.html", "x/doc/index.html", false, false},
+
+		// Directory-only patterns.
+		{"logs/", "logs", true, true},
+		{"logs/", "logs", false, false},
+		{"logs/", "a/logs", true, true},
+
+		// Double asterisks.
+		{"**/foo", "foo", false, true},
+		{"**/foo", "a/b/foo", false, true},
+		{"**/foo/bar", "x/foo/bar", false, true},
+		{"abc/**", "abc/x", false, true},
+		{"abc/**", "abc/x/y", false, true},
+		{"abc/**", "abc", true, false},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"a/**/b", "a/xb", false, false},
+		{"a**b", "axxb", false, true},
+		{"a**b", "ax/xb", false, false},
+
+		// Escapes.
+		{`\#notcomment`, "#notcomment", false, true},
+		{`\!important`, "!important", false, true},
+		{`star\*`, "star*", false, true},
+		{`star\*`, "starx", false, false},
+		{`trailing\ `, "trailing ", false, true},
+		{"[unclosed", "[unclosed", false, true},
+	}
+	for _, tt := range tests {
+		p := parsePattern(tt.pattern, "", ".gogitignore", 1)
+		if p == nil {
+			t.Errorf("parsePattern(%q) returned nil", tt.pattern)
+			continue
+		}
+		if got := p.matches(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%q matching %q (dir=%v) = %v, want %v", tt.pattern, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestPatternBase(t *testing.T) {
+	p := parsePattern("*.tmp", "sub", "sub/.gogitignore", 3)
+	if !p.matches("sub/a.tmp", false) || !p.matches("sub/deep/a.tmp", false) {
+		t.Error("pattern should match below its directory")
+	}
+	if p.matches("a.tmp", false) || p.matches("subway/a.tmp", false) {
+		t.Error("pattern should not match outside its directory")
+	}
+
+	anchored := parsePattern("/out", "sub", "sub/.gogitignore", 4)
+	if !anchored.matches("sub/out", true) || anchored.matches("sub/x/out", true) {
+		t.Error("anchored pattern should only match directly below its directory")
+	}
+}
+
+func TestParsePattern_Skipped(t *testing.T) {
+	for _, text := range []string{"", "   ", "# comment", "/", "!"} {
+		if p := parsePattern(text, "", "f", 1); p != nil {
+			t.Errorf("parsePattern(%q) should be skipped, got %+v", text, p)
+		}
+	}
+}
+
+func TestParsePattern_Fields(t *testing.T) {
+	p := parsePattern("!/keep/me/   ", "", ".gogitignore", 7)
+	if !p.Negated() || !p.dirOnly || !p.anchored {
+		t.Errorf("unexpected flags: %+v", p)
+	}
+	if p.Text != "!/keep/me/" {
+		t.Errorf("trailing spaces should be trimmed, got %q", p.Text)
+	}
+	if p.String() != ".gogitignore:7:!/keep/me/" {
+		t.Errorf("unexpected String: %s", p)
+	}
+}
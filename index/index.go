@@ -7,43 +7,123 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"slices"
 	"sort"
+	"time"
 
 	"gogit/repo"
 )
 
 const (
-	indexMagic   = "GIDX"
-	indexVersion = 1
+	indexMagic = "GIDX"
+
+	// Version 1 entries hold 32-bit seconds and sizes, with the rest of
+	// the stat data in the STAT extension. Version 2 entries hold 64-bit
+	// seconds and sizes, nanoseconds and flags inline.
+	IndexVersion1 = 1
+	IndexVersion2 = 2
+
+	// indexVersion is the version written for a new index.
+	indexVersion = IndexVersion2
+)
+
+// Merge stages. A resolved path has a single stage 0 entry; an unmerged path
+// has up to three entries holding the base, our and their versions.
+const (
+	StageMerged = 0
+	StageBase   = 1
+	StageOurs   = 2
+	StageTheirs = 3
+)
+
+// The stage is stored on disk in the bits above the 16-bit file mode.
+const (
+	stageShift = 16
+	modeMask   = 0xffff
+)
+
+// Entry flags. They can only be stored in a version 2 index.
+const (
+	// FlagAssumeUnchanged makes commands treat the working tree file as
+	// matching the entry without looking at it.
+	FlagAssumeUnchanged uint16 = 1 << iota
+	// FlagSkipWorktree marks a path whose working tree file is not
+	// maintained; like assume-unchanged, it is never compared.
+	FlagSkipWorktree
+	// FlagIntentToAdd records a path that will be added later. Its entry
+	// holds the empty blob and is left out of trees.
+	FlagIntentToAdd
+)
+
+// Fixed part of an entry on disk, before the path, for each version:
+//
+//	v1: ctime(4) mtime(4) size(4) hash(20) mode(4) pathlen(2)
+//	v2: ctime(8) ctime-ns(4) mtime(8) mtime-ns(4) dev ino uid gid(16)
+//	    size(8) hash(20) mode(4) flags(2) pathlen(2)
+const (
+	entryFixedLenV1 = 38
+	entryFixedLenV2 = 76
 )
 
-// Entry represents a single index entry.
+// Entry represents a single index entry. The stat fields record the
+// working tree file as it was when the entry was staged (see SetStat).
 type Entry struct {
-	Ctime uint32
-	Mtime uint32
-	Size  uint32
-	Hash  string // 40-char hex SHA1
-	Mode  uint32
-	Path  string
+	Ctime     int64 // seconds
+	CtimeNsec uint32
+	Mtime     int64 // seconds
+	MtimeNsec uint32
+	Dev       uint32
+	Ino       uint32
+	UID       uint32
+	GID       uint32
+	Size      uint64
+	Hash      string // 40-char hex SHA1
+	Mode      uint32
+	Flags     uint16
+	Path      string
+	Stage     int
+}
+
+// IntentToAdd reports whether the entry only records that its path will
+// be added.
+func (e *Entry) IntentToAdd() bool {
+	return e.Flags&FlagIntentToAdd != 0
 }
 
 // Index represents the staging area.
 type Index struct {
+	// Entries are kept sorted by path, then stage, so that lookups can
+	// use binary search. Code that builds the slice directly must keep
+	// that order or call Sort.
 	Entries []Entry
+
+	// Version is the on-disk format version; 0 means the default for a
+	// new index.
+	Version uint32
+
+	// timestamp is the modification time of the index file when it was
+	// read; entries modified at or after it are racily clean.
+	timestamp time.Time
 }
 
-// ReadIndex reads the index file from disk.
+// ReadIndex reads the index file from disk, in either format version.
 func ReadIndex(root string) (*Index, error) {
 	path := repo.IndexPath(root)
-	data, err := os.ReadFile(path)
+	// Stat before reading: if the file is replaced in between, the older
+	// timestamp only makes more entries count as racy.
+	info, err := os.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return &Index{}, nil
 		}
 		return nil, err
 	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
 
-	if len(data) < 12 {
+	if len(data) < 12+sha1.Size {
 		return nil, fmt.Errorf("index file too short")
 	}
 
@@ -67,111 +147,370 @@ func ReadIndex(root string) (*Index, error) {
 	binary.Read(r, binary.BigEndian, &version)
 	binary.Read(r, binary.BigEndian, &count)
 
-	if version != indexVersion {
+	if version != IndexVersion1 && version != IndexVersion2 {
 		return nil, fmt.Errorf("unsupported index version: %d", version)
 	}
 
-	idx := &Index{Entries: make([]Entry, 0, count)}
+	idx := &Index{Entries: make([]Entry, 0, count), Version: version, timestamp: info.ModTime()}
 	for i := uint32(0); i < count; i++ {
-		var e Entry
+		idx.Entries = append(idx.Entries, readEntry(r, version))
+	}
+
+	if err := readExtensions(r, idx); err != nil {
+		return nil, err
+	}
+	// Indexes are written sorted, but lookups must not depend on it.
+	idx.Sort()
+	return idx, nil
+}
+
+// readEntry reads one entry in the given format version, including the
+// padding that aligns it to 8 bytes.
+func readEntry(r *bytes.Reader, version uint32) Entry {
+	var e Entry
+	fixedLen := entryFixedLenV2
+	if version == IndexVersion1 {
+		var ctime, mtime, size uint32
+		binary.Read(r, binary.BigEndian, &ctime)
+		binary.Read(r, binary.BigEndian, &mtime)
+		binary.Read(r, binary.BigEndian, &size)
+		e.Ctime, e.Mtime, e.Size = int64(ctime), int64(mtime), uint64(size)
+		fixedLen = entryFixedLenV1
+	} else {
 		binary.Read(r, binary.BigEndian, &e.Ctime)
+		binary.Read(r, binary.BigEndian, &e.CtimeNsec)
 		binary.Read(r, binary.BigEndian, &e.Mtime)
+		binary.Read(r, binary.BigEndian, &e.MtimeNsec)
+		binary.Read(r, binary.BigEndian, &e.Dev)
+		binary.Read(r, binary.BigEndian, &e.Ino)
+		binary.Read(r, binary.BigEndian, &e.UID)
+		binary.Read(r, binary.BigEndian, &e.GID)
 		binary.Read(r, binary.BigEndian, &e.Size)
+	}
 
-		hashBytes := make([]byte, 20)
-		r.Read(hashBytes)
-		e.Hash = hex.EncodeToString(hashBytes)
-
-		binary.Read(r, binary.BigEndian, &e.Mode)
-
-		var pathLen uint16
-		binary.Read(r, binary.BigEndian, &pathLen)
-		pathBytes := make([]byte, pathLen)
-		r.Read(pathBytes)
-		e.Path = string(pathBytes)
-
-		// Read padding to 8-byte boundary
-		// Entry size so far: 4+4+4+20+4+2+pathLen = 38+pathLen
-		entryLen := 38 + int(pathLen)
-		padLen := (8 - (entryLen % 8)) % 8
-		if padLen > 0 {
-			pad := make([]byte, padLen)
-			r.Read(pad)
-		}
+	hashBytes := make([]byte, 20)
+	r.Read(hashBytes)
+	e.Hash = hex.EncodeToString(hashBytes)
 
-		idx.Entries = append(idx.Entries, e)
+	var modeAndStage uint32
+	binary.Read(r, binary.BigEndian, &modeAndStage)
+	e.Mode = modeAndStage & modeMask
+	e.Stage = int(modeAndStage>>stageShift) & 3
+
+	if version != IndexVersion1 {
+		binary.Read(r, binary.BigEndian, &e.Flags)
 	}
 
-	return idx, nil
+	var pathLen uint16
+	binary.Read(r, binary.BigEndian, &pathLen)
+	pathBytes := make([]byte, pathLen)
+	r.Read(pathBytes)
+	e.Path = string(pathBytes)
+
+	// Read padding to 8-byte boundary
+	padLen := entryPadding(fixedLen + int(pathLen))
+	if padLen > 0 {
+		pad := make([]byte, padLen)
+		r.Read(pad)
+	}
+	return e
 }
 
-// WriteIndex writes the index to disk.
+// WriteIndex writes the index to disk under index.lock, replacing the file
+// atomically. Commands that read the index before changing it should use
+// ReadIndexLocked instead, so that no other process changes it in between.
 func WriteIndex(root string, idx *Index) error {
-	sort.Slice(idx.Entries, func(i, j int) bool {
-		return idx.Entries[i].Path < idx.Entries[j].Path
-	})
+	lock, err := LockIndex(root)
+	if err != nil {
+		return err
+	}
+	defer lock.Rollback()
+	return lock.Commit(idx)
+}
+
+// encodeIndex serializes the index in idx.Version, or the default version
+// for a new index. A version 1 index is upgraded to version 2 when an entry
+// has flags, which version 1 cannot store.
+func encodeIndex(idx *Index) ([]byte, error) {
+	version := idx.Version
+	switch version {
+	case 0:
+		version = indexVersion
+	case IndexVersion1:
+		for _, e := range idx.Entries {
+			if e.Flags != 0 {
+				version = IndexVersion2
+				break
+			}
+		}
+	case IndexVersion2:
+	default:
+		return nil, fmt.Errorf("unsupported index version: %d", version)
+	}
+
+	idx.Sort()
+	idx.smudgeRacyEntries()
 
 	var buf bytes.Buffer
 
 	// Header
 	buf.WriteString(indexMagic)
-	binary.Write(&buf, binary.BigEndian, uint32(indexVersion))
+	binary.Write(&buf, binary.BigEndian, version)
 	binary.Write(&buf, binary.BigEndian, uint32(len(idx.Entries)))
 
 	for _, e := range idx.Entries {
-		binary.Write(&buf, binary.BigEndian, e.Ctime)
-		binary.Write(&buf, binary.BigEndian, e.Mtime)
-		binary.Write(&buf, binary.BigEndian, e.Size)
-
-		hashBytes, _ := hex.DecodeString(e.Hash)
-		buf.Write(hashBytes)
-
-		binary.Write(&buf, binary.BigEndian, e.Mode)
-		binary.Write(&buf, binary.BigEndian, uint16(len(e.Path)))
-		buf.WriteString(e.Path)
-
-		// Pad to 8-byte boundary
-		entryLen := 38 + len(e.Path)
-		padLen := (8 - (entryLen % 8)) % 8
-		for k := 0; k < padLen; k++ {
-			buf.WriteByte(0)
-		}
+		writeEntry(&buf, e, version)
+	}
+	if version == IndexVersion1 {
+		writeStatExtension(&buf, idx)
 	}
 
 	// Checksum
 	h := sha1.Sum(buf.Bytes())
 	buf.Write(h[:])
+	return buf.Bytes(), nil
+}
 
-	return os.WriteFile(repo.IndexPath(root), buf.Bytes(), 0644)
+// writeEntry writes one entry in the given format version, padded to a
+// multiple of 8 bytes. Version 1 truncates times and sizes to 32 bits.
+func writeEntry(buf *bytes.Buffer, e Entry, version uint32) {
+	fixedLen := entryFixedLenV2
+	if version == IndexVersion1 {
+		binary.Write(buf, binary.BigEndian, uint32(e.Ctime))
+		binary.Write(buf, binary.BigEndian, uint32(e.Mtime))
+		binary.Write(buf, binary.BigEndian, uint32(e.Size))
+		fixedLen = entryFixedLenV1
+	} else {
+		binary.Write(buf, binary.BigEndian, e.Ctime)
+		binary.Write(buf, binary.BigEndian, e.CtimeNsec)
+		binary.Write(buf, binary.BigEndian, e.Mtime)
+		binary.Write(buf, binary.BigEndian, e.MtimeNsec)
+		binary.Write(buf, binary.BigEndian, e.Dev)
+		binary.Write(buf, binary.BigEndian, e.Ino)
+		binary.Write(buf, binary.BigEndian, e.UID)
+		binary.Write(buf, binary.BigEndian, e.GID)
+		binary.Write(buf, binary.BigEndian, e.Size)
+	}
+
+	hashBytes, _ := hex.DecodeString(e.Hash)
+	buf.Write(hashBytes)
+
+	binary.Write(buf, binary.BigEndian, e.Mode&modeMask|uint32(e.Stage)<<stageShift)
+	if version != IndexVersion1 {
+		binary.Write(buf, binary.BigEndian, e.Flags)
+	}
+	binary.Write(buf, binary.BigEndian, uint16(len(e.Path)))
+	buf.WriteString(e.Path)
+
+	// Pad to 8-byte boundary
+	for k := entryPadding(fixedLen + len(e.Path)); k > 0; k-- {
+		buf.WriteByte(0)
+	}
 }
 
-// AddEntry adds or updates an entry in the index.
+// entryPadding returns the number of zero bytes that follow an entry of
+// entryLen bytes to align the next one to 8 bytes.
+func entryPadding(entryLen int) int {
+	return (8 - (entryLen % 8)) % 8
+}
+
+// entryLess orders entries by path, then stage.
+func entryLess(a, b *Entry) bool {
+	if a.Path != b.Path {
+		return a.Path < b.Path
+	}
+	return a.Stage < b.Stage
+}
+
+// Sort puts Entries in index order, if they are not already.
+func (idx *Index) Sort() {
+	less := func(i, j int) bool { return entryLess(&idx.Entries[i], &idx.Entries[j]) }
+	if !sort.SliceIsSorted(idx.Entries, less) {
+		sort.SliceStable(idx.Entries, less)
+	}
+}
+
+// find returns the position of the entry for path at stage, or where it
+// would be inserted, and whether it exists.
+func (idx *Index) find(path string, stage int) (int, bool) {
+	i := sort.Search(len(idx.Entries), func(i int) bool {
+		e := &idx.Entries[i]
+		return e.Path > path || e.Path == path && e.Stage >= stage
+	})
+	return i, i < len(idx.Entries) && idx.Entries[i].Path == path && idx.Entries[i].Stage == stage
+}
+
+// PrefixRange returns the half-open range of Entries whose paths start
+// with prefix. Paths sharing a prefix are adjacent in the sorted index.
+func (idx *Index) PrefixRange(prefix string) (lo, hi int) {
+	lo = sort.Search(len(idx.Entries), func(i int) bool {
+		return idx.Entries[i].Path >= prefix
+	})
+	hi = lo + sort.Search(len(idx.Entries)-lo, func(i int) bool {
+		p := idx.Entries[lo+i].Path
+		return len(p) < len(prefix) || p[:len(prefix)] != prefix
+	})
+	return lo, hi
+}
+
+// EntriesInDir returns the entries below directory dir, all stages
+// included, as a subslice of Entries. dir "" or "." selects every entry.
+func (idx *Index) EntriesInDir(dir string) []Entry {
+	if dir == "" || dir == "." {
+		return idx.Entries
+	}
+	lo, hi := idx.PrefixRange(dir + "/")
+	return idx.Entries[lo:hi]
+}
+
+// Tracked reports whether path is in the index, at any stage, or is a
+// directory containing tracked files.
+func (idx *Index) Tracked(path string) bool {
+	if i, _ := idx.find(path, StageMerged); i < len(idx.Entries) && idx.Entries[i].Path == path {
+		return true
+	}
+	return len(idx.EntriesInDir(path)) > 0
+}
+
+// addToGroup applies adding e to the entries of a single path, sorted by
+// stage: a stage 0 entry resolves any conflict by replacing every stage,
+// and a conflict stage replaces its own stage and the resolved entry.
+func addToGroup(group []Entry, e Entry) []Entry {
+	kept := group[:0]
+	for _, existing := range group {
+		if existing.Stage == e.Stage || e.Stage == StageMerged || existing.Stage == StageMerged {
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	i := sort.Search(len(kept), func(i int) bool { return kept[i].Stage > e.Stage })
+	return slices.Insert(kept, i, e)
+}
+
+// AddEntry adds or updates an entry in the index. Adding a stage 0 entry
+// resolves any conflict on the path by dropping its stage 1-3 entries;
+// adding a conflict stage drops the resolved entry. Adding many entries
+// one by one costs a shift of the entries after each; use AddEntries.
 func (idx *Index) AddEntry(e Entry) {
-	for i, existing := range idx.Entries {
-		if existing.Path == e.Path {
-			idx.Entries[i] = e
-			return
+	lo, _ := idx.find(e.Path, 0)
+	hi, _ := idx.find(e.Path, StageTheirs+1)
+	group := addToGroup(slices.Clone(idx.Entries[lo:hi]), e)
+	idx.Entries = slices.Replace(idx.Entries, lo, hi, group...)
+}
+
+// AddEntries adds or updates many entries with the rules of AddEntry, in
+// a single merge pass over the index. When entries holds the same path
+// more than once, they are applied in order.
+func (idx *Index) AddEntries(entries []Entry) {
+	added := slices.Clone(entries)
+	sort.SliceStable(added, func(i, j int) bool { return added[i].Path < added[j].Path })
+
+	merged := make([]Entry, 0, len(idx.Entries)+len(added))
+	i := 0
+	for j := 0; j < len(added); {
+		path := added[j].Path
+		for i < len(idx.Entries) && idx.Entries[i].Path < path {
+			merged = append(merged, idx.Entries[i])
+			i++
 		}
+		var group []Entry
+		for i < len(idx.Entries) && idx.Entries[i].Path == path {
+			group = append(group, idx.Entries[i])
+			i++
+		}
+		for ; j < len(added) && added[j].Path == path; j++ {
+			group = addToGroup(group, added[j])
+		}
+		merged = append(merged, group...)
 	}
-	idx.Entries = append(idx.Entries, e)
+	idx.Entries = append(merged, idx.Entries[i:]...)
 }
 
-// RemoveEntry removes an entry from the index by path.
+// RemoveEntry removes all entries for a path, including conflict stages.
 func (idx *Index) RemoveEntry(path string) {
-	for i, e := range idx.Entries {
-		if e.Path == path {
-			idx.Entries = append(idx.Entries[:i], idx.Entries[i+1:]...)
-			return
-		}
+	lo, _ := idx.find(path, 0)
+	hi, _ := idx.find(path, StageTheirs+1)
+	idx.Entries = slices.Delete(idx.Entries, lo, hi)
+}
+
+// RemoveEntries removes all entries for the given paths in a single pass.
+func (idx *Index) RemoveEntries(paths []string) {
+	remove := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		remove[p] = true
+	}
+	idx.Entries = slices.DeleteFunc(idx.Entries, func(e Entry) bool { return remove[e.Path] })
+}
+
+// RemoveDir removes every entry below directory dir.
+func (idx *Index) RemoveDir(dir string) {
+	if dir == "" || dir == "." {
+		idx.Entries = idx.Entries[:0]
+		return
 	}
+	lo, hi := idx.PrefixRange(dir + "/")
+	idx.Entries = slices.Delete(idx.Entries, lo, hi)
 }
 
-// LookupEntry finds an entry by path.
+// LookupEntry finds the resolved (stage 0) entry for a path.
 func (idx *Index) LookupEntry(path string) *Entry {
-	for i, e := range idx.Entries {
-		if e.Path == path {
-			return &idx.Entries[i]
+	return idx.LookupStage(path, StageMerged)
+}
+
+// LookupStage finds the entry for a path at the given stage.
+func (idx *Index) LookupStage(path string, stage int) *Entry {
+	if i, ok := idx.find(path, stage); ok {
+		return &idx.Entries[i]
+	}
+	return nil
+}
+
+// HasUnmerged reports whether any path still has conflict stages.
+func (idx *Index) HasUnmerged() bool {
+	for _, e := range idx.Entries {
+		if e.Stage != StageMerged {
+			return true
 		}
 	}
+	return false
+}
+
+// Unmerged returns the sorted list of paths that have conflict stages.
+func (idx *Index) Unmerged() []string {
+	var paths []string
+	for _, e := range idx.Entries {
+		if e.Stage != StageMerged && (len(paths) == 0 || paths[len(paths)-1] != e.Path) {
+			paths = append(paths, e.Path)
+		}
+	}
+	return paths
+}
+
+// ConflictStages returns the base, ours and theirs entries of an unmerged
+// path. Any of them may be nil, e.g. when the path was added on both sides
+// or deleted on one.
+func (idx *Index) ConflictStages(path string) (base, ours, theirs *Entry) {
+	return idx.LookupStage(path, StageBase), idx.LookupStage(path, StageOurs), idx.LookupStage(path, StageTheirs)
+}
+
+// Resolve collapses the conflict stages of a path by promoting the entry at
+// the given stage to stage 0. Resolving to a stage that does not exist
+// (e.g. ours after we deleted the file) removes the path.
+func (idx *Index) Resolve(path string, stage int) error {
+	if stage < StageBase || stage > StageTheirs {
+		return fmt.Errorf("invalid stage %d", stage)
+	}
+	if idx.LookupStage(path, StageBase) == nil && idx.LookupStage(path, StageOurs) == nil &&
+		idx.LookupStage(path, StageTheirs) == nil {
+		return fmt.Errorf("path '%s' is not unmerged", path)
+	}
+	chosen := idx.LookupStage(path, stage)
+	if chosen == nil {
+		idx.RemoveEntry(path)
+		return nil
+	}
+	e := *chosen
+	e.Stage = StageMerged
+	idx.AddEntry(e)
 	return nil
 }
@@ -2,7 +2,9 @@ package index
 
 import (
 	"crypto/sha1"
+	"bytes"
 	"encoding/binary"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -279,3 +281,401 @@ func TestReadIndex_PermissionError(t *testing.T) {
 		t.Fatal("expected error for unreadable index file")
 	}
 }
+
+func TestWriteAndReadIndex_Stages(t *testing.T) {
+	root := setupIndexDir(t)
+	idx := &Index{
+		Entries: []Entry{
+			{Hash: "3333333333333333333333333333333333333333", Mode: 0100644, Path: "c.txt", Stage: StageTheirs},
+			{Hash: "1111111111111111111111111111111111111111", Mode: 0100644, Path: "c.txt", Stage: StageBase},
+			{Hash: "2222222222222222222222222222222222222222", Mode: 0100755, Path: "c.txt", Stage: StageOurs},
+			{Hash: "aabbccddee00112233445566778899aabbccddee", Mode: 0100644, Path: "a.txt"},
+		},
+	}
+	if err := WriteIndex(root, idx); err != nil {
+		t.Fatalf("WriteIndex failed: %v", err)
+	}
+
+	got, err := ReadIndex(root)
+	if err != nil {
+		t.Fatalf("ReadIndex failed: %v", err)
+	}
+	if len(got.Entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(got.Entries))
+	}
+	wantStages := []int{StageMerged, StageBase, StageOurs, StageTheirs}
+	for i, e := range got.Entries {
+		if e.Stage != wantStages[i] {
+			t.Errorf("entry %d: expected stage %d, got %d", i, wantStages[i], e.Stage)
+		}
+	}
+	if got.Entries[2].Mode != 0100755 {
+		t.Errorf("mode should survive alongside the stage, got %o", got.Entries[2].Mode)
+	}
+}
+
+func conflictedIndex() *Index {
+	return &Index{Entries: []Entry{
+		{Hash: "1111111111111111111111111111111111111111", Path: "f", Stage: StageBase},
+		{Hash: "2222222222222222222222222222222222222222", Path: "f", Stage: StageOurs},
+		{Hash: "3333333333333333333333333333333333333333", Path: "f", Stage: StageTheirs},
+		{Hash: "4444444444444444444444444444444444444444", Path: "g"},
+	}}
+}
+
+func TestUnmerged(t *testing.T) {
+	idx := conflictedIndex()
+	if !idx.HasUnmerged() {
+		t.Error("expected unmerged entries")
+	}
+	paths := idx.Unmerged()
+	if len(paths) != 1 || paths[0] != "f" {
+		t.Errorf("unexpected unmerged paths: %v", paths)
+	}
+	if idx.LookupEntry("f") != nil {
+		t.Error("LookupEntry should only return resolved entries")
+	}
+
+	base, ours, theirs := idx.ConflictStages("f")
+	if base == nil || ours == nil || theirs == nil || ours.Hash[0] != '2' {
+		t.Error("expected all three conflict stages")
+	}
+
+	if (&Index{}).HasUnmerged() {
+		t.Error("empty index should have no unmerged entries")
+	}
+}
+
+func TestAddEntry_CollapsesStages(t *testing.T) {
+	idx := conflictedIndex()
+	idx.AddEntry(Entry{Hash: "5555555555555555555555555555555555555555", Path: "f"})
+
+	if idx.HasUnmerged() {
+		t.Error("adding a stage 0 entry should resolve the conflict")
+	}
+	if len(idx.Entries) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(idx.Entries))
+	}
+	if e := idx.LookupEntry("f"); e == nil || e.Hash[0] != '5' {
+		t.Error("resolved entry should be present")
+	}
+}
+
+func TestAddEntry_StageReplacesResolved(t *testing.T) {
+	idx := &Index{Entries: []Entry{{Hash: "1111111111111111111111111111111111111111", Path: "f"}}}
+	idx.AddEntry(Entry{Hash: "2222222222222222222222222222222222222222", Path: "f", Stage: StageOurs})
+	idx.AddEntry(Entry{Hash: "3333333333333333333333333333333333333333", Path: "f", Stage: StageTheirs})
+
+	if idx.LookupEntry("f") != nil {
+		t.Error("stage 0 entry should be dropped when conflict stages are added")
+	}
+	if len(idx.Entries) != 2 {
+		t.Errorf("expected 2 stage entries, got %d", len(idx.Entries))
+	}
+}
+
+func TestRemoveEntry_AllStages(t *testing.T) {
+	idx := conflictedIndex()
+	idx.RemoveEntry("f")
+	if len(idx.Entries) != 1 || idx.Entries[0].Path != "g" {
+		t.Errorf("expected only g to remain, got %v", idx.Entries)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	idx := conflictedIndex()
+	if err := idx.Resolve("f", StageTheirs); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	e := idx.LookupEntry("f")
+	if e == nil || e.Hash[0] != '3' || idx.HasUnmerged() {
+		t.Error("expected theirs to be promoted to stage 0")
+	}
+}
+
+func TestResolve_MissingStageRemovesPath(t *testing.T) {
+	idx := &Index{Entries: []Entry{
+		{Hash: "1111111111111111111111111111111111111111", Path: "f", Stage: StageBase},
+		{Hash: "3333333333333333333333333333333333333333", Path: "f", Stage: StageTheirs},
+	}}
+	if err := idx.Resolve("f", StageOurs); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if len(idx.Entries) != 0 {
+		t.Errorf("expected path to be removed, got %v", idx.Entries)
+	}
+}
+
+func TestResolve_Errors(t *testing.T) {
+	idx := conflictedIndex()
+	if err := idx.Resolve("f", 0); err == nil {
+		t.Error("expected error for invalid stage")
+	}
+	if err := idx.Resolve("g", StageOurs); err == nil {
+		t.Error("expected error for path without conflict")
+	}
+}
+
+func TestWriteIndex_DefaultVersion(t *testing.T) {
+	root := setupIndexDir(t)
+	WriteIndex(root, &Index{})
+	idx, err := ReadIndex(root)
+	if err != nil {
+		t.Fatalf("ReadIndex failed: %v", err)
+	}
+	if idx.Version != IndexVersion2 {
+		t.Errorf("expected version 2, got %d", idx.Version)
+	}
+}
+
+func TestWriteAndReadIndex_Version2(t *testing.T) {
+	root := setupIndexDir(t)
+	want := Entry{
+		Ctime: 5000000000, CtimeNsec: 999999999, Mtime: 5000000001, MtimeNsec: 1,
+		Dev: 1, Ino: 2, UID: 3, GID: 4, Size: 5 << 30,
+		Hash: "aabbccddee00112233445566778899aabbccddee", Mode: 0100755,
+		Flags: FlagAssumeUnchanged | FlagSkipWorktree, Path: "big.bin", Stage: StageOurs,
+	}
+	if err := WriteIndex(root, &Index{Entries: []Entry{want}}); err != nil {
+		t.Fatalf("WriteIndex failed: %v", err)
+	}
+
+	idx, err := ReadIndex(root)
+	if err != nil {
+		t.Fatalf("ReadIndex failed: %v", err)
+	}
+	if idx.Entries[0] != want {
+		t.Errorf("expected %+v, got %+v", want, idx.Entries[0])
+	}
+}
+
+func TestWriteIndex_Version1(t *testing.T) {
+	root := setupIndexDir(t)
+	idx := &Index{Version: IndexVersion1, Entries: []Entry{
+		{Mtime: 5000000000, Size: 5 << 30, Hash: "aabbccddee00112233445566778899aabbccddee", Mode: 0100644, Path: "big.bin"},
+	}}
+	WriteIndex(root, idx)
+
+	idx2, err := ReadIndex(root)
+	if err != nil {
+		t.Fatalf("ReadIndex failed: %v", err)
+	}
+	if idx2.Version != IndexVersion1 {
+		t.Errorf("expected version 1 to be kept, got %d", idx2.Version)
+	}
+	e := idx2.Entries[0]
+	if e.Mtime != 5000000000&0xffffffff || e.Size != (5<<30)&0xffffffff {
+		t.Errorf("expected 32-bit truncation, got mtime %d size %d", e.Mtime, e.Size)
+	}
+}
+
+func TestWriteIndex_Version1UpgradedForFlags(t *testing.T) {
+	root := setupIndexDir(t)
+	idx := &Index{Version: IndexVersion1, Entries: []Entry{
+		{Hash: "aabbccddee00112233445566778899aabbccddee", Mode: 0100644, Flags: FlagIntentToAdd, Path: "new.txt"},
+	}}
+	WriteIndex(root, idx)
+
+	idx2, _ := ReadIndex(root)
+	if idx2.Version != IndexVersion2 {
+		t.Errorf("expected upgrade to version 2, got %d", idx2.Version)
+	}
+	if !idx2.Entries[0].IntentToAdd() {
+		t.Error("expected intent-to-add flag to be kept")
+	}
+}
+
+func TestWriteIndex_UnsupportedVersion(t *testing.T) {
+	root := setupIndexDir(t)
+	if err := WriteIndex(root, &Index{Version: 3}); err == nil {
+		t.Error("expected error for unsupported version")
+	}
+}
+
+func indexPaths(idx *Index) []string {
+	var paths []string
+	for _, e := range idx.Entries {
+		paths = append(paths, fmt.Sprintf("%s:%d", e.Path, e.Stage))
+	}
+	return paths
+}
+
+func TestAddEntry_KeepsSorted(t *testing.T) {
+	idx := &Index{}
+	for _, p := range []string{"b", "a/z", "c", "a.txt", "a/b"} {
+		idx.AddEntry(Entry{Path: p})
+	}
+	got := fmt.Sprint(indexPaths(idx))
+	if want := "[a.txt:0 a/b:0 a/z:0 b:0 c:0]"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestAddEntries(t *testing.T) {
+	idx := conflictedIndex()
+	idx.AddEntry(Entry{Path: "a", Hash: "old"})
+	idx.AddEntries([]Entry{
+		{Path: "z"},
+		{Path: "a", Hash: "first"},
+		{Path: "f"},
+		{Path: "g", Stage: StageOurs},
+		{Path: "a", Hash: "second"},
+		{Path: "b"},
+	})
+
+	got := fmt.Sprint(indexPaths(idx))
+	if want := "[a:0 b:0 f:0 g:2 z:0]"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if e := idx.LookupEntry("a"); e == nil || e.Hash != "second" {
+		t.Errorf("the last entry for a path should win, got %+v", e)
+	}
+}
+
+func TestAddEntries_Empty(t *testing.T) {
+	idx := conflictedIndex()
+	idx.AddEntries(nil)
+	if len(idx.Entries) != 4 {
+		t.Errorf("expected index unchanged, got %v", indexPaths(idx))
+	}
+}
+
+func TestAddEntries_Many(t *testing.T) {
+	var entries []Entry
+	for i := 9999; i >= 0; i-- {
+		entries = append(entries, Entry{Path: fmt.Sprintf("dir%d/file%d", i%10, i)})
+	}
+	idx := &Index{}
+	idx.AddEntries(entries)
+	idx.AddEntries(entries[:100])
+
+	if len(idx.Entries) != 10000 {
+		t.Fatalf("expected 10000 entries, got %d", len(idx.Entries))
+	}
+	for i := 1; i < len(idx.Entries); i++ {
+		if !entryLess(&idx.Entries[i-1], &idx.Entries[i]) {
+			t.Fatalf("entries out of order at %d: %s, %s", i, idx.Entries[i-1].Path, idx.Entries[i].Path)
+		}
+	}
+	if len(idx.EntriesInDir("dir3")) != 1000 {
+		t.Errorf("expected 1000 entries in dir3, got %d", len(idx.EntriesInDir("dir3")))
+	}
+	if idx.LookupEntry("dir7/file1237") == nil {
+		t.Error("expected to find dir7/file1237")
+	}
+}
+
+func TestRemoveEntries(t *testing.T) {
+	idx := conflictedIndex()
+	idx.AddEntries([]Entry{{Path: "a"}, {Path: "b"}})
+	idx.RemoveEntries([]string{"f", "b", "missing"})
+
+	got := fmt.Sprint(indexPaths(idx))
+	if want := "[a:0 g:0]"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func dirIndex() *Index {
+	idx := &Index{}
+	idx.AddEntries([]Entry{
+		{Path: "dir.txt"},
+		{Path: "dir/a"},
+		{Path: "dir/sub/b"},
+		{Path: "dir0/c"},
+		{Path: "di"},
+		{Path: "top"},
+	})
+	return idx
+}
+
+func TestEntriesInDir(t *testing.T) {
+	idx := dirIndex()
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"dir", "[dir/a:0 dir/sub/b:0]"},
+		{"dir/sub", "[dir/sub/b:0]"},
+		{"di", "[]"},
+		{"missing", "[]"},
+		{"", "[di:0 dir.txt:0 dir/a:0 dir/sub/b:0 dir0/c:0 top:0]"},
+		{".", "[di:0 dir.txt:0 dir/a:0 dir/sub/b:0 dir0/c:0 top:0]"},
+	}
+	for _, tt := range tests {
+		got := fmt.Sprint(indexPaths(&Index{Entries: idx.EntriesInDir(tt.dir)}))
+		if got != tt.want {
+			t.Errorf("EntriesInDir(%q) = %s, want %s", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestPrefixRange(t *testing.T) {
+	idx := dirIndex()
+	lo, hi := idx.PrefixRange("dir")
+	if lo != 1 || hi != 5 {
+		t.Errorf("expected range [1, 5), got [%d, %d)", lo, hi)
+	}
+	lo, hi = idx.PrefixRange("zzz")
+	if lo != hi {
+		t.Errorf("expected empty range, got [%d, %d)", lo, hi)
+	}
+}
+
+func TestTracked(t *testing.T) {
+	idx := dirIndex()
+	idx.AddEntry(Entry{Path: "conflict", Stage: StageTheirs})
+	for path, want := range map[string]bool{
+		"dir.txt":  true,
+		"dir":      true,
+		"dir/sub":  true,
+		"conflict": true,
+		"dir/s":    false,
+		"d":        false,
+		"nope":     false,
+	} {
+		if got := idx.Tracked(path); got != want {
+			t.Errorf("Tracked(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestRemoveDir(t *testing.T) {
+	idx := dirIndex()
+	idx.RemoveDir("dir")
+	got := fmt.Sprint(indexPaths(idx))
+	if want := "[di:0 dir.txt:0 dir0/c:0 top:0]"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	idx.RemoveDir(".")
+	if len(idx.Entries) != 0 {
+		t.Errorf("expected empty index, got %v", indexPaths(idx))
+	}
+}
+
+func TestReadIndex_SortsEntries(t *testing.T) {
+	root := setupIndexDir(t)
+	var buf bytes.Buffer
+	buf.WriteString(indexMagic)
+	binary.Write(&buf, binary.BigEndian, uint32(IndexVersion2))
+	binary.Write(&buf, binary.BigEndian, uint32(3))
+	for _, p := range []string{"c", "a", "b"} {
+		writeEntry(&buf, Entry{Hash: "1111111111111111111111111111111111111111", Mode: 0100644, Path: p}, IndexVersion2)
+	}
+	h := sha1.Sum(buf.Bytes())
+	buf.Write(h[:])
+	os.WriteFile(repo.IndexPath(root), buf.Bytes(), 0644)
+
+	idx, err := ReadIndex(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := fmt.Sprint(indexPaths(idx))
+	if want := "[a:0 b:0 c:0]"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if idx.LookupEntry("a") == nil {
+		t.Error("expected lookups to work after sorting")
+	}
+}
@@ -0,0 +1,61 @@
+package index
+
+import (
+	"gogit/lockfile"
+	"gogit/repo"
+)
+
+// Lock is held on index.lock while an index is changed. Only one process
+// can hold it; the new index is written into the lock file and renamed
+// over the index, so a crash never leaves a partly written index behind.
+type Lock struct {
+	file *lockfile.Lock
+}
+
+// LockIndex acquires index.lock. It fails, naming the lock file, while
+// another gogit process holds it.
+func LockIndex(root string) (*Lock, error) {
+	file, err := lockfile.Acquire(repo.IndexPath(root))
+	if err != nil {
+		return nil, err
+	}
+	return &Lock{file: file}, nil
+}
+
+// ReadIndexLocked acquires index.lock and reads the index while holding
+// it, for commands that modify the index they read. The caller writes the
+// result with Commit or releases the lock with Rollback.
+func ReadIndexLocked(root string) (*Index, *Lock, error) {
+	lock, err := LockIndex(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	idx, err := ReadIndex(root)
+	if err != nil {
+		lock.Rollback()
+		return nil, nil, err
+	}
+	return idx, lock, nil
+}
+
+// Commit writes idx into the lock file and renames it over the index,
+// releasing the lock. On failure the index is left unchanged. If idx could
+// not be encoded or written the lock is still held until Rollback;
+// otherwise it is released whether or not the rename succeeds, and Rollback
+// does nothing.
+func (l *Lock) Commit(idx *Index) error {
+	data, err := encodeIndex(idx)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(data); err != nil {
+		return err
+	}
+	return l.file.Commit()
+}
+
+// Rollback releases the lock without touching the index. It is safe to
+// call after Commit, so it can be deferred.
+func (l *Lock) Rollback() {
+	l.file.Rollback()
+}
@@ -0,0 +1,97 @@
+package index
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gogit/lockfile"
+	"gogit/repo"
+)
+
+func TestReadIndexLocked(t *testing.T) {
+	root := setupIndexDir(t)
+	WriteIndex(root, &Index{Entries: []Entry{
+		{Hash: "aabbccddee00112233445566778899aabbccddee", Mode: 0100644, Path: "a.txt"},
+	}})
+
+	idx, lock, err := ReadIndexLocked(root)
+	if err != nil {
+		t.Fatalf("ReadIndexLocked failed: %v", err)
+	}
+	defer lock.Rollback()
+
+	// A second writer is turned away while the lock is held.
+	err = WriteIndex(root, &Index{})
+	if err == nil || !strings.Contains(err.Error(), "another gogit process") {
+		t.Errorf("expected lock error, got %v", err)
+	}
+
+	idx.AddEntry(Entry{Hash: "1122334455667788990011223344556677889900", Mode: 0100644, Path: "b.txt"})
+	if err := lock.Commit(idx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if _, err := os.Stat(repo.IndexPath(root) + lockfile.Suffix); !os.IsNotExist(err) {
+		t.Error("expected lock file to be gone after Commit")
+	}
+
+	idx2, _ := ReadIndex(root)
+	if len(idx2.Entries) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(idx2.Entries))
+	}
+}
+
+func TestLock_Rollback(t *testing.T) {
+	root := setupIndexDir(t)
+	idx, lock, err := ReadIndexLocked(root)
+	if err != nil {
+		t.Fatalf("ReadIndexLocked failed: %v", err)
+	}
+	idx.AddEntry(Entry{Hash: "aabbccddee00112233445566778899aabbccddee", Mode: 0100644, Path: "a.txt"})
+	lock.Rollback()
+
+	if _, err := os.Stat(repo.IndexPath(root)); !os.IsNotExist(err) {
+		t.Error("rolled back index should not be written")
+	}
+	if err := WriteIndex(root, idx); err != nil {
+		t.Errorf("lock should be released after Rollback: %v", err)
+	}
+}
+
+func TestLock_CommitError(t *testing.T) {
+	root := setupIndexDir(t)
+	WriteIndex(root, &Index{})
+	lock, err := LockIndex(root)
+	if err != nil {
+		t.Fatalf("LockIndex failed: %v", err)
+	}
+	defer lock.Rollback()
+
+	if err := lock.Commit(&Index{Version: 9}); err == nil {
+		t.Fatal("expected error for unsupported version")
+	}
+	if _, err := os.Stat(repo.IndexPath(root) + lockfile.Suffix); err != nil {
+		t.Error("lock should still be held after a failed Commit")
+	}
+	lock.Rollback()
+	if _, err := ReadIndex(root); err != nil {
+		t.Errorf("index should be unchanged: %v", err)
+	}
+}
+
+func TestReadIndexLocked_Errors(t *testing.T) {
+	root := setupIndexDir(t)
+	os.WriteFile(repo.IndexPath(root)+lockfile.Suffix, nil, 0644)
+	if _, _, err := ReadIndexLocked(root); err == nil {
+		t.Error("expected error while the lock is held")
+	}
+	os.Remove(repo.IndexPath(root) + lockfile.Suffix)
+
+	os.WriteFile(repo.IndexPath(root), []byte("garbage"), 0644)
+	if _, _, err := ReadIndexLocked(root); err == nil {
+		t.Error("expected error for corrupt index")
+	}
+	if _, err := os.Stat(repo.IndexPath(root) + lockfile.Suffix); !os.IsNotExist(err) {
+		t.Error("lock should be released when reading fails")
+	}
+}
@@ -0,0 +1,136 @@
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
+)
+
+// statExtension is the signature of the index extension holding the stat
+// fields that do not fit the version 1 entry layout: nanoseconds, device,
+// inode, uid and gid. Indexes written before it existed simply lack it;
+// version 2 entries hold these fields themselves.
+const statExtension = "STAT"
+
+// statExtensionEntrySize is the number of bytes the extension holds per
+// entry: six 32-bit fields.
+const statExtensionEntrySize = 24
+
+// sysStat is the part of a file's stat data that os.FileInfo does not
+// expose portably; see the platform-specific fileSysStat.
+type sysStat struct {
+	ctime              time.Time
+	dev, ino, uid, gid uint32
+}
+
+// SetStat records info's stat data in the entry, so that a later
+// StatMatches can tell the file is unchanged without reading it.
+func (e *Entry) SetStat(info os.FileInfo) {
+	sys := fileSysStat(info)
+	mtime := info.ModTime()
+	e.Ctime = sys.ctime.Unix()
+	e.CtimeNsec = uint32(sys.ctime.Nanosecond())
+	e.Mtime = mtime.Unix()
+	e.MtimeNsec = uint32(mtime.Nanosecond())
+	e.Dev = sys.dev
+	e.Ino = sys.ino
+	e.UID = sys.uid
+	e.GID = sys.gid
+	e.Size = uint64(info.Size())
+}
+
+// StatMatches reports whether info has the stat data recorded in the
+// entry, i.e. whether the file has not been touched since it was staged.
+func (e *Entry) StatMatches(info os.FileInfo) bool {
+	if !info.Mode().IsRegular() || (e.Mode&0111 != 0) != (info.Mode()&0111 != 0) {
+		return false
+	}
+	var other Entry
+	other.SetStat(info)
+	return e.Ctime == other.Ctime && e.CtimeNsec == other.CtimeNsec &&
+		e.Mtime == other.Mtime && e.MtimeNsec == other.MtimeNsec &&
+		e.Dev == other.Dev && e.Ino == other.Ino &&
+		e.UID == other.UID && e.GID == other.GID &&
+		e.Size == other.Size
+}
+
+// IsRacy reports whether e is racily clean: its file was modified no
+// earlier than the index was written, so a change made within the same
+// timestamp granularity would leave the stat data unchanged. Such entries
+// must be compared by content even when StatMatches.
+func (idx *Index) IsRacy(e *Entry) bool {
+	if idx.timestamp.IsZero() {
+		return false
+	}
+	mtime := time.Unix(e.Mtime, int64(e.MtimeNsec))
+	return !mtime.Before(idx.timestamp)
+}
+
+// UpToDate reports whether the file described by info can be assumed to
+// match e without hashing its content.
+func (idx *Index) UpToDate(e *Entry, info os.FileInfo) bool {
+	return e.StatMatches(info) && !idx.IsRacy(e)
+}
+
+// smudgeRacyEntries clears the size of entries that are racy with respect
+// to the index being replaced. Once the new index is written with a later
+// timestamp they would no longer look racy, so a change that went unnoticed
+// behind matching stat data would be hidden for good; a zero size forces
+// the next check to compare content instead.
+func (idx *Index) smudgeRacyEntries() {
+	for i := range idx.Entries {
+		if idx.IsRacy(&idx.Entries[i]) {
+			idx.Entries[i].Size = 0
+		}
+	}
+}
+
+// writeStatExtension appends the STAT extension for the entries, in order.
+func writeStatExtension(buf *bytes.Buffer, idx *Index) {
+	if len(idx.Entries) == 0 {
+		return
+	}
+	buf.WriteString(statExtension)
+	binary.Write(buf, binary.BigEndian, uint32(len(idx.Entries)*statExtensionEntrySize))
+	for _, e := range idx.Entries {
+		for _, v := range []uint32{e.CtimeNsec, e.MtimeNsec, e.Dev, e.Ino, e.UID, e.GID} {
+			binary.Write(buf, binary.BigEndian, v)
+		}
+	}
+}
+
+// readExtensions parses the extensions between the last entry and the
+// checksum, each a 4-byte signature and a 32-bit length followed by its
+// data. Unknown extensions are skipped.
+func readExtensions(r *bytes.Reader, idx *Index) error {
+	for r.Len() > 20 {
+		if r.Len()-20 < 8 {
+			return fmt.Errorf("index extension header truncated")
+		}
+		sig := make([]byte, 4)
+		r.Read(sig)
+		var size uint32
+		binary.Read(r, binary.BigEndian, &size)
+		if int64(size) > int64(r.Len()-20) {
+			return fmt.Errorf("index extension %s truncated", sig)
+		}
+		data := make([]byte, size)
+		r.Read(data)
+
+		if string(sig) != statExtension || idx.Version != IndexVersion1 {
+			continue
+		}
+		if len(data) != len(idx.Entries)*statExtensionEntrySize {
+			return fmt.Errorf("index extension %s has %d bytes for %d entries", sig, len(data), len(idx.Entries))
+		}
+		for i := range idx.Entries {
+			e := &idx.Entries[i]
+			for j, field := range []*uint32{&e.CtimeNsec, &e.MtimeNsec, &e.Dev, &e.Ino, &e.UID, &e.GID} {
+				*field = binary.BigEndian.Uint32(data[i*statExtensionEntrySize+j*4:])
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package index
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func fileSysStat(info os.FileInfo) sysStat {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return sysStat{ctime: info.ModTime()}
+	}
+	return sysStat{
+		ctime: time.Unix(st.Ctimespec.Sec, st.Ctimespec.Nsec),
+		dev:   uint32(st.Dev),
+		ino:   uint32(st.Ino),
+		uid:   st.Uid,
+		gid:   st.Gid,
+	}
+}
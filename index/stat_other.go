@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package index
+
+import "os"
+
+// fileSysStat falls back to the modification time as change time where
+// the platform's stat data is not available; device, inode and owner are
+// then left zero and never cause a mismatch.
+func fileSysStat(info os.FileInfo) sysStat {
+	return sysStat{ctime: info.ModTime()}
+}
@@ -0,0 +1,214 @@
+package index
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gogit/repo"
+)
+
+func writeStatFile(t *testing.T, content string) (string, os.FileInfo) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "f.txt")
+	os.WriteFile(path, []byte(content), 0644)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, info
+}
+
+func TestSetStat(t *testing.T) {
+	_, info := writeStatFile(t, "hello")
+	var e Entry
+	e.SetStat(info)
+
+	mtime := info.ModTime()
+	if e.Mtime != mtime.Unix() || e.MtimeNsec != uint32(mtime.Nanosecond()) {
+		t.Errorf("mtime not recorded: %d.%d vs %v", e.Mtime, e.MtimeNsec, mtime)
+	}
+	if e.Size != 5 {
+		t.Errorf("expected size 5, got %d", e.Size)
+	}
+	if e.Ctime == 0 {
+		t.Error("expected ctime to be recorded")
+	}
+}
+
+func TestStatMatches(t *testing.T) {
+	path, info := writeStatFile(t, "hello")
+	e := Entry{Mode: 0100644}
+	e.SetStat(info)
+	if !e.StatMatches(info) {
+		t.Error("expected stat data to match")
+	}
+
+	later := info.ModTime().Add(time.Second)
+	os.Chtimes(path, later, later)
+	touched, _ := os.Stat(path)
+	if e.StatMatches(touched) {
+		t.Error("expected a changed mtime not to match")
+	}
+
+	e.SetStat(touched)
+	os.Chmod(path, 0755)
+	chmodded, _ := os.Stat(path)
+	e.SetStat(chmodded)
+	if e.StatMatches(chmodded) {
+		t.Error("expected a changed executable bit not to match")
+	}
+
+	e.SetStat(info)
+	e.Size = 0
+	if e.StatMatches(info) {
+		t.Error("expected a smudged size not to match")
+	}
+
+	dirInfo, _ := os.Stat(filepath.Dir(path))
+	e.SetStat(dirInfo)
+	if e.StatMatches(dirInfo) {
+		t.Error("a directory never matches a file entry")
+	}
+}
+
+func TestIsRacy(t *testing.T) {
+	_, info := writeStatFile(t, "hello")
+	var e Entry
+	e.SetStat(info)
+
+	idx := &Index{}
+	if idx.IsRacy(&e) {
+		t.Error("an index never read from disk has no racy entries")
+	}
+	idx.timestamp = info.ModTime()
+	if !idx.IsRacy(&e) {
+		t.Error("an entry modified at the index timestamp is racy")
+	}
+	if idx.UpToDate(&e, info) {
+		t.Error("a racy entry is not up to date")
+	}
+	idx.timestamp = info.ModTime().Add(time.Nanosecond)
+	if idx.IsRacy(&e) {
+		t.Error("an entry older than the index is not racy")
+	}
+	if !idx.UpToDate(&e, info) {
+		t.Error("expected entry to be up to date")
+	}
+}
+
+func TestWriteIndex_SmudgesRacyEntries(t *testing.T) {
+	root := setupIndexDir(t)
+	idx := &Index{Entries: []Entry{
+		{Mtime: 100, Size: 5, Hash: "aabbccddee00112233445566778899aabbccddee", Mode: 0100644, Path: "old.txt"},
+		{Mtime: 300, Size: 5, Hash: "aabbccddee00112233445566778899aabbccddee", Mode: 0100644, Path: "racy.txt"},
+	}}
+	idx.timestamp = time.Unix(200, 0)
+	if err := WriteIndex(root, idx); err != nil {
+		t.Fatalf("WriteIndex failed: %v", err)
+	}
+
+	idx2, _ := ReadIndex(root)
+	if idx2.LookupEntry("old.txt").Size != 5 {
+		t.Error("entry older than the index should keep its size")
+	}
+	if idx2.LookupEntry("racy.txt").Size != 0 {
+		t.Error("racy entry should be smudged")
+	}
+}
+
+func TestReadIndex_SetsTimestamp(t *testing.T) {
+	root := setupIndexDir(t)
+	WriteIndex(root, &Index{})
+	stamp := time.Unix(1000, 500)
+	os.Chtimes(repo.IndexPath(root), stamp, stamp)
+
+	idx, err := ReadIndex(root)
+	if err != nil {
+		t.Fatalf("ReadIndex failed: %v", err)
+	}
+	if !idx.timestamp.Equal(stamp) {
+		t.Errorf("expected timestamp %v, got %v", stamp, idx.timestamp)
+	}
+}
+
+func TestWriteAndReadIndex_StatExtension(t *testing.T) {
+	root := setupIndexDir(t)
+	want := Entry{
+		Ctime: 1, CtimeNsec: 2, Mtime: 3, MtimeNsec: 4, Dev: 5, Ino: 6, UID: 7, GID: 8, Size: 9,
+		Hash: "aabbccddee00112233445566778899aabbccddee", Mode: 0100644, Path: "f.txt",
+	}
+	WriteIndex(root, &Index{Entries: []Entry{want}, Version: IndexVersion1})
+
+	idx, err := ReadIndex(root)
+	if err != nil {
+		t.Fatalf("ReadIndex failed: %v", err)
+	}
+	if idx.Version != IndexVersion1 || idx.Entries[0] != want {
+		t.Errorf("expected %+v, got %+v", want, idx.Entries[0])
+	}
+}
+
+// writeRawIndex writes a version 1 index with one entry for "f.txt",
+// followed by extra bytes and a valid checksum.
+func writeRawIndex(t *testing.T, root string, extra []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString(indexMagic)
+	binary.Write(&buf, binary.BigEndian, uint32(1))
+	binary.Write(&buf, binary.BigEndian, uint32(1))
+	binary.Write(&buf, binary.BigEndian, []uint32{1, 2, 3})
+	buf.Write(make([]byte, 20))
+	binary.Write(&buf, binary.BigEndian, uint32(0100644))
+	binary.Write(&buf, binary.BigEndian, uint16(5))
+	buf.WriteString("f.txt")
+	buf.Write(make([]byte, 5)) // pad 43 bytes to 48
+	buf.Write(extra)
+	h := sha1.Sum(buf.Bytes())
+	buf.Write(h[:])
+	os.WriteFile(repo.IndexPath(root), buf.Bytes(), 0644)
+}
+
+func TestReadIndex_WithoutStatExtension(t *testing.T) {
+	root := setupIndexDir(t)
+	writeRawIndex(t, root, nil)
+
+	idx, err := ReadIndex(root)
+	if err != nil {
+		t.Fatalf("ReadIndex failed: %v", err)
+	}
+	e := idx.Entries[0]
+	if e.Path != "f.txt" || e.Mtime != 2 || e.MtimeNsec != 0 || e.Ino != 0 {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
+
+func TestReadIndex_SkipsUnknownExtension(t *testing.T) {
+	root := setupIndexDir(t)
+	writeRawIndex(t, root, []byte("TREE\x00\x00\x00\x02ab"))
+
+	if _, err := ReadIndex(root); err != nil {
+		t.Fatalf("unknown extension should be skipped: %v", err)
+	}
+}
+
+func TestReadIndex_BadExtension(t *testing.T) {
+	tests := map[string][]byte{
+		"truncated header": []byte("STA"),
+		"truncated data":   []byte("TREE\x00\x00\x00\x09ab"),
+		"wrong size":       []byte("STAT\x00\x00\x00\x04abcd"),
+	}
+	for name, extra := range tests {
+		t.Run(name, func(t *testing.T) {
+			root := setupIndexDir(t)
+			writeRawIndex(t, root, extra)
+			if _, err := ReadIndex(root); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Package lockfile updates files through an exclusively created
+// "<path>.lock" that is renamed into place, as git does.
+package lockfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Suffix is appended to a file's path to name its lock.
+const Suffix = ".lock"
+
+// Lock is an exclusive lock on a file, held while "<path>.lock" exists.
+type Lock struct {
+	path string
+	f    *os.File
+}
+
+// Acquire takes the lock for path, creating parent directories as needed.
+// It fails if the lock is already held.
+func Acquire(path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path+Suffix, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, fmt.Errorf("unable to create '%s': file exists; another gogit process seems to be running, or a previous one crashed (remove the file to continue)", path+Suffix)
+		}
+		return nil, err
+	}
+	return &Lock{path: path, f: f}, nil
+}
+
+// Path returns the path of the file the lock protects.
+func (l *Lock) Path() string {
+	return l.path
+}
+
+// Write appends data to the new content of the file.
+func (l *Lock) Write(data []byte) (int, error) {
+	if l.f == nil {
+		return 0, fmt.Errorf("lock for '%s' is not held", l.path)
+	}
+	return l.f.Write(data)
+}
+
+// Commit makes the written content visible by renaming the lock over the
+// file, releasing the lock. On failure the lock is released and the file is
+// left unchanged.
+func (l *Lock) Commit() error {
+	if l.f == nil {
+		return fmt.Errorf("lock for '%s' is not held", l.path)
+	}
+	f := l.f
+	l.f = nil
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), l.path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// Rollback releases the lock without touching the file. It is safe to call
+// after Commit, so it can be deferred.
+func (l *Lock) Rollback() {
+	if l.f == nil {
+		return
+	}
+	l.f.Close()
+	os.Remove(l.f.Name())
+	l.f = nil
+}
+
+// WriteFile replaces the content of path atomically under its lock.
+func WriteFile(path string, data []byte) error {
+	l, err := Acquire(path)
+	if err != nil {
+		return err
+	}
+	defer l.Rollback()
+	if _, err := l.Write(data); err != nil {
+		return err
+	}
+	return l.Commit()
+}
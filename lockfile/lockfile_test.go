@@ -0,0 +1,115 @@
+package lockfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteFile_ReplacesContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "file")
+	if err := WriteFile(path, []byte("one\n")); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := WriteFile(path, []byte("two\n")); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "two\n" {
+		t.Errorf("expected 'two', got %q", data)
+	}
+	if _, err := os.Stat(path + Suffix); !os.IsNotExist(err) {
+		t.Error("lock file should be gone after commit")
+	}
+}
+
+func TestAcquire_AlreadyLocked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	l, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer l.Rollback()
+
+	_, err = Acquire(path)
+	if err == nil || !strings.Contains(err.Error(), "file exists") {
+		t.Fatalf("expected lock contention error, got %v", err)
+	}
+	if err := WriteFile(path, []byte("x")); err == nil {
+		t.Fatal("expected WriteFile to fail while locked")
+	}
+}
+
+func TestRollback_LeavesFileUntouched(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(path, []byte("original"), 0644)
+
+	l, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	l.Write([]byte("partial"))
+	l.Rollback()
+	l.Rollback() // idempotent
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "original" {
+		t.Errorf("expected original content, got %q", data)
+	}
+	if _, err := os.Stat(path + Suffix); !os.IsNotExist(err) {
+		t.Error("lock file should be removed by rollback")
+	}
+	if l.Path() != path {
+		t.Errorf("unexpected path %s", l.Path())
+	}
+}
+
+func TestLock_UseAfterRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	l, _ := Acquire(path)
+	if err := l.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if _, err := l.Write([]byte("x")); err == nil {
+		t.Error("expected Write after Commit to fail")
+	}
+	if err := l.Commit(); err == nil {
+		t.Error("expected second Commit to fail")
+	}
+	l.Rollback() // no-op after commit
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("committed file should exist: %v", err)
+	}
+}
+
+func TestCommit_RenameError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	// A non-empty directory at the target cannot be replaced by a file.
+	os.MkdirAll(filepath.Join(path, "sub"), 0755)
+
+	err := WriteFile(path, []byte("x"))
+	if err == nil {
+		t.Fatal("expected error when target is a directory")
+	}
+	if _, err := os.Stat(path + Suffix); !os.IsNotExist(err) {
+		t.Error("lock file should be removed after a failed commit")
+	}
+}
+
+func TestAcquire_MkdirError(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "blocker"), []byte("x"), 0644)
+	if _, err := Acquire(filepath.Join(dir, "blocker", "file")); err == nil {
+		t.Fatal("expected error when parent is a file")
+	}
+}
+
+func TestAcquire_PermissionError(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ro")
+	os.MkdirAll(dir, 0555)
+	defer os.Chmod(dir, 0755)
+	if _, err := Acquire(filepath.Join(dir, "file")); err == nil {
+		t.Fatal("expected error in read-only directory")
+	}
+}
@@ -3,8 +3,11 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"gogit/cmd"
+	"gogit/index"
 )
 
 var osExit = os.Exit
@@ -24,11 +27,24 @@ func run(args []string) int {
 	case "init":
 		err = cmd.Init()
 	case "add":
-		if len(args) < 3 {
-			fmt.Fprintln(os.Stderr, "usage: gogit add <path>...")
+		force := false
+		var paths []string
+		for _, a := range args[2:] {
+			if a == "-f" || a == "--force" {
+				force = true
+			} else {
+				paths = append(paths, a)
+			}
+		}
+		if len(paths) == 0 {
+			fmt.Fprintln(os.Stderr, "usage: gogit add [-f] <path>...")
 			return 1
 		}
-		err = cmd.Add(args[2:])
+		if force {
+			err = cmd.AddForce(paths)
+		} else {
+			err = cmd.Add(paths)
+		}
 	case "status":
 		err = cmd.Status()
 	case "commit":
@@ -39,33 +55,128 @@ func run(args []string) int {
 				break
 			}
 		}
-		if msg == "" {
+		// A merge in progress supplies its prepared message.
+		if msg == "" && !cmd.MergeInProgress() {
 			fmt.Fprintln(os.Stderr, "usage: gogit commit -m \"message\"")
 			return 1
 		}
 		err = cmd.Commit(msg)
 	case "log":
-		err = cmd.Log()
+		var opts cmd.LogOptions
+		var revs []string
+		for _, a := range args[2:] {
+			if a == "--stat" {
+				opts.Stat = true
+			} else {
+				revs = append(revs, a)
+			}
+		}
+		err = cmd.Log(opts, revs...)
 	case "diff":
-		err = cmd.Diff()
+		return runDiff(args[2:])
+	case "apply":
+		return runApply(args[2:])
 	case "branch":
-		name := ""
-		if len(args) >= 3 {
-			name = args[2]
-		}
-		err = cmd.Branch(name)
+		return runBranch(args[2:])
+	case "tag":
+		return runTag(args[2:])
 	case "checkout":
 		if len(args) < 3 {
-			fmt.Fprintln(os.Stderr, "usage: gogit checkout <branch>")
+			fmt.Fprintln(os.Stderr, "usage: gogit checkout <branch> | <commit>")
 			return 1
 		}
 		err = cmd.Checkout(args[2])
 	case "merge":
 		if len(args) < 3 {
-			fmt.Fprintln(os.Stderr, "usage: gogit merge <branch>")
+			fmt.Fprintln(os.Stderr, "usage: gogit merge <branch> | --continue | --abort")
+			return 1
+		}
+		switch args[2] {
+		case "--continue":
+			err = cmd.MergeContinue()
+		case "--abort":
+			err = cmd.MergeAbort()
+		default:
+			err = cmd.Merge(args[2])
+		}
+	case "merge-base":
+		return runMergeBase(args[2:])
+	case "rev-parse":
+		verify, short := false, false
+		var revs []string
+		for _, a := range args[2:] {
+			switch a {
+			case "--verify":
+				verify = true
+			case "--short":
+				short = true
+			default:
+				revs = append(revs, a)
+			}
+		}
+		if len(revs) == 0 {
+			fmt.Fprintln(os.Stderr, "usage: gogit rev-parse [--verify] [--short] <revision>...")
 			return 1
 		}
-		err = cmd.Merge(args[2])
+		err = cmd.RevParse(revs, verify, short)
+	case "update-index":
+		return runUpdateIndex(args[2:])
+	case "check-ignore":
+		verbose, noIndex := false, false
+		var paths []string
+		for _, a := range args[2:] {
+			switch a {
+			case "-v", "--verbose":
+				verbose = true
+			case "--no-index":
+				noIndex = true
+			default:
+				paths = append(paths, a)
+			}
+		}
+		if len(paths) == 0 {
+			fmt.Fprintln(os.Stderr, "usage: gogit check-ignore [-v] [--no-index] <path>...")
+			return 1
+		}
+		ignored, err := cmd.CheckIgnore(paths, verbose, noIndex)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		if !ignored {
+			return 1
+		}
+		return 0
+	case "check-ref-format":
+		allowOneLevel, branch := false, false
+		var names []string
+		for _, a := range args[2:] {
+			switch a {
+			case "--allow-onelevel":
+				allowOneLevel = true
+			case "--branch":
+				branch = true
+			default:
+				names = append(names, a)
+			}
+		}
+		if len(names) != 1 {
+			fmt.Fprintln(os.Stderr, "usage: gogit check-ref-format [--allow-onelevel] <refname> | --branch <branchname>")
+			return 1
+		}
+		err = cmd.CheckRefFormat(names[0], allowOneLevel, branch)
+	case "config":
+		return runConfig(args[2:])
+	case "reflog":
+		name := ""
+		if len(args) >= 3 {
+			name = args[2]
+		}
+		err = cmd.Reflog(name)
+	case "gc":
+		err = cmd.GC()
+	case "repack":
+		err = cmd.Repack()
 	default:
 		fmt.Fprintf(os.Stderr, "unknown command: %s\n", args[1])
 		usage()
@@ -79,6 +190,418 @@ func run(args []string) int {
 	return 0
 }
 
+func runMergeBase(args []string) int {
+	all, isAncestor := false, false
+	var revs []string
+	for _, a := range args {
+		switch a {
+		case "--all":
+			all = true
+		case "--is-ancestor":
+			isAncestor = true
+		default:
+			revs = append(revs, a)
+		}
+	}
+	if len(revs) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: gogit merge-base [--all | --is-ancestor] <commit> <commit>")
+		return 1
+	}
+
+	if isAncestor {
+		ok, err := cmd.MergeBaseIsAncestor(revs[0], revs[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		if !ok {
+			return 1
+		}
+		return 0
+	}
+
+	if err := cmd.MergeBase(revs[0], revs[1], all); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runBranch(args []string) int {
+	var mode string
+	force, verbose := false, false
+	var names []string
+	for _, a := range args {
+		switch a {
+		case "-d", "-m":
+			mode = a
+		case "-D", "-M":
+			mode, force = strings.ToLower(a), true
+		case "-f", "--force":
+			force = true
+		case "-v", "--verbose":
+			verbose = true
+		default:
+			names = append(names, a)
+		}
+	}
+
+	var err error
+	switch {
+	case mode == "-d":
+		if len(names) == 0 {
+			fmt.Fprintln(os.Stderr, "usage: gogit branch -d | -D <branch>...")
+			return 1
+		}
+		if err := cmd.BranchDelete(names, force); err != nil {
+			printErrors(err)
+			return 1
+		}
+		return 0
+	case mode == "-m":
+		switch len(names) {
+		case 1:
+			err = cmd.BranchRename("", names[0], force)
+		case 2:
+			err = cmd.BranchRename(names[0], names[1], force)
+		default:
+			fmt.Fprintln(os.Stderr, "usage: gogit branch -m | -M [<old>] <new>")
+			return 1
+		}
+	case len(names) == 0:
+		err = cmd.BranchList(verbose)
+	case len(names) > 2:
+		fmt.Fprintln(os.Stderr, "usage: gogit branch [-f] <name> [<start>]")
+		return 1
+	default:
+		start := ""
+		if len(names) == 2 {
+			start = names[1]
+		}
+		if force {
+			err = cmd.BranchForce(names[0], start)
+		} else {
+			err = cmd.BranchAt(names[0], start)
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runTag(args []string) int {
+	list, del, annotate, force := false, false, false, false
+	message := ""
+	var names []string
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; a {
+		case "-l", "--list":
+			list = true
+		case "-d", "--delete":
+			del = true
+		case "-a", "--annotate":
+			annotate = true
+		case "-f", "--force":
+			force = true
+		case "-m":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "error: option -m requires a value")
+				return 1
+			}
+			message = args[i+1]
+			i++
+		default:
+			names = append(names, a)
+		}
+	}
+
+	var err error
+	switch {
+	case del:
+		if len(names) == 0 {
+			fmt.Fprintln(os.Stderr, "usage: gogit tag -d <tagname>...")
+			return 1
+		}
+		if err := cmd.TagDelete(names); err != nil {
+			printErrors(err)
+			return 1
+		}
+		return 0
+	case list || len(names) == 0:
+		pattern := ""
+		if len(names) > 0 {
+			pattern = names[0]
+		}
+		err = cmd.TagList(pattern)
+	case len(names) > 2 || (annotate && message == ""):
+		fmt.Fprintln(os.Stderr, "usage: gogit tag [-a -m <msg>] [-f] <tagname> [<rev>]")
+		return 1
+	default:
+		target := ""
+		if len(names) == 2 {
+			target = names[1]
+		}
+		err = cmd.TagCreate(names[0], target, message, force)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runConfig(args []string) int {
+	var loc cmd.ConfigLocation
+	action := ""
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; a {
+		case "--system", "--global", "--local":
+			loc.Scope = strings.TrimPrefix(a, "--")
+		case "-f", "--file":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "error: option --file requires a value")
+				return 1
+			}
+			loc.File = args[i+1]
+			i++
+		case "--get", "--get-all", "--add", "--unset", "--unset-all", "-l", "--list":
+			action = a
+		default:
+			rest = append(rest, a)
+		}
+	}
+	if action == "" {
+		action = "--get"
+		if len(rest) == 2 {
+			action = "--set"
+		}
+	}
+
+	want := map[string]int{"--get": 1, "--get-all": 1, "--set": 2, "--add": 2, "--unset": 1, "--unset-all": 1, "-l": 0, "--list": 0}
+	if len(rest) != want[action] {
+		fmt.Fprintln(os.Stderr, "usage: gogit config [--system | --global | --local | --file <path>] (<key> [<value>] | --get-all <key> | --add <key> <value> | --unset[-all] <key> | --list)")
+		return 1
+	}
+
+	var err error
+	switch action {
+	case "--get", "--get-all":
+		var found bool
+		found, err = cmd.ConfigGet(loc, rest[0], action == "--get-all")
+		if err == nil && !found {
+			return 1
+		}
+	case "--set", "--add":
+		err = cmd.ConfigSet(loc, rest[0], rest[1], action == "--add")
+	case "--unset", "--unset-all":
+		err = cmd.ConfigUnset(loc, rest[0], action == "--unset-all")
+	default:
+		err = cmd.ConfigList(loc)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runUpdateIndex(args []string) int {
+	var opts cmd.UpdateIndexOptions
+	flags := map[string]uint16{
+		"assume-unchanged": index.FlagAssumeUnchanged,
+		"skip-worktree":    index.FlagSkipWorktree,
+		"intent-to-add":    index.FlagIntentToAdd,
+	}
+	var paths []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			paths = append(paths, args[i+1:]...)
+			break
+		}
+		if a == "--index-version" {
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "error: option --index-version requires a value")
+				return 1
+			}
+			v, err := strconv.ParseUint(args[i+1], 10, 32)
+			if err != nil || v == 0 {
+				fmt.Fprintf(os.Stderr, "error: invalid index version '%s'\n", args[i+1])
+				return 1
+			}
+			opts.Version = uint32(v)
+			i++
+			continue
+		}
+		switch {
+		case strings.HasPrefix(a, "--no-") && flags[a[len("--no-"):]] != 0:
+			flag := flags[a[len("--no-"):]]
+			opts.Clear |= flag
+			opts.Set &^= flag
+		case strings.HasPrefix(a, "--") && flags[a[len("--"):]] != 0:
+			flag := flags[a[len("--"):]]
+			opts.Set |= flag
+			opts.Clear &^= flag
+		default:
+			paths = append(paths, a)
+		}
+	}
+	if len(paths) == 0 && opts.Version == 0 {
+		fmt.Fprintln(os.Stderr, "usage: gogit update-index [--[no-]assume-unchanged] [--[no-]skip-worktree] [--[no-]intent-to-add] [--index-version <n>] [--] <path>...")
+		return 1
+	}
+
+	if err := cmd.UpdateIndex(paths, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runDiff(args []string) int {
+	var opts cmd.DiffOptions
+	var revs []string
+	var err error
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--diff-algorithm":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "error: option --diff-algorithm requires a value")
+				return 1
+			}
+			opts.Algorithm = args[i+1]
+			i++
+		case strings.HasPrefix(a, "--diff-algorithm="):
+			opts.Algorithm = a[len("--diff-algorithm="):]
+		case a == "--cached" || a == "--staged":
+			opts.Cached = true
+		case a == "--binary":
+			opts.Binary = true
+		case a == "-a" || a == "--text":
+			opts.Text = true
+		case a == "--stat":
+			opts.Format = cmd.DiffStat
+		case strings.HasPrefix(a, "--stat="):
+			opts.Format = cmd.DiffStat
+			if opts.StatWidth, err = strconv.Atoi(a[len("--stat="):]); err != nil || opts.StatWidth <= 0 {
+				fmt.Fprintf(os.Stderr, "error: invalid --stat width '%s'\n", a[len("--stat="):])
+				return 1
+			}
+		case a == "--numstat":
+			opts.Format = cmd.DiffNumStat
+		case a == "--shortstat":
+			opts.Format = cmd.DiffShortStat
+		case a == "--name-only":
+			opts.Format = cmd.DiffNameOnly
+		case a == "--name-status":
+			opts.Format = cmd.DiffNameStatus
+		case a == "--no-renames":
+			opts.Renames = "false"
+		case strings.HasPrefix(a, "-M"), strings.HasPrefix(a, "--find-renames"):
+			if opts.RenameThreshold, err = parseSimilarity(renameScore(a, "-M", "--find-renames")); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return 1
+			}
+			opts.Renames = "true"
+		case strings.HasPrefix(a, "-C"), strings.HasPrefix(a, "--find-copies"):
+			if opts.RenameThreshold, err = parseSimilarity(renameScore(a, "-C", "--find-copies")); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return 1
+			}
+			opts.Renames = "copies"
+		case a == "--":
+			opts.Paths = append(opts.Paths, args[i+1:]...)
+			i = len(args)
+		default:
+			revs = append(revs, a)
+		}
+	}
+
+	if err := cmd.Diff(opts, revs...); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runApply(args []string) int {
+	var opts cmd.ApplyOptions
+	var files []string
+	for _, a := range args {
+		switch {
+		case a == "--check":
+			opts.Check = true
+		case a == "--cached":
+			opts.Cached = true
+		case a == "--index":
+			opts.Index = true
+		case a == "-3" || a == "--3way":
+			opts.ThreeWay = true
+		case a == "-R" || a == "--reverse":
+			opts.Reverse = true
+		case strings.HasPrefix(a, "-C"):
+			n, err := strconv.Atoi(a[len("-C"):])
+			if err != nil || n < 0 {
+				fmt.Fprintf(os.Stderr, "error: invalid context '%s'\n", a[len("-C"):])
+				return 1
+			}
+			opts.Fuzz, opts.MinContext = true, n
+		case a != "-" && strings.HasPrefix(a, "-"):
+			fmt.Fprintln(os.Stderr, "usage: gogit apply [--check] [--cached | --index] [-3] [-R] [-C<n>] [<patch>...]")
+			return 1
+		default:
+			files = append(files, a)
+		}
+	}
+
+	if err := cmd.Apply(opts, files...); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// renameScore returns the score given to -M or -C, in its short form
+// ("-M50%") or its long one ("--find-renames=50%").
+func renameScore(arg, short, long string) string {
+	if score, ok := strings.CutPrefix(arg, long); ok {
+		return strings.TrimPrefix(score, "=")
+	}
+	return strings.TrimPrefix(arg, short)
+}
+
+// parseSimilarity parses the score of -M and -C as git does: "90%" is a
+// percentage, while digits alone are a fraction with the decimal point
+// before them, so "5" is 50% and "05" is 5%. An empty score gives zero, the
+// default.
+func parseSimilarity(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		n, err := strconv.Atoi(pct)
+		if err != nil || n < 0 || n > 100 {
+			return 0, fmt.Errorf("invalid similarity '%s'", s)
+		}
+		return n, nil
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("invalid similarity '%s'", s)
+		}
+	}
+	digits := (s + "00")[:3]
+	n, _ := strconv.Atoi(digits)
+	return min(n/10, 100), nil
+}
+
 func usage() {
 	fmt.Fprintln(os.Stderr, "usage: gogit <command> [<args>]")
 	fmt.Fprintln(os.Stderr, "")
@@ -89,7 +612,30 @@ func usage() {
 	fmt.Fprintln(os.Stderr, "  commit     Record changes to repository")
 	fmt.Fprintln(os.Stderr, "  log        Show commit history")
 	fmt.Fprintln(os.Stderr, "  diff       Show changes in working tree")
-	fmt.Fprintln(os.Stderr, "  branch     List or create branches")
-	fmt.Fprintln(os.Stderr, "  checkout   Switch branches")
+	fmt.Fprintln(os.Stderr, "  apply      Apply a patch to the working tree or index")
+	fmt.Fprintln(os.Stderr, "  branch     List, create, rename or delete branches")
+	fmt.Fprintln(os.Stderr, "  tag        Create, list or delete tags")
+	fmt.Fprintln(os.Stderr, "  checkout   Switch branches or detach HEAD at a commit")
 	fmt.Fprintln(os.Stderr, "  merge      Merge a branch")
+	fmt.Fprintln(os.Stderr, "  merge-base Find common ancestors of two commits")
+	fmt.Fprintln(os.Stderr, "  rev-parse  Resolve revision expressions to object names")
+	fmt.Fprintln(os.Stderr, "  config     Get and set repository or global options")
+	fmt.Fprintln(os.Stderr, "  reflog     Show the history of a ref")
+	fmt.Fprintln(os.Stderr, "  update-index Set index entry flags or the index format version")
+	fmt.Fprintln(os.Stderr, "  check-ignore Explain which ignore rule matches a path")
+	fmt.Fprintln(os.Stderr, "  check-ref-format Validate a ref or branch name")
+	fmt.Fprintln(os.Stderr, "  gc         Pack objects and clean up the repository")
+	fmt.Fprintln(os.Stderr, "  repack     Pack loose objects into a packfile")
+}
+
+// printErrors prints each of the errors joined in err on its own line, as
+// commands that go on past a failing argument return them.
+func printErrors(err error) {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, e := range errs {
+		fmt.Fprintf(os.Stderr, "error: %v\n", e)
+	}
 }
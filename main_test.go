@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"gogit/index"
 )
 
 func setupMainTestRepo(t *testing.T) string {
@@ -15,6 +17,8 @@ func setupMainTestRepo(t *testing.T) string {
 
 	t.Setenv("GOGIT_AUTHOR_NAME", "Test")
 	t.Setenv("GOGIT_AUTHOR_EMAIL", "test@test.com")
+	t.Setenv("GOGIT_CONFIG_GLOBAL", filepath.Join(dir, ".gogitconfig"))
+	t.Setenv("GOGIT_CONFIG_SYSTEM", filepath.Join(dir, ".gogitconfig-system"))
 
 	if code := run([]string{"gogit", "init"}); code != 0 {
 		t.Fatalf("init failed with code %d", code)
@@ -82,6 +86,31 @@ func TestRun_CommitNoMessage(t *testing.T) {
 	}
 }
 
+func TestRun_CommitMergeWithoutMessage(t *testing.T) {
+	dir := setupMainTestRepo(t)
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("base\n"), 0644)
+	run([]string{"gogit", "add", "f.txt"})
+	run([]string{"gogit", "commit", "-m", "init"})
+	run([]string{"gogit", "branch", "feature"})
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("main\n"), 0644)
+	run([]string{"gogit", "add", "f.txt"})
+	run([]string{"gogit", "commit", "-m", "main"})
+	run([]string{"gogit", "checkout", "feature"})
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("feature\n"), 0644)
+	run([]string{"gogit", "add", "f.txt"})
+	run([]string{"gogit", "commit", "-m", "feature"})
+	run([]string{"gogit", "checkout", "main"})
+	if code := run([]string{"gogit", "merge", "feature"}); code != 1 {
+		t.Fatalf("expected a conflict, got exit code %d", code)
+	}
+
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("resolved\n"), 0644)
+	run([]string{"gogit", "add", "f.txt"})
+	if code := run([]string{"gogit", "commit"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+}
+
 func TestRun_CommitNoMFlag(t *testing.T) {
 	setupMainTestRepo(t)
 	code := run([]string{"gogit", "commit", "no-flag"})
@@ -127,6 +156,13 @@ func TestRun_Log(t *testing.T) {
 	}
 }
 
+func TestRun_LogStat(t *testing.T) {
+	setupMainTestRepo(t)
+	if code := run([]string{"gogit", "log", "--stat"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+}
+
 func TestRun_Diff(t *testing.T) {
 	setupMainTestRepo(t)
 	code := run([]string{"gogit", "diff"})
@@ -135,6 +171,138 @@ func TestRun_Diff(t *testing.T) {
 	}
 }
 
+func TestRun_Apply(t *testing.T) {
+	dir := setupMainTestRepo(t)
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("one\n"), 0644)
+	run([]string{"gogit", "add", "f.txt"})
+	patch := filepath.Join(t.TempDir(), "change.patch")
+	os.WriteFile(patch, []byte("--- a/f.txt\n+++ b/f.txt\n@@ -1 +1 @@\n-one\n+two\n"), 0644)
+
+	for _, args := range [][]string{
+		{"apply", "--check", patch},
+		{"apply", patch},
+		{"apply", "-R", patch},
+		{"apply", "--cached", "-C1", patch},
+		{"apply", "-3", "--reverse", "--cached", patch},
+		{"apply", "--index", patch},
+		{"apply", "--reverse", "--index", patch},
+	} {
+		if code := run(append([]string{"gogit"}, args...)); code != 0 {
+			t.Errorf("%v: expected exit code 0, got %d", args, code)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "f.txt")); string(data) != "one\n" {
+		t.Errorf("unexpected content %q", data)
+	}
+	for _, args := range [][]string{
+		{"apply", "-R", patch},
+		{"apply", "-Cx", patch},
+		{"apply", "--unknown", patch},
+		{"apply", filepath.Join(dir, "missing.patch")},
+	} {
+		if code := run(append([]string{"gogit"}, args...)); code != 1 {
+			t.Errorf("%v: expected exit code 1, got %d", args, code)
+		}
+	}
+}
+
+func TestRun_DiffAlgorithm(t *testing.T) {
+	setupMainTestRepo(t)
+	for _, args := range [][]string{
+		{"gogit", "diff", "--diff-algorithm=patience"},
+		{"gogit", "diff", "--diff-algorithm", "histogram"},
+	} {
+		if code := run(args); code != 0 {
+			t.Errorf("%v: expected exit code 0, got %d", args, code)
+		}
+	}
+	if code := run([]string{"gogit", "diff", "--diff-algorithm=lcs"}); code != 1 {
+		t.Errorf("expected exit code 1 for an unknown algorithm, got %d", code)
+	}
+	if code := run([]string{"gogit", "diff", "--diff-algorithm"}); code != 1 {
+		t.Errorf("expected exit code 1 for a missing value, got %d", code)
+	}
+}
+
+func TestRun_DiffCachedAndPaths(t *testing.T) {
+	dir := setupMainTestRepo(t)
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("hi"), 0644)
+	run([]string{"gogit", "add", "f.txt"})
+	for _, args := range [][]string{
+		{"gogit", "diff", "--cached"},
+		{"gogit", "diff", "--staged", "--", "f.txt"},
+		{"gogit", "diff", "--", "f.txt", "missing.txt"},
+	} {
+		if code := run(args); code != 0 {
+			t.Errorf("%v: expected exit code 0, got %d", args, code)
+		}
+	}
+	run([]string{"gogit", "commit", "-m", "init"})
+	if code := run([]string{"gogit", "diff", "HEAD", "HEAD", "--", "f.txt"}); code != 0 {
+		t.Errorf("expected exit code 0 for two revisions, got %d", code)
+	}
+	if code := run([]string{"gogit", "diff", "--cached", "HEAD..HEAD"}); code != 1 {
+		t.Errorf("expected exit code 1 for a range with --cached, got %d", code)
+	}
+}
+
+func TestRun_DiffOptions(t *testing.T) {
+	setupMainTestRepo(t)
+	for _, args := range [][]string{
+		{"gogit", "diff", "-M"},
+		{"gogit", "diff", "-M90%"},
+		{"gogit", "diff", "--find-renames=5"},
+		{"gogit", "diff", "-C"},
+		{"gogit", "diff", "--find-copies"},
+		{"gogit", "diff", "--no-renames"},
+		{"gogit", "diff", "--binary"},
+		{"gogit", "diff", "-a"},
+		{"gogit", "diff", "--text"},
+		{"gogit", "diff", "--stat"},
+		{"gogit", "diff", "--stat=40"},
+		{"gogit", "diff", "--numstat"},
+		{"gogit", "diff", "--shortstat"},
+		{"gogit", "diff", "--name-only"},
+		{"gogit", "diff", "--name-status"},
+	} {
+		if code := run(args); code != 0 {
+			t.Errorf("%v: expected exit code 0, got %d", args, code)
+		}
+	}
+	for _, arg := range []string{"-Mx", "-M101%", "--find-copies=-1", "--stat=0", "--stat=wide"} {
+		if code := run([]string{"gogit", "diff", arg}); code != 1 {
+			t.Errorf("%s: expected exit code 1, got %d", arg, code)
+		}
+	}
+}
+
+func TestParseSimilarity(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"50%", 50},
+		{"100%", 100},
+		{"5", 50},
+		{"05", 5},
+		{"75", 75},
+		{"1", 10},
+		{"999", 99},
+	}
+	for _, tt := range tests {
+		got, err := parseSimilarity(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseSimilarity(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"x", "%", "-5", "200%", "5.0"} {
+		if _, err := parseSimilarity(in); err == nil {
+			t.Errorf("parseSimilarity(%q): expected error", in)
+		}
+	}
+}
+
 func TestRun_BranchNoArgs(t *testing.T) {
 	dir := setupMainTestRepo(t)
 	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("hi"), 0644)
@@ -201,6 +369,26 @@ func TestRun_Merge(t *testing.T) {
 	}
 }
 
+func TestRun_GC(t *testing.T) {
+	dir := setupMainTestRepo(t)
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("hi"), 0644)
+	run([]string{"gogit", "add", "f.txt"})
+	run([]string{"gogit", "commit", "-m", "init"})
+
+	code := run([]string{"gogit", "gc"})
+	if code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+}
+
+func TestRun_Repack(t *testing.T) {
+	setupMainTestRepo(t)
+	code := run([]string{"gogit", "repack"})
+	if code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+}
+
 func TestUsage(t *testing.T) {
 	// Just make sure it doesn't panic
 	usage()
@@ -229,3 +417,308 @@ func TestMainFunction(t *testing.T) {
 		t.Errorf("expected exit code 1, got %d", exitCode)
 	}
 }
+
+func TestRun_MergeAbortAndContinue(t *testing.T) {
+	setupMainTestRepo(t)
+	if code := run([]string{"gogit", "merge", "--abort"}); code != 1 {
+		t.Errorf("expected exit code 1 without a merge, got %d", code)
+	}
+	if code := run([]string{"gogit", "merge", "--continue"}); code != 1 {
+		t.Errorf("expected exit code 1 without a merge, got %d", code)
+	}
+}
+
+func TestRun_MergeBase(t *testing.T) {
+	dir := setupMainTestRepo(t)
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("hi"), 0644)
+	run([]string{"gogit", "add", "f.txt"})
+	run([]string{"gogit", "commit", "-m", "init"})
+	run([]string{"gogit", "branch", "feature"})
+
+	if code := run([]string{"gogit", "merge-base", "main", "feature"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	if code := run([]string{"gogit", "merge-base", "--all", "main", "feature"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	if code := run([]string{"gogit", "merge-base", "--is-ancestor", "main", "feature"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	if code := run([]string{"gogit", "merge-base", "main"}); code != 1 {
+		t.Errorf("expected exit code 1 for missing argument, got %d", code)
+	}
+	if code := run([]string{"gogit", "merge-base", "main", "nope"}); code != 1 {
+		t.Errorf("expected exit code 1 for bad revision, got %d", code)
+	}
+	if code := run([]string{"gogit", "merge-base", "--is-ancestor", "main", "nope"}); code != 1 {
+		t.Errorf("expected exit code 1 for bad revision, got %d", code)
+	}
+}
+
+func TestRun_MergeBaseNotAncestor(t *testing.T) {
+	dir := setupMainTestRepo(t)
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("hi"), 0644)
+	run([]string{"gogit", "add", "f.txt"})
+	run([]string{"gogit", "commit", "-m", "init"})
+	run([]string{"gogit", "branch", "feature"})
+	os.WriteFile(filepath.Join(dir, "g.txt"), []byte("g"), 0644)
+	run([]string{"gogit", "add", "g.txt"})
+	run([]string{"gogit", "commit", "-m", "second"})
+
+	if code := run([]string{"gogit", "merge-base", "--is-ancestor", "main", "feature"}); code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+}
+
+func TestRun_RevParse(t *testing.T) {
+	dir := setupMainTestRepo(t)
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("hi"), 0644)
+	run([]string{"gogit", "add", "f.txt"})
+	run([]string{"gogit", "commit", "-m", "init"})
+
+	if code := run([]string{"gogit", "rev-parse", "HEAD", "HEAD:f.txt"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	if code := run([]string{"gogit", "rev-parse", "--verify", "--short", "main"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	if code := run([]string{"gogit", "rev-parse"}); code != 1 {
+		t.Errorf("expected exit code 1 for missing revision, got %d", code)
+	}
+	if code := run([]string{"gogit", "rev-parse", "HEAD~5"}); code != 1 {
+		t.Errorf("expected exit code 1 for bad revision, got %d", code)
+	}
+}
+
+func TestRun_RevisionArguments(t *testing.T) {
+	dir := setupMainTestRepo(t)
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("hi"), 0644)
+	run([]string{"gogit", "add", "f.txt"})
+	run([]string{"gogit", "commit", "-m", "init"})
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("changed"), 0644)
+	run([]string{"gogit", "add", "f.txt"})
+	run([]string{"gogit", "commit", "-m", "second"})
+
+	for _, args := range [][]string{
+		{"gogit", "log", "HEAD~1..HEAD"},
+		{"gogit", "diff", "HEAD~1"},
+		{"gogit", "branch", "old", "HEAD~1"},
+		{"gogit", "checkout", "HEAD~1"},
+	} {
+		if code := run(args); code != 0 {
+			t.Errorf("%v: expected exit code 0, got %d", args[1:], code)
+		}
+	}
+}
+
+func TestRun_Reflog(t *testing.T) {
+	dir := setupMainTestRepo(t)
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("hi"), 0644)
+	run([]string{"gogit", "add", "f.txt"})
+	run([]string{"gogit", "commit", "-m", "init"})
+
+	if code := run([]string{"gogit", "reflog"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	if code := run([]string{"gogit", "reflog", "main"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	if code := run([]string{"gogit", "rev-parse", "HEAD@{0}"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	if code := run([]string{"gogit", "reflog", "nope"}); code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+}
+
+func TestRun_CheckRefFormat(t *testing.T) {
+	if code := run([]string{"gogit", "check-ref-format", "refs/heads/main"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	if code := run([]string{"gogit", "check-ref-format", "--allow-onelevel", "main"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	if code := run([]string{"gogit", "check-ref-format", "--branch", "feature/login"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	if code := run([]string{"gogit", "check-ref-format", "refs/heads/a..b"}); code != 1 {
+		t.Errorf("expected exit code 1 for invalid name, got %d", code)
+	}
+	if code := run([]string{"gogit", "check-ref-format"}); code != 1 {
+		t.Errorf("expected exit code 1 for missing name, got %d", code)
+	}
+}
+
+func TestRun_BranchManagement(t *testing.T) {
+	dir := setupMainTestRepo(t)
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("hi"), 0644)
+	run([]string{"gogit", "add", "f.txt"})
+	run([]string{"gogit", "commit", "-m", "init"})
+
+	for _, args := range [][]string{
+		{"gogit", "branch", "topic"},
+		{"gogit", "branch", "-v"},
+		{"gogit", "branch", "-f", "topic", "HEAD"},
+		{"gogit", "branch", "-m", "topic", "renamed"},
+		{"gogit", "branch", "-M", "trunk"},
+		{"gogit", "branch", "-d", "renamed"},
+		{"gogit", "branch", "--force", "other"},
+		{"gogit", "branch", "-D", "other"},
+	} {
+		if code := run(args); code != 0 {
+			t.Errorf("%v: expected exit code 0, got %d", args[1:], code)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"gogit", "branch", "-d"},
+		{"gogit", "branch", "-m"},
+		{"gogit", "branch", "-m", "a", "b", "c"},
+		{"gogit", "branch", "a", "b", "c"},
+		{"gogit", "branch", "-d", "trunk"},
+		{"gogit", "branch", "-d", "missing", "trunk"},
+	} {
+		if code := run(args); code != 1 {
+			t.Errorf("%v: expected exit code 1, got %d", args[1:], code)
+		}
+	}
+}
+
+func TestRun_Tag(t *testing.T) {
+	dir := setupMainTestRepo(t)
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("hi"), 0644)
+	run([]string{"gogit", "add", "f.txt"})
+	run([]string{"gogit", "commit", "-m", "init"})
+
+	for _, args := range [][]string{
+		{"gogit", "tag", "light"},
+		{"gogit", "tag", "-a", "-m", "release", "v1.0", "HEAD"},
+		{"gogit", "tag", "-m", "again", "-f", "v1.0"},
+		{"gogit", "tag"},
+		{"gogit", "tag", "-l", "v*"},
+		{"gogit", "log", "v1.0"},
+		{"gogit", "tag", "-d", "light", "v1.0"},
+	} {
+		if code := run(args); code != 0 {
+			t.Errorf("%v: expected exit code 0, got %d", args[1:], code)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"gogit", "tag", "-a", "v2"},
+		{"gogit", "tag", "v2", "-m"},
+		{"gogit", "tag", "a", "b", "c"},
+		{"gogit", "tag", "-d"},
+		{"gogit", "tag", "-d", "missing"},
+		{"gogit", "tag", "-d", "../heads/main", "missing"},
+	} {
+		if code := run(args); code != 1 {
+			t.Errorf("%v: expected exit code 1, got %d", args[1:], code)
+		}
+	}
+}
+
+func TestRun_Config(t *testing.T) {
+	setupMainTestRepo(t)
+
+	for _, args := range [][]string{
+		{"gogit", "config", "user.name", "Jane"},
+		{"gogit", "config", "user.name"},
+		{"gogit", "config", "--get", "user.name"},
+		{"gogit", "config", "--global", "user.email", "jane@example.com"},
+		{"gogit", "config", "--add", "remote.origin.fetch", "a"},
+		{"gogit", "config", "--add", "remote.origin.fetch", "b"},
+		{"gogit", "config", "--get-all", "remote.origin.fetch"},
+		{"gogit", "config", "--unset-all", "remote.origin.fetch"},
+		{"gogit", "config", "--local", "--unset", "user.name"},
+		{"gogit", "config", "--file", "other", "core.x", "1"},
+		{"gogit", "config", "-f", "other", "--list"},
+		{"gogit", "config", "-l"},
+	} {
+		if code := run(args); code != 0 {
+			t.Errorf("%v: expected exit code 0, got %d", args[1:], code)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"gogit", "config"},
+		{"gogit", "config", "user.name"},
+		{"gogit", "config", "a", "b", "c"},
+		{"gogit", "config", "--list", "x"},
+		{"gogit", "config", "--file"},
+		{"gogit", "config", "nosection", "x"},
+		{"gogit", "config", "--unset", "user.name"},
+	} {
+		if code := run(args); code != 1 {
+			t.Errorf("%v: expected exit code 1, got %d", args[1:], code)
+		}
+	}
+}
+
+func TestRun_CheckIgnore(t *testing.T) {
+	dir := setupMainTestRepo(t)
+	os.WriteFile(filepath.Join(dir, ".gogitignore"), []byte("*.log\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "a.log"), []byte("x"), 0644)
+
+	if code := run([]string{"gogit", "check-ignore", "-v", "a.log"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	if code := run([]string{"gogit", "check-ignore", "--no-index", "main.go"}); code != 1 {
+		t.Errorf("expected exit code 1 for a path that is not ignored, got %d", code)
+	}
+	if code := run([]string{"gogit", "check-ignore"}); code != 1 {
+		t.Errorf("expected exit code 1 for missing paths, got %d", code)
+	}
+	if code := run([]string{"gogit", "check-ignore", "../x"}); code != 1 {
+		t.Errorf("expected exit code 1 for a path outside the repo, got %d", code)
+	}
+
+	if code := run([]string{"gogit", "add", "a.log"}); code != 1 {
+		t.Errorf("expected exit code 1 adding an ignored file, got %d", code)
+	}
+	if code := run([]string{"gogit", "add", "-f", "a.log"}); code != 0 {
+		t.Errorf("expected exit code 0 for forced add, got %d", code)
+	}
+	if code := run([]string{"gogit", "add", "-f"}); code != 1 {
+		t.Errorf("expected exit code 1 for missing paths, got %d", code)
+	}
+}
+
+func TestRun_UpdateIndex(t *testing.T) {
+	dir := setupMainTestRepo(t)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new\n"), 0644)
+	run([]string{"gogit", "add", "main.go"})
+
+	if code := run([]string{"gogit", "update-index", "--assume-unchanged", "--skip-worktree", "main.go"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	if code := run([]string{"gogit", "update-index", "--no-skip-worktree", "main.go"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	if code := run([]string{"gogit", "update-index", "--intent-to-add", "--", "new.txt"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	idx, _ := index.ReadIndex(dir)
+	if e := idx.LookupEntry("main.go"); e.Flags != index.FlagAssumeUnchanged {
+		t.Errorf("expected only assume-unchanged, got %d", e.Flags)
+	}
+	if e := idx.LookupEntry("new.txt"); e == nil || !e.IntentToAdd() {
+		t.Errorf("expected intent-to-add entry, got %+v", e)
+	}
+
+	if code := run([]string{"gogit", "update-index", "--index-version", "1"}); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	for _, args := range [][]string{
+		{"update-index"},
+		{"update-index", "--index-version"},
+		{"update-index", "--index-version", "x"},
+		{"update-index", "--index-version", "7"},
+		{"update-index", "--skip-worktree", "missing.txt"},
+	} {
+		if code := run(append([]string{"gogit"}, args...)); code != 1 {
+			t.Errorf("%v: expected exit code 1, got %d", args, code)
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"os/user"
 	"strings"
 	"time"
+
+	"gogit/config"
 )
 
 // Commit represents a parsed commit object.
@@ -19,7 +21,10 @@ type Commit struct {
 
 // WriteCommit creates a commit object and returns its hash.
 func WriteCommit(root, treeHash string, parents []string, message string) (string, error) {
-	author := formatAuthor()
+	author, err := formatAuthor(root)
+	if err != nil {
+		return "", err
+	}
 	timestamp := formatTimestamp()
 
 	var buf strings.Builder
@@ -72,8 +77,29 @@ func ParseCommit(data []byte) (*Commit, error) {
 // userLookup is a variable wrapping user.Current so tests can override it.
 var userLookup = user.Current
 
-func formatAuthor() string {
+// Signature returns the current user's identity and time in the form used
+// by commit headers and reflogs: "Name <email> 1700000000 +0000".
+func Signature(root string) (string, error) {
+	author, err := formatAuthor(root)
+	if err != nil {
+		return "", err
+	}
+	return author + " " + formatTimestamp(), nil
+}
+
+// formatAuthor returns "Name <email>" for the current user. The name and
+// email come from GOGIT_AUTHOR_NAME and GOGIT_AUTHOR_EMAIL, then from
+// user.name and user.email in the configuration of the repository at root,
+// then from the login name.
+func formatAuthor(root string) (string, error) {
+	cfg, err := config.Load(root)
+	if err != nil {
+		return "", err
+	}
 	name := os.Getenv("GOGIT_AUTHOR_NAME")
+	if name == "" {
+		name = cfg.GetString("user.name", "")
+	}
 	if name == "" {
 		if u, err := userLookup(); err == nil {
 			name = u.Username
@@ -82,10 +108,13 @@ func formatAuthor() string {
 		}
 	}
 	email := os.Getenv("GOGIT_AUTHOR_EMAIL")
+	if email == "" {
+		email = cfg.GetString("user.email", "")
+	}
 	if email == "" {
 		email = name + "@localhost"
 	}
-	return fmt.Sprintf("%s <%s>", name, email)
+	return fmt.Sprintf("%s <%s>", name, email), nil
 }
 
 func formatTimestamp() string {
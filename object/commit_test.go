@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/user"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -119,7 +120,7 @@ func TestFormatAuthor_WithEnvVars(t *testing.T) {
 	t.Setenv("GOGIT_AUTHOR_NAME", "Env User")
 	t.Setenv("GOGIT_AUTHOR_EMAIL", "env@test.com")
 
-	author := formatAuthor()
+	author, _ := formatAuthor("")
 	if author != "Env User <env@test.com>" {
 		t.Errorf("unexpected author: %s", author)
 	}
@@ -129,7 +130,7 @@ func TestFormatAuthor_NoEnvVars(t *testing.T) {
 	os.Unsetenv("GOGIT_AUTHOR_NAME")
 	os.Unsetenv("GOGIT_AUTHOR_EMAIL")
 
-	author := formatAuthor()
+	author, _ := formatAuthor("")
 	// Should contain username@localhost when no env vars
 	if !strings.Contains(author, "<") || !strings.Contains(author, ">") {
 		t.Errorf("author should have email brackets: %s", author)
@@ -143,7 +144,7 @@ func TestFormatAuthor_NameOnlyNoEmail(t *testing.T) {
 	t.Setenv("GOGIT_AUTHOR_NAME", "JustName")
 	os.Unsetenv("GOGIT_AUTHOR_EMAIL")
 
-	author := formatAuthor()
+	author, _ := formatAuthor("")
 	if author != "JustName <JustName@localhost>" {
 		t.Errorf("unexpected author: %s", author)
 	}
@@ -159,7 +160,7 @@ func TestFormatAuthor_UserLookupError(t *testing.T) {
 	}
 	defer func() { userLookup = orig }()
 
-	author := formatAuthor()
+	author, _ := formatAuthor("")
 	if !strings.Contains(author, "Unknown") {
 		t.Errorf("expected 'Unknown' fallback, got %s", author)
 	}
@@ -221,3 +222,64 @@ func TestFormatTimestamp_PositiveOffset(t *testing.T) {
 		t.Errorf("expected positive offset for Asia/Tokyo, got %s", parts[1])
 	}
 }
+
+// isolateConfig points the global and system configuration at files that
+// do not exist, so the user's own settings cannot leak into a test.
+func isolateConfig(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("GOGIT_CONFIG_GLOBAL", filepath.Join(dir, "global"))
+	t.Setenv("GOGIT_CONFIG_SYSTEM", filepath.Join(dir, "system"))
+}
+
+func TestFormatAuthor_FromConfig(t *testing.T) {
+	isolateConfig(t)
+	os.Unsetenv("GOGIT_AUTHOR_NAME")
+	os.Unsetenv("GOGIT_AUTHOR_EMAIL")
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, ".gogit"), 0755)
+	os.WriteFile(filepath.Join(root, ".gogit", "config"), []byte("[user]\n\tname = Config User\n\temail = config@example.com\n"), 0644)
+
+	author, err := formatAuthor(root)
+	if err != nil {
+		t.Fatalf("formatAuthor failed: %v", err)
+	}
+	if author != "Config User <config@example.com>" {
+		t.Errorf("unexpected author: %s", author)
+	}
+
+	// The environment still wins over the configuration.
+	t.Setenv("GOGIT_AUTHOR_NAME", "Env User")
+	author, _ = formatAuthor(root)
+	if author != "Env User <config@example.com>" {
+		t.Errorf("unexpected author: %s", author)
+	}
+}
+
+func TestFormatAuthor_GlobalConfig(t *testing.T) {
+	isolateConfig(t)
+	os.Unsetenv("GOGIT_AUTHOR_NAME")
+	os.Unsetenv("GOGIT_AUTHOR_EMAIL")
+	os.WriteFile(os.Getenv("GOGIT_CONFIG_GLOBAL"), []byte("[user]\n\tname = Global\n"), 0644)
+
+	author, err := formatAuthor("")
+	if err != nil {
+		t.Fatalf("formatAuthor failed: %v", err)
+	}
+	if author != "Global <Global@localhost>" {
+		t.Errorf("unexpected author: %s", author)
+	}
+}
+
+func TestWriteCommit_BadConfig(t *testing.T) {
+	isolateConfig(t)
+	root := setupObjectStore(t)
+	os.WriteFile(filepath.Join(root, ".gogit", "config"), []byte("[user\n"), 0644)
+
+	if _, err := WriteCommit(root, "abc", nil, "msg"); err == nil {
+		t.Error("expected error for malformed config")
+	}
+	if _, err := Signature(root); err == nil {
+		t.Error("expected error for malformed config")
+	}
+}
@@ -0,0 +1,196 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// deltaBlockSize is the granularity used to index the base when searching
+// for copyable regions. Matches shorter than this are emitted as inserts.
+const deltaBlockSize = 16
+
+// maxInsertLen is the largest literal run a single insert opcode can carry.
+const maxInsertLen = 0x7f
+
+// maxCopyLen is the largest run a single copy opcode can carry.
+const maxCopyLen = 0xffffff
+
+// CreateDelta encodes target as a git-style delta against base. The result
+// starts with the base and target sizes as varints followed by copy and
+// insert instructions.
+func CreateDelta(base, target []byte) []byte {
+	var out bytes.Buffer
+	writeDeltaSize(&out, len(base))
+	writeDeltaSize(&out, len(target))
+
+	// Index every aligned block of the base by its content.
+	blocks := make(map[string][]int)
+	for i := 0; i+deltaBlockSize <= len(base); i += deltaBlockSize {
+		key := string(base[i : i+deltaBlockSize])
+		blocks[key] = append(blocks[key], i)
+	}
+
+	var pending []byte
+	flushInsert := func() {
+		for len(pending) > 0 {
+			n := len(pending)
+			if n > maxInsertLen {
+				n = maxInsertLen
+			}
+			out.WriteByte(byte(n))
+			out.Write(pending[:n])
+			pending = pending[n:]
+		}
+	}
+
+	i := 0
+	for i < len(target) {
+		bestOff, bestLen := 0, 0
+		if i+deltaBlockSize <= len(target) {
+			for _, off := range blocks[string(target[i:i+deltaBlockSize])] {
+				n := deltaBlockSize
+				for off+n < len(base) && i+n < len(target) && base[off+n] == target[i+n] {
+					n++
+				}
+				if n > bestLen {
+					bestOff, bestLen = off, n
+				}
+			}
+		}
+
+		if bestLen < deltaBlockSize {
+			pending = append(pending, target[i])
+			i++
+			continue
+		}
+
+		flushInsert()
+		for bestLen > 0 {
+			n := bestLen
+			if n > maxCopyLen {
+				n = maxCopyLen
+			}
+			writeCopyOp(&out, bestOff, n)
+			bestOff += n
+			bestLen -= n
+			i += n
+		}
+	}
+	flushInsert()
+
+	return out.Bytes()
+}
+
+// ApplyDelta reconstructs a target object from base and a delta produced by
+// CreateDelta (or by git).
+func ApplyDelta(base, delta []byte) ([]byte, error) {
+	srcSize, delta, err := readDeltaSize(delta)
+	if err != nil {
+		return nil, err
+	}
+	if srcSize != len(base) {
+		return nil, fmt.Errorf("delta base size mismatch: expected %d, got %d", srcSize, len(base))
+	}
+	dstSize, delta, err := readDeltaSize(delta)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, dstSize)
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+
+		switch {
+		case op&0x80 != 0:
+			// Copy from base: the low bits select which offset/size bytes follow.
+			var off, size int
+			for k := uint(0); k < 4; k++ {
+				if op&(1<<k) != 0 {
+					if len(delta) == 0 {
+						return nil, fmt.Errorf("truncated delta copy instruction")
+					}
+					off |= int(delta[0]) << (8 * k)
+					delta = delta[1:]
+				}
+			}
+			for k := uint(0); k < 3; k++ {
+				if op&(0x10<<k) != 0 {
+					if len(delta) == 0 {
+						return nil, fmt.Errorf("truncated delta copy instruction")
+					}
+					size |= int(delta[0]) << (8 * k)
+					delta = delta[1:]
+				}
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if off+size > len(base) {
+				return nil, fmt.Errorf("delta copy out of range")
+			}
+			out = append(out, base[off:off+size]...)
+		case op != 0:
+			// Insert literal bytes.
+			n := int(op)
+			if n > len(delta) {
+				return nil, fmt.Errorf("truncated delta insert instruction")
+			}
+			out = append(out, delta[:n]...)
+			delta = delta[n:]
+		default:
+			return nil, fmt.Errorf("invalid delta opcode 0")
+		}
+	}
+
+	if len(out) != dstSize {
+		return nil, fmt.Errorf("delta result size mismatch: expected %d, got %d", dstSize, len(out))
+	}
+	return out, nil
+}
+
+func writeDeltaSize(buf *bytes.Buffer, n int) {
+	for {
+		b := byte(n & 0x7f)
+		n >>= 7
+		if n == 0 {
+			buf.WriteByte(b)
+			return
+		}
+		buf.WriteByte(b | 0x80)
+	}
+}
+
+func readDeltaSize(data []byte) (int, []byte, error) {
+	var n int
+	var shift uint
+	for i, b := range data {
+		n |= int(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			return n, data[i+1:], nil
+		}
+	}
+	return 0, nil, fmt.Errorf("truncated delta header")
+}
+
+func writeCopyOp(buf *bytes.Buffer, off, size int) {
+	op := byte(0x80)
+	var args []byte
+	for k := uint(0); k < 4; k++ {
+		if b := byte(off >> (8 * k)); b != 0 {
+			op |= 1 << k
+			args = append(args, b)
+		}
+	}
+	if size != 0x10000 {
+		for k := uint(0); k < 3; k++ {
+			if b := byte(size >> (8 * k)); b != 0 {
+				op |= 0x10 << k
+				args = append(args, b)
+			}
+		}
+	}
+	buf.WriteByte(op)
+	buf.Write(args)
+}
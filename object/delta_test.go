@@ -0,0 +1,94 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCreateDelta_Roundtrip(t *testing.T) {
+	base := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog\n", 50))
+	target := append([]byte("header line\n"), base[:1000]...)
+	target = append(target, []byte("inserted in the middle\n")...)
+	target = append(target, base[1000:]...)
+
+	delta := CreateDelta(base, target)
+	if len(delta) >= len(target)/2 {
+		t.Errorf("delta should be much smaller than target: %d vs %d", len(delta), len(target))
+	}
+
+	got, err := ApplyDelta(base, delta)
+	if err != nil {
+		t.Fatalf("ApplyDelta failed: %v", err)
+	}
+	if !bytes.Equal(got, target) {
+		t.Error("roundtrip mismatch")
+	}
+}
+
+func TestCreateDelta_Unrelated(t *testing.T) {
+	base := []byte("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	target := []byte(strings.Repeat("z", 300))
+
+	got, err := ApplyDelta(base, CreateDelta(base, target))
+	if err != nil {
+		t.Fatalf("ApplyDelta failed: %v", err)
+	}
+	if !bytes.Equal(got, target) {
+		t.Error("roundtrip mismatch for long insert")
+	}
+}
+
+func TestCreateDelta_Empty(t *testing.T) {
+	got, err := ApplyDelta(nil, CreateDelta(nil, nil))
+	if err != nil {
+		t.Fatalf("ApplyDelta failed: %v", err)
+	}
+	if len(got) != 0 {
+		t.Error("expected empty result")
+	}
+}
+
+func TestCreateDelta_LargeCopy(t *testing.T) {
+	base := bytes.Repeat([]byte("0123456789abcdef"), 0x2000) // 128 KiB
+	target := append([]byte{}, base...)
+
+	got, err := ApplyDelta(base, CreateDelta(base, target))
+	if err != nil {
+		t.Fatalf("ApplyDelta failed: %v", err)
+	}
+	if !bytes.Equal(got, target) {
+		t.Error("roundtrip mismatch for large copy")
+	}
+}
+
+func TestApplyDelta_BaseSizeMismatch(t *testing.T) {
+	delta := CreateDelta([]byte("abc"), []byte("abc"))
+	if _, err := ApplyDelta([]byte("abcd"), delta); err == nil {
+		t.Fatal("expected error for base size mismatch")
+	}
+}
+
+func TestApplyDelta_TruncatedHeader(t *testing.T) {
+	if _, err := ApplyDelta(nil, []byte{0x80}); err == nil {
+		t.Fatal("expected error for truncated header")
+	}
+	if _, err := ApplyDelta(nil, []byte{0x00, 0x80}); err == nil {
+		t.Fatal("expected error for truncated target size")
+	}
+}
+
+func TestApplyDelta_BadInstructions(t *testing.T) {
+	tests := map[string][]byte{
+		"zero opcode":       {0x03, 0x01, 0x00},
+		"short insert":      {0x03, 0x05, 0x05, 'a'},
+		"copy out of range": {0x03, 0x05, 0x90, 0x05},
+		"truncated copy":    {0x03, 0x03, 0x91},
+		"size mismatch":     {0x03, 0x05, 0x90, 0x03},
+	}
+	for name, delta := range tests {
+		if _, err := ApplyDelta([]byte("abc"), delta); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
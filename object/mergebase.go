@@ -0,0 +1,122 @@
+package object
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Ancestors returns every commit reachable from the given commits through
+// any parent, including the commits themselves.
+func Ancestors(root string, heads ...string) (map[string]bool, error) {
+	seen := make(map[string]bool)
+	queue := append([]string{}, heads...)
+	for len(queue) > 0 {
+		h := queue[0]
+		queue = queue[1:]
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+		commit, err := ReadCommit(root, h)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, commit.Parents...)
+	}
+	return seen, nil
+}
+
+// IsAncestor reports whether ancestor is reachable from descendant. A
+// commit is considered its own ancestor.
+func IsAncestor(root, ancestor, descendant string) (bool, error) {
+	if ancestor == descendant {
+		return true, nil
+	}
+	reachable, err := Ancestors(root, descendant)
+	if err != nil {
+		return false, err
+	}
+	return reachable[ancestor], nil
+}
+
+// MergeBases returns the best common ancestors of two commits: common
+// ancestors that are not themselves ancestors of another common ancestor.
+// Criss-cross histories can have more than one. Results are ordered newest
+// first by committer date.
+func MergeBases(root, a, b string) ([]string, error) {
+	return MergeBasesOf(root, []string{a}, []string{b})
+}
+
+// MergeBasesOf is MergeBases generalized to two sets of commits, each
+// standing for a (possibly virtual) commit whose parents are the set.
+func MergeBasesOf(root string, left, right []string) ([]string, error) {
+	leftAnc, err := Ancestors(root, left...)
+	if err != nil {
+		return nil, err
+	}
+	rightAnc, err := Ancestors(root, right...)
+	if err != nil {
+		return nil, err
+	}
+
+	var common []string
+	for h := range leftAnc {
+		if rightAnc[h] {
+			common = append(common, h)
+		}
+	}
+	if len(common) == 0 {
+		return nil, nil
+	}
+
+	// Anything strictly reachable from a common ancestor is redundant. The
+	// walk shares one visited set, so each commit is read at most once.
+	var parents []string
+	for _, h := range common {
+		commit, err := ReadCommit(root, h)
+		if err != nil {
+			return nil, err
+		}
+		parents = append(parents, commit.Parents...)
+	}
+	redundant, err := Ancestors(root, parents...)
+	if err != nil {
+		return nil, err
+	}
+
+	var best []string
+	times := make(map[string]int64)
+	for _, h := range common {
+		if redundant[h] {
+			continue
+		}
+		commit, err := ReadCommit(root, h)
+		if err != nil {
+			return nil, err
+		}
+		times[h] = commitTime(commit.Committer)
+		best = append(best, h)
+	}
+	sort.Slice(best, func(i, j int) bool {
+		if times[best[i]] != times[best[j]] {
+			return times[best[i]] > times[best[j]]
+		}
+		return best[i] < best[j]
+	})
+	return best, nil
+}
+
+// commitTime extracts the Unix timestamp from an identity line of the form
+// "Name <email> 1700000000 +0000". It returns 0 if none is present.
+func commitTime(ident string) int64 {
+	fields := strings.Fields(ident)
+	if len(fields) < 2 {
+		return 0
+	}
+	ts, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return ts
+}
@@ -0,0 +1,134 @@
+package object
+
+import (
+	"testing"
+)
+
+// writeTestCommit writes a commit with an empty tree and the given parents.
+// The message keeps otherwise identical commits distinct.
+func writeTestCommit(t *testing.T, root, msg string, parents ...string) string {
+	t.Helper()
+	tree, err := WriteTree(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := WriteCommit(root, tree, parents, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestIsAncestor_MergeParents(t *testing.T) {
+	root := setupObjectStore(t)
+	base := writeTestCommit(t, root, "base")
+	side := writeTestCommit(t, root, "side", base)
+	main := writeTestCommit(t, root, "main", base)
+	merge := writeTestCommit(t, root, "merge", main, side)
+
+	for _, anc := range []string{base, side, main, merge} {
+		ok, err := IsAncestor(root, anc, merge)
+		if err != nil || !ok {
+			t.Errorf("%s should be an ancestor of the merge (err=%v)", anc[:7], err)
+		}
+	}
+	if ok, _ := IsAncestor(root, merge, side); ok {
+		t.Error("merge should not be an ancestor of side")
+	}
+}
+
+func TestIsAncestor_MissingCommit(t *testing.T) {
+	root := setupObjectStore(t)
+	if _, err := IsAncestor(root, "a", "0000000000000000000000000000000000000000"); err == nil {
+		t.Fatal("expected error for missing commit")
+	}
+}
+
+func TestMergeBases_Simple(t *testing.T) {
+	root := setupObjectStore(t)
+	base := writeTestCommit(t, root, "base")
+	a := writeTestCommit(t, root, "a", base)
+	b := writeTestCommit(t, root, "b", base)
+
+	bases, err := MergeBases(root, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bases) != 1 || bases[0] != base {
+		t.Errorf("expected [%s], got %v", base[:7], bases)
+	}
+}
+
+func TestMergeBases_AfterMerge(t *testing.T) {
+	// main: base - m1 - merge(m1, f1) - m2
+	// feat: base - f1 - f2
+	// The best base of m2 and f2 is f1, which only a walk over second
+	// parents can find.
+	root := setupObjectStore(t)
+	base := writeTestCommit(t, root, "base")
+	m1 := writeTestCommit(t, root, "m1", base)
+	f1 := writeTestCommit(t, root, "f1", base)
+	merge := writeTestCommit(t, root, "merge", m1, f1)
+	m2 := writeTestCommit(t, root, "m2", merge)
+	f2 := writeTestCommit(t, root, "f2", f1)
+
+	bases, err := MergeBases(root, m2, f2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bases) != 1 || bases[0] != f1 {
+		t.Errorf("expected [%s], got %v", f1[:7], bases)
+	}
+}
+
+func TestMergeBases_CrissCross(t *testing.T) {
+	root := setupObjectStore(t)
+	base := writeTestCommit(t, root, "base")
+	a1 := writeTestCommit(t, root, "a1", base)
+	b1 := writeTestCommit(t, root, "b1", base)
+	a2 := writeTestCommit(t, root, "a2", a1, b1)
+	b2 := writeTestCommit(t, root, "b2", b1, a1)
+
+	bases, err := MergeBases(root, a2, b2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bases) != 2 {
+		t.Fatalf("expected 2 merge bases, got %v", bases)
+	}
+	got := map[string]bool{bases[0]: true, bases[1]: true}
+	if !got[a1] || !got[b1] {
+		t.Errorf("expected a1 and b1, got %v", bases)
+	}
+}
+
+func TestMergeBases_Unrelated(t *testing.T) {
+	root := setupObjectStore(t)
+	a := writeTestCommit(t, root, "a")
+	b := writeTestCommit(t, root, "b")
+
+	bases, err := MergeBases(root, a, b)
+	if err != nil || len(bases) != 0 {
+		t.Errorf("expected no bases, got %v (%v)", bases, err)
+	}
+}
+
+func TestMergeBases_MissingCommit(t *testing.T) {
+	root := setupObjectStore(t)
+	a := writeTestCommit(t, root, "a")
+	if _, err := MergeBases(root, a, "0000000000000000000000000000000000000000"); err == nil {
+		t.Fatal("expected error for missing commit")
+	}
+	if _, err := MergeBases(root, "0000000000000000000000000000000000000000", a); err == nil {
+		t.Fatal("expected error for missing commit")
+	}
+}
+
+func TestCommitTime(t *testing.T) {
+	if got := commitTime("A <a@b> 1700000000 +0100"); got != 1700000000 {
+		t.Errorf("expected 1700000000, got %d", got)
+	}
+	if commitTime("") != 0 || commitTime("A <a@b> notanumber +0000") != 0 {
+		t.Error("expected 0 for malformed identities")
+	}
+}
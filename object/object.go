@@ -9,6 +9,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"gogit/repo"
 )
@@ -30,6 +32,9 @@ func WriteObject(root, objType string, content []byte) (string, error) {
 	if _, err := os.Stat(objPath); err == nil {
 		return hash, nil // already exists
 	}
+	if packContains(root, hash) {
+		return hash, nil // already packed
+	}
 
 	if err := os.MkdirAll(filepath.Dir(objPath), 0755); err != nil {
 		return "", err
@@ -49,12 +54,20 @@ func WriteObject(root, objType string, content []byte) (string, error) {
 }
 
 // ReadObject reads and decompresses an object, returning its type and content.
+// Loose objects take precedence; packs are consulted when no loose file exists.
 func ReadObject(root, hash string) (string, []byte, error) {
 	objPath := filepath.Join(repo.ObjectsPath(root), hash[:2], hash[2:])
 
 	data, err := os.ReadFile(objPath)
 	if err != nil {
-		return "", nil, fmt.Errorf("object not found: %s", hash)
+		objType, content, found, perr := readPackedObject(root, hash)
+		if perr != nil {
+			return "", nil, perr
+		}
+		if !found {
+			return "", nil, fmt.Errorf("object not found: %s", hash)
+		}
+		return objType, content, nil
 	}
 
 	r, err := zlib.NewReader(bytes.NewReader(data))
@@ -84,3 +97,103 @@ func ReadObject(root, hash string) (string, []byte, error) {
 	content := raw[nullIdx+1:]
 	return objType, content, nil
 }
+
+// HasObject reports whether an object exists, either loose or packed.
+func HasObject(root, hash string) bool {
+	if len(hash) < 3 {
+		return false
+	}
+	objPath := filepath.Join(repo.ObjectsPath(root), hash[:2], hash[2:])
+	if _, err := os.Stat(objPath); err == nil {
+		return true
+	}
+	return packContains(root, hash)
+}
+
+// LooseObjects returns the hashes of all loose objects in the store.
+func LooseObjects(root string) ([]string, error) {
+	objDir := repo.ObjectsPath(root)
+	dirs, err := os.ReadDir(objDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var hashes []string
+	for _, d := range dirs {
+		if !d.IsDir() || len(d.Name()) != 2 {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(objDir, d.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if !f.IsDir() && len(f.Name()) == 38 {
+				hashes = append(hashes, d.Name()+f.Name())
+			}
+		}
+	}
+	return hashes, nil
+}
+
+// FindObjects returns the hashes of all objects, loose or packed, whose hex
+// name starts with prefix. The prefix must be lowercase hex.
+func FindObjects(root, prefix string) ([]string, error) {
+	if len(prefix) < 2 {
+		return nil, fmt.Errorf("object name prefix too short: %s", prefix)
+	}
+	seen := make(map[string]bool)
+	var matches []string
+	add := func(h string) {
+		if strings.HasPrefix(h, prefix) && !seen[h] {
+			seen[h] = true
+			matches = append(matches, h)
+		}
+	}
+
+	dir := filepath.Join(repo.ObjectsPath(root), prefix[:2])
+	files, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, f := range files {
+		if !f.IsDir() && len(f.Name()) == 38 {
+			add(prefix[:2] + f.Name())
+		}
+	}
+
+	packed, err := PackedObjects(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range packed {
+		add(h)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// UniqueAbbrev returns the shortest prefix of hash, at least minLen long,
+// that names no other object in the store.
+func UniqueAbbrev(root, hash string, minLen int) (string, error) {
+	matches, err := FindObjects(root, hash[:minLen])
+	if err != nil {
+		return "", err
+	}
+	n := minLen
+	for _, m := range matches {
+		if m == hash {
+			continue
+		}
+		common := 0
+		for common < len(hash) && m[common] == hash[common] {
+			common++
+		}
+		if common+1 > n {
+			n = common + 1
+		}
+	}
+	return hash[:n], nil
+}
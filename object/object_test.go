@@ -3,6 +3,7 @@ package object
 import (
 	"bytes"
 	"compress/zlib"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -205,3 +206,83 @@ func TestReadObject_InvalidHeader(t *testing.T) {
 		t.Fatal("expected error for invalid header")
 	}
 }
+
+func TestFindObjects(t *testing.T) {
+	root := setupObjectStore(t)
+	h, _ := WriteBlob(root, []byte("findme"))
+
+	matches, err := FindObjects(root, h[:6])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(matches) != 1 || matches[0] != h {
+		t.Errorf("expected [%s], got %v", h, matches)
+	}
+
+	matches, err = FindObjects(root, "ffffff")
+	if err != nil || len(matches) != 0 {
+		t.Errorf("expected no matches, got %v (%v)", matches, err)
+	}
+	if _, err := FindObjects(root, "f"); err == nil {
+		t.Error("expected error for one-character prefix")
+	}
+}
+
+func TestFindObjects_Packed(t *testing.T) {
+	root := setupObjectStore(t)
+	h, _ := WriteBlob(root, []byte("packed"))
+	if _, err := WritePack(root, []string{h}); err != nil {
+		t.Fatal(err)
+	}
+	os.RemoveAll(filepath.Join(root, repo.GogitDir, "objects", h[:2]))
+
+	matches, err := FindObjects(root, h[:5])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(matches) != 1 || matches[0] != h {
+		t.Errorf("expected packed match %s, got %v", h, matches)
+	}
+}
+
+func TestFindObjects_Unreadable(t *testing.T) {
+	root := setupObjectStore(t)
+	h, _ := WriteBlob(root, []byte("x"))
+	dir := filepath.Join(root, repo.GogitDir, "objects", h[:2])
+	os.Chmod(dir, 0000)
+	defer os.Chmod(dir, 0755)
+
+	if _, err := FindObjects(root, h[:4]); err == nil {
+		t.Error("expected error for unreadable object directory")
+	}
+}
+
+func TestUniqueAbbrev(t *testing.T) {
+	root := setupObjectStore(t)
+	h, _ := WriteBlob(root, []byte("abbrev"))
+
+	short, err := UniqueAbbrev(root, h, 7)
+	if err != nil || short != h[:7] {
+		t.Errorf("expected %s, got %s (%v)", h[:7], short, err)
+	}
+
+	// Add an object sharing the first four characters; a four-character
+	// abbreviation must grow past the shared prefix.
+	for i := 0; ; i++ {
+		content := []byte(fmt.Sprintf("other %d", i))
+		other := HashBlob(content)
+		if other[:4] != h[:4] {
+			continue
+		}
+		WriteBlob(root, content)
+		common := 4
+		for other[common] == h[common] {
+			common++
+		}
+		short, err = UniqueAbbrev(root, h, 4)
+		if err != nil || short != h[:common+1] {
+			t.Errorf("expected %s, got %s (%v)", h[:common+1], short, err)
+		}
+		break
+	}
+}
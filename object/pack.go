@@ -0,0 +1,592 @@
+package object
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gogit/repo"
+)
+
+// Pack object type codes as used by git's packfile format.
+const (
+	packCommit   = 1
+	packTree     = 2
+	packBlob     = 3
+	packTag      = 4
+	packOfsDelta = 6
+	packRefDelta = 7
+)
+
+const (
+	packSignature  = "PACK"
+	packVersion    = 2
+	idxVersion     = 2
+	deltaWindow    = 10
+	maxDeltaDepth  = 50
+	largeOffsetBit = 0x80000000
+)
+
+var idxSignature = []byte{0xff, 't', 'O', 'c'}
+
+var packTypeCodes = map[string]int{
+	"commit": packCommit,
+	"tree":   packTree,
+	"blob":   packBlob,
+	"tag":    packTag,
+}
+
+var packTypeNames = map[int]string{
+	packCommit: "commit",
+	packTree:   "tree",
+	packBlob:   "blob",
+	packTag:    "tag",
+}
+
+// PackStats summarizes the result of WritePack.
+type PackStats struct {
+	Name    string // pack-<sha1>, without extension
+	Objects int
+	Deltas  int
+}
+
+type packEntry struct {
+	hash    string
+	objType string
+	content []byte
+	offset  int64
+	crc     uint32
+	base    *packEntry
+	delta   []byte
+	depth   int
+}
+
+// WritePack writes the given objects into a new pack and index under the
+// pack directory. Objects are delta-compressed against similar objects of the
+// same type and stored as OFS_DELTA entries.
+func WritePack(root string, hashes []string) (*PackStats, error) {
+	entries := make([]*packEntry, 0, len(hashes))
+	seen := make(map[string]bool)
+	for _, h := range hashes {
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+		objType, content, err := ReadObject(root, h)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := packTypeCodes[objType]; !ok {
+			return nil, fmt.Errorf("cannot pack object %s of type %s", h, objType)
+		}
+		entries = append(entries, &packEntry{hash: h, objType: objType, content: content})
+	}
+
+	// Group similar objects together so the delta window sees good candidates.
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].objType != entries[j].objType {
+			return packTypeCodes[entries[i].objType] < packTypeCodes[entries[j].objType]
+		}
+		return len(entries[i].content) > len(entries[j].content)
+	})
+
+	stats := &PackStats{Objects: len(entries)}
+	for i, e := range entries {
+		for k := i - 1; k >= 0 && k >= i-deltaWindow; k-- {
+			cand := entries[k]
+			if cand.objType != e.objType || cand.depth >= maxDeltaDepth {
+				continue
+			}
+			d := CreateDelta(cand.content, e.content)
+			if len(d) >= len(e.content)/2 {
+				continue
+			}
+			if e.delta == nil || len(d) < len(e.delta) {
+				e.base = cand
+				e.delta = d
+				e.depth = cand.depth + 1
+			}
+		}
+		if e.base != nil {
+			stats.Deltas++
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(packSignature)
+	binary.Write(&buf, binary.BigEndian, uint32(packVersion))
+	binary.Write(&buf, binary.BigEndian, uint32(len(entries)))
+
+	for _, e := range entries {
+		e.offset = int64(buf.Len())
+		var raw bytes.Buffer
+		if e.base != nil {
+			writePackObjectHeader(&raw, packOfsDelta, len(e.delta))
+			writeOfsDeltaOffset(&raw, e.offset-e.base.offset)
+			writeCompressed(&raw, e.delta)
+		} else {
+			writePackObjectHeader(&raw, packTypeCodes[e.objType], len(e.content))
+			writeCompressed(&raw, e.content)
+		}
+		e.crc = crc32.ChecksumIEEE(raw.Bytes())
+		buf.Write(raw.Bytes())
+	}
+
+	packSum := sha1.Sum(buf.Bytes())
+	buf.Write(packSum[:])
+
+	packDir := repo.PackPath(root)
+	if err := os.MkdirAll(packDir, 0755); err != nil {
+		return nil, err
+	}
+	stats.Name = "pack-" + hex.EncodeToString(packSum[:])
+
+	idxData, err := encodePackIndex(entries, packSum[:])
+	if err != nil {
+		return nil, err
+	}
+
+	// An identical pack is already in place (e.g. repacking a packed repo).
+	if _, err := os.Stat(filepath.Join(packDir, stats.Name+".idx")); err == nil {
+		return stats, nil
+	}
+
+	// Write the pack before its index so readers never see an index that
+	// points at a missing pack.
+	if err := writePackFile(packDir, stats.Name+".pack", buf.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writePackFile(packDir, stats.Name+".idx", idxData); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// writePackFile writes data to a temporary file in the pack directory and
+// renames it to name, so that a reader never sees it half written.
+func writePackFile(packDir, name string, data []byte) error {
+	f, err := os.CreateTemp(packDir, "tmp_pack_")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0444)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(packDir, name))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func encodePackIndex(entries []*packEntry, packSum []byte) ([]byte, error) {
+	sorted := make([]*packEntry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].hash < sorted[j].hash })
+
+	var buf bytes.Buffer
+	buf.Write(idxSignature)
+	binary.Write(&buf, binary.BigEndian, uint32(idxVersion))
+
+	var fanout [256]uint32
+	for _, e := range sorted {
+		b, err := hex.DecodeString(e.hash[:2])
+		if err != nil {
+			return nil, err
+		}
+		fanout[b[0]]++
+	}
+	var total uint32
+	for i := range fanout {
+		total += fanout[i]
+		binary.Write(&buf, binary.BigEndian, total)
+	}
+
+	for _, e := range sorted {
+		raw, err := hex.DecodeString(e.hash)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(raw)
+	}
+	for _, e := range sorted {
+		binary.Write(&buf, binary.BigEndian, e.crc)
+	}
+
+	var large []uint64
+	for _, e := range sorted {
+		if e.offset >= largeOffsetBit {
+			binary.Write(&buf, binary.BigEndian, uint32(largeOffsetBit|len(large)))
+			large = append(large, uint64(e.offset))
+		} else {
+			binary.Write(&buf, binary.BigEndian, uint32(e.offset))
+		}
+	}
+	for _, off := range large {
+		binary.Write(&buf, binary.BigEndian, off)
+	}
+
+	buf.Write(packSum)
+	idxSum := sha1.Sum(buf.Bytes())
+	buf.Write(idxSum[:])
+	return buf.Bytes(), nil
+}
+
+func writePackObjectHeader(buf *bytes.Buffer, typeCode, size int) {
+	b := byte(typeCode<<4) | byte(size&0x0f)
+	size >>= 4
+	for size > 0 {
+		buf.WriteByte(b | 0x80)
+		b = byte(size & 0x7f)
+		size >>= 7
+	}
+	buf.WriteByte(b)
+}
+
+// writeOfsDeltaOffset encodes the distance back to the base object using
+// git's offset encoding, where each continuation byte implicitly adds one.
+func writeOfsDeltaOffset(buf *bytes.Buffer, ofs int64) {
+	var tmp [10]byte
+	pos := len(tmp) - 1
+	tmp[pos] = byte(ofs & 0x7f)
+	for ofs >>= 7; ofs > 0; ofs >>= 7 {
+		ofs--
+		pos--
+		tmp[pos] = 0x80 | byte(ofs&0x7f)
+	}
+	buf.Write(tmp[pos:])
+}
+
+func writeCompressed(buf *bytes.Buffer, data []byte) {
+	w := zlib.NewWriter(buf)
+	w.Write(data)
+	w.Close()
+}
+
+// packIndex is a parsed .idx file.
+type packIndex struct {
+	packPath string
+	fanout   [256]uint32
+	hashes   []byte // count*20 raw hashes in sorted order
+	offsets  []int64
+}
+
+func (pi *packIndex) count() int {
+	return int(pi.fanout[255])
+}
+
+// find returns the pack offset of the object, or -1 if absent.
+func (pi *packIndex) find(hash string) int64 {
+	raw, err := hex.DecodeString(hash)
+	if err != nil || len(raw) != 20 {
+		return -1
+	}
+	lo := 0
+	if raw[0] > 0 {
+		lo = int(pi.fanout[raw[0]-1])
+	}
+	hi := int(pi.fanout[raw[0]])
+	i := lo + sort.Search(hi-lo, func(k int) bool {
+		return bytes.Compare(pi.hashes[(lo+k)*20:(lo+k+1)*20], raw) >= 0
+	})
+	if i < hi && bytes.Equal(pi.hashes[i*20:(i+1)*20], raw) {
+		return pi.offsets[i]
+	}
+	return -1
+}
+
+func (pi *packIndex) hashAt(i int) string {
+	return hex.EncodeToString(pi.hashes[i*20 : (i+1)*20])
+}
+
+func readPackIndex(idxPath string) (*packIndex, error) {
+	data, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8+256*4+40 || !bytes.Equal(data[:4], idxSignature) {
+		return nil, fmt.Errorf("invalid pack index: %s", filepath.Base(idxPath))
+	}
+	if v := binary.BigEndian.Uint32(data[4:8]); v != idxVersion {
+		return nil, fmt.Errorf("unsupported pack index version: %d", v)
+	}
+	sum := sha1.Sum(data[:len(data)-20])
+	if !bytes.Equal(sum[:], data[len(data)-20:]) {
+		return nil, fmt.Errorf("pack index checksum mismatch: %s", filepath.Base(idxPath))
+	}
+
+	pi := &packIndex{packPath: strings.TrimSuffix(idxPath, ".idx") + ".pack"}
+	pos := 8
+	for i := range pi.fanout {
+		pi.fanout[i] = binary.BigEndian.Uint32(data[pos:])
+		pos += 4
+	}
+	n := pi.count()
+	need := pos + n*20 + n*4 + n*4 + 40
+	if len(data) < need {
+		return nil, fmt.Errorf("pack index truncated: %s", filepath.Base(idxPath))
+	}
+
+	pi.hashes = data[pos : pos+n*20]
+	pos += n * 20
+	pos += n * 4 // skip crc32 table
+
+	smallOffsets := data[pos : pos+n*4]
+	largeStart := pos + n*4
+	pi.offsets = make([]int64, n)
+	for i := 0; i < n; i++ {
+		off := binary.BigEndian.Uint32(smallOffsets[i*4:])
+		if off&largeOffsetBit == 0 {
+			pi.offsets[i] = int64(off)
+			continue
+		}
+		at := largeStart + int(off&^largeOffsetBit)*8
+		if at+8 > len(data)-40 {
+			return nil, fmt.Errorf("pack index truncated: %s", filepath.Base(idxPath))
+		}
+		pi.offsets[i] = int64(binary.BigEndian.Uint64(data[at:]))
+	}
+	return pi, nil
+}
+
+// packSet is the parsed indexes of one repository's packs, with the
+// names, sizes and modification times of the index files they came from.
+type packSet struct {
+	files   []os.FileInfo
+	indexes []*packIndex
+}
+
+// packCache holds the packSet of each repository root read so far, so that
+// object lookups do not parse every index again.
+var packCache = struct {
+	sync.Mutex
+	sets map[string]*packSet
+}{sets: make(map[string]*packSet)}
+
+// listPackIndexes returns the indexes of all packs in the repository. They
+// are parsed once and read again only when an index file is added, removed
+// or changed. An index that cannot be read is skipped with a warning, so
+// that the other packs can still be used.
+func listPackIndexes(root string) ([]*packIndex, error) {
+	packDir := repo.PackPath(root)
+	matches, err := filepath.Glob(filepath.Join(packDir, "pack-*.idx"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	var files []os.FileInfo
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil {
+			continue // removed since the glob
+		}
+		files = append(files, info)
+	}
+
+	packCache.Lock()
+	defer packCache.Unlock()
+	if set, ok := packCache.sets[root]; ok && sameFiles(set.files, files) {
+		return set.indexes, nil
+	}
+	set := &packSet{files: files}
+	for _, info := range files {
+		pi, err := readPackIndex(filepath.Join(packDir, info.Name()))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping pack: %v\n", err)
+			continue
+		}
+		set.indexes = append(set.indexes, pi)
+	}
+	packCache.sets[root] = set
+	return set.indexes, nil
+}
+
+// sameFiles reports whether two listings name the same files with the same
+// sizes and modification times.
+func sameFiles(a, b []os.FileInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name() != b[i].Name() || a[i].Size() != b[i].Size() || !a[i].ModTime().Equal(b[i].ModTime()) {
+			return false
+		}
+	}
+	return true
+}
+
+// packContains reports whether any pack index lists the object.
+func packContains(root, hash string) bool {
+	packs, err := listPackIndexes(root)
+	if err != nil {
+		return false
+	}
+	for _, pi := range packs {
+		if pi.find(hash) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// readPackedObject looks an object up in all packs. The boolean result is
+// false when no pack contains it.
+func readPackedObject(root, hash string) (string, []byte, bool, error) {
+	packs, err := listPackIndexes(root)
+	if err != nil {
+		return "", nil, false, err
+	}
+	for _, pi := range packs {
+		off := pi.find(hash)
+		if off < 0 {
+			continue
+		}
+		f, err := os.Open(pi.packPath)
+		if err != nil {
+			return "", nil, false, err
+		}
+		defer f.Close()
+		objType, content, err := readPackObjectAt(root, f, off, 0)
+		if err != nil {
+			return "", nil, false, err
+		}
+		return objType, content, true, nil
+	}
+	return "", nil, false, nil
+}
+
+func readPackObjectAt(root string, f *os.File, offset int64, depth int) (string, []byte, error) {
+	if depth > maxDeltaDepth*2 {
+		return "", nil, fmt.Errorf("pack delta chain too deep")
+	}
+	r := bufio.NewReader(io.NewSectionReader(f, offset, 1<<62))
+
+	c, err := r.ReadByte()
+	if err != nil {
+		return "", nil, err
+	}
+	typeCode := int(c>>4) & 0x07
+	size := int(c & 0x0f)
+	shift := uint(4)
+	for c&0x80 != 0 {
+		if c, err = r.ReadByte(); err != nil {
+			return "", nil, err
+		}
+		size |= int(c&0x7f) << shift
+		shift += 7
+	}
+
+	switch typeCode {
+	case packCommit, packTree, packBlob, packTag:
+		content, err := inflateExactly(r, size)
+		if err != nil {
+			return "", nil, err
+		}
+		return packTypeNames[typeCode], content, nil
+
+	case packOfsDelta:
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", nil, err
+		}
+		rel := int64(c & 0x7f)
+		for c&0x80 != 0 {
+			if c, err = r.ReadByte(); err != nil {
+				return "", nil, err
+			}
+			rel = ((rel + 1) << 7) | int64(c&0x7f)
+		}
+		if rel <= 0 || rel > offset {
+			return "", nil, fmt.Errorf("invalid delta base offset")
+		}
+		delta, err := inflateExactly(r, size)
+		if err != nil {
+			return "", nil, err
+		}
+		baseType, base, err := readPackObjectAt(root, f, offset-rel, depth+1)
+		if err != nil {
+			return "", nil, err
+		}
+		content, err := ApplyDelta(base, delta)
+		return baseType, content, err
+
+	case packRefDelta:
+		var raw [20]byte
+		if _, err := io.ReadFull(r, raw[:]); err != nil {
+			return "", nil, err
+		}
+		delta, err := inflateExactly(r, size)
+		if err != nil {
+			return "", nil, err
+		}
+		baseType, base, err := ReadObject(root, hex.EncodeToString(raw[:]))
+		if err != nil {
+			return "", nil, err
+		}
+		content, err := ApplyDelta(base, delta)
+		return baseType, content, err
+	}
+	return "", nil, fmt.Errorf("unknown pack object type %d", typeCode)
+}
+
+func inflateExactly(r io.Reader, size int) ([]byte, error) {
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	data := make([]byte, size)
+	if _, err := io.ReadFull(zr, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// PackedObjects returns the hashes of every object stored in packs.
+func PackedObjects(root string) ([]string, error) {
+	packs, err := listPackIndexes(root)
+	if err != nil {
+		return nil, err
+	}
+	var hashes []string
+	for _, pi := range packs {
+		for i := 0; i < pi.count(); i++ {
+			hashes = append(hashes, pi.hashAt(i))
+		}
+	}
+	return hashes, nil
+}
+
+// PackFiles returns the base names (pack-<sha1>) of all packs whose index
+// could be read, which are the packs PackedObjects lists.
+func PackFiles(root string) ([]string, error) {
+	packs, err := listPackIndexes(root)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, pi := range packs {
+		names = append(names, strings.TrimSuffix(filepath.Base(pi.packPath), ".pack"))
+	}
+	return names, nil
+}
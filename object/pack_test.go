@@ -0,0 +1,270 @@
+package object
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gogit/repo"
+)
+
+func removeLoose(t *testing.T, root string, hashes ...string) {
+	t.Helper()
+	for _, h := range hashes {
+		if err := os.Remove(filepath.Join(repo.ObjectsPath(root), h[:2], h[2:])); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWritePack_ReadBack(t *testing.T) {
+	root := setupObjectStore(t)
+	base := strings.Repeat("shared content line\n", 100)
+	h1, _ := WriteBlob(root, []byte(base))
+	h2, _ := WriteBlob(root, []byte(base+"one more line\n"))
+	h3, _ := WriteBlob(root, []byte("small"))
+	tree, _ := WriteTree(root, []TreeEntry{{Mode: "100644", Name: "a", Hash: h1}})
+
+	stats, err := WritePack(root, []string{h1, h2, h3, tree, h1})
+	if err != nil {
+		t.Fatalf("WritePack failed: %v", err)
+	}
+	if stats.Objects != 4 {
+		t.Errorf("expected 4 objects, got %d", stats.Objects)
+	}
+	if stats.Deltas != 1 {
+		t.Errorf("expected 1 delta, got %d", stats.Deltas)
+	}
+
+	removeLoose(t, root, h1, h2, h3, tree)
+
+	for hash, want := range map[string]string{h1: base, h2: base + "one more line\n", h3: "small"} {
+		objType, content, err := ReadObject(root, hash)
+		if err != nil {
+			t.Fatalf("ReadObject(%s) failed: %v", hash[:7], err)
+		}
+		if objType != "blob" || string(content) != want {
+			t.Errorf("unexpected object %s: %s %q", hash[:7], objType, content)
+		}
+	}
+	entries, err := ReadTree(root, tree)
+	if err != nil || len(entries) != 1 || entries[0].Hash != h1 {
+		t.Errorf("tree not readable from pack: %v %v", entries, err)
+	}
+}
+
+func TestWritePack_Deterministic(t *testing.T) {
+	root := setupObjectStore(t)
+	h, _ := WriteBlob(root, []byte("x"))
+	s1, err := WritePack(root, []string{h})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := WritePack(root, []string{h})
+	if err != nil {
+		t.Fatalf("rewriting an identical pack failed: %v", err)
+	}
+	if s1.Name != s2.Name {
+		t.Errorf("expected same pack name, got %s and %s", s1.Name, s2.Name)
+	}
+}
+
+func TestWritePack_MissingObject(t *testing.T) {
+	root := setupObjectStore(t)
+	if _, err := WritePack(root, []string{"0000000000000000000000000000000000000000"}); err == nil {
+		t.Fatal("expected error for missing object")
+	}
+}
+
+func TestWritePack_MkdirError(t *testing.T) {
+	root := setupObjectStore(t)
+	h, _ := WriteBlob(root, []byte("x"))
+	os.WriteFile(repo.PackPath(root), []byte("blocker"), 0644)
+	if _, err := WritePack(root, []string{h}); err == nil {
+		t.Fatal("expected error when pack dir cannot be created")
+	}
+}
+
+func TestWriteObject_SkipsPackedObject(t *testing.T) {
+	root := setupObjectStore(t)
+	h, _ := WriteBlob(root, []byte("packed"))
+	WritePack(root, []string{h})
+	removeLoose(t, root, h)
+
+	if _, err := WriteBlob(root, []byte("packed")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.ObjectsPath(root), h[:2], h[2:])); !os.IsNotExist(err) {
+		t.Error("packed object should not be rewritten as a loose object")
+	}
+}
+
+func TestHasObject(t *testing.T) {
+	root := setupObjectStore(t)
+	loose, _ := WriteBlob(root, []byte("loose"))
+	packed, _ := WriteBlob(root, []byte("packed"))
+	WritePack(root, []string{packed})
+	removeLoose(t, root, packed)
+
+	if !HasObject(root, loose) || !HasObject(root, packed) {
+		t.Error("expected both objects to exist")
+	}
+	if HasObject(root, "0000000000000000000000000000000000000000") || HasObject(root, "ab") {
+		t.Error("unexpected object found")
+	}
+}
+
+func TestLooseAndPackedObjects(t *testing.T) {
+	root := setupObjectStore(t)
+	h1, _ := WriteBlob(root, []byte("one"))
+	h2, _ := WriteBlob(root, []byte("two"))
+	os.MkdirAll(repo.PackPath(root), 0755)
+
+	loose, err := LooseObjects(root)
+	if err != nil || len(loose) != 2 {
+		t.Fatalf("expected 2 loose objects, got %v (%v)", loose, err)
+	}
+
+	stats, _ := WritePack(root, []string{h1, h2})
+	packed, err := PackedObjects(root)
+	if err != nil || len(packed) != 2 {
+		t.Fatalf("expected 2 packed objects, got %v (%v)", packed, err)
+	}
+	names, err := PackFiles(root)
+	if err != nil || len(names) != 1 || names[0] != stats.Name {
+		t.Errorf("unexpected pack files: %v (%v)", names, err)
+	}
+}
+
+func TestLooseObjects_NoDir(t *testing.T) {
+	hashes, err := LooseObjects(t.TempDir())
+	if err != nil || hashes != nil {
+		t.Errorf("expected no objects, got %v (%v)", hashes, err)
+	}
+}
+
+func TestReadObject_CorruptPackIndex(t *testing.T) {
+	root := setupObjectStore(t)
+	h, _ := WriteBlob(root, []byte("packed"))
+	stats, _ := WritePack(root, []string{h})
+	removeLoose(t, root, h)
+	os.WriteFile(filepath.Join(repo.PackPath(root), "pack-0000.idx"), []byte("garbage"), 0644)
+
+	// The unreadable index is skipped and the valid pack still serves.
+	if _, content, err := ReadObject(root, h); err != nil || string(content) != "packed" {
+		t.Fatalf("expected the packed object, got %q (%v)", content, err)
+	}
+	if !HasObject(root, h) {
+		t.Error("HasObject should find the packed object")
+	}
+	if hashes, err := PackedObjects(root); err != nil || len(hashes) != 1 {
+		t.Errorf("expected one packed object, got %v (%v)", hashes, err)
+	}
+	if names, err := PackFiles(root); err != nil || len(names) != 1 || names[0] != stats.Name {
+		t.Errorf("expected only the readable pack, got %v (%v)", names, err)
+	}
+}
+
+func TestListPackIndexes_Cache(t *testing.T) {
+	root := setupObjectStore(t)
+	h1, _ := WriteBlob(root, []byte("one"))
+	WritePack(root, []string{h1})
+
+	first, err := listPackIndexes(root)
+	if err != nil || len(first) != 1 {
+		t.Fatalf("expected one pack, got %d (%v)", len(first), err)
+	}
+	if again, _ := listPackIndexes(root); again[0] != first[0] {
+		t.Error("an unchanged pack directory should not be parsed again")
+	}
+
+	h2, _ := WriteBlob(root, []byte("two"))
+	WritePack(root, []string{h2})
+	removeLoose(t, root, h1, h2)
+	if _, content, err := ReadObject(root, h2); err != nil || string(content) != "two" {
+		t.Errorf("a new pack should be seen, got %q (%v)", content, err)
+	}
+
+	entries, _ := os.ReadDir(repo.PackPath(root))
+	if len(entries) != 4 {
+		t.Errorf("expected two packs and their indexes and no temporary files, got %d entries", len(entries))
+	}
+}
+
+func TestReadPackIndex_ChecksumMismatch(t *testing.T) {
+	root := setupObjectStore(t)
+	h, _ := WriteBlob(root, []byte("x"))
+	stats, _ := WritePack(root, []string{h})
+	idxPath := filepath.Join(repo.PackPath(root), stats.Name+".idx")
+
+	data, _ := os.ReadFile(idxPath)
+	data[len(data)-1] ^= 0xff
+	os.Chmod(idxPath, 0644)
+	os.WriteFile(idxPath, data, 0644)
+
+	if _, err := readPackIndex(idxPath); err == nil {
+		t.Fatal("expected checksum error")
+	}
+}
+
+func TestPackIndex_LargeOffsets(t *testing.T) {
+	entries := []*packEntry{
+		{hash: "1111111111111111111111111111111111111111", offset: 12},
+		{hash: "2222222222222222222222222222222222222222", offset: 0x90000000},
+	}
+	data, err := encodePackIndex(entries, bytes.Repeat([]byte{0}, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "pack-x.idx")
+	os.WriteFile(path, data, 0644)
+
+	pi, err := readPackIndex(path)
+	if err != nil {
+		t.Fatalf("readPackIndex failed: %v", err)
+	}
+	if pi.find(entries[0].hash) != 12 || pi.find(entries[1].hash) != 0x90000000 {
+		t.Error("offsets did not roundtrip")
+	}
+	if pi.find("3333333333333333333333333333333333333333") != -1 || pi.find("zz") != -1 {
+		t.Error("unexpected match for absent hash")
+	}
+}
+
+func TestReadPackedObject_RefDelta(t *testing.T) {
+	root := setupObjectStore(t)
+	base := []byte(strings.Repeat("ref delta base\n", 20))
+	target := append(append([]byte{}, base...), "tail\n"...)
+	baseHash, _ := WriteBlob(root, base)
+	targetHash := HashBlob(target)
+
+	// Hand-build a thin pack holding only a REF_DELTA against a loose base.
+	delta := CreateDelta(base, target)
+	var raw bytes.Buffer
+	writePackObjectHeader(&raw, packRefDelta, len(delta))
+	baseRaw, _ := hex.DecodeString(baseHash)
+	raw.Write(baseRaw)
+	writeCompressed(&raw, delta)
+
+	var pack bytes.Buffer
+	pack.WriteString(packSignature)
+	pack.Write([]byte{0, 0, 0, 2, 0, 0, 0, 1})
+	pack.Write(raw.Bytes())
+	pack.Write(bytes.Repeat([]byte{0}, 20))
+
+	idx, _ := encodePackIndex([]*packEntry{{hash: targetHash, offset: 12}}, bytes.Repeat([]byte{0}, 20))
+	os.MkdirAll(repo.PackPath(root), 0755)
+	os.WriteFile(filepath.Join(repo.PackPath(root), "pack-thin.pack"), pack.Bytes(), 0644)
+	os.WriteFile(filepath.Join(repo.PackPath(root), "pack-thin.idx"), idx, 0644)
+
+	objType, content, err := ReadObject(root, targetHash)
+	if err != nil {
+		t.Fatalf("ReadObject failed: %v", err)
+	}
+	if objType != "blob" || !bytes.Equal(content, target) {
+		t.Error("REF_DELTA object did not resolve correctly")
+	}
+}
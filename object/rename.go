@@ -0,0 +1,246 @@
+package object
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultRenameThreshold is the similarity, in percent, from which a deleted
+// and an added file are taken to be a rename when no other is given.
+const DefaultRenameThreshold = 50
+
+// DefaultRenameLimit caps the sources and destinations compared by content
+// when no other limit is given.
+const DefaultRenameLimit = 1000
+
+// RenameOptions controls rename and copy detection.
+type RenameOptions struct {
+	// Threshold is the least similarity, in percent, for two files to be
+	// paired.
+	Threshold int
+	// Copies also pairs added files with modified ones, and lets a deleted
+	// file be the source of more than one added file.
+	Copies bool
+	// Limit skips the comparison by content when there are more sources or
+	// destinations than this; exact renames are still found. Zero means
+	// DefaultRenameLimit.
+	Limit int
+}
+
+// chunkSize caps the length of the pieces content is split into for
+// scoring, so that files without newlines can still be compared.
+const chunkSize = 64
+
+// renameCandidate is a file that may be paired: a source (the old side of
+// a deleted or modified file) or a destination (an added file).
+type renameCandidate struct {
+	change  int // index into the changes
+	path    string
+	mode    string
+	hash    string
+	deleted bool // a source that no longer exists
+}
+
+// renameMatch pairs a destination with a source.
+type renameMatch struct {
+	dst, src int
+	score    int
+}
+
+// DetectRenames replaces each added file in changes that is similar enough
+// to a deleted one with a single Change from OldPath to Path, and with
+// opts.Copies records copies of modified and deleted files as well. Files
+// with equal hashes are paired first, then the remaining ones by content,
+// most similar first. read returns the content of a blob. The result is
+// sorted by path.
+func DetectRenames(changes []Change, read func(hash string) ([]byte, error), opts RenameOptions) ([]Change, error) {
+	var srcs, dsts []renameCandidate
+	for i, c := range changes {
+		switch {
+		case c.Unmerged || c.OldPath != "":
+		case c.OldHash != "" && c.NewHash == "":
+			srcs = append(srcs, renameCandidate{i, c.Path, c.OldMode, c.OldHash, true})
+		case c.OldHash == "" && c.NewHash != "":
+			dsts = append(dsts, renameCandidate{i, c.Path, c.NewMode, c.NewHash, false})
+		case opts.Copies && c.OldHash != "" && c.NewHash != "":
+			srcs = append(srcs, renameCandidate{i, c.Path, c.OldMode, c.OldHash, false})
+		}
+	}
+	if len(srcs) == 0 || len(dsts) == 0 {
+		return changes, nil
+	}
+
+	srcOf := make([]int, len(dsts))
+	for i := range srcOf {
+		srcOf[i] = -1
+	}
+	used := make([]bool, len(srcs))
+	available := func(s int) bool { return opts.Copies || !used[s] }
+
+	// Exact renames, preferring a source with the same file name.
+	byHash := make(map[string][]int)
+	for s, src := range srcs {
+		if src.hash != emptyBlobHash {
+			byHash[src.hash] = append(byHash[src.hash], s)
+		}
+	}
+	for d, dst := range dsts {
+		best := -1
+		for _, s := range byHash[dst.hash] {
+			if !available(s) || !sameFileType(srcs[s].mode, dst.mode) {
+				continue
+			}
+			if best < 0 || path.Base(srcs[s].path) == path.Base(dst.path) && path.Base(srcs[best].path) != path.Base(dst.path) {
+				best = s
+			}
+		}
+		if best >= 0 {
+			srcOf[d], used[best] = best, true
+		}
+	}
+
+	// Renames by content.
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultRenameLimit
+	}
+	var matches []renameMatch
+	if len(srcs) <= limit && len(dsts) <= limit {
+		contents := make(map[string]*fileChunks)
+		load := func(hash string) (*fileChunks, error) {
+			if fc, ok := contents[hash]; ok {
+				return fc, nil
+			}
+			data, err := read(hash)
+			if err != nil {
+				return nil, err
+			}
+			fc := splitChunks(data)
+			contents[hash] = fc
+			return fc, nil
+		}
+		for d, dst := range dsts {
+			if srcOf[d] >= 0 || dst.hash == emptyBlobHash {
+				continue
+			}
+			dstChunks, err := load(dst.hash)
+			if err != nil {
+				return nil, err
+			}
+			for s, src := range srcs {
+				if !available(s) || src.hash == emptyBlobHash || !sameFileType(src.mode, dst.mode) {
+					continue
+				}
+				srcChunks, err := load(src.hash)
+				if err != nil {
+					return nil, err
+				}
+				if score := similarity(srcChunks, dstChunks, opts.Threshold); score >= opts.Threshold {
+					matches = append(matches, renameMatch{d, s, score})
+				}
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	score := make([]int, len(dsts))
+	for i := range score {
+		score[i] = 100
+	}
+	for _, m := range matches {
+		if srcOf[m.dst] >= 0 || !available(m.src) {
+			continue
+		}
+		srcOf[m.dst], used[m.src], score[m.dst] = m.src, true, m.score
+	}
+
+	// A deleted source becomes a rename to the first of its destinations
+	// and a copy to any others; a modified one is only ever copied.
+	renamedTo := make(map[int]int)
+	for d := range dsts {
+		s := srcOf[d]
+		if _, ok := renamedTo[s]; s >= 0 && srcs[s].deleted && !ok {
+			renamedTo[s] = d
+		}
+	}
+	drop := make(map[int]bool)
+	result := make([]Change, 0, len(changes))
+	for d, dst := range dsts {
+		s := srcOf[d]
+		if s < 0 {
+			continue
+		}
+		src := srcs[s]
+		c := &changes[dst.change]
+		paired := Change{
+			Path:       c.Path,
+			OldPath:    src.path,
+			OldMode:    src.mode,
+			OldHash:    src.hash,
+			NewMode:    c.NewMode,
+			NewHash:    c.NewHash,
+			Copied:     renamedTo[s] != d || !src.deleted,
+			Similarity: score[d],
+		}
+		result = append(result, paired)
+		drop[dst.change] = true
+		if !paired.Copied {
+			drop[src.change] = true
+		}
+	}
+	for i, c := range changes {
+		if !drop[i] {
+			result = append(result, c)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result, nil
+}
+
+// emptyBlobHash is the hash of an empty file. Empty files have nothing to
+// compare, so they are never paired.
+var emptyBlobHash = HashBlob(nil)
+
+// sameFileType reports whether two modes are of the same kind of file, so
+// that a symlink is not paired with a regular file.
+func sameFileType(a, b string) bool {
+	return strings.HasPrefix(a, "120") == strings.HasPrefix(b, "120")
+}
+
+// fileChunks is content split into lines of at most chunkSize bytes, with
+// the number of bytes each distinct chunk accounts for.
+type fileChunks struct {
+	size   int
+	chunks map[string]int
+}
+
+func splitChunks(data []byte) *fileChunks {
+	fc := &fileChunks{size: len(data), chunks: make(map[string]int)}
+	for len(data) > 0 {
+		n := 0
+		for n < len(data) && n < chunkSize {
+			n++
+			if data[n-1] == '\n' {
+				break
+			}
+		}
+		fc.chunks[string(data[:n])] += n
+		data = data[n:]
+	}
+	return fc
+}
+
+// similarity returns the share, in percent, of the larger of two files
+// made up of chunks found in both. Pairs whose sizes alone rule out
+// reaching threshold score zero without being compared.
+func similarity(a, b *fileChunks, threshold int) int {
+	larger, smaller := max(a.size, b.size), min(a.size, b.size)
+	if larger == 0 || smaller*100 < larger*threshold {
+		return 0
+	}
+	shared := 0
+	for chunk, n := range a.chunks {
+		shared += min(n, b.chunks[chunk])
+	}
+	return shared * 100 / larger
+}
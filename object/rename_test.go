@@ -0,0 +1,233 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// formatRenames renders changes as "R90 old -> new", "C100 old -> new",
+// "A path", "D path", "M path" or "U path".
+func formatRenames(changes []Change) string {
+	var parts []string
+	for _, c := range changes {
+		switch {
+		case c.Unmerged:
+			parts = append(parts, "U "+c.Path)
+		case c.OldPath != "" && c.Copied:
+			parts = append(parts, fmt.Sprintf("C%d %s -> %s", c.Similarity, c.OldPath, c.Path))
+		case c.OldPath != "":
+			parts = append(parts, fmt.Sprintf("R%d %s -> %s", c.Similarity, c.OldPath, c.Path))
+		case c.OldHash == "":
+			parts = append(parts, "A "+c.Path)
+		case c.NewHash == "":
+			parts = append(parts, "D "+c.Path)
+		default:
+			parts = append(parts, "M "+c.Path)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// renameFixture builds changes from blobs kept in memory and counts how
+// often they are read.
+type renameFixture struct {
+	blobs map[string][]byte
+	reads int
+}
+
+func (f *renameFixture) blob(content string) string {
+	hash := HashBlob([]byte(content))
+	f.blobs[hash] = []byte(content)
+	return hash
+}
+
+func (f *renameFixture) read(hash string) ([]byte, error) {
+	f.reads++
+	content, ok := f.blobs[hash]
+	if !ok {
+		return nil, fmt.Errorf("blob %s not found", hash)
+	}
+	return content, nil
+}
+
+// change returns an added (A), deleted (D) or modified (M) file; a modified
+// file gets content as its old side and content plus a line as its new one.
+func (f *renameFixture) change(status, path, mode, content string) Change {
+	c := Change{Path: path}
+	switch status {
+	case "A":
+		c.NewMode, c.NewHash = mode, f.blob(content)
+	case "D":
+		c.OldMode, c.OldHash = mode, f.blob(content)
+	case "M":
+		c.OldMode, c.OldHash = mode, f.blob(content)
+		c.NewMode, c.NewHash = mode, f.blob(content+"more\n")
+	}
+	return c
+}
+
+func newRenameFixture() *renameFixture {
+	return &renameFixture{blobs: make(map[string][]byte)}
+}
+
+// numbered returns n lines "prefix 1" to "prefix n".
+func numbered(prefix string, n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "%s %d\n", prefix, i)
+	}
+	return b.String()
+}
+
+func TestDetectRenames(t *testing.T) {
+	lines := numbered("line", 10)
+	edited := strings.Replace(lines, "line 5\n", "five\n", 1)
+	rewritten := numbered("other", 10)
+
+	tests := []struct {
+		name  string
+		files [][3]string // status, path, content
+		opts  RenameOptions
+		want  string
+	}{
+		{"exact", [][3]string{{"D", "a", lines}, {"A", "b", lines}}, RenameOptions{Threshold: 50}, "R100 a -> b"},
+		{"similar", [][3]string{{"D", "a", lines}, {"A", "b", edited}}, RenameOptions{Threshold: 50}, "R90 a -> b"},
+		{"below threshold", [][3]string{{"D", "a", lines}, {"A", "b", edited}}, RenameOptions{Threshold: 95}, "D a, A b"},
+		{"unrelated", [][3]string{{"D", "a", lines}, {"A", "b", rewritten}}, RenameOptions{Threshold: 50}, "D a, A b"},
+		{"best match wins", [][3]string{
+			{"D", "a", lines}, {"A", "b", edited}, {"A", "c", lines},
+		}, RenameOptions{Threshold: 50}, "A b, R100 a -> c"},
+		{"same name preferred", [][3]string{
+			{"D", "x/f", lines}, {"D", "y/g", lines}, {"A", "z/g", lines},
+		}, RenameOptions{Threshold: 50}, "D x/f, R100 y/g -> z/g"},
+		{"one rename per source", [][3]string{
+			{"D", "a", lines}, {"A", "b", lines}, {"A", "c", lines},
+		}, RenameOptions{Threshold: 50}, "R100 a -> b, A c"},
+		{"copies of a deleted file", [][3]string{
+			{"D", "a", lines}, {"A", "b", lines}, {"A", "c", edited},
+		}, RenameOptions{Threshold: 50, Copies: true}, "R100 a -> b, C90 a -> c"},
+		{"copy of a modified file", [][3]string{
+			{"M", "a", lines}, {"A", "b", lines},
+		}, RenameOptions{Threshold: 50, Copies: true}, "M a, C100 a -> b"},
+		{"modified files are not renamed", [][3]string{
+			{"M", "a", lines}, {"A", "b", lines},
+		}, RenameOptions{Threshold: 50}, "M a, A b"},
+		{"empty files", [][3]string{{"D", "a", ""}, {"A", "b", ""}}, RenameOptions{Threshold: 0}, "D a, A b"},
+	}
+	for _, tt := range tests {
+		f := newRenameFixture()
+		var changes []Change
+		for _, file := range tt.files {
+			changes = append(changes, f.change(file[0], file[1], "100644", file[2]))
+		}
+		got, err := DetectRenames(changes, f.read, tt.opts)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if s := formatRenames(got); s != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, s, tt.want)
+		}
+	}
+}
+
+func TestDetectRenames_KeepsModes(t *testing.T) {
+	f := newRenameFixture()
+	lines := numbered("line", 10)
+	changes := []Change{
+		f.change("D", "run.sh", "100755", lines),
+		f.change("A", "bin/run.sh", "100644", lines),
+		f.change("D", "link", "120000", lines),
+		f.change("A", "file", "100644", lines),
+	}
+	got, err := DetectRenames(changes, f.read, RenameOptions{Threshold: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A symlink is not paired with a regular file.
+	if s := formatRenames(got); s != "R100 run.sh -> bin/run.sh, A file, D link" {
+		t.Fatalf("unexpected result %q", s)
+	}
+	if got[0].OldMode != "100755" || got[0].NewMode != "100644" {
+		t.Errorf("expected the modes of both sides, got %s -> %s", got[0].OldMode, got[0].NewMode)
+	}
+}
+
+func TestDetectRenames_ExactNeedsNoContent(t *testing.T) {
+	f := newRenameFixture()
+	lines := numbered("line", 10)
+	changes := []Change{f.change("D", "a", "100644", lines), f.change("A", "b", "100644", lines)}
+	if _, err := DetectRenames(changes, f.read, RenameOptions{Threshold: 50}); err != nil {
+		t.Fatal(err)
+	}
+	if f.reads != 0 {
+		t.Errorf("expected no blobs to be read, got %d reads", f.reads)
+	}
+}
+
+func TestDetectRenames_Limit(t *testing.T) {
+	f := newRenameFixture()
+	lines := numbered("line", 10)
+	edited := strings.Replace(lines, "line 5\n", "five\n", 1)
+	changes := []Change{
+		f.change("D", "a", "100644", lines),
+		f.change("D", "b", "100644", edited),
+		f.change("A", "c", "100644", lines),
+		f.change("A", "d", "100644", edited+"x\n"),
+	}
+	got, err := DetectRenames(changes, f.read, RenameOptions{Threshold: 50, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := formatRenames(got); s != "D b, R100 a -> c, A d" {
+		t.Errorf("expected only the exact rename past the limit, got %q", s)
+	}
+}
+
+func TestDetectRenames_ReadError(t *testing.T) {
+	f := newRenameFixture()
+	changes := []Change{
+		f.change("D", "a", "100644", numbered("line", 10)),
+		f.change("A", "b", "100644", numbered("line", 11)),
+	}
+	f.blobs = map[string][]byte{}
+	if _, err := DetectRenames(changes, f.read, RenameOptions{Threshold: 50}); err == nil {
+		t.Error("expected the read error to be returned")
+	}
+}
+
+func TestDetectRenames_NothingToPair(t *testing.T) {
+	f := newRenameFixture()
+	changes := []Change{
+		f.change("M", "a", "100644", "x\n"),
+		{Path: "b", Unmerged: true},
+		f.change("A", "c", "100644", "x\n"),
+	}
+	got, err := DetectRenames(changes, f.read, RenameOptions{Threshold: 50})
+	if err != nil || formatRenames(got) != "M a, U b, A c" {
+		t.Errorf("expected changes unchanged, got %q, %v", formatRenames(got), err)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b      string
+		threshold int
+		want      int
+	}{
+		{"a\nb\nc\nd\n", "a\nb\nc\nd\n", 50, 100},
+		{"a\nb\nc\nd\n", "a\nb\nc\nx\n", 50, 75},
+		{"a\nb\nc\nd\n", "a\nb\n", 50, 50},
+		{"a\nb\nc\nd\n", "a\n", 50, 0}, // too small to reach the threshold
+		{"a\nb\nc\nd\n", "a\n", 0, 25},
+		// Lines longer than a chunk are split, so long lines still match
+		// in part.
+		{strings.Repeat("x", 128) + "\n", strings.Repeat("x", 64) + "y\n", 0, 49},
+	}
+	for _, tt := range tests {
+		if got := similarity(splitChunks([]byte(tt.a)), splitChunks([]byte(tt.b)), tt.threshold); got != tt.want {
+			t.Errorf("similarity(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
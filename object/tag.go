@@ -0,0 +1,75 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tag represents a parsed annotated tag object.
+type Tag struct {
+	Object  string
+	Type    string
+	Name    string
+	Tagger  string
+	Message string
+}
+
+// WriteTag creates an annotated tag object named name that points at the
+// object objHash of type objType, and returns its hash.
+func WriteTag(root, objHash, objType, name, message string) (string, error) {
+	tagger, err := Signature(root)
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "object %s\n", objHash)
+	fmt.Fprintf(&buf, "type %s\n", objType)
+	fmt.Fprintf(&buf, "tag %s\n", name)
+	fmt.Fprintf(&buf, "tagger %s\n", tagger)
+	fmt.Fprintf(&buf, "\n%s\n", message)
+
+	return WriteObject(root, "tag", []byte(buf.String()))
+}
+
+// ReadTag reads and parses a tag object.
+func ReadTag(root, hash string) (*Tag, error) {
+	objType, content, err := ReadObject(root, hash)
+	if err != nil {
+		return nil, err
+	}
+	if objType != "tag" {
+		return nil, fmt.Errorf("object %s is a %s, not a tag", hash, objType)
+	}
+	return ParseTag(content)
+}
+
+// ParseTag parses tag content into a Tag struct.
+func ParseTag(data []byte) (*Tag, error) {
+	t := &Tag{}
+	text := string(data)
+
+	parts := strings.SplitN(text, "\n\n", 2)
+	if len(parts) == 2 {
+		t.Message = strings.TrimSpace(parts[1])
+	}
+
+	for _, line := range strings.Split(parts[0], "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "object":
+			t.Object = value
+		case "type":
+			t.Type = value
+		case "tag":
+			t.Name = value
+		case "tagger":
+			t.Tagger = value
+		}
+	}
+
+	if t.Object == "" || t.Type == "" {
+		return nil, fmt.Errorf("invalid tag object: missing object or type")
+	}
+	return t, nil
+}
@@ -0,0 +1,63 @@
+package object
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteTagAndReadTag(t *testing.T) {
+	root := setupObjectStore(t)
+	blobHash, _ := WriteBlob(root, []byte("file"))
+	treeHash, _ := WriteTree(root, []TreeEntry{{Mode: "100644", Name: "f.txt", Hash: blobHash}})
+
+	t.Setenv("GOGIT_AUTHOR_NAME", "Test User")
+	t.Setenv("GOGIT_AUTHOR_EMAIL", "test@example.com")
+	commitHash, _ := WriteCommit(root, treeHash, nil, "initial commit")
+
+	hash, err := WriteTag(root, commitHash, "commit", "v1.0", "release 1.0\n\nnotes")
+	if err != nil {
+		t.Fatalf("WriteTag failed: %v", err)
+	}
+	objType, _, _ := ReadObject(root, hash)
+	if objType != "tag" {
+		t.Errorf("expected tag object, got %s", objType)
+	}
+
+	tag, err := ReadTag(root, hash)
+	if err != nil {
+		t.Fatalf("ReadTag failed: %v", err)
+	}
+	if tag.Object != commitHash || tag.Type != "commit" || tag.Name != "v1.0" {
+		t.Errorf("unexpected tag header: %+v", tag)
+	}
+	if !strings.HasPrefix(tag.Tagger, "Test User <test@example.com> ") {
+		t.Errorf("unexpected tagger: %q", tag.Tagger)
+	}
+	if tag.Message != "release 1.0\n\nnotes" {
+		t.Errorf("message mismatch: got %q", tag.Message)
+	}
+}
+
+func TestReadTag_NotATag(t *testing.T) {
+	root := setupObjectStore(t)
+	blobHash, _ := WriteBlob(root, []byte("file"))
+
+	_, err := ReadTag(root, blobHash)
+	if err == nil || !strings.Contains(err.Error(), "not a tag") {
+		t.Errorf("expected not a tag error, got %v", err)
+	}
+}
+
+func TestReadTag_NotFound(t *testing.T) {
+	root := setupObjectStore(t)
+
+	if _, err := ReadTag(root, "0000000000000000000000000000000000000001"); err == nil {
+		t.Error("expected error for missing object")
+	}
+}
+
+func TestParseTag_Invalid(t *testing.T) {
+	if _, err := ParseTag([]byte("tag v1\n\nmsg\n")); err == nil {
+		t.Error("expected error for tag without object and type")
+	}
+}
@@ -86,8 +86,14 @@ func ParseTree(data []byte) ([]TreeEntry, error) {
 }
 
 // BuildTreeFromIndex builds a tree hierarchy from index entries and writes
-// all tree objects to the store. Returns the root tree hash.
+// all tree objects to the store. Returns the root tree hash. It refuses to
+// write a tree while the index still holds unmerged entries. Intent-to-add
+// entries are left out.
 func BuildTreeFromIndex(root string, idx *index.Index) (string, error) {
+	if unmerged := idx.Unmerged(); len(unmerged) > 0 {
+		return "", fmt.Errorf("cannot write tree: unmerged entries for %s", strings.Join(unmerged, ", "))
+	}
+
 	// Group entries by directory
 	type dirEntry struct {
 		name    string
@@ -100,6 +106,9 @@ func BuildTreeFromIndex(root string, idx *index.Index) (string, error) {
 	rootDir := &dirEntry{entries: make(map[string]*dirEntry)}
 
 	for _, e := range idx.Entries {
+		if e.IntentToAdd() {
+			continue
+		}
 		parts := strings.Split(e.Path, "/")
 		cur := rootDir
 		for i, part := range parts {
@@ -180,3 +189,34 @@ func FlattenTree(root, treeHash, prefix string) (map[string]string, error) {
 	}
 	return result, nil
 }
+
+// LookupPath finds the entry at a slash-separated path below a tree. It
+// returns nil if the path does not exist.
+func LookupPath(root, treeHash, p string) (*TreeEntry, error) {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	hash := treeHash
+	for i, name := range parts {
+		entries, err := ReadTree(root, hash)
+		if err != nil {
+			return nil, err
+		}
+		var found *TreeEntry
+		for j := range entries {
+			if entries[j].Name == name {
+				found = &entries[j]
+				break
+			}
+		}
+		if found == nil {
+			return nil, nil
+		}
+		if i == len(parts)-1 {
+			return found, nil
+		}
+		if found.Mode != "40000" {
+			return nil, nil
+		}
+		hash = found.Hash
+	}
+	return nil, nil
+}
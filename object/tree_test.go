@@ -2,6 +2,7 @@ package object
 
 import (
 	"encoding/hex"
+	"strings"
 	"testing"
 
 	"gogit/index"
@@ -177,6 +178,28 @@ func TestBuildTreeFromIndex_Flat(t *testing.T) {
 	}
 }
 
+func TestBuildTreeFromIndex_SkipsIntentToAdd(t *testing.T) {
+	root := setupObjectStore(t)
+	h1, _ := WriteBlob(root, []byte("content1"))
+	empty, _ := WriteBlob(root, nil)
+
+	idx := &index.Index{
+		Entries: []index.Entry{
+			{Path: "a.txt", Hash: h1, Mode: 0100644},
+			{Path: "later/b.txt", Hash: empty, Mode: 0100644, Flags: index.FlagIntentToAdd},
+		},
+	}
+
+	treeHash, err := BuildTreeFromIndex(root, idx)
+	if err != nil {
+		t.Fatalf("BuildTreeFromIndex failed: %v", err)
+	}
+	flat, _ := FlattenTree(root, treeHash, "")
+	if len(flat) != 1 || flat["a.txt"] != h1 {
+		t.Errorf("expected only a.txt, got %v", flat)
+	}
+}
+
 func TestBuildTreeFromIndex_Nested(t *testing.T) {
 	root := setupObjectStore(t)
 	h1, _ := WriteBlob(root, []byte("root file"))
@@ -232,6 +255,22 @@ func TestBuildTreeFromIndex_BadBlobHash(t *testing.T) {
 	}
 }
 
+func TestBuildTreeFromIndex_RefusesUnmerged(t *testing.T) {
+	root := setupObjectStore(t)
+	h, _ := WriteBlob(root, []byte("x"))
+	idx := &index.Index{
+		Entries: []index.Entry{
+			{Path: "ok.txt", Hash: h, Mode: 0100644},
+			{Path: "conflict.txt", Hash: h, Mode: 0100644, Stage: index.StageOurs},
+		},
+	}
+
+	_, err := BuildTreeFromIndex(root, idx)
+	if err == nil || !strings.Contains(err.Error(), "conflict.txt") {
+		t.Fatalf("expected unmerged error naming the path, got %v", err)
+	}
+}
+
 func TestFlattenTree_WithPrefix(t *testing.T) {
 	root := setupObjectStore(t)
 	h1, _ := WriteBlob(root, []byte("content"))
@@ -278,3 +317,47 @@ func TestFlattenTree_BadSubtreeHash(t *testing.T) {
 		t.Fatal("expected error for bad subtree hash")
 	}
 }
+
+func TestLookupPath(t *testing.T) {
+	root := setupObjectStore(t)
+	blob, _ := WriteBlob(root, []byte("x"))
+	sub, _ := WriteTree(root, []TreeEntry{{Mode: "100644", Name: "f.txt", Hash: blob}})
+	top, _ := WriteTree(root, []TreeEntry{
+		{Mode: "40000", Name: "dir", Hash: sub},
+		{Mode: "100644", Name: "top.txt", Hash: blob},
+	})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"top.txt", blob},
+		{"dir", sub},
+		{"dir/", sub},
+		{"dir/f.txt", blob},
+		{"missing", ""},
+		{"dir/missing", ""},
+		{"top.txt/f.txt", ""},
+	}
+	for _, tt := range tests {
+		e, err := LookupPath(root, top, tt.path)
+		if err != nil {
+			t.Errorf("LookupPath(%q): unexpected error: %v", tt.path, err)
+			continue
+		}
+		got := ""
+		if e != nil {
+			got = e.Hash
+		}
+		if got != tt.want {
+			t.Errorf("LookupPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLookupPath_MissingTree(t *testing.T) {
+	root := setupObjectStore(t)
+	if _, err := LookupPath(root, "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", "a"); err == nil {
+		t.Error("expected error for missing tree")
+	}
+}
@@ -0,0 +1,210 @@
+package object
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gogit/index"
+)
+
+// Change is a file that differs between two trees, or between a tree and
+// the index. OldMode and OldHash are empty for an added file, NewMode and
+// NewHash for a deleted one. Modes are written as in trees, such as
+// "100644". A rename or copy found by DetectRenames has OldPath set to its
+// source, with the old mode and hash taken from there.
+type Change struct {
+	Path       string
+	OldPath    string
+	OldMode    string
+	OldHash    string
+	NewMode    string
+	NewHash    string
+	Unmerged   bool // the index holds only conflict stages for Path
+	Copied     bool // OldPath still exists, so Path is a copy of it
+	Similarity int  // percent of content shared with OldPath
+}
+
+// InPaths reports whether p is one of paths or lies in a directory among
+// them. An empty list, or a path of "" or ".", matches everything.
+func InPaths(p string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, q := range paths {
+		if q == "" || q == "." || p == q || strings.HasPrefix(p, q+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// dirInPaths reports whether directory dir may hold files matched by paths.
+func dirInPaths(dir string, paths []string) bool {
+	if InPaths(dir, paths) {
+		return true
+	}
+	for _, q := range paths {
+		if strings.HasPrefix(q, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffTrees compares two trees and returns the files that differ, sorted by
+// path. An empty hash stands for an empty tree. Subtrees with equal hashes
+// are skipped without being read, as are those outside paths (see
+// InPaths).
+func DiffTrees(root, oldTree, newTree string, paths []string) ([]Change, error) {
+	var changes []Change
+	if err := diffTrees(root, oldTree, newTree, "", paths, &changes); err != nil {
+		return nil, err
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func diffTrees(root, oldTree, newTree, prefix string, paths []string, changes *[]Change) error {
+	if oldTree == newTree {
+		return nil
+	}
+	oldEntries, err := readTreeOrEmpty(root, oldTree)
+	if err != nil {
+		return err
+	}
+	newEntries, err := readTreeOrEmpty(root, newTree)
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string][2]*TreeEntry)
+	for i := range oldEntries {
+		pair := byName[oldEntries[i].Name]
+		pair[0] = &oldEntries[i]
+		byName[oldEntries[i].Name] = pair
+	}
+	for i := range newEntries {
+		pair := byName[newEntries[i].Name]
+		pair[1] = &newEntries[i]
+		byName[newEntries[i].Name] = pair
+	}
+
+	for name, pair := range byName {
+		p := path.Join(prefix, name)
+		oldE, newE := pair[0], pair[1]
+
+		// A name that is a tree on one side and a file on the other is
+		// compared as two separate paths.
+		var oldSub, newSub string
+		if oldE != nil && oldE.Mode == "40000" {
+			oldSub, oldE = oldE.Hash, nil
+		}
+		if newE != nil && newE.Mode == "40000" {
+			newSub, newE = newE.Hash, nil
+		}
+		if (oldSub != "" || newSub != "") && dirInPaths(p, paths) {
+			if err := diffTrees(root, oldSub, newSub, p, paths, changes); err != nil {
+				return err
+			}
+		}
+
+		if oldE == nil && newE == nil || !InPaths(p, paths) {
+			continue
+		}
+		c := Change{Path: p}
+		if oldE != nil {
+			c.OldMode, c.OldHash = oldE.Mode, oldE.Hash
+		}
+		if newE != nil {
+			c.NewMode, c.NewHash = newE.Mode, newE.Hash
+		}
+		if c.OldMode != c.NewMode || c.OldHash != c.NewHash {
+			*changes = append(*changes, c)
+		}
+	}
+	return nil
+}
+
+func readTreeOrEmpty(root, hash string) ([]TreeEntry, error) {
+	if hash == "" {
+		return nil, nil
+	}
+	return ReadTree(root, hash)
+}
+
+// DiffTreeIndex compares a tree with the index and returns the files that
+// differ, sorted by path. An empty tree hash stands for an empty tree.
+// Paths whose index entries are all conflict stages are reported as
+// Unmerged, and intent-to-add entries count as absent.
+func DiffTreeIndex(root, treeHash string, idx *index.Index, paths []string) ([]Change, error) {
+	tree, err := FlattenTreeEntries(root, treeHash, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for i := range idx.Entries {
+		e := &idx.Entries[i]
+		if !InPaths(e.Path, paths) || e.IntentToAdd() {
+			continue
+		}
+		if e.Stage != index.StageMerged {
+			if len(changes) == 0 || changes[len(changes)-1].Path != e.Path {
+				changes = append(changes, Change{Path: e.Path, Unmerged: true})
+			}
+			delete(tree, e.Path)
+			continue
+		}
+		c := Change{Path: e.Path, NewMode: fmt.Sprintf("%o", e.Mode), NewHash: e.Hash}
+		if te, ok := tree[e.Path]; ok {
+			c.OldMode, c.OldHash = te.Mode, te.Hash
+			delete(tree, e.Path)
+		}
+		if c.OldMode != c.NewMode || c.OldHash != c.NewHash {
+			changes = append(changes, c)
+		}
+	}
+	for p, te := range tree {
+		changes = append(changes, Change{Path: p, OldMode: te.Mode, OldHash: te.Hash})
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// FlattenTreeEntries returns the files below a tree that lie within paths,
+// keyed by path, with Name set to the full path. An empty hash stands for
+// an empty tree.
+func FlattenTreeEntries(root, treeHash string, paths []string) (map[string]TreeEntry, error) {
+	entries := make(map[string]TreeEntry)
+	if err := flattenEntries(root, treeHash, "", paths, entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// flattenEntries adds the files below a tree that lie within paths to
+// entries, keyed by path, with Name set to the full path.
+func flattenEntries(root, treeHash, prefix string, paths []string, entries map[string]TreeEntry) error {
+	list, err := readTreeOrEmpty(root, treeHash)
+	if err != nil {
+		return err
+	}
+	for _, e := range list {
+		p := path.Join(prefix, e.Name)
+		if e.Mode == "40000" {
+			if dirInPaths(p, paths) {
+				if err := flattenEntries(root, e.Hash, p, paths, entries); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if InPaths(p, paths) {
+			e.Name = p
+			entries[p] = e
+		}
+	}
+	return nil
+}
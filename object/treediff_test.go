@@ -0,0 +1,189 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gogit/index"
+)
+
+// formatChanges renders changes as "path old->new" with short hashes, or
+// "path unmerged".
+func formatChanges(changes []Change) string {
+	short := func(mode, hash string) string {
+		if hash == "" {
+			return "-"
+		}
+		return mode + ":" + hash[:4]
+	}
+	var parts []string
+	for _, c := range changes {
+		if c.Unmerged {
+			parts = append(parts, c.Path+" unmerged")
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s %s->%s", c.Path, short(c.OldMode, c.OldHash), short(c.NewMode, c.NewHash)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func TestInPaths(t *testing.T) {
+	tests := []struct {
+		p     string
+		paths []string
+		want  bool
+	}{
+		{"a.txt", nil, true},
+		{"a.txt", []string{"a.txt"}, true},
+		{"dir/a.txt", []string{"dir"}, true},
+		{"dir/sub/a.txt", []string{"dir/sub"}, true},
+		{"dir.txt", []string{"dir"}, false},
+		{"dir/a.txt", []string{"dir/a"}, false},
+		{"other", []string{"dir", "other"}, true},
+		{"x", []string{"."}, true},
+	}
+	for _, tt := range tests {
+		if got := InPaths(tt.p, tt.paths); got != tt.want {
+			t.Errorf("InPaths(%q, %q) = %v, want %v", tt.p, tt.paths, got, tt.want)
+		}
+	}
+}
+
+func TestDiffTrees(t *testing.T) {
+	root := setupObjectStore(t)
+	one, _ := WriteBlob(root, []byte("1"))
+	two, _ := WriteBlob(root, []byte("2"))
+	same, _ := WriteTree(root, []TreeEntry{{Mode: "100644", Name: "s", Hash: one}})
+	oldSub, _ := WriteTree(root, []TreeEntry{
+		{Mode: "100644", Name: "a", Hash: one},
+		{Mode: "100644", Name: "b", Hash: one},
+	})
+	newSub, _ := WriteTree(root, []TreeEntry{
+		{Mode: "100644", Name: "a", Hash: two},
+		{Mode: "100755", Name: "b", Hash: one},
+		{Mode: "100644", Name: "c", Hash: one},
+	})
+	fileToDir, _ := WriteTree(root, []TreeEntry{{Mode: "100644", Name: "x", Hash: two}})
+	oldTree, _ := WriteTree(root, []TreeEntry{
+		{Mode: "40000", Name: "dir", Hash: oldSub},
+		{Mode: "40000", Name: "same", Hash: same},
+		{Mode: "100644", Name: "gone", Hash: one},
+		{Mode: "100644", Name: "swap", Hash: one},
+	})
+	newTree, _ := WriteTree(root, []TreeEntry{
+		{Mode: "40000", Name: "dir", Hash: newSub},
+		{Mode: "40000", Name: "same", Hash: same},
+		{Mode: "40000", Name: "swap", Hash: fileToDir},
+		{Mode: "100644", Name: "dir.txt", Hash: two},
+	})
+
+	changes, err := DiffTrees(root, oldTree, newTree, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "dir.txt -->100644:" + two[:4] +
+		", dir/a 100644:" + one[:4] + "->100644:" + two[:4] +
+		", dir/b 100644:" + one[:4] + "->100755:" + one[:4] +
+		", dir/c -->100644:" + one[:4] +
+		", gone 100644:" + one[:4] + "->-" +
+		", swap 100644:" + one[:4] + "->-" +
+		", swap/x -->100644:" + two[:4]
+	if got := formatChanges(changes); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	changes, _ = DiffTrees(root, oldTree, newTree, []string{"dir"})
+	if got := formatChanges(changes); !strings.HasPrefix(got, "dir/a ") || strings.Contains(got, "dir.txt") || len(changes) != 3 {
+		t.Errorf("expected only the files in dir, got %s", got)
+	}
+
+	changes, _ = DiffTrees(root, "", oldSub, nil)
+	if got := formatChanges(changes); got != "a -->100644:"+one[:4]+", b -->100644:"+one[:4] {
+		t.Errorf("expected everything added against an empty tree, got %s", got)
+	}
+	if changes, _ := DiffTrees(root, oldTree, oldTree, nil); len(changes) != 0 {
+		t.Errorf("expected no changes between equal trees, got %s", formatChanges(changes))
+	}
+}
+
+func TestDiffTrees_SkipsEqualSubtrees(t *testing.T) {
+	root := setupObjectStore(t)
+	blob, _ := WriteBlob(root, []byte("x"))
+	// The subtree does not exist, so reading it would fail.
+	missing := "1111111111111111111111111111111111111111"
+	oldTree, _ := WriteTree(root, []TreeEntry{{Mode: "40000", Name: "big", Hash: missing}})
+	newTree, _ := WriteTree(root, []TreeEntry{
+		{Mode: "40000", Name: "big", Hash: missing},
+		{Mode: "100644", Name: "f", Hash: blob},
+	})
+
+	changes, err := DiffTrees(root, oldTree, newTree, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "f" {
+		t.Errorf("expected only f, got %s", formatChanges(changes))
+	}
+
+	// Subtrees outside the paths are not read either.
+	otherTree, _ := WriteTree(root, []TreeEntry{{Mode: "40000", Name: "big", Hash: blob}})
+	if _, err := DiffTrees(root, newTree, otherTree, []string{"f"}); err != nil {
+		t.Errorf("expected big to be skipped, got %v", err)
+	}
+	if _, err := DiffTrees(root, newTree, otherTree, nil); err == nil {
+		t.Error("expected an error reading the missing subtree")
+	}
+}
+
+func TestDiffTreeIndex(t *testing.T) {
+	root := setupObjectStore(t)
+	one, _ := WriteBlob(root, []byte("1"))
+	two, _ := WriteBlob(root, []byte("2"))
+	sub, _ := WriteTree(root, []TreeEntry{{Mode: "100644", Name: "a", Hash: one}})
+	tree, _ := WriteTree(root, []TreeEntry{
+		{Mode: "40000", Name: "dir", Hash: sub},
+		{Mode: "100644", Name: "conflict", Hash: one},
+		{Mode: "100644", Name: "gone", Hash: one},
+		{Mode: "100644", Name: "same", Hash: one},
+		{Mode: "100644", Name: "mode", Hash: one},
+	})
+
+	idx := &index.Index{}
+	idx.AddEntries([]index.Entry{
+		{Path: "dir/a", Hash: two, Mode: 0100644},
+		{Path: "conflict", Hash: one, Mode: 0100644, Stage: index.StageOurs},
+		{Path: "conflict", Hash: two, Mode: 0100644, Stage: index.StageTheirs},
+		{Path: "same", Hash: one, Mode: 0100644},
+		{Path: "mode", Hash: one, Mode: 0100755},
+		{Path: "new", Hash: two, Mode: 0100644},
+		{Path: "planned", Hash: two, Mode: 0100644, Flags: index.FlagIntentToAdd},
+	})
+
+	changes, err := DiffTreeIndex(root, tree, idx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "conflict unmerged" +
+		", dir/a 100644:" + one[:4] + "->100644:" + two[:4] +
+		", gone 100644:" + one[:4] + "->-" +
+		", mode 100644:" + one[:4] + "->100755:" + one[:4] +
+		", new -->100644:" + two[:4]
+	if got := formatChanges(changes); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	changes, _ = DiffTreeIndex(root, tree, idx, []string{"dir", "gone"})
+	if got := formatChanges(changes); got != "dir/a 100644:"+one[:4]+"->100644:"+two[:4]+", gone 100644:"+one[:4]+"->-" {
+		t.Errorf("expected only dir and gone, got %s", got)
+	}
+
+	changes, _ = DiffTreeIndex(root, "", idx, []string{"same"})
+	if got := formatChanges(changes); got != "same -->100644:"+one[:4] {
+		t.Errorf("expected same added against an empty tree, got %s", got)
+	}
+
+	if _, err := DiffTreeIndex(root, "1111111111111111111111111111111111111111", idx, nil); err == nil {
+		t.Error("expected an error for a missing tree")
+	}
+}
@@ -0,0 +1,74 @@
+package object
+
+import "container/heap"
+
+// RevList returns the commits reachable from include but not from exclude,
+// newest first by committer date. Commits with equal dates keep the order
+// in which the walk reached them, so a linear history is listed from its
+// tip down regardless of timestamps.
+func RevList(root string, include, exclude []string) ([]string, error) {
+	hidden, err := Ancestors(root, exclude...)
+	if err != nil {
+		return nil, err
+	}
+
+	var queue commitQueue
+	seen := make(map[string]bool)
+	seq := 0
+	push := func(hash string) error {
+		if seen[hash] || hidden[hash] {
+			return nil
+		}
+		seen[hash] = true
+		commit, err := ReadCommit(root, hash)
+		if err != nil {
+			return err
+		}
+		heap.Push(&queue, queuedCommit{hash, commit, commitTime(commit.Committer), seq})
+		seq++
+		return nil
+	}
+
+	for _, h := range include {
+		if err := push(h); err != nil {
+			return nil, err
+		}
+	}
+	var result []string
+	for queue.Len() > 0 {
+		c := heap.Pop(&queue).(queuedCommit)
+		result = append(result, c.hash)
+		for _, p := range c.commit.Parents {
+			if err := push(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+type queuedCommit struct {
+	hash   string
+	commit *Commit
+	time   int64
+	seq    int
+}
+
+// commitQueue is a max-heap on commit time, breaking ties by arrival order.
+type commitQueue []queuedCommit
+
+func (q commitQueue) Len() int { return len(q) }
+func (q commitQueue) Less(i, j int) bool {
+	if q[i].time != q[j].time {
+		return q[i].time > q[j].time
+	}
+	return q[i].seq < q[j].seq
+}
+func (q commitQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)   { *q = append(*q, x.(queuedCommit)) }
+func (q *commitQueue) Pop() any {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
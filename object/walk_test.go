@@ -0,0 +1,82 @@
+package object
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRevList_Linear(t *testing.T) {
+	root := setupObjectStore(t)
+	c1 := writeTestCommit(t, root, "c1")
+	c2 := writeTestCommit(t, root, "c2", c1)
+	c3 := writeTestCommit(t, root, "c3", c2)
+
+	got, err := RevList(root, []string{c3}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint([]string{c3, c2, c1}) {
+		t.Errorf("expected tip-first order, got %v", got)
+	}
+}
+
+func TestRevList_Exclude(t *testing.T) {
+	root := setupObjectStore(t)
+	c1 := writeTestCommit(t, root, "c1")
+	c2 := writeTestCommit(t, root, "c2", c1)
+	c3 := writeTestCommit(t, root, "c3", c2)
+
+	got, err := RevList(root, []string{c3}, []string{c1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint([]string{c3, c2}) {
+		t.Errorf("expected c3 c2, got %v", got)
+	}
+}
+
+func TestRevList_MergeVisitsAllParents(t *testing.T) {
+	root := setupObjectStore(t)
+	base := writeTestCommit(t, root, "base")
+	a := writeTestCommit(t, root, "a", base)
+	b := writeTestCommit(t, root, "b", base)
+	m := writeTestCommit(t, root, "merge", a, b)
+
+	got, err := RevList(root, []string{m}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint([]string{m, a, b, base}) {
+		t.Errorf("expected each commit once in walk order, got %v", got)
+	}
+}
+
+func TestRevList_NewestFirst(t *testing.T) {
+	root := setupObjectStore(t)
+	tree, _ := WriteTree(root, nil)
+	old, _ := WriteObject(root, "commit", []byte("tree "+tree+"\nauthor A <a> 100 +0000\ncommitter A <a> 100 +0000\n\nold\n"))
+	newer, _ := WriteObject(root, "commit", []byte("tree "+tree+"\nauthor A <a> 200 +0000\ncommitter A <a> 200 +0000\n\nnew\n"))
+
+	got, err := RevList(root, []string{old, newer}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint([]string{newer, old}) {
+		t.Errorf("expected newest first, got %v", got)
+	}
+}
+
+func TestRevList_MissingCommit(t *testing.T) {
+	root := setupObjectStore(t)
+	if _, err := RevList(root, []string{"deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"}, nil); err == nil {
+		t.Error("expected error for missing include")
+	}
+	c1 := writeTestCommit(t, root, "c1")
+	if _, err := RevList(root, []string{c1}, []string{"deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"}); err == nil {
+		t.Error("expected error for missing exclude")
+	}
+	bad := writeTestCommit(t, root, "bad", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	if _, err := RevList(root, []string{bad}, nil); err == nil {
+		t.Error("expected error for missing parent")
+	}
+}
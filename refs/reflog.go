@@ -0,0 +1,215 @@
+package refs
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gogit/object"
+	"gogit/repo"
+)
+
+// ZeroHash stands for "no commit" on either side of a reflog entry.
+const ZeroHash = "0000000000000000000000000000000000000000"
+
+// ReflogEntry is a single line of a reflog: the ref moved from Old to New,
+// by Identity ("Name <email> <unix time> <tz>"), for the given Message.
+type ReflogEntry struct {
+	Old      string
+	New      string
+	Identity string
+	Message  string
+}
+
+// Time returns when the update was recorded, or the zero time if the
+// identity carries no timestamp.
+func (e ReflogEntry) Time() time.Time {
+	fields := strings.Fields(e.Identity)
+	if len(fields) < 2 {
+		return time.Time{}
+	}
+	ts, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0)
+}
+
+func (e ReflogEntry) String() string {
+	return fmt.Sprintf("%s %s %s\t%s\n", e.Old, e.New, e.Identity, e.Message)
+}
+
+// ReflogPath returns the file holding the reflog of a ref.
+func ReflogPath(root, refPath string) string {
+	return filepath.Join(repo.LogsPath(root), filepath.FromSlash(refPath))
+}
+
+// hasReflog reports whether updates to refPath are logged: HEAD and
+// branches are, as with git's default core.logAllRefUpdates.
+func hasReflog(refPath string) bool {
+	return refPath == "HEAD" || strings.HasPrefix(refPath, "refs/heads/")
+}
+
+// AppendReflog records that refPath moved from oldHash to newHash. Empty
+// hashes are written as ZeroHash.
+func AppendReflog(root, refPath, oldHash, newHash, message string) error {
+	if oldHash == "" {
+		oldHash = ZeroHash
+	}
+	if newHash == "" {
+		newHash = ZeroHash
+	}
+	identity, err := object.Signature(root)
+	if err != nil {
+		return err
+	}
+	entry := ReflogEntry{
+		Old:      oldHash,
+		New:      newHash,
+		Identity: identity,
+		Message:  strings.ReplaceAll(message, "\n", " "),
+	}
+
+	logPath := ReflogPath(root, refPath)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(entry.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadReflog returns the reflog of a ref, oldest entry first. A ref without
+// a reflog has no entries.
+func ReadReflog(root, refPath string) ([]ReflogEntry, error) {
+	f, err := os.Open(ReflogPath(root, refPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []ReflogEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		head, message, _ := strings.Cut(line, "\t")
+		fields := strings.SplitN(head, " ", 3)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("invalid reflog entry in %s: %q", refPath, line)
+		}
+		entries = append(entries, ReflogEntry{
+			Old:      fields[0],
+			New:      fields[1],
+			Identity: fields[2],
+			Message:  message,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// writeReflog replaces the reflog of a ref with entries.
+func writeReflog(root, refPath string, entries []ReflogEntry) error {
+	var buf strings.Builder
+	for _, e := range entries {
+		buf.WriteString(e.String())
+	}
+	logPath := ReflogPath(root, refPath)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(logPath, []byte(buf.String()), 0644)
+}
+
+// ListReflogs returns the refs that have a reflog, e.g. "HEAD" and
+// "refs/heads/main".
+func ListReflogs(root string) ([]string, error) {
+	logsDir := repo.LogsPath(root)
+	var names []string
+	err := filepath.WalkDir(logsDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == logsDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(logsDir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	return names, err
+}
+
+// ExpireReflogs drops reflog entries recorded before cutoff from every
+// reflog and returns how many were removed.
+func ExpireReflogs(root string, cutoff time.Time) (int, error) {
+	names, err := ListReflogs(root)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, name := range names {
+		entries, err := ReadReflog(root, name)
+		if err != nil {
+			return removed, err
+		}
+		var kept []ReflogEntry
+		for _, e := range entries {
+			if e.Time().Before(cutoff) {
+				continue
+			}
+			kept = append(kept, e)
+		}
+		if len(kept) == len(entries) {
+			continue
+		}
+		if err := writeReflog(root, name, kept); err != nil {
+			return removed, err
+		}
+		removed += len(entries) - len(kept)
+	}
+	return removed, nil
+}
+
+// reflogEntryAt returns the value ref@{n} names: the ref's value n updates
+// ago.
+func reflogEntryAt(root, refPath, current string, n int) (string, error) {
+	if n == 0 {
+		return current, nil
+	}
+	entries, err := ReadReflog(root, refPath)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "", fmt.Errorf("no reflog for '%s'", refPath)
+	}
+	if n >= len(entries) {
+		return "", fmt.Errorf("log for '%s' only has %d entries", refPath, len(entries))
+	}
+	return entries[len(entries)-1-n].New, nil
+}
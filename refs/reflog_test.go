@@ -0,0 +1,223 @@
+package refs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gogit/repo"
+)
+
+func TestAppendReflog_RoundTrip(t *testing.T) {
+	root := setupRefsDir(t)
+	t.Setenv("GOGIT_AUTHOR_NAME", "Test")
+	t.Setenv("GOGIT_AUTHOR_EMAIL", "test@test.com")
+
+	if err := AppendReflog(root, "refs/heads/main", "", "aaa", "commit (initial): one"); err != nil {
+		t.Fatalf("AppendReflog failed: %v", err)
+	}
+	if err := AppendReflog(root, "refs/heads/main", "aaa", "bbb", "commit: two\nlines"); err != nil {
+		t.Fatalf("AppendReflog failed: %v", err)
+	}
+
+	entries, err := ReadReflog(root, "refs/heads/main")
+	if err != nil {
+		t.Fatalf("ReadReflog failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Old != ZeroHash || entries[0].New != "aaa" || entries[0].Message != "commit (initial): one" {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Old != "aaa" || entries[1].New != "bbb" || entries[1].Message != "commit: two lines" {
+		t.Errorf("unexpected second entry: %+v", entries[1])
+	}
+	if !strings.HasPrefix(entries[0].Identity, "Test <test@test.com> ") {
+		t.Errorf("unexpected identity: %q", entries[0].Identity)
+	}
+	if time.Since(entries[0].Time()) > time.Minute {
+		t.Errorf("unexpected entry time: %v", entries[0].Time())
+	}
+}
+
+func TestReadReflog_Missing(t *testing.T) {
+	root := setupRefsDir(t)
+	entries, err := ReadReflog(root, "refs/heads/none")
+	if err != nil || entries != nil {
+		t.Errorf("expected no entries, got %v (%v)", entries, err)
+	}
+}
+
+func TestReadReflog_Invalid(t *testing.T) {
+	root := setupRefsDir(t)
+	os.MkdirAll(filepath.Join(repo.LogsPath(root), "refs", "heads"), 0755)
+	os.WriteFile(ReflogPath(root, "refs/heads/main"), []byte("garbage\n"), 0644)
+	if _, err := ReadReflog(root, "refs/heads/main"); err == nil {
+		t.Fatal("expected error for malformed entry")
+	}
+}
+
+func TestReadReflog_Unreadable(t *testing.T) {
+	root := setupRefsDir(t)
+	os.MkdirAll(ReflogPath(root, "HEAD"), 0755)
+	if _, err := ReadReflog(root, "HEAD"); err == nil {
+		t.Fatal("expected error when reflog is a directory")
+	}
+}
+
+func TestReflogEntry_TimeWithoutTimestamp(t *testing.T) {
+	for _, ident := range []string{"", "Name <e> notanumber +0000"} {
+		if !(ReflogEntry{Identity: ident}).Time().IsZero() {
+			t.Errorf("expected zero time for identity %q", ident)
+		}
+	}
+}
+
+func TestWriteRef_LogsBranchAndHead(t *testing.T) {
+	root := setupRefsDir(t)
+	os.WriteFile(repo.HeadPath(root), []byte("ref: refs/heads/main\n"), 0644)
+
+	WriteRef(root, "refs/heads/main", "aaa", "commit (initial): one")
+	WriteRef(root, "refs/heads/main", "bbb", "commit: two")
+	WriteRef(root, "refs/heads/other", "ccc", "branch: Created from HEAD")
+
+	mainLog, _ := ReadReflog(root, "refs/heads/main")
+	if len(mainLog) != 2 || mainLog[1].Old != "aaa" || mainLog[1].New != "bbb" {
+		t.Errorf("unexpected main reflog: %+v", mainLog)
+	}
+	headLog, _ := ReadReflog(root, "HEAD")
+	if len(headLog) != 2 || headLog[1].Message != "commit: two" {
+		t.Errorf("expected HEAD to log updates of the current branch, got %+v", headLog)
+	}
+	otherLog, _ := ReadReflog(root, "refs/heads/other")
+	if len(otherLog) != 1 || otherLog[0].Old != ZeroHash {
+		t.Errorf("unexpected other reflog: %+v", otherLog)
+	}
+}
+
+func TestWriteRef_UnloggedRef(t *testing.T) {
+	root := setupRefsDir(t)
+	WriteRef(root, "refs/tags/v1", "aaa", "tag")
+	if _, err := os.Stat(ReflogPath(root, "refs/tags/v1")); !os.IsNotExist(err) {
+		t.Error("expected no reflog for a tag")
+	}
+}
+
+func TestWriteRef_ReflogError(t *testing.T) {
+	root := setupRefsDir(t)
+	// A file where the logs directory should be blocks the reflog.
+	os.WriteFile(repo.LogsPath(root), []byte("blocker"), 0644)
+	if err := WriteRef(root, "refs/heads/main", "aaa", "test"); err == nil {
+		t.Fatal("expected error when reflog cannot be written")
+	}
+}
+
+func TestUpdateHead_LogsMove(t *testing.T) {
+	root := setupRefsDir(t)
+	os.WriteFile(repo.HeadPath(root), []byte("ref: refs/heads/main\n"), 0644)
+	WriteRef(root, "refs/heads/main", "aaa", "test")
+	WriteRef(root, "refs/heads/feature", "bbb", "test")
+
+	if err := UpdateHead(root, "ref: refs/heads/feature", "checkout: moving from main to feature"); err != nil {
+		t.Fatalf("UpdateHead failed: %v", err)
+	}
+	if err := UpdateHead(root, "ccc", "checkout: moving from feature to ccc"); err != nil {
+		t.Fatalf("UpdateHead failed: %v", err)
+	}
+
+	log, _ := ReadReflog(root, "HEAD")
+	if len(log) != 3 {
+		t.Fatalf("expected 3 HEAD entries, got %d", len(log))
+	}
+	if log[1].Old != "aaa" || log[1].New != "bbb" || log[1].Message != "checkout: moving from main to feature" {
+		t.Errorf("unexpected checkout entry: %+v", log[1])
+	}
+	if log[2].Old != "bbb" || log[2].New != "ccc" {
+		t.Errorf("unexpected detach entry: %+v", log[2])
+	}
+}
+
+func TestListReflogs(t *testing.T) {
+	root := setupRefsDir(t)
+	names, err := ListReflogs(root)
+	if err != nil || len(names) != 0 {
+		t.Fatalf("expected no reflogs, got %v (%v)", names, err)
+	}
+
+	os.WriteFile(repo.HeadPath(root), []byte("ref: refs/heads/main\n"), 0644)
+	WriteRef(root, "refs/heads/main", "aaa", "test")
+	WriteRef(root, "refs/heads/feature/x", "bbb", "test")
+
+	names, err = ListReflogs(root)
+	if err != nil {
+		t.Fatalf("ListReflogs failed: %v", err)
+	}
+	want := "HEAD refs/heads/feature/x refs/heads/main"
+	if strings.Join(names, " ") != want {
+		t.Errorf("expected %q, got %q", want, strings.Join(names, " "))
+	}
+}
+
+func TestExpireReflogs(t *testing.T) {
+	root := setupRefsDir(t)
+	os.MkdirAll(filepath.Join(repo.LogsPath(root), "refs", "heads"), 0755)
+	old := ReflogEntry{ZeroHash, "aaa", "T <t> 100 +0000", "old"}
+	recent := ReflogEntry{"aaa", "bbb", "T <t> 5000 +0000", "recent"}
+	writeReflog(root, "refs/heads/main", []ReflogEntry{old, recent})
+	writeReflog(root, "HEAD", []ReflogEntry{recent})
+
+	removed, err := ExpireReflogs(root, time.Unix(1000, 0))
+	if err != nil {
+		t.Fatalf("ExpireReflogs failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 expired entry, got %d", removed)
+	}
+	entries, _ := ReadReflog(root, "refs/heads/main")
+	if len(entries) != 1 || entries[0].Message != "recent" {
+		t.Errorf("expected only the recent entry to remain, got %+v", entries)
+	}
+}
+
+func TestExpireReflogs_ReadError(t *testing.T) {
+	root := setupRefsDir(t)
+	os.MkdirAll(repo.LogsPath(root), 0755)
+	os.WriteFile(ReflogPath(root, "HEAD"), []byte("garbage\n"), 0644)
+	if _, err := ExpireReflogs(root, time.Now()); err == nil {
+		t.Fatal("expected error for malformed reflog")
+	}
+}
+
+func TestResolveRevision_ReflogSelector(t *testing.T) {
+	h := setupRevHistory(t)
+	// setupRevHistory pointed main at m; move it twice more. HEAD only
+	// logs the two later moves, as it was attached after the first write.
+	WriteRef(h.root, "refs/heads/main", h.c3, "reset: moving to c3")
+	WriteRef(h.root, "refs/heads/main", h.c2, "reset: moving to c2")
+
+	tests := map[string]string{
+		"main@{0}":   h.c2,
+		"main@{1}":   h.c3,
+		"main@{2}":   h.m,
+		"main@{1}~1": h.c2,
+		"HEAD@{1}":   h.c3,
+	}
+	for rev, want := range tests {
+		got, err := ResolveRevision(h.root, rev)
+		if err != nil {
+			t.Errorf("ResolveRevision(%q): unexpected error: %v", rev, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ResolveRevision(%q) = %s, want %s", rev, got, want)
+		}
+	}
+
+	_, err := ResolveRevision(h.root, "main@{3}")
+	if err == nil || !strings.Contains(err.Error(), "only has 3 entries") {
+		t.Errorf("expected out-of-range error, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package refs
+
+import (
+	"fmt"
+	"strings"
+
+	"gogit/lockfile"
+)
+
+// CheckRefFormat validates a full ref name against git's rules
+// (git check-ref-format). Unless allowOneLevel is set the name must have at
+// least two components, as in "refs/heads/main".
+func CheckRefFormat(name string, allowOneLevel bool) error {
+	invalid := func(reason string) error {
+		return fmt.Errorf("'%s' is not a valid ref name: %s", name, reason)
+	}
+
+	switch {
+	case name == "":
+		return invalid("empty name")
+	case name == "@":
+		return invalid("cannot be '@'")
+	case strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/"):
+		return invalid("cannot begin or end with '/'")
+	case strings.HasSuffix(name, "."):
+		return invalid("cannot end with '.'")
+	case strings.Contains(name, ".."):
+		return invalid("cannot contain '..'")
+	case strings.Contains(name, "@{"):
+		return invalid("cannot contain '@{'")
+	case strings.Contains(name, "//"):
+		return invalid("cannot contain '//'")
+	}
+	for _, c := range name {
+		if c < 0x20 || c == 0x7f {
+			return invalid("cannot contain control characters")
+		}
+		if strings.ContainsRune(" ~^:?*[\\", c) {
+			return invalid(fmt.Sprintf("cannot contain '%c'", c))
+		}
+	}
+
+	components := strings.Split(name, "/")
+	if len(components) < 2 && !allowOneLevel {
+		return invalid("must contain at least one '/'")
+	}
+	for _, comp := range components {
+		if strings.HasPrefix(comp, ".") {
+			return invalid("components cannot begin with '.'")
+		}
+		if strings.HasSuffix(comp, lockfile.Suffix) {
+			return invalid("components cannot end with '" + lockfile.Suffix + "'")
+		}
+	}
+	return nil
+}
+
+// ValidateBranchName reports whether name can be used as a branch name.
+func ValidateBranchName(name string) error {
+	if name == "HEAD" || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("'%s' is not a valid branch name", name)
+	}
+	if err := CheckRefFormat(BranchRef(name), false); err != nil {
+		return fmt.Errorf("'%s' is not a valid branch name", name)
+	}
+	return nil
+}
+
+// ValidateTagName reports whether name can be used as a tag name.
+func ValidateTagName(name string) error {
+	if strings.HasPrefix(name, "-") || CheckRefFormat(TagRef(name), false) != nil {
+		return fmt.Errorf("'%s' is not a valid tag name", name)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package refs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckRefFormat(t *testing.T) {
+	valid := []string{
+		"refs/heads/main",
+		"refs/heads/feature/login",
+		"refs/tags/v1.0",
+		"heads/a-b_c",
+	}
+	for _, name := range valid {
+		if err := CheckRefFormat(name, false); err != nil {
+			t.Errorf("%q should be valid: %v", name, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"@",
+		"main",
+		"/refs/heads/main",
+		"refs/heads/main/",
+		"refs/heads/main.",
+		"refs/heads/a..b",
+		"refs/heads/a@{1}",
+		"refs/heads//main",
+		"refs/heads/a b",
+		"refs/heads/a~1",
+		"refs/heads/a^",
+		"refs/heads/a:b",
+		"refs/heads/a?",
+		"refs/heads/a*",
+		"refs/heads/a[b",
+		"refs/heads/a\\b",
+		"refs/heads/a\tb",
+		"refs/heads/.hidden",
+		"refs/heads/main.lock",
+		"refs/heads/../../HEAD",
+	}
+	for _, name := range invalid {
+		if err := CheckRefFormat(name, false); err == nil {
+			t.Errorf("%q should be invalid", name)
+		}
+	}
+}
+
+func TestCheckRefFormat_OneLevel(t *testing.T) {
+	if err := CheckRefFormat("HEAD", true); err != nil {
+		t.Errorf("expected one-level name to be allowed: %v", err)
+	}
+	err := CheckRefFormat("HEAD", false)
+	if err == nil || !strings.Contains(err.Error(), "at least one '/'") {
+		t.Errorf("expected one-level error, got %v", err)
+	}
+}
+
+func TestValidateBranchName(t *testing.T) {
+	for _, name := range []string{"main", "feature/login", "fix-1.2"} {
+		if err := ValidateBranchName(name); err != nil {
+			t.Errorf("%q should be a valid branch name: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "HEAD", "-f", "a..b", "../../HEAD", "foo.lock", "foo/"} {
+		err := ValidateBranchName(name)
+		if err == nil || !strings.Contains(err.Error(), "not a valid branch name") {
+			t.Errorf("%q: expected invalid branch name error, got %v", name, err)
+		}
+	}
+}
+
+func TestValidateTagName(t *testing.T) {
+	for _, name := range []string{"v1.0", "release/2024", "HEAD"} {
+		if err := ValidateTagName(name); err != nil {
+			t.Errorf("%q should be a valid tag name: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "-a", "v1..2", "v1^", "v1.lock"} {
+		err := ValidateTagName(name)
+		if err == nil || !strings.Contains(err.Error(), "not a valid tag name") {
+			t.Errorf("%q: expected invalid tag name error, got %v", name, err)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"gogit/lockfile"
 	"gogit/repo"
 )
 
@@ -56,40 +57,74 @@ func ReadRef(root, refPath string) (string, error) {
 	return strings.TrimSpace(string(data)), nil
 }
 
-// WriteRef writes a commit hash to a ref file.
-func WriteRef(root, refPath, hash string) error {
-	fullPath := filepath.Join(repo.GogitPath(root), refPath)
-	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+// WriteRef writes a commit hash to a ref file, unconditionally. The update
+// is recorded with reason in the ref's reflog, and in HEAD's when HEAD
+// points at the ref.
+func WriteRef(root, refPath, hash, reason string) error {
+	return UpdateRef(root, refPath, hash, "", reason)
+}
+
+// UpdateHead updates the HEAD file, either to "ref: <ref>" or to a detached
+// commit hash, and records the move in HEAD's reflog with reason.
+func UpdateHead(root, content, reason string) error {
+	oldHash, _ := ResolveHead(root)
+	if err := lockfile.WriteFile(repo.HeadPath(root), []byte(content+"\n")); err != nil {
+		return err
+	}
+	newHash, err := ResolveHead(root)
+	if err != nil {
 		return err
 	}
-	return os.WriteFile(fullPath, []byte(hash+"\n"), 0644)
+	return AppendReflog(root, "HEAD", oldHash, newHash, reason)
 }
 
-// UpdateHead updates the HEAD file.
-func UpdateHead(root, content string) error {
-	return os.WriteFile(repo.HeadPath(root), []byte(content+"\n"), 0644)
+// ListBranches returns all branch names, including hierarchical ones such
+// as "feature/login", in sorted order.
+func ListBranches(root string) ([]string, error) {
+	return listRefs(root, "heads")
 }
 
-// ListBranches returns all branch names.
-func ListBranches(root string) ([]string, error) {
-	headsDir := filepath.Join(repo.RefsPath(root), "heads")
-	entries, err := os.ReadDir(headsDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
+// ListTags returns all tag names in sorted order.
+func ListTags(root string) ([]string, error) {
+	return listRefs(root, "tags")
+}
+
+// listRefs returns the names of the refs below refs/<kind>, relative to it.
+func listRefs(root, kind string) ([]string, error) {
+	dir := filepath.Join(repo.RefsPath(root), kind)
+	var names []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
 		}
-		return nil, err
-	}
-	var branches []string
-	for _, e := range entries {
-		if !e.IsDir() {
-			branches = append(branches, e.Name())
+		if path == dir && !d.IsDir() {
+			return fmt.Errorf("not a directory: %s", path)
+		}
+		if d.IsDir() || strings.HasSuffix(d.Name(), lockfile.Suffix) {
+			return nil
 		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return branches, nil
+	return names, nil
 }
 
 // BranchRef returns the ref path for a branch.
 func BranchRef(name string) string {
 	return fmt.Sprintf("refs/heads/%s", name)
 }
+
+// TagRef returns the ref path for a tag.
+func TagRef(name string) string {
+	return fmt.Sprintf("refs/tags/%s", name)
+}
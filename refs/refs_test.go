@@ -3,6 +3,7 @@ package refs
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"gogit/repo"
@@ -39,7 +40,7 @@ func TestReadHead_NoFile(t *testing.T) {
 func TestResolveHead_SymbolicRef(t *testing.T) {
 	root := setupRefsDir(t)
 	os.WriteFile(repo.HeadPath(root), []byte("ref: refs/heads/main\n"), 0644)
-	WriteRef(root, "refs/heads/main", "abc123")
+	WriteRef(root, "refs/heads/main", "abc123", "test")
 
 	hash, err := ResolveHead(root)
 	if err != nil {
@@ -121,7 +122,7 @@ func TestCurrentBranch_NoHead(t *testing.T) {
 func TestReadRef_Exists(t *testing.T) {
 	root := setupRefsDir(t)
 	refPath := "refs/heads/main"
-	WriteRef(root, refPath, "hash123")
+	WriteRef(root, refPath, "hash123", "test")
 
 	hash, err := ReadRef(root, refPath)
 	if err != nil {
@@ -145,7 +146,7 @@ func TestReadRef_NotExists(t *testing.T) {
 
 func TestReadRef_PermissionError(t *testing.T) {
 	root := setupRefsDir(t)
-	WriteRef(root, "refs/heads/secret", "hash")
+	WriteRef(root, "refs/heads/secret", "hash", "test")
 	// Make the file unreadable
 	refFile := filepath.Join(repo.GogitPath(root), "refs", "heads", "secret")
 	os.Chmod(refFile, 0000)
@@ -159,7 +160,7 @@ func TestReadRef_PermissionError(t *testing.T) {
 
 func TestWriteRef(t *testing.T) {
 	root := setupRefsDir(t)
-	err := WriteRef(root, "refs/heads/test", "somehash")
+	err := WriteRef(root, "refs/heads/test", "somehash", "test")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -172,7 +173,7 @@ func TestWriteRef(t *testing.T) {
 
 func TestWriteRef_CreatesDirectories(t *testing.T) {
 	root := setupRefsDir(t)
-	err := WriteRef(root, "refs/tags/v1.0", "taghash")
+	err := WriteRef(root, "refs/tags/v1.0", "taghash", "test")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -189,7 +190,7 @@ func TestWriteRef_MkdirError(t *testing.T) {
 	blocker := filepath.Join(repo.GogitPath(root), "refs", "blocker")
 	os.WriteFile(blocker, []byte("file"), 0644)
 
-	err := WriteRef(root, "refs/blocker/branch", "hash")
+	err := WriteRef(root, "refs/blocker/branch", "hash", "test")
 	if err == nil {
 		t.Fatal("expected error when cannot create directory (file in the way)")
 	}
@@ -197,7 +198,7 @@ func TestWriteRef_MkdirError(t *testing.T) {
 
 func TestUpdateHead(t *testing.T) {
 	root := setupRefsDir(t)
-	err := UpdateHead(root, "ref: refs/heads/feature")
+	err := UpdateHead(root, "ref: refs/heads/feature", "test")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -210,8 +211,8 @@ func TestUpdateHead(t *testing.T) {
 
 func TestListBranches_WithBranches(t *testing.T) {
 	root := setupRefsDir(t)
-	WriteRef(root, "refs/heads/main", "h1")
-	WriteRef(root, "refs/heads/feature", "h2")
+	WriteRef(root, "refs/heads/main", "h1", "test")
+	WriteRef(root, "refs/heads/feature", "h2", "test")
 
 	branches, err := ListBranches(root)
 	if err != nil {
@@ -237,7 +238,7 @@ func TestListBranches_NoDir(t *testing.T) {
 
 func TestListBranches_SkipsDirectories(t *testing.T) {
 	root := setupRefsDir(t)
-	WriteRef(root, "refs/heads/main", "h1")
+	WriteRef(root, "refs/heads/main", "h1", "test")
 	os.MkdirAll(filepath.Join(repo.RefsPath(root), "heads", "subdir"), 0755)
 
 	branches, err := ListBranches(root)
@@ -272,3 +273,76 @@ func TestBranchRef(t *testing.T) {
 		t.Errorf("unexpected ref: %s", ref)
 	}
 }
+
+func TestListBranches_SkipsLockFiles(t *testing.T) {
+	root := setupRefsDir(t)
+	WriteRef(root, "refs/heads/main", "aaa", "test")
+	os.WriteFile(filepath.Join(repo.RefsPath(root), "heads", "main.lock"), nil, 0644)
+
+	branches, err := ListBranches(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(branches) != 1 || branches[0] != "main" {
+		t.Errorf("expected only main, got %v", branches)
+	}
+}
+
+func TestListBranches_Hierarchical(t *testing.T) {
+	root := setupRefsDir(t)
+	WriteRef(root, "refs/heads/main", "aaa", "test")
+	WriteRef(root, "refs/heads/feature/login", "bbb", "test")
+	WriteRef(root, "refs/heads/feature/ui/menu", "ccc", "test")
+
+	branches, err := ListBranches(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"feature/login", "feature/ui/menu", "main"}
+	if strings.Join(branches, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, branches)
+	}
+}
+
+func TestListBranches_HeadsNotDirectory(t *testing.T) {
+	root := setupRefsDir(t)
+	headsDir := filepath.Join(repo.RefsPath(root), "heads")
+	os.RemoveAll(headsDir)
+	os.WriteFile(headsDir, []byte("x"), 0644)
+
+	if _, err := ListBranches(root); err == nil {
+		t.Fatal("expected error when refs/heads is a file")
+	}
+}
+
+func TestListTags(t *testing.T) {
+	root := setupRefsDir(t)
+	WriteRef(root, TagRef("v2.0"), "bbb", "test")
+	WriteRef(root, TagRef("v1.0"), "aaa", "test")
+	WriteRef(root, TagRef("rc/v3"), "ccc", "test")
+	WriteRef(root, BranchRef("main"), "ddd", "test")
+
+	tags, err := ListTags(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"rc/v3", "v1.0", "v2.0"}
+	if strings.Join(tags, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, tags)
+	}
+}
+
+func TestListTags_NoDir(t *testing.T) {
+	root := setupRefsDir(t)
+
+	tags, err := ListTags(root)
+	if err != nil || tags != nil {
+		t.Errorf("expected no tags, got %v, %v", tags, err)
+	}
+}
+
+func TestTagRef(t *testing.T) {
+	if ref := TagRef("v1.0"); ref != "refs/tags/v1.0" {
+		t.Errorf("unexpected ref: %s", ref)
+	}
+}
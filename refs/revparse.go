@@ -0,0 +1,385 @@
+package refs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gogit/object"
+)
+
+// minAbbrev is the shortest hex prefix accepted as an abbreviated hash.
+const minAbbrev = 4
+
+// refSearchPath lists where a short ref name is looked up, in order of
+// precedence, as git does.
+var refSearchPath = []string{"%s", "refs/%s", "refs/tags/%s", "refs/heads/%s"}
+
+// RevRange is the set of commits reachable from Include but not from Exclude.
+type RevRange struct {
+	Include []string
+	Exclude []string
+}
+
+// ResolveRevision resolves a revision expression to an object hash. It
+// accepts full and abbreviated hashes, ref names (HEAD, branches, tags and
+// full ref paths) followed by any of the suffixes ~<n>, ^<n>, ^{<type>} and
+// @{<n>}, and <rev>:<path> to name a blob or tree inside a commit. Annotated
+// tags are peeled wherever a commit or tree is needed.
+func ResolveRevision(root, rev string) (string, error) {
+	if i := strings.IndexByte(rev, ':'); i >= 0 {
+		return resolveTreePath(root, rev, rev[:i], rev[i+1:])
+	}
+
+	// Ref names cannot contain '~' or '^', so the first one starts the
+	// suffix operators.
+	base, ops := rev, ""
+	if i := strings.IndexAny(rev, "~^"); i >= 0 {
+		base, ops = rev[:i], rev[i:]
+	}
+	hash, err := resolveBase(root, rev, base)
+	if err != nil {
+		return "", err
+	}
+
+	for ops != "" {
+		op := ops[0]
+		ops = ops[1:]
+		if op == '^' && strings.HasPrefix(ops, "{") {
+			end := strings.IndexByte(ops, '}')
+			if end < 0 {
+				return "", unknownRevision(rev)
+			}
+			if hash, err = peel(root, rev, hash, ops[1:end]); err != nil {
+				return "", err
+			}
+			ops = ops[end+1:]
+			continue
+		}
+
+		digits := len(ops) - len(strings.TrimLeft(ops, "0123456789"))
+		n := 1
+		if digits > 0 {
+			if n, err = strconv.Atoi(ops[:digits]); err != nil {
+				return "", unknownRevision(rev)
+			}
+			ops = ops[digits:]
+		}
+
+		if hash, err = peel(root, rev, hash, "commit"); err != nil {
+			return "", err
+		}
+		switch op {
+		case '~':
+			for ; n > 0; n-- {
+				if hash, err = nthParent(root, rev, hash, 1); err != nil {
+					return "", err
+				}
+			}
+		case '^':
+			if n > 0 {
+				if hash, err = nthParent(root, rev, hash, n); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+	return hash, nil
+}
+
+// ResolveCommit resolves a revision expression and peels it to a commit.
+func ResolveCommit(root, rev string) (string, error) {
+	hash, err := ResolveRevision(root, rev)
+	if err != nil {
+		return "", err
+	}
+	return peel(root, rev, hash, "commit")
+}
+
+// IsRange reports whether expr is a range (A..B, A...B) or an exclusion
+// (^A) rather than a single revision.
+func IsRange(expr string) bool {
+	if strings.ContainsRune(expr, ':') {
+		return false
+	}
+	return strings.HasPrefix(expr, "^") || strings.Contains(expr, "..")
+}
+
+// ResolveRange resolves a list of revision arguments into the commits to
+// include and exclude. "A..B" includes B and excludes A; "A...B" includes
+// both and excludes their merge bases; "^A" excludes A. An omitted side of
+// a range means HEAD.
+func ResolveRange(root string, args []string) (*RevRange, error) {
+	r := &RevRange{}
+	for _, arg := range args {
+		if !IsRange(arg) {
+			hash, err := ResolveCommit(root, arg)
+			if err != nil {
+				return nil, err
+			}
+			r.Include = append(r.Include, hash)
+			continue
+		}
+		if strings.HasPrefix(arg, "^") {
+			hash, err := ResolveCommit(root, arg[1:])
+			if err != nil {
+				return nil, err
+			}
+			r.Exclude = append(r.Exclude, hash)
+			continue
+		}
+
+		sep := ".."
+		if strings.Contains(arg, "...") {
+			sep = "..."
+		}
+		i := strings.Index(arg, sep)
+		from, to := orHead(arg[:i]), orHead(arg[i+len(sep):])
+		a, err := ResolveCommit(root, from)
+		if err != nil {
+			return nil, err
+		}
+		b, err := ResolveCommit(root, to)
+		if err != nil {
+			return nil, err
+		}
+		if sep == ".." {
+			r.Include = append(r.Include, b)
+			r.Exclude = append(r.Exclude, a)
+			continue
+		}
+		bases, err := object.MergeBases(root, a, b)
+		if err != nil {
+			return nil, err
+		}
+		r.Include = append(r.Include, a, b)
+		r.Exclude = append(r.Exclude, bases...)
+	}
+	return r, nil
+}
+
+// ResolveRef reads a ref, following symbolic refs ("ref: <target>"). It
+// returns "" if the ref does not exist.
+func ResolveRef(root, refPath string) (string, error) {
+	for depth := 0; depth < 5; depth++ {
+		value, err := ReadRef(root, refPath)
+		if err != nil || !strings.HasPrefix(value, "ref: ") {
+			return value, err
+		}
+		refPath = strings.TrimPrefix(value, "ref: ")
+	}
+	return "", fmt.Errorf("symbolic ref nesting too deep: %s", refPath)
+}
+
+func orHead(rev string) string {
+	if rev == "" {
+		return "HEAD"
+	}
+	return rev
+}
+
+func unknownRevision(rev string) error {
+	return fmt.Errorf("unknown revision '%s'", rev)
+}
+
+// resolveBase resolves the part of a revision before any ~ or ^ operators:
+// a name optionally followed by a reflog selector.
+func resolveBase(root, rev, base string) (string, error) {
+	name, selector := base, ""
+	if i := strings.Index(base, "@{"); i >= 0 && strings.HasSuffix(base, "}") {
+		name, selector = base[:i], base[i+2:len(base)-1]
+	}
+	if name == "" || name == "@" {
+		name = "HEAD"
+	}
+
+	hash, refPath, err := resolveName(root, name)
+	if err != nil {
+		return "", err
+	}
+	if hash == "" {
+		return "", unknownRevision(rev)
+	}
+	if selector == "" {
+		return hash, nil
+	}
+	n, err := strconv.Atoi(selector)
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("unsupported reflog selector '@{%s}'", selector)
+	}
+	if refPath == "" {
+		return "", fmt.Errorf("'%s' is not a ref", name)
+	}
+	return reflogEntryAt(root, refPath, hash, n)
+}
+
+// resolveName resolves a ref name or (abbreviated) object hash. It returns
+// the hash and, for refs, the full ref path; the hash is "" if nothing
+// matches.
+func resolveName(root, name string) (string, string, error) {
+	hexName := isHex(name)
+	if hexName && len(name) == 40 && object.HasObject(root, name) {
+		return name, "", nil
+	}
+
+	refPath, err := FullRefName(root, name)
+	if err != nil {
+		return "", "", err
+	}
+	if refPath != "" {
+		hash, err := ResolveRef(root, refPath)
+		if err != nil {
+			return "", "", err
+		}
+		if hash != "" {
+			return hash, refPath, nil
+		}
+	}
+
+	if !hexName || len(name) < minAbbrev {
+		return "", "", nil
+	}
+	matches, err := object.FindObjects(root, name)
+	if err != nil {
+		return "", "", err
+	}
+	switch len(matches) {
+	case 0:
+		return "", "", nil
+	case 1:
+		return matches[0], "", nil
+	default:
+		return "", "", fmt.Errorf("short object ID %s is ambiguous (%d candidates)", name, len(matches))
+	}
+}
+
+// FullRefName returns the full path of the ref a short name refers to, such
+// as "refs/heads/main" for "main", or "" if no such ref exists. A name that
+// is not a valid ref name refers to no ref, so that no path outside the
+// refs is ever looked up.
+func FullRefName(root, name string) (string, error) {
+	if CheckRefFormat(name, true) != nil {
+		return "", nil
+	}
+	for _, pattern := range refSearchPath {
+		refPath := fmt.Sprintf(pattern, name)
+		if pattern == "%s" && !strings.HasPrefix(name, "refs/") && !isPseudoRef(name) {
+			continue
+		}
+		value, err := ReadRef(root, refPath)
+		if err != nil {
+			return "", err
+		}
+		if value != "" {
+			return refPath, nil
+		}
+	}
+	return "", nil
+}
+
+// isPseudoRef reports whether name is a top-level ref such as HEAD,
+// ORIG_HEAD or MERGE_HEAD, stored directly in the repository directory.
+func isPseudoRef(name string) bool {
+	if !strings.HasSuffix(name, "HEAD") {
+		return false
+	}
+	for _, c := range name {
+		if (c < 'A' || c > 'Z') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// peel dereferences an object until it has the wanted type, following tags
+// to the objects they point at. "object" accepts any object; an empty type
+// (as in rev^{}) follows tags until it reaches something that is not one.
+func peel(root, rev, hash, want string) (string, error) {
+	switch want {
+	case "object":
+		if !object.HasObject(root, hash) {
+			return "", fmt.Errorf("object not found: %s", hash)
+		}
+		return hash, nil
+	case "", "commit", "tree", "blob", "tag":
+	default:
+		return "", fmt.Errorf("%s: unknown object type '%s'", rev, want)
+	}
+
+	for {
+		objType, content, err := object.ReadObject(root, hash)
+		if err != nil {
+			return "", err
+		}
+		if objType == want || (want == "" && objType != "tag") {
+			return hash, nil
+		}
+		switch {
+		case objType == "tag":
+			tag, err := object.ParseTag(content)
+			if err != nil {
+				return "", err
+			}
+			hash = tag.Object
+			continue
+		case objType == "commit" && want == "tree":
+			commit, err := object.ParseCommit(content)
+			if err != nil {
+				return "", err
+			}
+			return commit.TreeHash, nil
+		}
+		return "", fmt.Errorf("%s: expected %s type, but the object dereferences to %s type", rev, want, objType)
+	}
+}
+
+// nthParent returns the n-th (1-based) parent of a commit.
+func nthParent(root, rev, hash string, n int) (string, error) {
+	commit, err := object.ReadCommit(root, hash)
+	if err != nil {
+		return "", err
+	}
+	if n > len(commit.Parents) {
+		return "", unknownRevision(rev)
+	}
+	return commit.Parents[n-1], nil
+}
+
+// resolveTreePath resolves <rev>:<path> to the blob or tree at path in the
+// tree of rev. An empty path names the tree itself.
+func resolveTreePath(root, rev, base, path string) (string, error) {
+	if base == "" {
+		return "", fmt.Errorf("index paths are not supported: '%s'", rev)
+	}
+	hash, err := ResolveRevision(root, base)
+	if err != nil {
+		return "", err
+	}
+	tree, err := peel(root, rev, hash, "tree")
+	if err != nil {
+		return "", err
+	}
+	if strings.Trim(path, "/") == "" {
+		return tree, nil
+	}
+	entry, err := object.LookupPath(root, tree, path)
+	if err != nil {
+		return "", err
+	}
+	if entry == nil {
+		return "", fmt.Errorf("path '%s' does not exist in '%s'", path, base)
+	}
+	return entry.Hash, nil
+}
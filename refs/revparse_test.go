@@ -0,0 +1,383 @@
+package refs
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"gogit/object"
+	"gogit/repo"
+)
+
+// revHistory is a small history used by the revision tests:
+//
+//	c1 -- c2 -- c3 -- m (main, HEAD)
+//	        \        /
+//	         s1 -----  (side)
+type revHistory struct {
+	root               string
+	c1, c2, c3, s1, m  string
+	tree, blob, subdir string
+}
+
+func setupRevHistory(t *testing.T) *revHistory {
+	t.Helper()
+	root := setupRefsDir(t)
+	t.Setenv("GOGIT_AUTHOR_NAME", "Test")
+	t.Setenv("GOGIT_AUTHOR_EMAIL", "test@test.com")
+	h := &revHistory{root: root}
+
+	var err error
+	if h.blob, err = object.WriteBlob(root, []byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if h.subdir, err = object.WriteTree(root, []object.TreeEntry{{Mode: "100644", Name: "b.txt", Hash: h.blob}}); err != nil {
+		t.Fatal(err)
+	}
+	if h.tree, err = object.WriteTree(root, []object.TreeEntry{
+		{Mode: "100644", Name: "a.txt", Hash: h.blob},
+		{Mode: "40000", Name: "dir", Hash: h.subdir},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	commit := func(msg string, parents ...string) string {
+		hash, err := object.WriteCommit(root, h.tree, parents, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	h.c1 = commit("c1")
+	h.c2 = commit("c2", h.c1)
+	h.c3 = commit("c3", h.c2)
+	h.s1 = commit("s1", h.c2)
+	h.m = commit("merge", h.c3, h.s1)
+
+	WriteRef(root, "refs/heads/main", h.m, "test")
+	WriteRef(root, "refs/heads/side", h.s1, "test")
+	os.WriteFile(repo.HeadPath(root), []byte("ref: refs/heads/main\n"), 0644)
+	return h
+}
+
+func TestResolveRevision(t *testing.T) {
+	h := setupRevHistory(t)
+	tests := []struct {
+		rev  string
+		want string
+	}{
+		{"HEAD", h.m},
+		{"@", h.m},
+		{"main", h.m},
+		{"side", h.s1},
+		{"refs/heads/side", h.s1},
+		{"heads/side", h.s1},
+		{h.c2, h.c2},
+		{h.c2[:7], h.c2},
+		{"HEAD~1", h.c3},
+		{"HEAD~", h.c3},
+		{"HEAD~3", h.c1},
+		{"HEAD^", h.c3},
+		{"HEAD^1", h.c3},
+		{"HEAD^2", h.s1},
+		{"HEAD^2~1", h.c2},
+		{"HEAD^^", h.c2},
+		{"HEAD~2^", h.c1},
+		{"HEAD^0", h.m},
+		{"main^{commit}", h.m},
+		{"main^{tree}", h.tree},
+		{"main^{}", h.m},
+		{"HEAD@{0}", h.m},
+		{"@{0}", h.m},
+		{"side@{0}~1", h.c2},
+		{"HEAD:a.txt", h.blob},
+		{"HEAD:dir", h.subdir},
+		{"HEAD:dir/b.txt", h.blob},
+		{"HEAD~2:dir/b.txt", h.blob},
+		{"HEAD:", h.tree},
+		{"main^{tree}:a.txt", h.blob},
+	}
+	for _, tt := range tests {
+		got, err := ResolveRevision(h.root, tt.rev)
+		if err != nil {
+			t.Errorf("ResolveRevision(%q): unexpected error: %v", tt.rev, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ResolveRevision(%q) = %s, want %s", tt.rev, got, tt.want)
+		}
+	}
+}
+
+func TestResolveRevision_Errors(t *testing.T) {
+	h := setupRevHistory(t)
+	tests := []struct {
+		rev     string
+		wantErr string
+	}{
+		{"nope", "unknown revision"},
+		{"HEAD~4", "unknown revision"},
+		{"HEAD^3", "unknown revision"},
+		{"HEAD^{tree", "unknown revision"},
+		{"HEAD^{bogus}", "unknown object type"},
+		{"HEAD:a.txt^{commit}", "path 'a.txt^{commit}' does not exist"},
+		{"HEAD:missing", "path 'missing' does not exist in 'HEAD'"},
+		{"HEAD:a.txt/x", "does not exist"},
+		{":a.txt", "index paths are not supported"},
+		{"nope:a.txt", "unknown revision"},
+		{"HEAD@{1}", "no reflog"},
+		{"HEAD@{yesterday}", "unsupported reflog selector"},
+		{h.c2[:7] + "@{0}", "is not a ref"},
+		{"abc", "unknown revision"},
+		{"0000", "unknown revision"},
+	}
+	for _, tt := range tests {
+		_, err := ResolveRevision(h.root, tt.rev)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ResolveRevision(%q): expected error containing %q, got %v", tt.rev, tt.wantErr, err)
+		}
+	}
+}
+
+func TestResolveRevision_TypeMismatch(t *testing.T) {
+	h := setupRevHistory(t)
+	_, err := ResolveRevision(h.root, h.blob+"^{commit}")
+	if err == nil || !strings.Contains(err.Error(), "dereferences to blob type") {
+		t.Errorf("expected type mismatch error, got %v", err)
+	}
+	_, err = ResolveRevision(h.root, h.blob+"~1")
+	if err == nil || !strings.Contains(err.Error(), "dereferences to blob type") {
+		t.Errorf("expected type mismatch error, got %v", err)
+	}
+}
+
+func TestResolveRevision_AmbiguousAbbrev(t *testing.T) {
+	h := setupRevHistory(t)
+
+	// Find two blobs whose hashes share a 4-character prefix.
+	byPrefix := make(map[string]string)
+	var a, b string
+	for i := 0; a == ""; i++ {
+		content := fmt.Sprintf("blob %d", i)
+		hash := object.HashBlob([]byte(content))
+		if other, ok := byPrefix[hash[:4]]; ok {
+			a, b = other, content
+			break
+		}
+		byPrefix[hash[:4]] = content
+	}
+	ha, _ := object.WriteBlob(h.root, []byte(a))
+	object.WriteBlob(h.root, []byte(b))
+
+	_, err := ResolveRevision(h.root, ha[:4])
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguity error, got %v", err)
+	}
+	got, err := ResolveRevision(h.root, ha[:12])
+	if err != nil || got != ha {
+		t.Errorf("expected longer prefix to resolve to %s, got %s (%v)", ha, got, err)
+	}
+}
+
+func TestResolveRevision_RefBeatsAbbrev(t *testing.T) {
+	h := setupRevHistory(t)
+	name := h.c1[:6]
+	WriteRef(h.root, "refs/heads/"+name, h.s1, "test")
+
+	got, err := ResolveRevision(h.root, name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != h.s1 {
+		t.Errorf("expected branch %s to win over abbreviated hash, got %s", name, got)
+	}
+}
+
+func TestResolveRevision_PseudoRefs(t *testing.T) {
+	h := setupRevHistory(t)
+	os.WriteFile(repo.GogitPath(h.root)+"/ORIG_HEAD", []byte(h.c1+"\n"), 0644)
+	// Lowercase top-level files such as "config" are not refs.
+	os.WriteFile(repo.GogitPath(h.root)+"/config", []byte(h.c1+"\n"), 0644)
+
+	got, err := ResolveRevision(h.root, "ORIG_HEAD")
+	if err != nil || got != h.c1 {
+		t.Errorf("expected ORIG_HEAD to resolve to %s, got %s (%v)", h.c1, got, err)
+	}
+	if _, err := ResolveRevision(h.root, "config"); err == nil {
+		t.Error("expected 'config' not to resolve as a ref")
+	}
+}
+
+func TestFullRefName_InvalidNames(t *testing.T) {
+	h := setupRevHistory(t)
+	// refs/heads/../../HEAD would be HEAD itself.
+	for _, name := range []string{"../../HEAD", "heads/../../HEAD", "main/", "a b", ""} {
+		if refPath, err := FullRefName(h.root, name); err != nil || refPath != "" {
+			t.Errorf("FullRefName(%q) = %q (%v), expected no ref", name, refPath, err)
+		}
+	}
+	if refPath, _ := FullRefName(h.root, "main"); refPath != "refs/heads/main" {
+		t.Errorf("expected refs/heads/main, got %q", refPath)
+	}
+	if _, err := ResolveRevision(h.root, "../../HEAD"); err == nil {
+		t.Error("expected '../../HEAD' not to resolve")
+	}
+}
+
+func TestResolveRevision_ReadRefError(t *testing.T) {
+	h := setupRevHistory(t)
+	os.MkdirAll(repo.GogitPath(h.root)+"/refs/heads/broken/sub", 0755)
+	if _, err := ResolveRevision(h.root, "refs/heads/broken"); err == nil {
+		t.Fatal("expected error when ref is unreadable")
+	}
+}
+
+func TestResolveCommit(t *testing.T) {
+	h := setupRevHistory(t)
+	got, err := ResolveCommit(h.root, "side")
+	if err != nil || got != h.s1 {
+		t.Errorf("expected %s, got %s (%v)", h.s1, got, err)
+	}
+	if _, err := ResolveCommit(h.root, "HEAD^{tree}"); err == nil {
+		t.Error("expected error resolving a tree as a commit")
+	}
+	if _, err := ResolveCommit(h.root, "nope"); err == nil {
+		t.Error("expected error for unknown revision")
+	}
+}
+
+func TestIsRange(t *testing.T) {
+	tests := map[string]bool{
+		"HEAD":        false,
+		"HEAD^":       false,
+		"a..b":        true,
+		"a...b":       true,
+		"..b":         true,
+		"^a":          true,
+		"HEAD:../x":   false,
+		"main@{0}~2":  false,
+		"HEAD^{tree}": false,
+	}
+	for expr, want := range tests {
+		if got := IsRange(expr); got != want {
+			t.Errorf("IsRange(%q) = %v, want %v", expr, got, want)
+		}
+	}
+}
+
+func TestResolveRange(t *testing.T) {
+	h := setupRevHistory(t)
+	tests := []struct {
+		args    []string
+		include []string
+		exclude []string
+	}{
+		{[]string{"main"}, []string{h.m}, nil},
+		{[]string{"side..main"}, []string{h.m}, []string{h.s1}},
+		{[]string{"..side"}, []string{h.s1}, []string{h.m}},
+		{[]string{"side.."}, []string{h.m}, []string{h.s1}},
+		{[]string{"HEAD~1...side"}, []string{h.c3, h.s1}, []string{h.c2}},
+		{[]string{"main", "^side"}, []string{h.m}, []string{h.s1}},
+	}
+	for _, tt := range tests {
+		r, err := ResolveRange(h.root, tt.args)
+		if err != nil {
+			t.Errorf("ResolveRange(%v): unexpected error: %v", tt.args, err)
+			continue
+		}
+		if fmt.Sprint(r.Include) != fmt.Sprint(tt.include) || fmt.Sprint(r.Exclude) != fmt.Sprint(tt.exclude) {
+			t.Errorf("ResolveRange(%v) = %v / %v, want %v / %v", tt.args, r.Include, r.Exclude, tt.include, tt.exclude)
+		}
+	}
+}
+
+func TestResolveRange_Errors(t *testing.T) {
+	h := setupRevHistory(t)
+	for _, arg := range []string{"nope", "^nope", "nope..main", "main..nope", "nope...main", "main...nope"} {
+		if _, err := ResolveRange(h.root, []string{arg}); err == nil {
+			t.Errorf("ResolveRange(%q): expected error", arg)
+		}
+	}
+}
+
+func TestResolveRef_Symbolic(t *testing.T) {
+	root := setupRefsDir(t)
+	WriteRef(root, "refs/heads/main", "abc123", "test")
+	WriteRef(root, "refs/heads/alias", "ref: refs/heads/main", "test")
+
+	hash, err := ResolveRef(root, "refs/heads/alias")
+	if err != nil || hash != "abc123" {
+		t.Errorf("expected 'abc123', got '%s' (%v)", hash, err)
+	}
+	hash, err = ResolveRef(root, "refs/heads/missing")
+	if err != nil || hash != "" {
+		t.Errorf("expected empty hash for missing ref, got '%s' (%v)", hash, err)
+	}
+}
+
+func TestResolveRef_Loop(t *testing.T) {
+	root := setupRefsDir(t)
+	WriteRef(root, "refs/heads/a", "ref: refs/heads/b", "test")
+	WriteRef(root, "refs/heads/b", "ref: refs/heads/a", "test")
+
+	if _, err := ResolveRef(root, "refs/heads/a"); err == nil {
+		t.Fatal("expected error for symbolic ref loop")
+	}
+}
+
+func TestResolveRevision_Tags(t *testing.T) {
+	h := setupRevHistory(t)
+	tagObj, err := object.WriteTag(h.root, h.c3, "commit", "v1", "release")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A tag of a tag must be peeled twice.
+	nested, err := object.WriteTag(h.root, tagObj, "tag", "v1-signed", "again")
+	if err != nil {
+		t.Fatal(err)
+	}
+	WriteRef(h.root, TagRef("v1"), tagObj, "test")
+	WriteRef(h.root, TagRef("nested"), nested, "test")
+	WriteRef(h.root, TagRef("light"), h.c1, "test")
+	WriteRef(h.root, TagRef("blobtag"), h.blob, "test")
+
+	tests := []struct {
+		rev  string
+		want string
+	}{
+		{"v1", tagObj},
+		{"tags/v1", tagObj},
+		{"refs/tags/v1", tagObj},
+		{"v1^{}", h.c3},
+		{"v1^{tag}", tagObj},
+		{"v1^{commit}", h.c3},
+		{"v1^{tree}", h.tree},
+		{"v1~1", h.c2},
+		{"v1^0", h.c3},
+		{"v1:a.txt", h.blob},
+		{"nested^{}", h.c3},
+		{"nested^{commit}", h.c3},
+		{"nested~2", h.c1},
+		{"light", h.c1},
+		{"light^{}", h.c1},
+		{"blobtag^{blob}", h.blob},
+	}
+	for _, tt := range tests {
+		got, err := ResolveRevision(h.root, tt.rev)
+		if err != nil {
+			t.Errorf("ResolveRevision(%q): unexpected error: %v", tt.rev, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ResolveRevision(%q) = %s, want %s", tt.rev, got, tt.want)
+		}
+	}
+
+	if got, err := ResolveCommit(h.root, "nested"); err != nil || got != h.c3 {
+		t.Errorf("ResolveCommit(nested) = %s, %v; want %s", got, err, h.c3)
+	}
+	if _, err := ResolveCommit(h.root, "blobtag"); err == nil || !strings.Contains(err.Error(), "dereferences to blob type") {
+		t.Errorf("expected peel error for blob tag, got %v", err)
+	}
+}
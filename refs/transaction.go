@@ -0,0 +1,302 @@
+package refs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gogit/lockfile"
+	"gogit/repo"
+)
+
+// Transaction groups ref updates so they are applied together: every ref is
+// locked and its expected value checked before any of them changes, so a
+// failed check leaves all refs untouched.
+type Transaction struct {
+	root    string
+	updates []*refUpdate
+}
+
+type refUpdate struct {
+	refPath     string
+	newHash     string // "" for a deletion
+	expectedOld string // "" to skip the check, ZeroHash for "must not exist"
+	reason      string
+	oldHash     string
+	lock        *lockfile.Lock
+}
+
+// NewTransaction starts an empty transaction.
+func NewTransaction(root string) *Transaction {
+	return &Transaction{root: root}
+}
+
+// Update queues setting refPath to newHash. If expectedOld is non-empty the
+// ref must currently hold it; ZeroHash means the ref must not exist.
+func (t *Transaction) Update(refPath, newHash, expectedOld, reason string) {
+	t.updates = append(t.updates, &refUpdate{refPath: refPath, newHash: newHash, expectedOld: expectedOld, reason: reason})
+}
+
+// Delete queues removing refPath and its reflog, subject to the same
+// expectedOld check as Update.
+func (t *Transaction) Delete(refPath, expectedOld, reason string) {
+	t.updates = append(t.updates, &refUpdate{refPath: refPath, expectedOld: expectedOld, reason: reason})
+}
+
+// Commit locks all queued refs, verifies their expected values and applies
+// the updates. If any lock or check fails nothing is changed.
+func (t *Transaction) Commit() error {
+	defer t.rollback()
+
+	seen := make(map[string]bool)
+	for _, u := range t.updates {
+		if seen[u.refPath] {
+			return fmt.Errorf("multiple updates for ref '%s' not allowed", u.refPath)
+		}
+		seen[u.refPath] = true
+	}
+	for _, u := range t.updates {
+		if err := checkRefPath(u.refPath); err != nil {
+			return err
+		}
+		if u.newHash == "" {
+			continue
+		}
+		// Within one transaction, foo and foo/bar cannot both be written.
+		for _, other := range t.updates {
+			if other.newHash != "" && strings.HasPrefix(other.refPath, u.refPath+"/") {
+				return fmt.Errorf("cannot lock ref '%s': '%s' would also be created", other.refPath, u.refPath)
+			}
+		}
+	}
+
+	for _, u := range t.updates {
+		if err := t.prepare(u); err != nil {
+			return err
+		}
+	}
+
+	head, _ := ReadHead(t.root)
+	for _, u := range t.updates {
+		fullPath := filepath.Join(repo.GogitPath(t.root), u.refPath)
+		if u.newHash == "" {
+			if err := os.Remove(fullPath); err != nil {
+				return err
+			}
+			u.lock.Rollback()
+			os.Remove(ReflogPath(t.root, u.refPath))
+			// Leave no empty directories behind to block a later foo ref.
+			pruneEmptyDirs(filepath.Dir(fullPath), repo.RefsPath(t.root))
+			pruneEmptyDirs(filepath.Dir(ReflogPath(t.root, u.refPath)), repo.LogsPath(t.root))
+			continue
+		}
+
+		if err := u.lock.Commit(); err != nil {
+			return err
+		}
+		if !hasReflog(u.refPath) {
+			continue
+		}
+		if err := AppendReflog(t.root, u.refPath, u.oldHash, u.newHash, u.reason); err != nil {
+			return err
+		}
+		if head == "ref: "+u.refPath {
+			if err := AppendReflog(t.root, "HEAD", u.oldHash, u.newHash, u.reason); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkRefPath reports whether a transaction may update or delete refPath:
+// HEAD, or a well-formed ref under refs/. Anything else could name a file
+// elsewhere in the repository directory.
+func checkRefPath(refPath string) error {
+	if refPath == "HEAD" {
+		return nil
+	}
+	if err := CheckRefFormat(refPath, false); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(refPath, "refs/") {
+		return fmt.Errorf("'%s' is not a ref under refs/", refPath)
+	}
+	return nil
+}
+
+// prepare locks a ref, checks its current value and stages its new one.
+func (t *Transaction) prepare(u *refUpdate) error {
+	fullPath := filepath.Join(repo.GogitPath(t.root), u.refPath)
+	if u.newHash != "" {
+		if err := CheckRefConflict(t.root, u.refPath); err != nil {
+			return err
+		}
+	}
+	lock, err := lockfile.Acquire(fullPath)
+	if err != nil {
+		return fmt.Errorf("cannot lock ref '%s': %v", u.refPath, err)
+	}
+	u.lock = lock
+
+	current, err := ReadRef(t.root, u.refPath)
+	if err != nil {
+		return err
+	}
+	u.oldHash = current
+	switch {
+	case u.expectedOld == ZeroHash && current != "":
+		return fmt.Errorf("cannot lock ref '%s': reference already exists", u.refPath)
+	case u.expectedOld != "" && u.expectedOld != ZeroHash && current != u.expectedOld:
+		if current == "" {
+			return fmt.Errorf("cannot lock ref '%s': unable to resolve reference", u.refPath)
+		}
+		return fmt.Errorf("cannot lock ref '%s': is at %s but expected %s", u.refPath, current, u.expectedOld)
+	case u.newHash == "" && current == "":
+		return fmt.Errorf("cannot delete ref '%s': it does not exist", u.refPath)
+	}
+
+	if u.newHash != "" {
+		if _, err := lock.Write([]byte(u.newHash + "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckRefConflict reports whether refPath cannot be created because a
+// ref exists at one of its parent paths (foo blocks foo/bar) or refs exist
+// below it (foo/bar blocks foo), since both would need the same file name.
+func CheckRefConflict(root, refPath string) error {
+	gogitDir := repo.GogitPath(root)
+	parts := strings.Split(refPath, "/")
+	for i := 2; i < len(parts); i++ {
+		parent := strings.Join(parts[:i], "/")
+		info, err := os.Stat(filepath.Join(gogitDir, parent))
+		if err == nil && !info.IsDir() {
+			return fmt.Errorf("cannot lock ref '%s': '%s' exists; cannot create '%s'", refPath, parent, refPath)
+		}
+	}
+
+	dir := filepath.Join(gogitDir, refPath)
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return nil
+	}
+	var blocker string
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && !strings.HasSuffix(path, lockfile.Suffix) {
+			rel, _ := filepath.Rel(gogitDir, path)
+			blocker = filepath.ToSlash(rel)
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if blocker != "" {
+		return fmt.Errorf("cannot lock ref '%s': '%s' exists; cannot create '%s'", refPath, blocker, refPath)
+	}
+	return fmt.Errorf("cannot lock ref '%s': there is a directory in the way", refPath)
+}
+
+// pruneEmptyDirs removes dir and its parents while they are empty, stopping
+// below top and never removing the first level below it (refs/heads).
+func pruneEmptyDirs(dir, top string) {
+	for {
+		parent := filepath.Dir(dir)
+		if dir == top || parent == top || !strings.HasPrefix(dir, top) {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = parent
+	}
+}
+
+func (t *Transaction) rollback() {
+	for _, u := range t.updates {
+		if u.lock != nil {
+			u.lock.Rollback()
+		}
+	}
+}
+
+// UpdateRef sets refPath to newHash under its lock, provided it currently
+// holds expectedOld (see Transaction.Update).
+func UpdateRef(root, refPath, newHash, expectedOld, reason string) error {
+	tx := NewTransaction(root)
+	tx.Update(refPath, newHash, expectedOld, reason)
+	return tx.Commit()
+}
+
+// DeleteRef removes refPath and its reflog, provided it currently holds
+// expectedOld (see Transaction.Update).
+func DeleteRef(root, refPath, expectedOld, reason string) error {
+	tx := NewTransaction(root)
+	tx.Delete(refPath, expectedOld, reason)
+	return tx.Commit()
+}
+
+// RenameRef moves oldRef to newRef, carrying its reflog along, and points
+// HEAD at newRef if it pointed at oldRef. Unless force is set, newRef must
+// not exist yet.
+func RenameRef(root, oldRef, newRef string, force bool, reason string) error {
+	if oldRef == newRef {
+		return fmt.Errorf("cannot rename '%s' to itself", oldRef)
+	}
+	if err := CheckRefFormat(newRef, false); err != nil {
+		return err
+	}
+	hash, err := ReadRef(root, oldRef)
+	if err != nil {
+		return err
+	}
+	if hash == "" {
+		return fmt.Errorf("refname '%s' not found", oldRef)
+	}
+	entries, err := ReadReflog(root, oldRef)
+	if err != nil {
+		return err
+	}
+	expectedNew := ZeroHash
+	if force {
+		expectedNew = ""
+	}
+
+	// Remove the old ref first so that renaming foo/bar to foo is not
+	// blocked by foo/bar itself; put it back if the new ref cannot be made.
+	if err := DeleteRef(root, oldRef, hash, reason); err != nil {
+		return err
+	}
+	if err := UpdateRef(root, newRef, hash, expectedNew, reason); err != nil {
+		if restoreErr := UpdateRef(root, oldRef, hash, ZeroHash, reason); restoreErr != nil {
+			return fmt.Errorf("%v; unable to restore '%s' at %s: %v", err, oldRef, hash, restoreErr)
+		}
+		if hasReflog(oldRef) {
+			writeReflog(root, oldRef, entries)
+		}
+		return err
+	}
+
+	if hasReflog(newRef) {
+		logged, err := ReadReflog(root, newRef)
+		if err != nil {
+			return err
+		}
+		if len(logged) > 0 {
+			// The branch did not change value; log the rename as such.
+			renamed := logged[len(logged)-1]
+			renamed.Old = hash
+			entries = append(entries, renamed)
+		}
+		if err := writeReflog(root, newRef, entries); err != nil {
+			return err
+		}
+	}
+
+	if head, _ := ReadHead(root); head == "ref: "+oldRef {
+		return UpdateHead(root, "ref: "+newRef, reason)
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package refs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gogit/repo"
+)
+
+func TestUpdateRef_CompareAndSwap(t *testing.T) {
+	root := setupRefsDir(t)
+
+	if err := UpdateRef(root, "refs/heads/main", "aaa", ZeroHash, "create"); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if err := UpdateRef(root, "refs/heads/main", "bbb", ZeroHash, "create again"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected 'already exists' error, got %v", err)
+	}
+	if err := UpdateRef(root, "refs/heads/main", "bbb", "zzz", "stale"); err == nil || !strings.Contains(err.Error(), "is at aaa but expected zzz") {
+		t.Errorf("expected mismatch error, got %v", err)
+	}
+	if err := UpdateRef(root, "refs/heads/main", "bbb", "aaa", "advance"); err != nil {
+		t.Fatalf("CAS update failed: %v", err)
+	}
+	if err := UpdateRef(root, "refs/heads/missing", "bbb", "aaa", "stale"); err == nil || !strings.Contains(err.Error(), "unable to resolve") {
+		t.Errorf("expected missing ref error, got %v", err)
+	}
+
+	hash, _ := ReadRef(root, "refs/heads/main")
+	if hash != "bbb" {
+		t.Errorf("expected 'bbb', got '%s'", hash)
+	}
+	log, _ := ReadReflog(root, "refs/heads/main")
+	if len(log) != 2 || log[1].Message != "advance" {
+		t.Errorf("expected failed updates not to be logged, got %+v", log)
+	}
+}
+
+func TestUpdateRef_Locked(t *testing.T) {
+	root := setupRefsDir(t)
+	WriteRef(root, "refs/heads/main", "aaa", "test")
+	lockPath := filepath.Join(repo.GogitPath(root), "refs", "heads", "main.lock")
+	os.WriteFile(lockPath, nil, 0644)
+
+	err := UpdateRef(root, "refs/heads/main", "bbb", "", "test")
+	if err == nil || !strings.Contains(err.Error(), "cannot lock ref") {
+		t.Fatalf("expected lock error, got %v", err)
+	}
+	hash, _ := ReadRef(root, "refs/heads/main")
+	if hash != "aaa" {
+		t.Errorf("ref should be unchanged, got '%s'", hash)
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Error("someone else's lock must not be removed")
+	}
+}
+
+func TestTransaction_AllOrNothing(t *testing.T) {
+	root := setupRefsDir(t)
+	WriteRef(root, "refs/heads/a", "aaa", "test")
+	WriteRef(root, "refs/heads/b", "bbb", "test")
+
+	tx := NewTransaction(root)
+	tx.Update("refs/heads/a", "a2", "aaa", "move a")
+	tx.Update("refs/heads/b", "b2", "wrong", "move b")
+	if err := tx.Commit(); err == nil {
+		t.Fatal("expected transaction to fail on mismatch")
+	}
+	for ref, want := range map[string]string{"refs/heads/a": "aaa", "refs/heads/b": "bbb"} {
+		if got, _ := ReadRef(root, ref); got != want {
+			t.Errorf("%s: expected %s to be untouched, got %s", ref, want, got)
+		}
+		if _, err := os.Stat(filepath.Join(repo.GogitPath(root), ref+".lock")); !os.IsNotExist(err) {
+			t.Errorf("%s: lock should be released", ref)
+		}
+	}
+
+	tx = NewTransaction(root)
+	tx.Update("refs/heads/a", "a2", "aaa", "move a")
+	tx.Update("refs/heads/c", "ccc", ZeroHash, "create c")
+	tx.Delete("refs/heads/b", "bbb", "delete b")
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+	if got, _ := ReadRef(root, "refs/heads/a"); got != "a2" {
+		t.Errorf("expected a2, got %s", got)
+	}
+	if got, _ := ReadRef(root, "refs/heads/c"); got != "ccc" {
+		t.Errorf("expected ccc, got %s", got)
+	}
+	if got, _ := ReadRef(root, "refs/heads/b"); got != "" {
+		t.Errorf("expected b to be deleted, got %s", got)
+	}
+	if _, err := os.Stat(ReflogPath(root, "refs/heads/b")); !os.IsNotExist(err) {
+		t.Error("expected b's reflog to be deleted")
+	}
+}
+
+func TestTransaction_DuplicateRef(t *testing.T) {
+	root := setupRefsDir(t)
+	tx := NewTransaction(root)
+	tx.Update("refs/heads/a", "a1", "", "one")
+	tx.Update("refs/heads/a", "a2", "", "two")
+	if err := tx.Commit(); err == nil || !strings.Contains(err.Error(), "multiple updates") {
+		t.Fatalf("expected duplicate ref error, got %v", err)
+	}
+}
+
+func TestTransaction_ReadError(t *testing.T) {
+	root := setupRefsDir(t)
+	os.MkdirAll(filepath.Join(repo.GogitPath(root), "refs", "heads", "dir", "sub"), 0755)
+	if err := UpdateRef(root, "refs/heads/dir", "aaa", "", "test"); err == nil {
+		t.Fatal("expected error when ref is a directory")
+	}
+}
+
+func TestTransaction_ReflogError(t *testing.T) {
+	root := setupRefsDir(t)
+	os.WriteFile(repo.HeadPath(root), []byte("ref: refs/heads/main\n"), 0644)
+	WriteRef(root, "refs/heads/main", "aaa", "test")
+	// Block HEAD's reflog only.
+	os.Remove(ReflogPath(root, "HEAD"))
+	os.MkdirAll(filepath.Join(ReflogPath(root, "HEAD"), "sub"), 0755)
+	if err := WriteRef(root, "refs/heads/main", "bbb", "test"); err == nil {
+		t.Fatal("expected error when HEAD's reflog cannot be written")
+	}
+}
+
+func TestDeleteRef(t *testing.T) {
+	root := setupRefsDir(t)
+	WriteRef(root, "refs/heads/gone", "aaa", "test")
+
+	if err := DeleteRef(root, "refs/heads/gone", "bbb", "delete"); err == nil {
+		t.Error("expected mismatch error")
+	}
+	if err := DeleteRef(root, "refs/heads/gone", "aaa", "delete"); err != nil {
+		t.Fatalf("DeleteRef failed: %v", err)
+	}
+	if err := DeleteRef(root, "refs/heads/gone", "", "delete"); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("expected error deleting a missing ref, got %v", err)
+	}
+}
+
+func TestDeleteRef_OutsideRefs(t *testing.T) {
+	root := setupRefsDir(t)
+	os.WriteFile(repo.HeadPath(root), []byte("ref: refs/heads/main\n"), 0644)
+	os.WriteFile(filepath.Join(repo.GogitPath(root), "config"), nil, 0644)
+
+	for _, refPath := range []string{"refs/heads/../../HEAD", "config", "logs/HEAD"} {
+		if err := DeleteRef(root, refPath, "", "delete"); err == nil {
+			t.Errorf("expected deleting %q to be refused", refPath)
+		}
+	}
+	if head, _ := ReadHead(root); head != "ref: refs/heads/main" {
+		t.Errorf("HEAD should be intact, got %q", head)
+	}
+	if _, err := os.Stat(filepath.Join(repo.GogitPath(root), "config")); err != nil {
+		t.Error("config should be intact")
+	}
+}
+
+func TestUpdateHead_Locked(t *testing.T) {
+	root := setupRefsDir(t)
+	os.WriteFile(repo.HeadPath(root), []byte("ref: refs/heads/main\n"), 0644)
+	os.WriteFile(repo.HeadPath(root)+".lock", nil, 0644)
+	if err := UpdateHead(root, "ref: refs/heads/other", "test"); err == nil {
+		t.Fatal("expected error when HEAD is locked")
+	}
+	head, _ := ReadHead(root)
+	if head != "ref: refs/heads/main" {
+		t.Errorf("HEAD should be unchanged, got %s", head)
+	}
+}
+
+func TestUpdateRef_InvalidName(t *testing.T) {
+	root := setupRefsDir(t)
+
+	err := UpdateRef(root, "refs/heads/a..b", "aaa", "", "test")
+	if err == nil || !strings.Contains(err.Error(), "not a valid ref name") {
+		t.Fatalf("expected invalid name error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.RefsPath(root), "heads", "a..b")); !os.IsNotExist(err) {
+		t.Error("invalid ref should not be written")
+	}
+}
+
+func TestUpdateRef_DirectoryFileConflict(t *testing.T) {
+	root := setupRefsDir(t)
+	WriteRef(root, "refs/heads/foo", "aaa", "test")
+
+	err := UpdateRef(root, "refs/heads/foo/bar", "bbb", "", "test")
+	if err == nil || !strings.Contains(err.Error(), "'refs/heads/foo' exists; cannot create 'refs/heads/foo/bar'") {
+		t.Errorf("expected conflict with parent ref, got %v", err)
+	}
+
+	WriteRef(root, "refs/heads/baz/qux", "aaa", "test")
+	err = UpdateRef(root, "refs/heads/baz", "bbb", "", "test")
+	if err == nil || !strings.Contains(err.Error(), "'refs/heads/baz/qux' exists; cannot create 'refs/heads/baz'") {
+		t.Errorf("expected conflict with ref below, got %v", err)
+	}
+
+	os.MkdirAll(filepath.Join(repo.RefsPath(root), "heads", "empty"), 0755)
+	err = UpdateRef(root, "refs/heads/empty", "bbb", "", "test")
+	if err == nil || !strings.Contains(err.Error(), "directory in the way") {
+		t.Errorf("expected directory in the way error, got %v", err)
+	}
+}
+
+func TestTransaction_DirectoryFileConflictInTransaction(t *testing.T) {
+	root := setupRefsDir(t)
+
+	tx := NewTransaction(root)
+	tx.Update("refs/heads/foo", "aaa", "", "test")
+	tx.Update("refs/heads/foo/bar", "bbb", "", "test")
+	err := tx.Commit()
+	if err == nil || !strings.Contains(err.Error(), "would also be created") {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+	if hash, _ := ReadRef(root, "refs/heads/foo"); hash != "" {
+		t.Error("no ref should have been written")
+	}
+}
+
+func TestDeleteRef_PrunesEmptyDirectories(t *testing.T) {
+	root := setupRefsDir(t)
+	WriteRef(root, "refs/heads/foo/bar", "aaa", "test")
+
+	if err := DeleteRef(root, "refs/heads/foo/bar", "aaa", "test"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.RefsPath(root), "heads", "foo")); !os.IsNotExist(err) {
+		t.Error("empty ref directory should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(repo.LogsPath(root), "refs", "heads", "foo")); !os.IsNotExist(err) {
+		t.Error("empty reflog directory should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(repo.RefsPath(root), "heads")); err != nil {
+		t.Error("refs/heads itself must be kept")
+	}
+	if err := UpdateRef(root, "refs/heads/foo", "bbb", ZeroHash, "test"); err != nil {
+		t.Errorf("expected foo to be creatable after deleting foo/bar: %v", err)
+	}
+}
+
+func TestRenameRef(t *testing.T) {
+	root := setupRefsDir(t)
+	WriteRef(root, "refs/heads/old", "aaa", "create")
+	WriteRef(root, "refs/heads/old", "bbb", "advance")
+	UpdateHead(root, "ref: refs/heads/old", "test")
+
+	if err := RenameRef(root, "refs/heads/old", "refs/heads/new", false, "rename"); err != nil {
+		t.Fatalf("RenameRef failed: %v", err)
+	}
+	if hash, _ := ReadRef(root, "refs/heads/old"); hash != "" {
+		t.Error("old ref should be gone")
+	}
+	if hash, _ := ReadRef(root, "refs/heads/new"); hash != "bbb" {
+		t.Errorf("expected new ref at bbb, got %q", hash)
+	}
+	if head, _ := ReadHead(root); head != "ref: refs/heads/new" {
+		t.Errorf("HEAD should follow the rename, got %q", head)
+	}
+	log, _ := ReadReflog(root, "refs/heads/new")
+	if len(log) != 3 || log[0].Message != "create" || log[2].Message != "rename" || log[2].Old != "bbb" {
+		t.Errorf("expected reflog moved with rename entry appended, got %+v", log)
+	}
+	if _, err := os.Stat(ReflogPath(root, "refs/heads/old")); !os.IsNotExist(err) {
+		t.Error("old reflog should be removed")
+	}
+}
+
+func TestRenameRef_ToParentPath(t *testing.T) {
+	root := setupRefsDir(t)
+	WriteRef(root, "refs/heads/foo/bar", "aaa", "test")
+
+	if err := RenameRef(root, "refs/heads/foo/bar", "refs/heads/foo", false, "rename"); err != nil {
+		t.Fatalf("RenameRef failed: %v", err)
+	}
+	if hash, _ := ReadRef(root, "refs/heads/foo"); hash != "aaa" {
+		t.Errorf("expected foo at aaa, got %q", hash)
+	}
+}
+
+func TestRenameRef_Errors(t *testing.T) {
+	root := setupRefsDir(t)
+	WriteRef(root, "refs/heads/a", "aaa", "one")
+	WriteRef(root, "refs/heads/b", "bbb", "two")
+
+	if err := RenameRef(root, "refs/heads/a", "refs/heads/a", false, "r"); err == nil {
+		t.Error("expected error renaming to itself")
+	}
+	if err := RenameRef(root, "refs/heads/a", "refs/heads/x..y", false, "r"); err == nil {
+		t.Error("expected error for invalid name")
+	}
+	if err := RenameRef(root, "refs/heads/missing", "refs/heads/c", false, "r"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	err := RenameRef(root, "refs/heads/a", "refs/heads/b", false, "r")
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected already exists error, got %v", err)
+	}
+	if hash, _ := ReadRef(root, "refs/heads/a"); hash != "aaa" {
+		t.Errorf("old ref should be restored, got %q", hash)
+	}
+	if log, _ := ReadReflog(root, "refs/heads/a"); len(log) != 1 || log[0].Message != "one" {
+		t.Errorf("old reflog should be restored, got %+v", log)
+	}
+
+	if err := RenameRef(root, "refs/heads/a", "refs/heads/b", true, "r"); err != nil {
+		t.Fatalf("forced rename failed: %v", err)
+	}
+	if hash, _ := ReadRef(root, "refs/heads/b"); hash != "aaa" {
+		t.Errorf("expected b overwritten with aaa, got %q", hash)
+	}
+	if log, _ := ReadReflog(root, "refs/heads/b"); len(log) != 2 || log[0].Message != "one" {
+		t.Errorf("expected b to carry a's reflog, got %+v", log)
+	}
+}
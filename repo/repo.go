@@ -43,6 +43,11 @@ func ObjectsPath(root string) string {
 	return filepath.Join(root, GogitDir, "objects")
 }
 
+// PackPath returns the path to the packfile directory.
+func PackPath(root string) string {
+	return filepath.Join(root, GogitDir, "objects", "pack")
+}
+
 // RefsPath returns the path to the refs directory.
 func RefsPath(root string) string {
 	return filepath.Join(root, GogitDir, "refs")
@@ -57,3 +62,30 @@ func HeadPath(root string) string {
 func IndexPath(root string) string {
 	return filepath.Join(root, GogitDir, "index")
 }
+
+// MergeHeadPath returns the path to the MERGE_HEAD file recording the commit
+// being merged while a conflicted merge is in progress.
+func MergeHeadPath(root string) string {
+	return filepath.Join(root, GogitDir, "MERGE_HEAD")
+}
+
+// MergeMsgPath returns the path to the prepared merge commit message.
+func MergeMsgPath(root string) string {
+	return filepath.Join(root, GogitDir, "MERGE_MSG")
+}
+
+// OrigHeadPath returns the path to ORIG_HEAD, the commit HEAD pointed at
+// before a merge started.
+func OrigHeadPath(root string) string {
+	return filepath.Join(root, GogitDir, "ORIG_HEAD")
+}
+
+// LogsPath returns the path to the directory holding reflogs.
+func LogsPath(root string) string {
+	return filepath.Join(root, GogitDir, "logs")
+}
+
+// ConfigPath returns the path to the repository's config file.
+func ConfigPath(root string) string {
+	return filepath.Join(root, GogitDir, "config")
+}
@@ -82,6 +82,14 @@ func TestObjectsPath(t *testing.T) {
 	}
 }
 
+func TestPackPath(t *testing.T) {
+	got := PackPath("/foo")
+	want := filepath.Join("/foo", GogitDir, "objects", "pack")
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
 func TestRefsPath(t *testing.T) {
 	got := RefsPath("/foo")
 	want := filepath.Join("/foo", GogitDir, "refs")
@@ -106,6 +114,27 @@ func TestIndexPath(t *testing.T) {
 	}
 }
 
+func TestLogsPath(t *testing.T) {
+	got := LogsPath("/foo")
+	want := filepath.Join("/foo", GogitDir, "logs")
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestMergeStatePaths(t *testing.T) {
+	tests := map[string]string{
+		MergeHeadPath("/foo"): filepath.Join("/foo", GogitDir, "MERGE_HEAD"),
+		MergeMsgPath("/foo"):  filepath.Join("/foo", GogitDir, "MERGE_MSG"),
+		OrigHeadPath("/foo"):  filepath.Join("/foo", GogitDir, "ORIG_HEAD"),
+	}
+	for got, want := range tests {
+		if got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+}
+
 func TestFindFrom_Success(t *testing.T) {
 	dir := resolveSymlinks(t, t.TempDir())
 	os.MkdirAll(filepath.Join(dir, GogitDir), 0755)
@@ -155,3 +184,11 @@ func TestFind_GetwdError(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
+
+func TestConfigPath(t *testing.T) {
+	got := ConfigPath("/foo")
+	want := filepath.Join("/foo", GogitDir, "config")
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}